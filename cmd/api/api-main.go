@@ -2,26 +2,87 @@ package main
 
 import (
 	"context"
-	"log"
+	"errors"
+	"flag"
+	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
+	_ "time/tzdata" // Embed the time zone database for the tz query parameter
 
 	"github.com/gin-gonic/gin"
+	"admin-statistics-api/internal/apierror"
 	"admin-statistics-api/internal/config"
 	"admin-statistics-api/internal/handler"
+	"admin-statistics-api/internal/logging"
+	"admin-statistics-api/internal/metrics"
 	"admin-statistics-api/internal/middleware"
+	"admin-statistics-api/internal/model"
 	"admin-statistics-api/internal/repository"
 	"admin-statistics-api/internal/service"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 )
 
+// commit is the git commit the binary was built from, for builds without VCS information:
+// go build -ldflags "-X main.commit=$(git rev-parse HEAD)"
+var commit string
+
 func main() {
-	// Load configuration
-	cfg := config.DefaultConfig()
+	// Load configuration from the defaults, a config file, the environment and flags
+	cfg, err := config.Load(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(0)
+	}
+	if err != nil {
+		fatal("Invalid configuration", "error", err)
+	}
+
+	// Log JSON lines to stdout, including what other packages log through slog and log.
+	// The level was validated with the rest of the configuration.
+	level, _ := logging.ParseLevel(cfg.Log.Level)
+	logger := logging.New(os.Stdout, level)
+	slog.SetDefault(logger)
+	for _, warning := range cfg.Warnings() {
+		slog.Warn("Unsafe configuration", "warning", warning)
+	}
+
+	// Export traces when an exporter is configured. Incoming and outgoing traceparent
+	// headers are handled whether or not spans are exported.
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	var spanExporter sdktrace.SpanExporter
+	switch cfg.Tracing.Exporter {
+	case "otlp":
+		endpoint := strings.TrimSuffix(cfg.Tracing.OTLPEndpoint, "/") + "/v1/traces"
+		spanExporter, err = otlptracehttp.New(context.Background(), otlptracehttp.WithEndpointURL(endpoint))
+	case "stdout":
+		spanExporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	case "none":
+	default:
+		fatal("Unknown tracing exporter", "exporter", cfg.Tracing.Exporter)
+	}
+	if err != nil {
+		fatal("Failed to create the trace exporter", "exporter", cfg.Tracing.Exporter, "error", err)
+	}
+	var tracerProvider *sdktrace.TracerProvider
+	if spanExporter != nil {
+		tracerProvider = sdktrace.NewTracerProvider(
+			sdktrace.WithBatcher(spanExporter, sdktrace.WithBatchTimeout(5*time.Second)),
+			sdktrace.WithResource(resource.NewSchemaless(attribute.String("service.name", cfg.Tracing.ServiceName))),
+		)
+		otel.SetTracerProvider(tracerProvider)
+		slog.Info("Exporting traces", "exporter", cfg.Tracing.Exporter)
+	}
 
 	// Connect to MongoDB
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
@@ -29,47 +90,253 @@ func main() {
 
 	client, err := mongo.Connect(ctx, options.Client().ApplyURI(cfg.MongoDB.URI))
 	if err != nil {
-		log.Fatalf("Failed to connect to MongoDB: %v", err)
+		fatal("Failed to connect to MongoDB", "error", err)
 	}
 	defer client.Disconnect(ctx)
 
 	// Check connection
 	err = client.Ping(ctx, nil)
 	if err != nil {
-		log.Fatalf("Failed to ping MongoDB: %v", err)
+		fatal("Failed to ping MongoDB", "error", err)
 	}
 
-	log.Println("Connected to MongoDB successfully")
+	slog.Info("Connected to MongoDB successfully")
 
 	// Initialize repositories, services, and handlers
 	db := client.Database(cfg.MongoDB.Database)
 	transactionRepo := repository.NewTransactionRepository(db, cfg.MongoDB.Collection)
-	
-	// Initialize Redis cache
-	redisCache, err := repository.NewRedisCache(cfg.Redis.URL)
-	if err != nil {
-		log.Fatalf("Failed to connect to Redis: %v", err)
+	if cfg.MongoDB.RollupCollection != "" {
+		transactionRepo.WithDailyRollups(cfg.MongoDB.RollupCollection, cfg.MongoDB.ReadRollups)
+		if err := transactionRepo.EnsureDailyRollupIndexes(ctx); err != nil {
+			slog.Warn("Failed to create the daily rollup index; rollup reads and repairs scan the collection", "error", err)
+		}
+	}
+
+	// Backfill the days whose rollups may be out of date after an insert
+	repairCtx, stopRepair := context.WithCancel(context.Background())
+	defer stopRepair()
+	if cfg.MongoDB.RollupCollection != "" {
+		go transactionRepo.RunRollupRepair(repairCtx, cfg.MongoDB.RollupRepairInterval)
 	}
-	defer redisCache.Close()
-	
-	log.Println("Connected to Redis successfully")
-	
-	// Ensure we're using the correct interface type
-	var cache repository.Cache = redisCache
 	
-	transactionService := service.NewTransactionService(transactionRepo, cache)
+	// Record query latencies, then fail fast while MongoDB keeps timing out instead of
+	// queueing requests behind it
+	var appMetrics *metrics.Metrics
+	var repo repository.TransactionRepositoryInterface = transactionRepo
+	if cfg.Metrics.Enabled {
+		appMetrics = metrics.New()
+		repo = repository.NewMetricsRepository(repo, appMetrics)
+	}
+
+	// Reports run for minutes by design, so their timeouts must not trip the breaker
+	reportRepo := repo
+
+	var mongoBreaker *repository.CircuitBreakerRepository
+	if cfg.MongoDB.BreakerThreshold > 0 {
+		mongoBreaker = repository.NewCircuitBreakerRepository(repo, cfg.MongoDB.BreakerThreshold, cfg.MongoDB.BreakerOpenFor)
+		repo = mongoBreaker
+	}
+
+	// Initialize the cache. While Redis is unreachable, results are cached in this process only.
+	memoryCache := repository.NewBoundedMemoryCache(cfg.MemoryCache.MaxEntries, cfg.MemoryCache.MaxBytes)
+	defer memoryCache.Close()
+
+	var redisCache *repository.RedisCache
+	var failoverCache *repository.FailoverCache
+	var cache repository.Cache = memoryCache
+	switch cfg.CacheBackend {
+	case "redis", "tiered":
+		redisCache, err = repository.DialRedisCache(cfg.Redis.URL)
+		if err != nil {
+			fatal("Invalid Redis URL", "error", err)
+		}
+		defer redisCache.Close()
+
+		var primary repository.Cache = redisCache
+		if cfg.CacheBackend == "tiered" {
+			primary = repository.NewTieredCache(memoryCache, redisCache, cfg.MemoryCache.L1TTL)
+		}
+		failoverCache = repository.NewFailoverCache(primary, memoryCache, redisCache.Ping, cfg.Redis.HealthInterval)
+		defer failoverCache.Close()
+		cache = failoverCache
+	case "memory":
+	default:
+		fatal("Unknown cache backend", "backend", cfg.CacheBackend)
+	}
+	if appMetrics != nil {
+		metricsCache := repository.NewMetricsCache(cache, appMetrics)
+		if redisCache != nil {
+			redisCache.WithErrorHandler(metricsCache.RecordError)
+		}
+		cache = metricsCache
+	}
+
+	transactionService := service.NewTransactionService(repo, cache)
+	transactionService.WithStaleGrace(cfg.CacheStale).WithCacheTTLs(cfg.CacheTimeout, cfg.CacheTTLs)
+	if cfg.Redis.LockQueries && redisCache != nil {
+		transactionService.WithLocker(repository.NewRedisLocker(redisCache), cfg.Redis.LockWait)
+	}
+
+	// Invalidate cached results when transactions are written outside the API
+	watchCtx, stopWatching := context.WithCancel(context.Background())
+	defer stopWatching()
+	if cfg.MongoDB.WatchInserts {
+		go transactionRepo.WatchInserts(watchCtx, func(createdAt ...time.Time) {
+			transactionService.InvalidateCache(watchCtx, createdAt...)
+		})
+	}
+
+	// Refresh the most requested results before they go stale
+	warmerCtx, stopWarmer := context.WithCancel(context.Background())
+	defer stopWarmer()
+	if cfg.CacheWarmer.Keys > 0 {
+		transactionService.WithCacheWarmer(cfg.CacheWarmer.Keys)
+		go transactionService.RunCacheWarmer(warmerCtx, cfg.CacheWarmer.Interval)
+	}
 	transactionHandler := handler.NewTransactionHandler(transactionService)
 
-	// Initialize Gin router
-	router := gin.Default()
+	// Run long reports in the background, keeping their results in MongoDB or Redis
+	var reportStore repository.ReportStore
+	switch cfg.Reports.Store {
+	case "mongo":
+		mongoReports := repository.NewMongoReportStore(db, cfg.Reports.Collection)
+		if err := mongoReports.EnsureIndexes(ctx); err != nil {
+			slog.Warn("Failed to create the report TTL index; expired reports stay stored", "error", err)
+		}
+		reportStore = mongoReports
+	case "redis":
+		if redisCache == nil {
+			fatal("The redis report store needs the redis or tiered cache backend")
+		}
+		reportStore = repository.NewRedisReportStore(redisCache)
+	default:
+		fatal("Unknown report store", "store", cfg.Reports.Store)
+	}
+
+	reportService := service.NewReportService(reportRepo, reportStore, cfg.Reports.Workers, cfg.Reports.QueueSize)
+	reportService.WithTimeout(cfg.Reports.Timeout).WithTTL(cfg.Reports.TTL)
+	reportsCtx, stopReports := context.WithCancel(context.Background())
+	defer stopReports()
+	reportsDone := make(chan struct{})
+	go func() {
+		reportService.Run(reportsCtx)
+		close(reportsDone)
+	}()
+	reportHandler := handler.NewReportHandler(reportService)
+
+	// Initialize API key storage
+	var keyStore repository.APIKeyStore
+	var keyFile *repository.FileAPIKeyStore
+	switch cfg.Auth.KeyStore {
+	case "mongo":
+		keyStore = repository.NewMongoAPIKeyStore(db, cfg.Auth.KeyCollection)
+	case "file":
+		keyFile, err = repository.NewFileAPIKeyStore(cfg.Auth.KeyFile)
+		if err != nil {
+			fatal("Failed to load API keys", "error", err)
+		}
+		keyStore = keyFile
+	default:
+		fatal("Unknown API key store", "store", cfg.Auth.KeyStore)
+	}
+
+	apiKeyService := service.NewAPIKeyService(keyStore, cfg.Auth.APIKey)
+	apiKeyHandler := handler.NewAPIKeyHandler(apiKeyService)
+
+	// Accept bearer JWTs when a secret or JWKS file is configured
+	var tokenVerifier middleware.TokenVerifier
+	if cfg.Auth.JWTEnabled() {
+		jwtService, err := service.NewJWTService(cfg.Auth)
+		if err != nil {
+			fatal("Failed to configure JWT auth", "error", err)
+		}
+		tokenVerifier = jwtService
+		slog.Info("JWT bearer auth enabled")
+	}
+
+	// Probes for the process and its dependencies; Redis is optional since the cache fails over to memory
+	healthChecks := []handler.HealthCheck{
+		{Name: "mongodb", Required: true, Check: func(ctx context.Context) error { return client.Ping(ctx, nil) }},
+	}
+	if redisCache != nil {
+		healthChecks = append(healthChecks, handler.HealthCheck{Name: "redis", Check: redisCache.Ping})
+	}
+	healthHandler := handler.NewHealthHandler(healthChecks, cfg.HTTP.ReadyTimeout, handler.ReadVersionInfo(commit))
+
+	// Initialize Gin router, logging requests with slog instead of gin's text logger
+	router := gin.New()
+
+	// Let handlers pass the request's context, which holds its span and logger, on to the service
+	router.ContextWithFallback = true
 
 	// Add middleware
-	router.Use(middleware.AuthMiddleware(cfg))
+	degradedChecks := make(map[string]func() bool)
+	if failoverCache != nil {
+		degradedChecks["cache"] = failoverCache.Healthy
+	}
+	if mongoBreaker != nil {
+		degradedChecks["mongodb"] = mongoBreaker.Healthy
+	}
+	router.Use(middleware.RequestIDMiddleware())
+	router.Use(middleware.TracingMiddleware(cfg.Tracing.ServiceName))
+	router.Use(middleware.TraceparentMiddleware())
+	router.Use(middleware.LoggingMiddleware(logger))
+	router.Use(middleware.RecoveryMiddleware())
+	if appMetrics != nil {
+		router.Use(middleware.MetricsMiddleware(appMetrics))
+	}
+	router.Use(middleware.DegradedMiddleware(degradedChecks))
+
+	// Unknown paths get the same error envelope as every other error
+	router.NoRoute(apierror.NoRoute)
+
+	// Health and version endpoints are not authenticated
+	router.GET("/healthz", healthHandler.Healthz)
+	router.GET("/readyz", healthHandler.Readyz)
+	router.GET("/version", healthHandler.Version)
+	if appMetrics != nil {
+		router.GET("/metrics", gin.WrapH(appMetrics.Handler()))
+	}
+
+	// Every other route requires an API key or token
+	api := router.Group("/", middleware.AuthMiddleware(apiKeyService, tokenVerifier))
+	// The limits are kept while rate limiting is disabled, so that a reload can enable it
+	var rateLimiter repository.RateLimiter = repository.NewMemoryRateLimiter()
+	if redisCache != nil {
+		rateLimiter = repository.NewRedisRateLimiter(redisCache)
+	}
+	rateLimits := middleware.NewRateLimits(cfg.RateLimit.Default, cfg.RateLimit.Routes)
+	if !cfg.RateLimit.Enabled {
+		rateLimits.Disable()
+	}
+	api.Use(middleware.RateLimitMiddleware(rateLimiter, rateLimits))
+
+	// Define routes, each group requiring its scope
+	stats := api.Group("/", middleware.RequireScope(model.ScopeStatsRead))
+	stats.GET("/gross_gaming_rev", transactionHandler.GetGrossGamingRevenue)
+	stats.GET("/gross_gaming_rev/series", transactionHandler.GetGrossGamingRevenueSeries)
+	stats.GET("/daily_wager_volume", transactionHandler.GetDailyWagerVolume)
+	stats.GET("/rounds/anomalies", transactionHandler.GetRoundAnomalies)
+
+	users := api.Group("/", middleware.RequireScope(model.ScopeUserRead))
+	users.GET("/user/:user_id/wager_percentile", transactionHandler.GetUserWagerPercentile)
+	users.GET("/user/:user_id/summary", transactionHandler.GetUserSummary)
+	users.GET("/leaderboard", transactionHandler.GetLeaderboard)
+
+	transactions := api.Group("/", middleware.RequireScope(model.ScopeTransactionsWrite))
+	transactions.POST("/transactions", transactionHandler.CreateTransaction)
+	transactions.POST("/transactions/batch", transactionHandler.CreateTransactionBatch)
 
-	// Define routes
-	router.GET("/gross_gaming_rev", transactionHandler.GetGrossGamingRevenue)
-	router.GET("/daily_wager_volume", transactionHandler.GetDailyWagerVolume)
-	router.GET("/user/:user_id/wager_percentile", transactionHandler.GetUserWagerPercentile)
+	// Each report type requires the scope of its endpoint, checked by the handler
+	api.POST("/reports", reportHandler.CreateReport)
+	api.GET("/reports/:id", reportHandler.GetReport)
+	api.DELETE("/reports/:id", reportHandler.CancelReport)
+
+	admin := api.Group("/admin", middleware.RequireScope(model.ScopeKeysAdmin))
+	admin.GET("/keys", apiKeyHandler.ListAPIKeys)
+	admin.POST("/keys", apiKeyHandler.CreateAPIKey)
+	admin.POST("/keys/:id/rotate", apiKeyHandler.RotateAPIKey)
+	admin.DELETE("/keys/:id", apiKeyHandler.RevokeAPIKey)
 
 	// Start HTTP server
 	server := &http.Server{
@@ -81,9 +348,25 @@ func main() {
 
 	// Start server in a goroutine
 	go func() {
-		log.Printf("Starting server on port %s", cfg.HTTP.Port)
+		slog.Info("Starting server", "port", cfg.HTTP.Port)
 		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("Failed to start server: %v", err)
+			fatal("Failed to start server", "error", err)
+		}
+	}()
+
+	// Reload the configuration on SIGHUP
+	reloader := &configReloader{
+		args:         os.Args[1:],
+		apiKeys:      apiKeyService,
+		keyFile:      keyFile,
+		transactions: transactionService,
+		rateLimits:   rateLimits,
+	}
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		for range hup {
+			reloader.reload()
 		}
 	}()
 
@@ -92,15 +375,74 @@ func main() {
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 
-	log.Println("Shutting down server...")
+	slog.Info("Shutting down server...")
 
 	// Give the server time to shutdown gracefully
 	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
 	if err := server.Shutdown(ctx); err != nil {
-		log.Fatalf("Server forced to shutdown: %v", err)
+		fatal("Server forced to shutdown", "error", err)
+	}
+
+	// Stop the report workers, which mark unfinished reports as failed
+	stopReports()
+	select {
+	case <-reportsDone:
+	case <-ctx.Done():
+		slog.Warn("Timed out marking unfinished reports as failed")
+	}
+
+	// Export the spans of the last requests
+	if tracerProvider != nil {
+		if err := tracerProvider.Shutdown(ctx); err != nil {
+			slog.Warn("Failed to export remaining spans", "error", err)
+		}
+	}
+
+	slog.Info("Server exited properly")
+}
+
+// configReloader applies a reloaded configuration to the parts of the API that can change
+// while serving: the default API key and the keys in an API key file, the cache TTLs and
+// the rate limits. Other settings take effect on restart.
+type configReloader struct {
+	args         []string
+	apiKeys      *service.APIKeyService
+	keyFile      *repository.FileAPIKeyStore // nil unless API keys are stored in a file
+	transactions *service.TransactionService
+	rateLimits   *middleware.RateLimits
+}
+
+// reload loads the configuration again and applies it, keeping the current one if it is invalid
+func (r *configReloader) reload() {
+	cfg, err := config.Load(r.args)
+	if err != nil {
+		slog.Error("Invalid configuration, keeping the current one", "error", err)
+		return
+	}
+
+	r.apiKeys.SetDefaultKey(cfg.Auth.APIKey)
+	if r.keyFile != nil {
+		if err := r.keyFile.Reload(); err != nil {
+			slog.Error("Failed to reload API keys", "error", err)
+		}
+	}
+	r.transactions.WithCacheTTLs(cfg.CacheTimeout, cfg.CacheTTLs)
+	if cfg.RateLimit.Enabled {
+		r.rateLimits.Set(cfg.RateLimit.Default, cfg.RateLimit.Routes)
+	} else {
+		r.rateLimits.Disable()
+	}
+
+	for _, warning := range cfg.Warnings() {
+		slog.Warn("Unsafe configuration", "warning", warning)
 	}
+	slog.Info("Configuration reloaded")
+}
 
-	log.Println("Server exited properly")
+// fatal logs an error and exits
+func fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
 }
\ No newline at end of file
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"log/slog"
+	"os"
+	"time"
+
+	"admin-statistics-api/internal/config"
+	"admin-statistics-api/internal/logging"
+	"admin-statistics-api/internal/repository"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func main() {
+	flags := flag.NewFlagSet("rollup", flag.ContinueOnError)
+	fromFlag := flags.String("from", "", "First day to backfill (YYYY-MM-DD); defaults to the earliest transaction")
+	toFlag := flags.String("to", "", "Last day to backfill (YYYY-MM-DD); defaults to the latest transaction")
+
+	// Load configuration from the defaults, a config file, the environment and flags
+	cfg, err := config.LoadWithFlags(flags, os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(0)
+	}
+	if err != nil {
+		fatal("Invalid configuration", "error", err)
+	}
+
+	// Log JSON lines to stdout, like the API
+	level, _ := logging.ParseLevel(cfg.Log.Level)
+	slog.SetDefault(logging.New(os.Stdout, level))
+
+	if cfg.MongoDB.RollupCollection == "" {
+		fatal("mongodb.rollup_collection (MONGODB_ROLLUP_COLLECTION) must be set")
+	}
+	from, err := parseDay(*fromFlag)
+	if err != nil {
+		fatal("Invalid -from", "error", err)
+	}
+	to, err := parseDay(*toFlag)
+	if err != nil {
+		fatal("Invalid -to", "error", err)
+	}
+
+	// Connect to MongoDB
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	defer cancel()
+
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(cfg.MongoDB.URI))
+	if err != nil {
+		fatal("Failed to connect to MongoDB", "error", err)
+	}
+	defer client.Disconnect(ctx)
+
+	// Check connection
+	err = client.Ping(ctx, nil)
+	if err != nil {
+		fatal("Failed to ping MongoDB", "error", err)
+	}
+	slog.Info("Connected to MongoDB successfully")
+
+	db := client.Database(cfg.MongoDB.Database)
+	transactionRepo := repository.NewTransactionRepository(db, cfg.MongoDB.Collection).
+		WithDailyRollups(cfg.MongoDB.RollupCollection, false)
+
+	if err := transactionRepo.EnsureDailyRollupIndexes(ctx); err != nil {
+		fatal("Failed to create the daily rollup index", "error", err)
+	}
+
+	slog.Info("Backfilling daily rollups", "collection", cfg.MongoDB.RollupCollection, "from", *fromFlag, "to", *toFlag)
+	startTime := time.Now()
+
+	if err := transactionRepo.BackfillDailyRollups(ctx, from, to); err != nil {
+		fatal("Failed to backfill daily rollups", "error", err)
+	}
+
+	slog.Info("Backfill complete", "duration_ms", time.Since(startTime).Milliseconds())
+}
+
+// parseDay parses a YYYY-MM-DD day in UTC, returning the zero time for an empty string
+func parseDay(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse("2006-01-02", value)
+}
+
+// fatal logs an error and exits
+func fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
+}
@@ -2,9 +2,12 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
 	"fmt"
 	"log"
 	"math/rand"
+	"os"
 	"time"
 
 	"admin-statistics-api/internal/config"
@@ -31,10 +34,16 @@ const (
 )
 
 func main() {
-	log.Println("Starting data seeding process...")
+	// Load configuration from the defaults, a config file, the environment and flags
+	cfg, err := config.Load(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(0)
+	}
+	if err != nil {
+		log.Fatalf("Invalid configuration: %v", err)
+	}
 
-	// Load configuration
-	cfg := config.DefaultConfig()
+	log.Println("Starting data seeding process...")
 
 	// Connect to MongoDB
 	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Second)
@@ -62,6 +71,13 @@ func main() {
 		log.Printf("Warning: Failed to drop collection: %v", err)
 	}
 
+	// Drop the daily rollups too, since they no longer match; rebuild them with cmd/rollup
+	if cfg.MongoDB.RollupCollection != "" {
+		if err := db.Collection(cfg.MongoDB.RollupCollection).Drop(ctx); err != nil {
+			log.Printf("Warning: Failed to drop rollup collection: %v", err)
+		}
+	}
+
 	// Generate user IDs
 	userIDs := generateUserIDs(numUsers)
 
@@ -75,8 +91,8 @@ func main() {
 
 	log.Printf("Generating %d game rounds for %d users...", numRounds, numUsers)
 	for i := 0; i < numRounds; i++ {
-		// Generate a random round ID
-		roundID := fmt.Sprintf("round-%d", i+1)
+		// Generate a round ID
+		roundID := model.GenerateULID()
 
 		// Choose a random user
 		userID := userIDs[rand.Intn(len(userIDs))]
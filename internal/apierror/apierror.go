@@ -0,0 +1,155 @@
+// Package apierror defines the errors the API returns to clients. Each error has a
+// machine-readable code that determines its HTTP status, and is written as a JSON
+// envelope with the code, a message, optional details and the request's ID.
+package apierror
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Code identifies the kind of an error to clients
+type Code string
+
+// Error codes
+const (
+	CodeValidation          Code = "validation_error"
+	CodeUnauthorized        Code = "unauthorized"
+	CodeForbidden           Code = "forbidden"
+	CodeNotFound            Code = "not_found"
+	CodeRateLimited         Code = "rate_limited"
+	CodeInternal            Code = "internal_error"
+	CodeUpstreamUnavailable Code = "upstream_unavailable"
+	CodeTimeout             Code = "timeout"
+)
+
+// Status returns the HTTP status code for errors with the code
+func (c Code) Status() int {
+	switch c {
+	case CodeValidation:
+		return http.StatusBadRequest
+	case CodeUnauthorized:
+		return http.StatusUnauthorized
+	case CodeForbidden:
+		return http.StatusForbidden
+	case CodeNotFound:
+		return http.StatusNotFound
+	case CodeRateLimited:
+		return http.StatusTooManyRequests
+	case CodeUpstreamUnavailable:
+		return http.StatusServiceUnavailable
+	case CodeTimeout:
+		return http.StatusGatewayTimeout
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// Detail describes one problem with a request, such as an invalid query parameter
+type Detail struct {
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message"`
+}
+
+// Error is an error returned to a client. Message and Details are shown to the client;
+// the cause is only logged.
+type Error struct {
+	Code       Code
+	Message    string
+	Details    []Detail
+	RetryAfter time.Duration // Sent as Retry-After when set
+	cause      error
+}
+
+// Error returns the message, followed by the cause if there is one
+func (e *Error) Error() string {
+	if e.cause != nil {
+		return e.Message + ": " + e.cause.Error()
+	}
+	return e.Message
+}
+
+// Unwrap returns the cause
+func (e *Error) Unwrap() error {
+	return e.cause
+}
+
+// Status returns the HTTP status code for the error
+func (e *Error) Status() int {
+	return e.Code.Status()
+}
+
+// Validation creates an error for a request that is malformed or fails validation
+func Validation(message string, details ...Detail) *Error {
+	return &Error{Code: CodeValidation, Message: message, Details: details}
+}
+
+// Unauthorized creates an error for a request without valid credentials
+func Unauthorized(message string) *Error {
+	return &Error{Code: CodeUnauthorized, Message: message}
+}
+
+// Forbidden creates an error for credentials that do not allow the request
+func Forbidden(message string) *Error {
+	return &Error{Code: CodeForbidden, Message: message}
+}
+
+// NotFound creates an error for a resource that does not exist
+func NotFound(message string) *Error {
+	return &Error{Code: CodeNotFound, Message: message}
+}
+
+// RateLimited creates an error for a client that must wait retryAfter before retrying
+func RateLimited(message string, retryAfter time.Duration) *Error {
+	return &Error{Code: CodeRateLimited, Message: message, RetryAfter: retryAfter}
+}
+
+// UpstreamUnavailable creates an error for a dependency that cannot be reached, caused
+// by err. A positive retryAfter tells the client when to retry.
+func UpstreamUnavailable(message string, retryAfter time.Duration, err error) *Error {
+	return &Error{Code: CodeUpstreamUnavailable, Message: message, RetryAfter: retryAfter, cause: err}
+}
+
+// Timeout creates an error for a request that took too long, caused by err
+func Timeout(message string, err error) *Error {
+	return &Error{Code: CodeTimeout, Message: message, cause: err}
+}
+
+// Internal creates an error for an unexpected failure caused by err
+func Internal(message string, err error) *Error {
+	return &Error{Code: CodeInternal, Message: message, cause: err}
+}
+
+// retryAfterError is implemented by errors of a dependency that is unavailable for a
+// while, such as the error of an open circuit breaker
+type retryAfterError interface {
+	error
+	RetryAfter() time.Duration
+}
+
+// From converts err to an *Error. Errors that already are one are returned as they
+// are, an error with a RetryAfter method, such as an open circuit breaker's, becomes
+// upstream_unavailable and deadlines and MongoDB timeouts become timeout. Anything else is an internal error reported with message,
+// so that the cause, such as a raw MongoDB error, never reaches the client.
+func From(err error, message string) *Error {
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		return apiErr
+	}
+
+	var retryErr retryAfterError
+	switch {
+	case errors.As(err, &retryErr):
+		return UpstreamUnavailable("Service temporarily unavailable, retry later", retryErr.RetryAfter(), err)
+	case errors.Is(err, context.DeadlineExceeded) || mongo.IsTimeout(err):
+		return Timeout("Request timed out, retry later", err)
+	case mongo.IsNetworkError(err):
+		return UpstreamUnavailable("Service temporarily unavailable, retry later", 0, err)
+	default:
+		return Internal(message, err)
+	}
+}
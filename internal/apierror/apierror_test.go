@@ -0,0 +1,142 @@
+package apierror
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"admin-statistics-api/internal/logging"
+)
+
+// circuitOpenError stands in for the error of an open circuit breaker
+type circuitOpenError struct {
+	retryAfter time.Duration
+}
+
+func (e circuitOpenError) Error() string             { return "mongodb is unavailable" }
+func (e circuitOpenError) RetryAfter() time.Duration { return e.retryAfter }
+
+func TestCodeStatus(t *testing.T) {
+	tests := map[Code]int{
+		CodeValidation:          http.StatusBadRequest,
+		CodeUnauthorized:        http.StatusUnauthorized,
+		CodeForbidden:           http.StatusForbidden,
+		CodeNotFound:            http.StatusNotFound,
+		CodeRateLimited:         http.StatusTooManyRequests,
+		CodeInternal:            http.StatusInternalServerError,
+		CodeUpstreamUnavailable: http.StatusServiceUnavailable,
+		CodeTimeout:             http.StatusGatewayTimeout,
+	}
+
+	for code, status := range tests {
+		assert.Equal(t, status, code.Status(), code)
+	}
+}
+
+func TestFrom(t *testing.T) {
+	t.Run("returns an API error as it is", func(t *testing.T) {
+		// Arrange
+		notFound := NotFound("API key not found")
+
+		// Act
+		apiErr := From(fmt.Errorf("revoking: %w", notFound), "Failed to revoke API key")
+
+		// Assert
+		assert.Same(t, notFound, apiErr)
+	})
+
+	t.Run("maps an open circuit breaker to upstream_unavailable", func(t *testing.T) {
+		// Arrange
+		openErr := circuitOpenError{retryAfter: 3 * time.Second}
+
+		// Act
+		apiErr := From(fmt.Errorf("query: %w", openErr), "Failed to calculate GGR")
+
+		// Assert
+		assert.Equal(t, CodeUpstreamUnavailable, apiErr.Code)
+		assert.Equal(t, 3*time.Second, apiErr.RetryAfter)
+		assert.NotContains(t, apiErr.Message, "mongodb")
+		assert.ErrorIs(t, apiErr, openErr)
+	})
+
+	t.Run("maps a deadline to timeout", func(t *testing.T) {
+		// Act
+		apiErr := From(fmt.Errorf("aggregate: %w", context.DeadlineExceeded), "Failed to calculate GGR")
+
+		// Assert
+		assert.Equal(t, CodeTimeout, apiErr.Code)
+		assert.Equal(t, http.StatusGatewayTimeout, apiErr.Status())
+	})
+
+	t.Run("reports any other error as internal with the message", func(t *testing.T) {
+		// Arrange
+		cause := errors.New("(Unauthorized) command aggregate requires authentication")
+
+		// Act
+		apiErr := From(cause, "Failed to calculate GGR")
+
+		// Assert
+		assert.Equal(t, CodeInternal, apiErr.Code)
+		assert.Equal(t, "Failed to calculate GGR", apiErr.Message)
+		assert.ErrorIs(t, apiErr, cause)
+	})
+}
+
+func TestAbort(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	serve := func(handler gin.HandlerFunc) (*httptest.ResponseRecorder, *gin.Context) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		req := httptest.NewRequest("GET", "/gross_gaming_rev", nil)
+		c.Request = req.WithContext(logging.ContextWithRequestID(req.Context(), "req-123"))
+		handler(c)
+		return w, c
+	}
+
+	t.Run("writes the envelope with the request ID", func(t *testing.T) {
+		// Act
+		w, c := serve(func(c *gin.Context) {
+			Abort(c, Validation("Invalid query parameters", Detail{Field: "from", Message: "is required"}))
+		})
+
+		// Assert
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.True(t, c.IsAborted())
+		assert.JSONEq(t, `{
+			"code": "validation_error",
+			"message": "Invalid query parameters",
+			"details": [{"field": "from", "message": "is required"}],
+			"requestId": "req-123"
+		}`, w.Body.String())
+	})
+
+	t.Run("keeps the cause out of the response", func(t *testing.T) {
+		// Act
+		w, c := serve(func(c *gin.Context) {
+			Abort(c, From(errors.New("connection reset by peer"), "Failed to calculate GGR"))
+		})
+
+		// Assert
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+		assert.JSONEq(t, `{"code":"internal_error","message":"Failed to calculate GGR","details":[],"requestId":"req-123"}`, w.Body.String())
+		assert.Contains(t, c.Errors.String(), "connection reset by peer", "The cause is logged with the request")
+	})
+
+	t.Run("sets Retry-After in whole seconds", func(t *testing.T) {
+		// Act
+		w, _ := serve(func(c *gin.Context) {
+			Abort(c, RateLimited("Rate limit exceeded, retry later", 1500*time.Millisecond))
+		})
+
+		// Assert
+		assert.Equal(t, http.StatusTooManyRequests, w.Code)
+		assert.Equal(t, "2", w.Header().Get("Retry-After"))
+	})
+}
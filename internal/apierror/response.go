@@ -0,0 +1,44 @@
+package apierror
+
+import (
+	"math"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"admin-statistics-api/internal/logging"
+)
+
+// Envelope is the JSON body of every error response
+type Envelope struct {
+	Code      Code     `json:"code"`
+	Message   string   `json:"message"`
+	Details   []Detail `json:"details"`
+	RequestID string   `json:"requestId"`
+}
+
+// Abort writes apiErr as an error response and stops the remaining handlers. Its cause
+// is attached to the context, so it is logged with the request but not sent to the client.
+func Abort(c *gin.Context, apiErr *Error) {
+	if apiErr.cause != nil {
+		c.Error(apiErr.cause)
+	}
+	if apiErr.RetryAfter > 0 {
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(apiErr.RetryAfter.Seconds()))))
+	}
+
+	details := apiErr.Details
+	if details == nil {
+		details = []Detail{}
+	}
+	c.AbortWithStatusJSON(apiErr.Status(), Envelope{
+		Code:      apiErr.Code,
+		Message:   apiErr.Message,
+		Details:   details,
+		RequestID: logging.RequestIDFromContext(c.Request.Context()),
+	})
+}
+
+// NoRoute handles requests for paths that no route matches
+func NoRoute(c *gin.Context) {
+	Abort(c, NotFound("No route for "+c.Request.Method+" "+c.Request.URL.Path))
+}
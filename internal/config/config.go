@@ -1,8 +1,9 @@
 package config
 
 import (
-	"os"
 	"time"
+
+	"admin-statistics-api/internal/model"
 )
 
 // Config stores application configuration
@@ -11,59 +12,199 @@ type Config struct {
 	HTTP         HTTPConfig
 	Auth         AuthConfig
 	Redis        RedisConfig
-	CacheTimeout time.Duration
+	RateLimit    RateLimitConfig
+	Metrics      MetricsConfig
+	Tracing      TracingConfig
+	Log          LogConfig
+	CacheBackend string                   // Where results are cached: "redis", "memory" or "tiered"
+	CacheTimeout time.Duration            // How long query results stay fresh in the cache
+	CacheTTLs    map[string]time.Duration // CacheTimeout by cache key prefix, e.g. "leaderboard"
+	CacheStale   time.Duration            // How long past CacheTimeout results are served while they refresh
+	CacheWarmer  CacheWarmerConfig
+	MemoryCache  MemoryCacheConfig
+	Reports      ReportConfig
 }
 
 // MongoDBConfig stores MongoDB configuration
 type MongoDBConfig struct {
-	URI        string
-	Database   string
-	Collection string
+	URI              string
+	Database         string
+	Collection       string
+	RollupCollection string // Daily rollups; empty disables them
+	ReadRollups      bool   // Serve whole days from the rollups once they are backfilled
+	WatchInserts     bool   // Invalidate cached results from a change stream; needs a replica set
+
+	RollupRepairInterval time.Duration // How often to backfill the days whose rollups may be out of date
+
+	BreakerThreshold int           // Consecutive timeouts or network errors before failing fast; 0 disables the breaker
+	BreakerOpenFor   time.Duration // How long to fail fast before trying MongoDB again
 }
 
 // HTTPConfig stores HTTP server configuration
 type HTTPConfig struct {
-	Port    string
-	Timeout time.Duration
+	Port         string
+	Timeout      time.Duration
+	ReadyTimeout time.Duration // Deadline for the dependency checks of /readyz
 }
 
 // AuthConfig stores authentication configuration
 type AuthConfig struct {
-	APIKey string
+	APIKey        string // Accepted with every scope; empty disables it
+	KeyStore      string // Where named API keys are stored: "mongo" or "file"
+	KeyCollection string // MongoDB collection for the "mongo" key store
+	KeyFile       string // JSON file for the "file" key store
+
+	// Bearer JWTs are accepted when JWTSecret or JWTJWKSFile is set
+	JWTSecret     string              // Shared secret for HS256 tokens
+	JWTJWKSFile   string              // JSON Web Key Set file with RS256 (and optionally HS256) keys
+	JWTIssuer     string              // Required "iss" claim; empty skips the check
+	JWTAudience   string              // Required "aud" claim; empty skips the check
+	JWTRolesClaim string              // Claim holding the token's roles
+	JWTRoleScopes map[string][]string // Scopes granted by each role
+	JWTClockSkew  time.Duration       // Leeway for "exp" and "nbf"
 }
 
 // RedisConfig stores Redis configuration
 type RedisConfig struct {
-	URL string
+	URL         string
+	LockQueries bool          // Coalesce cache misses across instances with a Redis lock
+	LockWait    time.Duration // How long to wait for another instance's query before running it
+
+	HealthInterval time.Duration // How often to check Redis, caching in memory while it is unreachable
+}
+
+// CacheWarmerConfig stores the settings for refreshing the most requested cache keys
+type CacheWarmerConfig struct {
+	Keys     int           // How many keys to refresh on each run; 0 disables the warmer
+	Interval time.Duration // Time between runs
+}
+
+// MemoryCacheConfig stores the bounds of the in-memory cache, used on its own by the
+// "memory" backend and in front of Redis by the "tiered" one
+type MemoryCacheConfig struct {
+	MaxEntries int           // 0 for no limit
+	MaxBytes   int64         // Estimated from the JSON size of the entries; 0 for no limit
+	L1TTL      time.Duration // How long the "tiered" backend keeps entries in memory
+}
+
+// ReportConfig stores the settings for running reports as background jobs
+type ReportConfig struct {
+	Store      string        // Where jobs and results are kept: "mongo" or "redis"
+	Collection string        // MongoDB collection for the "mongo" store
+	Workers    int           // Reports run at once by each instance
+	QueueSize  int           // Reports waiting for a worker before new ones are refused
+	Timeout    time.Duration // How long a report may run
+	TTL        time.Duration // How long a finished job and its result are kept
+}
+
+// MetricsConfig stores the settings for the Prometheus /metrics endpoint
+type MetricsConfig struct {
+	Enabled bool
+}
+
+// TracingConfig stores the settings for exporting OpenTelemetry traces
+type TracingConfig struct {
+	Exporter     string // "otlp", "stdout" or "none"
+	OTLPEndpoint string // Collector base URL for OTLP over HTTP
+	ServiceName  string
 }
 
-// DefaultConfig returns the default configuration
-func DefaultConfig() *Config {
+// LogConfig stores logging configuration
+type LogConfig struct {
+	Level string // "debug", "info", "warn" or "error"
+}
+
+// RateLimitConfig stores the request limits applied to each API key or token per route
+type RateLimitConfig struct {
+	Enabled bool
+	Default model.RateLimit            // Limit for routes without their own
+	Routes  map[string]model.RateLimit // Limits by route pattern, e.g. "/user/:user_id/wager_percentile"
+}
+
+// DefaultAPIKey is the default auth.api_key, for local development only. Anyone who has
+// read the README can use it with every scope.
+const DefaultAPIKey = "test-api-key"
+
+// defaults returns the configuration used where no file, environment variable or flag
+// sets a value
+func defaults() *Config {
 	return &Config{
 		MongoDB: MongoDBConfig{
-			URI:        getEnv("MONGODB_URI", "mongodb://localhost:27017"),
-			Database:   getEnv("MONGODB_DATABASE", "casino"),
-			Collection: getEnv("MONGODB_COLLECTION", "transactions"),
+			URI:                  "mongodb://localhost:27017",
+			Database:             "casino",
+			Collection:           "transactions",
+			RollupCollection:     "daily_stats",
+			RollupRepairInterval: time.Minute,
+			BreakerThreshold:     5,
+			BreakerOpenFor:       30 * time.Second,
 		},
 		HTTP: HTTPConfig{
-			Port:    getEnv("HTTP_PORT", "8080"),
-			Timeout: 30 * time.Second,
+			Port:         "8080",
+			Timeout:      30 * time.Second,
+			ReadyTimeout: 2 * time.Second,
 		},
 		Auth: AuthConfig{
-			APIKey: getEnv("API_KEY", "test-api-key"),
+			APIKey:        DefaultAPIKey,
+			KeyStore:      "mongo",
+			KeyCollection: "api_keys",
+			KeyFile:       "api-keys.json",
+			JWTRolesClaim: "roles",
+			JWTRoleScopes: map[string][]string{
+				"admin":   {model.ScopeStatsRead, model.ScopeUserRead, model.ScopeTransactionsWrite, model.ScopeKeysAdmin},
+				"analyst": {model.ScopeStatsRead, model.ScopeUserRead},
+				"viewer":  {model.ScopeStatsRead},
+			},
+			JWTClockSkew: 30 * time.Second,
 		},
 		Redis: RedisConfig{
-			URL: getEnv("REDIS_URL", "redis://localhost:6379/0"),
+			URL:      "redis://localhost:6379/0",
+			LockWait: 10 * time.Second,
+
+			HealthInterval: 5 * time.Second,
+		},
+		RateLimit: RateLimitConfig{
+			Enabled: true,
+			Default: model.RateLimit{Requests: 120, Period: time.Minute},
+			Routes: map[string]model.RateLimit{
+				"/user/:user_id/wager_percentile": {Requests: 20, Period: time.Minute},
+				"/leaderboard":                    {Requests: 30, Period: time.Minute},
+			},
+		},
+		Metrics: MetricsConfig{
+			Enabled: true,
 		},
+		Tracing: TracingConfig{
+			Exporter:     "none",
+			OTLPEndpoint: "http://localhost:4318",
+			ServiceName:  "admin-statistics-api",
+		},
+		Log: LogConfig{
+			Level: "info",
+		},
+		CacheBackend: "redis",
 		CacheTimeout: 5 * time.Minute,
+		CacheTTLs:    map[string]time.Duration{},
+		CacheStale:   time.Minute,
+		CacheWarmer: CacheWarmerConfig{
+			Interval: 4 * time.Minute,
+		},
+		MemoryCache: MemoryCacheConfig{
+			MaxEntries: 10000,
+			MaxBytes:   64 << 20,
+			L1TTL:      30 * time.Second,
+		},
+		Reports: ReportConfig{
+			Store:      "mongo",
+			Collection: "report_jobs",
+			Workers:    4,
+			QueueSize:  100,
+			Timeout:    10 * time.Minute,
+			TTL:        24 * time.Hour,
+		},
 	}
 }
 
-// getEnv gets an environment variable or returns a default value
-func getEnv(key, defaultValue string) string {
-	value := os.Getenv(key)
-	if value == "" {
-		return defaultValue
-	}
-	return value
-}
\ No newline at end of file
+// JWTEnabled reports whether bearer JWTs are accepted
+func (c AuthConfig) JWTEnabled() bool {
+	return c.JWTSecret != "" || c.JWTJWKSFile != ""
+}
@@ -0,0 +1,213 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// configFileEnv names the config file when the -config flag is not given
+const configFileEnv = "CONFIG_FILE"
+
+// Load builds the configuration from, in increasing precedence, the defaults, a YAML or
+// TOML config file, environment variables and the command-line flags in args, and
+// validates the result. The file is named by the -config flag or CONFIG_FILE; without
+// one only the other sources are used.
+//
+// Every setting has a key in the file, such as "mongodb.uri" for the "uri" key of the
+// "mongodb" table, an environment variable and a flag named after its key, such as
+// -mongodb-uri. Run with -h to list the flags.
+func Load(args []string) (*Config, error) {
+	return load(args, os.LookupEnv, os.Stderr)
+}
+
+// LoadWithFlags is Load for a command with flags of its own, defined on flags beforehand.
+// The setting flags are added to flags, which then parses args, so -h lists both and the
+// command reads its own flags from flags afterwards.
+func LoadWithFlags(flags *flag.FlagSet, args []string) (*Config, error) {
+	return loadFlags(flags, args, os.LookupEnv)
+}
+
+// load is Load with the environment and the destination of flag usage messages
+func load(args []string, lookupEnv func(string) (string, bool), usage io.Writer) (*Config, error) {
+	flags := flag.NewFlagSet("admin-statistics-api", flag.ContinueOnError)
+	flags.SetOutput(usage)
+	return loadFlags(flags, args, lookupEnv)
+}
+
+// loadFlags is LoadWithFlags with the environment
+func loadFlags(flags *flag.FlagSet, args []string, lookupEnv func(string) (string, bool)) (*Config, error) {
+	path := flags.String("config", "", "YAML or TOML config file (env "+configFileEnv+")")
+	values := make(map[string]*string, len(settings))
+	for _, s := range settings {
+		values[s.key] = flags.String(s.flagName(), "", fmt.Sprintf("sets %s (env %s)", s.key, s.env))
+	}
+	if err := flags.Parse(args); err != nil {
+		return nil, err
+	}
+	if flags.NArg() > 0 {
+		return nil, fmt.Errorf("unexpected arguments: %s", strings.Join(flags.Args(), " "))
+	}
+
+	c := defaults()
+
+	if *path == "" {
+		*path, _ = lookupEnv(configFileEnv)
+	}
+	if *path != "" {
+		if err := c.applyFile(*path); err != nil {
+			return nil, err
+		}
+	}
+
+	var errs []error
+	for _, s := range settings {
+		if value, ok := lookupEnv(s.env); ok && value != "" {
+			if err := s.set(c, value); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", s.env, err))
+			}
+		}
+	}
+
+	// Only flags given on the command line apply; they may set a value to empty
+	flags.Visit(func(f *flag.Flag) {
+		for _, s := range settings {
+			if s.flagName() == f.Name {
+				if err := s.set(c, *values[s.key]); err != nil {
+					errs = append(errs, fmt.Errorf("-%s: %w", f.Name, err))
+				}
+			}
+		}
+	})
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// applyFile sets the values in a YAML (.yaml or .yml) or TOML (.toml) config file
+func (c *Config) applyFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	var doc map[string]interface{}
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &doc)
+	case ".toml":
+		err = toml.Unmarshal(data, &doc)
+	default:
+		return fmt.Errorf("%s: unknown config file format %q, expected .yaml, .yml or .toml", path, ext)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	values := make(map[string]string)
+	if err := flattenFile("", doc, values); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	var errs []error
+	for _, s := range settings {
+		if value, ok := values[s.key]; ok {
+			if err := s.set(c, value); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %s: %w", path, s.key, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// flattenFile collects the values of a decoded config file by setting key, converting
+// them to the form the setting takes from an environment variable. Unknown keys are
+// rejected so that typos do not go unnoticed.
+func flattenFile(prefix string, doc map[string]interface{}, values map[string]string) error {
+	for _, name := range sortedKeys(doc) {
+		key := prefix + name
+		if isSettingKey(key) {
+			value, err := fileValue(doc[name])
+			if err != nil {
+				return fmt.Errorf("%s: %w", key, err)
+			}
+			values[key] = value
+			continue
+		}
+
+		table, ok := doc[name].(map[string]interface{})
+		if !ok || !isSettingPrefix(key+".") {
+			return fmt.Errorf("unknown setting %s", key)
+		}
+		if err := flattenFile(key+".", table, values); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fileValue converts a value from a config file to a string. Lists are joined with
+// commas and tables are written as "name=value;name=value", so that rate limits, for
+// example, can be written as a table of route to limit.
+func fileValue(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case []interface{}:
+		items := make([]string, len(v))
+		for i, item := range v {
+			s, err := fileValue(item)
+			if err != nil {
+				return "", err
+			}
+			items[i] = s
+		}
+		return strings.Join(items, ","), nil
+	case map[string]interface{}:
+		entries := make([]string, 0, len(v))
+		for _, name := range sortedKeys(v) {
+			s, err := fileValue(v[name])
+			if err != nil {
+				return "", err
+			}
+			entries = append(entries, name+"="+s)
+		}
+		return strings.Join(entries, ";"), nil
+	case bool, int, int64, uint64, float64:
+		return fmt.Sprint(v), nil
+	case nil:
+		return "", nil
+	default:
+		return "", fmt.Errorf("unsupported value %v", v)
+	}
+}
+
+func isSettingKey(key string) bool {
+	for _, s := range settings {
+		if s.key == key {
+			return true
+		}
+	}
+	return false
+}
+
+func isSettingPrefix(prefix string) bool {
+	for _, s := range settings {
+		if strings.HasPrefix(s.key, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,172 @@
+package config
+
+import (
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"admin-statistics-api/internal/model"
+)
+
+// env returns a lookup function for the given environment
+func env(vars map[string]string) func(string) (string, bool) {
+	return func(key string) (string, bool) {
+		value, ok := vars[key]
+		return value, ok
+	}
+}
+
+// writeFile writes a config file in a temporary directory and returns its path
+func writeFile(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	assert.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestLoad(t *testing.T) {
+	t.Run("uses the defaults", func(t *testing.T) {
+		// Act
+		cfg, err := load(nil, env(nil), io.Discard)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, defaults(), cfg)
+	})
+
+	t.Run("reads a YAML file", func(t *testing.T) {
+		// Arrange
+		path := writeFile(t, "config.yaml", `
+mongodb:
+  uri: mongodb://mongo:27017
+  breaker_threshold: 3
+http:
+  timeout: 45s
+rate_limit:
+  default: 60/1m
+  routes:
+    /leaderboard: 10/1m
+cache:
+  timeout: 2m
+  ttls:
+    leaderboard: 30s
+auth:
+  jwt_role_scopes:
+    viewer: [stats:read]
+`)
+
+		// Act
+		cfg, err := load([]string{"-config", path}, env(nil), io.Discard)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, "mongodb://mongo:27017", cfg.MongoDB.URI)
+		assert.Equal(t, 3, cfg.MongoDB.BreakerThreshold)
+		assert.Equal(t, 45*time.Second, cfg.HTTP.Timeout)
+		assert.Equal(t, model.RateLimit{Requests: 60, Period: time.Minute}, cfg.RateLimit.Default)
+		assert.Equal(t, map[string]model.RateLimit{"/leaderboard": {Requests: 10, Period: time.Minute}}, cfg.RateLimit.Routes)
+		assert.Equal(t, 2*time.Minute, cfg.CacheTimeout)
+		assert.Equal(t, map[string]time.Duration{"leaderboard": 30 * time.Second}, cfg.CacheTTLs)
+		assert.Equal(t, map[string][]string{"viewer": {"stats:read"}}, cfg.Auth.JWTRoleScopes)
+		assert.Equal(t, "casino", cfg.MongoDB.Database, "Settings missing from the file keep their defaults")
+	})
+
+	t.Run("reads a TOML file named by CONFIG_FILE", func(t *testing.T) {
+		path := writeFile(t, "config.toml", `
+[redis]
+url = "redis://cache:6379/1"
+lock_queries = true
+
+[cache]
+max_bytes = 1048576
+ttls = { percentile = "10m" }
+`)
+
+		cfg, err := load(nil, env(map[string]string{"CONFIG_FILE": path}), io.Discard)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "redis://cache:6379/1", cfg.Redis.URL)
+		assert.True(t, cfg.Redis.LockQueries)
+		assert.Equal(t, int64(1048576), cfg.MemoryCache.MaxBytes)
+		assert.Equal(t, map[string]time.Duration{"percentile": 10 * time.Minute}, cfg.CacheTTLs)
+	})
+
+	t.Run("applies environment variables over the file and flags over both", func(t *testing.T) {
+		path := writeFile(t, "config.yaml", "http:\n  port: \"8081\"\n  timeout: 10s\nauth:\n  api_key: from-file\n")
+		vars := map[string]string{"HTTP_PORT": "8082", "API_KEY": "from-env", "HTTP_TIMEOUT": ""}
+
+		cfg, err := load([]string{"-config", path, "-http-port", "8083", "-auth-api-key="}, env(vars), io.Discard)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "8083", cfg.HTTP.Port)
+		assert.Equal(t, 10*time.Second, cfg.HTTP.Timeout, "Empty environment variables are ignored")
+		assert.Empty(t, cfg.Auth.APIKey, "Flags can set values to empty")
+	})
+
+	t.Run("rejects unknown file settings", func(t *testing.T) {
+		path := writeFile(t, "config.yaml", "mongodb:\n  urii: mongodb://mongo:27017\n")
+
+		_, err := load([]string{"-config", path}, env(nil), io.Discard)
+
+		assert.ErrorContains(t, err, "unknown setting mongodb.urii")
+	})
+
+	t.Run("rejects unknown file formats", func(t *testing.T) {
+		path := writeFile(t, "config.json", "{}")
+
+		_, err := load([]string{"-config", path}, env(nil), io.Discard)
+
+		assert.ErrorContains(t, err, "unknown config file format")
+	})
+
+	t.Run("reports every invalid value", func(t *testing.T) {
+		vars := map[string]string{"CACHE_STALE": "soon", "RATE_LIMIT_ROUTES": "/leaderboard=lots"}
+
+		_, err := load([]string{"-redis-lock-queries", "maybe"}, env(vars), io.Discard)
+
+		assert.ErrorContains(t, err, "CACHE_STALE")
+		assert.ErrorContains(t, err, "RATE_LIMIT_ROUTES")
+		assert.ErrorContains(t, err, "-redis-lock-queries")
+	})
+
+	t.Run("validates the result", func(t *testing.T) {
+		_, err := load([]string{"-cache-timeout", "-5m"}, env(nil), io.Discard)
+
+		assert.ErrorContains(t, err, "cache.timeout: must be a positive duration")
+	})
+
+	t.Run("returns flag.ErrHelp for -h", func(t *testing.T) {
+		_, err := load([]string{"-h"}, env(nil), io.Discard)
+
+		assert.ErrorIs(t, err, flag.ErrHelp)
+	})
+}
+
+func TestLoadWithFlags(t *testing.T) {
+	// Arrange
+	flags := flag.NewFlagSet("rollup", flag.ContinueOnError)
+	from := flags.String("from", "", "First day")
+
+	// Act
+	cfg, err := loadFlags(flags, []string{"-from", "2023-01-01", "-http-port", "9090"}, env(nil))
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, "2023-01-01", *from)
+	assert.Equal(t, "9090", cfg.HTTP.Port)
+}
+
+func TestSettings(t *testing.T) {
+	keys := make(map[string]bool)
+	flags := make(map[string]bool)
+	envs := make(map[string]bool)
+	for _, s := range settings {
+		assert.False(t, keys[s.key], "Duplicate key %s", s.key)
+		assert.False(t, flags[s.flagName()], "Duplicate flag %s", s.flagName())
+		assert.False(t, envs[s.env], "Duplicate environment variable %s", s.env)
+		keys[s.key], flags[s.flagName()], envs[s.env] = true, true, true
+	}
+}
@@ -0,0 +1,237 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"admin-statistics-api/internal/model"
+)
+
+// setting is a configuration value that can be set from the config file, an
+// environment variable or a command-line flag
+type setting struct {
+	key string // Key in the config file, e.g. "mongodb.uri"
+	env string // Environment variable, e.g. "MONGODB_URI"
+	set func(c *Config, value string) error
+}
+
+// flagName returns the setting's command-line flag, its key with dashes, e.g. "mongodb-uri"
+func (s setting) flagName() string {
+	return strings.NewReplacer(".", "-", "_", "-").Replace(s.key)
+}
+
+// settings lists everything that can be configured
+var settings = []setting{
+	stringSetting("mongodb.uri", "MONGODB_URI", func(c *Config) *string { return &c.MongoDB.URI }),
+	stringSetting("mongodb.database", "MONGODB_DATABASE", func(c *Config) *string { return &c.MongoDB.Database }),
+	stringSetting("mongodb.collection", "MONGODB_COLLECTION", func(c *Config) *string { return &c.MongoDB.Collection }),
+	stringSetting("mongodb.rollup_collection", "MONGODB_ROLLUP_COLLECTION", func(c *Config) *string { return &c.MongoDB.RollupCollection }),
+	boolSetting("mongodb.read_rollups", "MONGODB_READ_ROLLUPS", func(c *Config) *bool { return &c.MongoDB.ReadRollups }),
+	durationSetting("mongodb.rollup_repair_interval", "MONGODB_ROLLUP_REPAIR_INTERVAL", func(c *Config) *time.Duration { return &c.MongoDB.RollupRepairInterval }),
+	boolSetting("mongodb.watch_inserts", "MONGODB_WATCH_INSERTS", func(c *Config) *bool { return &c.MongoDB.WatchInserts }),
+	intSetting("mongodb.breaker_threshold", "MONGODB_BREAKER_THRESHOLD", func(c *Config) *int { return &c.MongoDB.BreakerThreshold }),
+	durationSetting("mongodb.breaker_open", "MONGODB_BREAKER_OPEN", func(c *Config) *time.Duration { return &c.MongoDB.BreakerOpenFor }),
+
+	stringSetting("http.port", "HTTP_PORT", func(c *Config) *string { return &c.HTTP.Port }),
+	durationSetting("http.timeout", "HTTP_TIMEOUT", func(c *Config) *time.Duration { return &c.HTTP.Timeout }),
+	durationSetting("http.ready_timeout", "READY_TIMEOUT", func(c *Config) *time.Duration { return &c.HTTP.ReadyTimeout }),
+
+	stringSetting("auth.api_key", "API_KEY", func(c *Config) *string { return &c.Auth.APIKey }),
+	stringSetting("auth.key_store", "API_KEY_STORE", func(c *Config) *string { return &c.Auth.KeyStore }),
+	stringSetting("auth.key_collection", "API_KEY_COLLECTION", func(c *Config) *string { return &c.Auth.KeyCollection }),
+	stringSetting("auth.key_file", "API_KEY_FILE", func(c *Config) *string { return &c.Auth.KeyFile }),
+	stringSetting("auth.jwt_secret", "JWT_SECRET", func(c *Config) *string { return &c.Auth.JWTSecret }),
+	stringSetting("auth.jwt_jwks_file", "JWT_JWKS_FILE", func(c *Config) *string { return &c.Auth.JWTJWKSFile }),
+	stringSetting("auth.jwt_issuer", "JWT_ISSUER", func(c *Config) *string { return &c.Auth.JWTIssuer }),
+	stringSetting("auth.jwt_audience", "JWT_AUDIENCE", func(c *Config) *string { return &c.Auth.JWTAudience }),
+	stringSetting("auth.jwt_roles_claim", "JWT_ROLES_CLAIM", func(c *Config) *string { return &c.Auth.JWTRolesClaim }),
+	{key: "auth.jwt_role_scopes", env: "JWT_ROLE_SCOPES", set: func(c *Config, value string) error {
+		roleScopes, err := parseRoleScopes(value)
+		if err == nil {
+			c.Auth.JWTRoleScopes = roleScopes
+		}
+		return err
+	}},
+	durationSetting("auth.jwt_clock_skew", "JWT_CLOCK_SKEW", func(c *Config) *time.Duration { return &c.Auth.JWTClockSkew }),
+
+	stringSetting("redis.url", "REDIS_URL", func(c *Config) *string { return &c.Redis.URL }),
+	boolSetting("redis.lock_queries", "REDIS_LOCK_QUERIES", func(c *Config) *bool { return &c.Redis.LockQueries }),
+	durationSetting("redis.lock_wait", "REDIS_LOCK_WAIT", func(c *Config) *time.Duration { return &c.Redis.LockWait }),
+	durationSetting("redis.health_interval", "REDIS_HEALTH_INTERVAL", func(c *Config) *time.Duration { return &c.Redis.HealthInterval }),
+
+	boolSetting("rate_limit.enabled", "RATE_LIMIT_ENABLED", func(c *Config) *bool { return &c.RateLimit.Enabled }),
+	{key: "rate_limit.default", env: "RATE_LIMIT_DEFAULT", set: func(c *Config, value string) error {
+		limit, err := model.ParseRateLimit(value)
+		if err == nil {
+			c.RateLimit.Default = limit
+		}
+		return err
+	}},
+	{key: "rate_limit.routes", env: "RATE_LIMIT_ROUTES", set: func(c *Config, value string) error {
+		limits, err := parseRouteRateLimits(value)
+		if err == nil {
+			c.RateLimit.Routes = limits
+		}
+		return err
+	}},
+
+	boolSetting("metrics.enabled", "METRICS_ENABLED", func(c *Config) *bool { return &c.Metrics.Enabled }),
+
+	stringSetting("tracing.exporter", "TRACING_EXPORTER", func(c *Config) *string { return &c.Tracing.Exporter }),
+	stringSetting("tracing.otlp_endpoint", "OTEL_EXPORTER_OTLP_ENDPOINT", func(c *Config) *string { return &c.Tracing.OTLPEndpoint }),
+	stringSetting("tracing.service_name", "OTEL_SERVICE_NAME", func(c *Config) *string { return &c.Tracing.ServiceName }),
+
+	stringSetting("log.level", "LOG_LEVEL", func(c *Config) *string { return &c.Log.Level }),
+
+	stringSetting("cache.backend", "CACHE_BACKEND", func(c *Config) *string { return &c.CacheBackend }),
+	durationSetting("cache.timeout", "CACHE_TIMEOUT", func(c *Config) *time.Duration { return &c.CacheTimeout }),
+	{key: "cache.ttls", env: "CACHE_TTLS", set: func(c *Config, value string) error {
+		ttls, err := parseCacheTTLs(value)
+		if err == nil {
+			c.CacheTTLs = ttls
+		}
+		return err
+	}},
+	durationSetting("cache.stale", "CACHE_STALE", func(c *Config) *time.Duration { return &c.CacheStale }),
+	intSetting("cache.warmer_keys", "CACHE_WARMER_KEYS", func(c *Config) *int { return &c.CacheWarmer.Keys }),
+	durationSetting("cache.warmer_interval", "CACHE_WARMER_INTERVAL", func(c *Config) *time.Duration { return &c.CacheWarmer.Interval }),
+	intSetting("cache.max_entries", "CACHE_MAX_ENTRIES", func(c *Config) *int { return &c.MemoryCache.MaxEntries }),
+	{key: "cache.max_bytes", env: "CACHE_MAX_BYTES", set: func(c *Config, value string) error {
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err == nil {
+			c.MemoryCache.MaxBytes = n
+		}
+		return err
+	}},
+	durationSetting("cache.l1_ttl", "CACHE_L1_TTL", func(c *Config) *time.Duration { return &c.MemoryCache.L1TTL }),
+
+	stringSetting("reports.store", "REPORT_STORE", func(c *Config) *string { return &c.Reports.Store }),
+	stringSetting("reports.collection", "REPORT_COLLECTION", func(c *Config) *string { return &c.Reports.Collection }),
+	intSetting("reports.workers", "REPORT_WORKERS", func(c *Config) *int { return &c.Reports.Workers }),
+	intSetting("reports.queue_size", "REPORT_QUEUE_SIZE", func(c *Config) *int { return &c.Reports.QueueSize }),
+	durationSetting("reports.timeout", "REPORT_TIMEOUT", func(c *Config) *time.Duration { return &c.Reports.Timeout }),
+	durationSetting("reports.ttl", "REPORT_TTL", func(c *Config) *time.Duration { return &c.Reports.TTL }),
+}
+
+func stringSetting(key, env string, field func(c *Config) *string) setting {
+	return setting{key: key, env: env, set: func(c *Config, value string) error {
+		*field(c) = value
+		return nil
+	}}
+}
+
+func boolSetting(key, env string, field func(c *Config) *bool) setting {
+	return setting{key: key, env: env, set: func(c *Config, value string) error {
+		b, err := strconv.ParseBool(value)
+		if err == nil {
+			*field(c) = b
+		}
+		return err
+	}}
+}
+
+func intSetting(key, env string, field func(c *Config) *int) setting {
+	return setting{key: key, env: env, set: func(c *Config, value string) error {
+		n, err := strconv.Atoi(value)
+		if err == nil {
+			*field(c) = n
+		}
+		return err
+	}}
+}
+
+// durationSetting sets a duration written like "90s" or "5m"
+func durationSetting(key, env string, field func(c *Config) *time.Duration) setting {
+	return setting{key: key, env: env, set: func(c *Config, value string) error {
+		d, err := time.ParseDuration(value)
+		if err == nil {
+			*field(c) = d
+		}
+		return err
+	}}
+}
+
+// parseList splits entries written as "name=value;name=value", rejecting entries
+// without a name or value. Empty entries are skipped.
+func parseList(value string) (map[string]string, error) {
+	entries := make(map[string]string)
+	for _, entry := range strings.Split(value, ";") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		name, v, found := strings.Cut(entry, "=")
+		name, v = strings.TrimSpace(name), strings.TrimSpace(v)
+		if !found || name == "" || v == "" {
+			return nil, fmt.Errorf("entry %q is not name=value", entry)
+		}
+		entries[name] = v
+	}
+	return entries, nil
+}
+
+// parseRoleScopes parses role to scope mappings written as "role=scope,scope;role=scope"
+func parseRoleScopes(value string) (map[string][]string, error) {
+	entries, err := parseList(value)
+	if err != nil {
+		return nil, err
+	}
+
+	roleScopes := make(map[string][]string, len(entries))
+	for role, scopes := range entries {
+		for _, scope := range strings.Split(scopes, ",") {
+			if scope = strings.TrimSpace(scope); scope != "" {
+				roleScopes[role] = append(roleScopes[role], scope)
+			}
+		}
+	}
+	return roleScopes, nil
+}
+
+// parseRouteRateLimits parses per-route limits written as "route=100/1m;route=10/1s"
+func parseRouteRateLimits(value string) (map[string]model.RateLimit, error) {
+	entries, err := parseList(value)
+	if err != nil {
+		return nil, err
+	}
+
+	limits := make(map[string]model.RateLimit, len(entries))
+	for route, limit := range entries {
+		parsed, err := model.ParseRateLimit(limit)
+		if err != nil {
+			return nil, fmt.Errorf("route %s: %w", route, err)
+		}
+		limits[route] = parsed
+	}
+	return limits, nil
+}
+
+// parseCacheTTLs parses cache TTLs by key prefix written as "leaderboard=1m;ggr=10m"
+func parseCacheTTLs(value string) (map[string]time.Duration, error) {
+	entries, err := parseList(value)
+	if err != nil {
+		return nil, err
+	}
+
+	ttls := make(map[string]time.Duration, len(entries))
+	for prefix, ttl := range entries {
+		parsed, err := time.ParseDuration(ttl)
+		if err != nil {
+			return nil, fmt.Errorf("prefix %s: %w", prefix, err)
+		}
+		ttls[prefix] = parsed
+	}
+	return ttls, nil
+}
+
+// sortedKeys returns the keys of m in order
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
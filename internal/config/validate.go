@@ -0,0 +1,181 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"admin-statistics-api/internal/logging"
+	"admin-statistics-api/internal/model"
+)
+
+// cacheKeyPrefixes are the prefixes of the service's cache keys, one per query, which
+// CacheTTLs may set
+var cacheKeyPrefixes = []string{"ggr", "ggr_series", "daily_wager", "percentile", "user_summary", "leaderboard", "round_anomalies"}
+
+// Validate reports every invalid setting
+func (c *Config) Validate() error {
+	v := &validator{}
+
+	v.url("mongodb.uri", c.MongoDB.URI, "mongodb", "mongodb+srv")
+	v.required("mongodb.database", c.MongoDB.Database)
+	v.required("mongodb.collection", c.MongoDB.Collection)
+	if c.MongoDB.RollupCollection != "" {
+		v.positive("mongodb.rollup_repair_interval", c.MongoDB.RollupRepairInterval)
+	}
+	v.atLeast("mongodb.breaker_threshold", c.MongoDB.BreakerThreshold, 0)
+	if c.MongoDB.BreakerThreshold > 0 {
+		v.positive("mongodb.breaker_open", c.MongoDB.BreakerOpenFor)
+	}
+
+	if port, err := strconv.Atoi(c.HTTP.Port); err != nil || port < 1 || port > 65535 {
+		v.add("http.port", "must be a port number, got %q", c.HTTP.Port)
+	}
+	v.positive("http.timeout", c.HTTP.Timeout)
+	v.positive("http.ready_timeout", c.HTTP.ReadyTimeout)
+
+	if c.Auth.APIKey != strings.TrimSpace(c.Auth.APIKey) {
+		v.add("auth.api_key", "must not start or end with whitespace")
+	}
+	v.oneOf("auth.key_store", c.Auth.KeyStore, "mongo", "file")
+	if c.Auth.KeyStore == "mongo" {
+		v.required("auth.key_collection", c.Auth.KeyCollection)
+	}
+	if c.Auth.KeyStore == "file" {
+		v.required("auth.key_file", c.Auth.KeyFile)
+	}
+	if c.Auth.JWTEnabled() {
+		v.required("auth.jwt_roles_claim", c.Auth.JWTRolesClaim)
+	}
+	for _, role := range sortedKeys(c.Auth.JWTRoleScopes) {
+		for _, scope := range c.Auth.JWTRoleScopes[role] {
+			if !slices.Contains(model.AllScopes, scope) {
+				v.add("auth.jwt_role_scopes", "role %s has unknown scope %q", role, scope)
+			}
+		}
+	}
+	v.atLeastDuration("auth.jwt_clock_skew", c.Auth.JWTClockSkew, 0)
+
+	v.oneOf("cache.backend", c.CacheBackend, "redis", "memory", "tiered")
+	if c.CacheBackend != "memory" {
+		v.url("redis.url", c.Redis.URL, "redis", "rediss", "unix")
+	}
+	v.positive("redis.lock_wait", c.Redis.LockWait)
+	v.positive("redis.health_interval", c.Redis.HealthInterval)
+
+	for _, route := range sortedKeys(c.RateLimit.Routes) {
+		if !strings.HasPrefix(route, "/") {
+			v.add("rate_limit.routes", "route %q must start with /", route)
+		}
+	}
+
+	v.oneOf("tracing.exporter", c.Tracing.Exporter, "otlp", "stdout", "none")
+	if c.Tracing.Exporter == "otlp" {
+		v.url("tracing.otlp_endpoint", c.Tracing.OTLPEndpoint, "http", "https")
+		v.required("tracing.service_name", c.Tracing.ServiceName)
+	}
+
+	if _, err := logging.ParseLevel(c.Log.Level); err != nil {
+		v.add("log.level", "must be debug, info, warn or error, got %q", c.Log.Level)
+	}
+
+	v.positive("cache.timeout", c.CacheTimeout)
+	for _, prefix := range sortedKeys(c.CacheTTLs) {
+		if !slices.Contains(cacheKeyPrefixes, prefix) {
+			v.add("cache.ttls", "unknown cache key prefix %q, expected one of %s", prefix, strings.Join(cacheKeyPrefixes, ", "))
+		}
+		v.positive("cache.ttls "+prefix, c.CacheTTLs[prefix])
+	}
+	v.atLeastDuration("cache.stale", c.CacheStale, 0)
+	v.atLeast("cache.warmer_keys", c.CacheWarmer.Keys, 0)
+	if c.CacheWarmer.Keys > 0 {
+		v.positive("cache.warmer_interval", c.CacheWarmer.Interval)
+	}
+	v.atLeast("cache.max_entries", c.MemoryCache.MaxEntries, 0)
+	if c.MemoryCache.MaxBytes < 0 {
+		v.add("cache.max_bytes", "must not be negative")
+	}
+	if c.CacheBackend == "tiered" {
+		v.positive("cache.l1_ttl", c.MemoryCache.L1TTL)
+	}
+
+	v.oneOf("reports.store", c.Reports.Store, "mongo", "redis")
+	if c.Reports.Store == "mongo" {
+		v.required("reports.collection", c.Reports.Collection)
+	}
+	if c.Reports.Store == "redis" && c.CacheBackend == "memory" {
+		v.add("reports.store", "redis needs the redis or tiered cache backend")
+	}
+	v.atLeast("reports.workers", c.Reports.Workers, 1)
+	v.atLeast("reports.queue_size", c.Reports.QueueSize, 1)
+	v.positive("reports.timeout", c.Reports.Timeout)
+	v.positive("reports.ttl", c.Reports.TTL)
+
+	return errors.Join(v.errs...)
+}
+
+// Warnings reports valid settings that are unsafe outside local development
+func (c *Config) Warnings() []string {
+	var warnings []string
+	if c.Auth.APIKey == DefaultAPIKey {
+		warnings = append(warnings, "auth.api_key: the default key "+DefaultAPIKey+" has every scope; set API_KEY, or set it to empty to disable it")
+	}
+	return warnings
+}
+
+// validator collects the problems found by Validate
+type validator struct {
+	errs []error
+}
+
+func (v *validator) add(key, format string, args ...interface{}) {
+	v.errs = append(v.errs, fmt.Errorf("%s: %s", key, fmt.Sprintf(format, args...)))
+}
+
+func (v *validator) required(key, value string) {
+	if strings.TrimSpace(value) == "" {
+		v.add(key, "must not be empty")
+	}
+}
+
+func (v *validator) oneOf(key, value string, allowed ...string) {
+	if !slices.Contains(allowed, value) {
+		v.add(key, "must be one of %s, got %q", strings.Join(allowed, ", "), value)
+	}
+}
+
+// url checks that value is a URL with one of the given schemes and, except for unix
+// sockets, a host
+func (v *validator) url(key, value string, schemes ...string) {
+	u, err := url.Parse(value)
+	switch {
+	case err != nil:
+		v.add(key, "invalid URL: %v", err)
+	case !slices.Contains(schemes, u.Scheme):
+		v.add(key, "must be a %s URL, got %q", strings.Join(schemes, " or "), value)
+	case u.Scheme != "unix" && u.Host == "":
+		v.add(key, "must name a host, got %q", value)
+	}
+}
+
+func (v *validator) atLeast(key string, value, min int) {
+	if value < min {
+		v.add(key, "must be at least %d, got %d", min, value)
+	}
+}
+
+func (v *validator) atLeastDuration(key string, value, min time.Duration) {
+	if value < min {
+		v.add(key, "must be at least %s, got %s", min, value)
+	}
+}
+
+func (v *validator) positive(key string, value time.Duration) {
+	if value <= 0 {
+		v.add(key, "must be a positive duration, got %s", value)
+	}
+}
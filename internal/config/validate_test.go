@@ -0,0 +1,83 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidate(t *testing.T) {
+	t.Run("accepts the defaults", func(t *testing.T) {
+		assert.NoError(t, defaults().Validate())
+	})
+
+	invalid := map[string]struct {
+		change func(c *Config)
+		want   string
+	}{
+		"MongoDB URI scheme": {func(c *Config) { c.MongoDB.URI = "http://localhost:27017" }, "mongodb.uri: must be a mongodb or mongodb+srv URL"},
+		"MongoDB URI host":   {func(c *Config) { c.MongoDB.URI = "mongodb://" }, "mongodb.uri: must name a host"},
+		"empty database":     {func(c *Config) { c.MongoDB.Database = " " }, "mongodb.database: must not be empty"},
+		"port":               {func(c *Config) { c.HTTP.Port = "http" }, "http.port: must be a port number"},
+		"zero timeout":       {func(c *Config) { c.HTTP.Timeout = 0 }, "http.timeout: must be a positive duration"},
+		"padded API key":     {func(c *Config) { c.Auth.APIKey = " secret" }, "auth.api_key"},
+		"key store":          {func(c *Config) { c.Auth.KeyStore = "vault" }, "auth.key_store: must be one of mongo, file"},
+		"empty key file":     {func(c *Config) { c.Auth.KeyStore, c.Auth.KeyFile = "file", "" }, "auth.key_file: must not be empty"},
+		"unknown scope":      {func(c *Config) { c.Auth.JWTRoleScopes["viewer"] = []string{"stats:write"} }, `role viewer has unknown scope "stats:write"`},
+		"Redis URL":          {func(c *Config) { c.Redis.URL = "localhost:6379" }, "redis.url"},
+		"route":              {func(c *Config) { c.RateLimit.Routes["leaderboard"] = c.RateLimit.Default }, `route "leaderboard" must start with /`},
+		"exporter":           {func(c *Config) { c.Tracing.Exporter = "jaeger" }, "tracing.exporter"},
+		"OTLP endpoint":      {func(c *Config) { c.Tracing.Exporter, c.Tracing.OTLPEndpoint = "otlp", "collector:4318" }, "tracing.otlp_endpoint"},
+		"log level":          {func(c *Config) { c.Log.Level = "verbose" }, "log.level"},
+		"cache backend":      {func(c *Config) { c.CacheBackend = "disk" }, "cache.backend"},
+		"cache TTL prefix":   {func(c *Config) { c.CacheTTLs["leaderbord"] = time.Minute }, `unknown cache key prefix "leaderbord"`},
+		"cache TTL":          {func(c *Config) { c.CacheTTLs["ggr"] = -time.Minute }, "cache.ttls ggr: must be a positive duration"},
+		"negative stale":     {func(c *Config) { c.CacheStale = -time.Second }, "cache.stale: must be at least 0s"},
+		"breaker open":       {func(c *Config) { c.MongoDB.BreakerOpenFor = 0 }, "mongodb.breaker_open"},
+		"report store":       {func(c *Config) { c.Reports.Store = "s3" }, "reports.store: must be one of mongo, redis"},
+		"report store cache": {func(c *Config) { c.Reports.Store, c.CacheBackend = "redis", "memory" }, "reports.store: redis needs"},
+		"report workers":     {func(c *Config) { c.Reports.Workers = 0 }, "reports.workers: must be at least 1"},
+	}
+	for name, tc := range invalid {
+		t.Run("rejects "+name, func(t *testing.T) {
+			// Arrange
+			cfg := defaults()
+			tc.change(cfg)
+
+			// Act
+			err := cfg.Validate()
+
+			// Assert
+			assert.ErrorContains(t, err, tc.want)
+		})
+	}
+
+	t.Run("skips settings of unused features", func(t *testing.T) {
+		cfg := defaults()
+		cfg.CacheBackend = "memory"
+		cfg.Redis.URL = ""
+		cfg.MongoDB.BreakerThreshold = 0
+		cfg.MongoDB.BreakerOpenFor = 0
+
+		assert.NoError(t, cfg.Validate())
+	})
+}
+
+func TestWarnings(t *testing.T) {
+	t.Run("warns about the default API key", func(t *testing.T) {
+		warnings := defaults().Warnings()
+
+		assert.Len(t, warnings, 1)
+		assert.Contains(t, warnings[0], "auth.api_key")
+	})
+
+	t.Run("has no warnings with another or no API key", func(t *testing.T) {
+		for _, key := range []string{"secret", ""} {
+			cfg := defaults()
+			cfg.Auth.APIKey = key
+
+			assert.Empty(t, cfg.Warnings())
+		}
+	})
+}
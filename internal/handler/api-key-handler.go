@@ -0,0 +1,135 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"admin-statistics-api/internal/apierror"
+	"admin-statistics-api/internal/model"
+	"admin-statistics-api/internal/repository"
+	"admin-statistics-api/internal/service"
+)
+
+// APIKeyHandler handles HTTP requests for API key management
+type APIKeyHandler struct {
+	service  service.APIKeyServiceInterface
+	validate *validator.Validate
+}
+
+// NewAPIKeyHandler creates a new APIKeyHandler
+func NewAPIKeyHandler(service service.APIKeyServiceInterface) *APIKeyHandler {
+	return &APIKeyHandler{
+		service:  service,
+		validate: newValidator(),
+	}
+}
+
+// CreateAPIKeyRequest represents the body of a request to create an API key
+type CreateAPIKeyRequest struct {
+	Name      string     `json:"name" validate:"required,max=100"`
+	Scopes    []string   `json:"scopes" validate:"required,min=1,dive,oneof=stats:read user:read transactions:write keys:admin"`
+	ExpiresAt *time.Time `json:"expiresAt"`
+}
+
+// RotateAPIKeyRequest represents the body of a request to rotate an API key
+type RotateAPIKeyRequest struct {
+	GracePeriodSeconds int `json:"gracePeriodSeconds" validate:"min=0,max=2592000"` // Up to 30 days
+}
+
+// ListAPIKeys handles the endpoint listing every API key
+func (h *APIKeyHandler) ListAPIKeys(c *gin.Context) {
+	keys, err := h.service.ListAPIKeys(c)
+	if err != nil {
+		apierror.Abort(c, apierror.From(err, "Failed to list API keys"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": keys})
+}
+
+// CreateAPIKey handles the endpoint creating a named API key
+func (h *APIKeyHandler) CreateAPIKey(c *gin.Context) {
+	var request CreateAPIKeyRequest
+
+	// Parse request body
+	if err := c.ShouldBindJSON(&request); err != nil {
+		apierror.Abort(c, bodyError(err))
+		return
+	}
+
+	// Validate request
+	if err := h.validate.Struct(request); err != nil {
+		apierror.Abort(c, validationError(err))
+		return
+	}
+
+	key, secret, err := h.service.CreateAPIKey(c, request.Name, request.Scopes, request.ExpiresAt)
+	if err != nil {
+		respondAPIKeyError(c, "Failed to create API key", err)
+		return
+	}
+
+	respondWithSecret(c, key, secret)
+}
+
+// RotateAPIKey handles the endpoint replacing an API key with a new secret key
+func (h *APIKeyHandler) RotateAPIKey(c *gin.Context) {
+	var request RotateAPIKeyRequest
+
+	// The body is optional; without one the old key is revoked immediately
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&request); err != nil {
+			apierror.Abort(c, bodyError(err))
+			return
+		}
+	}
+
+	// Validate request
+	if err := h.validate.Struct(request); err != nil {
+		apierror.Abort(c, validationError(err))
+		return
+	}
+
+	gracePeriod := time.Duration(request.GracePeriodSeconds) * time.Second
+	key, secret, err := h.service.RotateAPIKey(c, c.Param("id"), gracePeriod)
+	if err != nil {
+		respondAPIKeyError(c, "Failed to rotate API key", err)
+		return
+	}
+
+	respondWithSecret(c, key, secret)
+}
+
+// RevokeAPIKey handles the endpoint revoking an API key
+func (h *APIKeyHandler) RevokeAPIKey(c *gin.Context) {
+	if err := h.service.RevokeAPIKey(c, c.Param("id")); err != nil {
+		respondAPIKeyError(c, "Failed to revoke API key", err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// respondAPIKeyError maps API key service errors to API errors, reporting any other
+// failure with message
+func respondAPIKeyError(c *gin.Context, message string, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidAPIKeyRequest):
+		apierror.Abort(c, apierror.Validation("Validation failed", apierror.Detail{Message: err.Error()}))
+	case errors.Is(err, repository.ErrAPIKeyNotFound):
+		apierror.Abort(c, apierror.NotFound("API key not found"))
+	default:
+		apierror.Abort(c, apierror.From(err, message))
+	}
+}
+
+// respondWithSecret returns a newly created key along with its secret key, which is only shown once
+func respondWithSecret(c *gin.Context, key model.APIKey, secret string) {
+	c.JSON(http.StatusCreated, gin.H{
+		"data":   key,
+		"secret": secret,
+	})
+}
@@ -0,0 +1,315 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"admin-statistics-api/internal/model"
+	"admin-statistics-api/internal/repository"
+	"admin-statistics-api/internal/service"
+)
+
+// MockAPIKeyService implements service.APIKeyServiceInterface for testing
+type MockAPIKeyService struct {
+	AuthenticateFn func(ctx context.Context, secret string) (model.APIKey, error)
+	ListFn         func(ctx context.Context) ([]model.APIKey, error)
+	CreateFn       func(ctx context.Context, name string, scopes []string, expiresAt *time.Time) (model.APIKey, string, error)
+	RotateFn       func(ctx context.Context, id string, gracePeriod time.Duration) (model.APIKey, string, error)
+	RevokeFn       func(ctx context.Context, id string) error
+}
+
+// Make sure MockAPIKeyService implements the interface
+var _ service.APIKeyServiceInterface = (*MockAPIKeyService)(nil)
+
+// Authenticate implements service.APIKeyServiceInterface
+func (m *MockAPIKeyService) Authenticate(ctx context.Context, secret string) (model.APIKey, error) {
+	if m.AuthenticateFn != nil {
+		return m.AuthenticateFn(ctx, secret)
+	}
+	return model.APIKey{}, errors.New("not implemented")
+}
+
+// ListAPIKeys implements service.APIKeyServiceInterface
+func (m *MockAPIKeyService) ListAPIKeys(ctx context.Context) ([]model.APIKey, error) {
+	if m.ListFn != nil {
+		return m.ListFn(ctx)
+	}
+	return nil, errors.New("not implemented")
+}
+
+// CreateAPIKey implements service.APIKeyServiceInterface
+func (m *MockAPIKeyService) CreateAPIKey(ctx context.Context, name string, scopes []string, expiresAt *time.Time) (model.APIKey, string, error) {
+	if m.CreateFn != nil {
+		return m.CreateFn(ctx, name, scopes, expiresAt)
+	}
+	return model.APIKey{}, "", errors.New("not implemented")
+}
+
+// RotateAPIKey implements service.APIKeyServiceInterface
+func (m *MockAPIKeyService) RotateAPIKey(ctx context.Context, id string, gracePeriod time.Duration) (model.APIKey, string, error) {
+	if m.RotateFn != nil {
+		return m.RotateFn(ctx, id, gracePeriod)
+	}
+	return model.APIKey{}, "", errors.New("not implemented")
+}
+
+// RevokeAPIKey implements service.APIKeyServiceInterface
+func (m *MockAPIKeyService) RevokeAPIKey(ctx context.Context, id string) error {
+	if m.RevokeFn != nil {
+		return m.RevokeFn(ctx, id)
+	}
+	return errors.New("not implemented")
+}
+
+// Setup the API key test router
+func setupAPIKeyTestRouter(mockService service.APIKeyServiceInterface) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+
+	handler := &APIKeyHandler{
+		service:  mockService,
+		validate: newValidator(),
+	}
+
+	router.GET("/admin/keys", handler.ListAPIKeys)
+	router.POST("/admin/keys", handler.CreateAPIKey)
+	router.POST("/admin/keys/:id/rotate", handler.RotateAPIKey)
+	router.DELETE("/admin/keys/:id", handler.RevokeAPIKey)
+
+	return router
+}
+
+func TestCreateAPIKey(t *testing.T) {
+	t.Run("returns 201 with the secret key", func(t *testing.T) {
+		// Arrange
+		var gotName string
+		var gotScopes []string
+		mockService := &MockAPIKeyService{
+			CreateFn: func(ctx context.Context, name string, scopes []string, expiresAt *time.Time) (model.APIKey, string, error) {
+				gotName, gotScopes = name, scopes
+				return model.APIKey{ID: "key-1", Name: name, Hash: "hash", Scopes: scopes}, "ak_key-1_secret", nil
+			},
+		}
+		router := setupAPIKeyTestRouter(mockService)
+
+		body := `{"name": "dashboard", "scopes": ["stats:read", "user:read"]}`
+		req, _ := http.NewRequest("POST", "/admin/keys", bytes.NewBufferString(body))
+		w := httptest.NewRecorder()
+
+		// Act
+		router.ServeHTTP(w, req)
+
+		// Assert
+		assert.Equal(t, 201, w.Code)
+		assert.Equal(t, "dashboard", gotName)
+		assert.Equal(t, []string{model.ScopeStatsRead, model.ScopeUserRead}, gotScopes)
+
+		var response map[string]interface{}
+		err := json.Unmarshal(w.Body.Bytes(), &response)
+		assert.NoError(t, err)
+		assert.Equal(t, "ak_key-1_secret", response["secret"])
+		data := response["data"].(map[string]interface{})
+		assert.Equal(t, "key-1", data["id"])
+		assert.NotContains(t, data, "hash", "The hash must never be returned")
+	})
+
+	t.Run("returns 400 with invalid requests", func(t *testing.T) {
+		bodies := map[string]string{
+			"missing name":  `{"scopes": ["stats:read"]}`,
+			"no scopes":     `{"name": "dashboard", "scopes": []}`,
+			"unknown scope": `{"name": "dashboard", "scopes": ["stats:write"]}`,
+			"invalid json":  `{"name":`,
+		}
+
+		for name, body := range bodies {
+			t.Run(name, func(t *testing.T) {
+				// Arrange
+				router := setupAPIKeyTestRouter(&MockAPIKeyService{})
+
+				req, _ := http.NewRequest("POST", "/admin/keys", bytes.NewBufferString(body))
+				w := httptest.NewRecorder()
+
+				// Act
+				router.ServeHTTP(w, req)
+
+				// Assert
+				assert.Equal(t, 400, w.Code)
+			})
+		}
+	})
+
+	t.Run("returns 400 when service rejects the request", func(t *testing.T) {
+		// Arrange
+		mockService := &MockAPIKeyService{
+			CreateFn: func(ctx context.Context, name string, scopes []string, expiresAt *time.Time) (model.APIKey, string, error) {
+				return model.APIKey{}, "", service.ErrInvalidAPIKeyRequest
+			},
+		}
+		router := setupAPIKeyTestRouter(mockService)
+
+		body := `{"name": "dashboard", "scopes": ["stats:read"], "expiresAt": "2000-01-01T00:00:00Z"}`
+		req, _ := http.NewRequest("POST", "/admin/keys", bytes.NewBufferString(body))
+		w := httptest.NewRecorder()
+
+		// Act
+		router.ServeHTTP(w, req)
+
+		// Assert
+		assert.Equal(t, 400, w.Code)
+	})
+}
+
+func TestRotateAPIKey(t *testing.T) {
+	t.Run("passes the grace period to service", func(t *testing.T) {
+		// Arrange
+		var gotID string
+		var gotGrace time.Duration
+		mockService := &MockAPIKeyService{
+			RotateFn: func(ctx context.Context, id string, gracePeriod time.Duration) (model.APIKey, string, error) {
+				gotID, gotGrace = id, gracePeriod
+				return model.APIKey{ID: "key-2"}, "ak_key-2_secret", nil
+			},
+		}
+		router := setupAPIKeyTestRouter(mockService)
+
+		req, _ := http.NewRequest("POST", "/admin/keys/key-1/rotate", bytes.NewBufferString(`{"gracePeriodSeconds": 3600}`))
+		w := httptest.NewRecorder()
+
+		// Act
+		router.ServeHTTP(w, req)
+
+		// Assert
+		assert.Equal(t, 201, w.Code)
+		assert.Equal(t, "key-1", gotID)
+		assert.Equal(t, time.Hour, gotGrace)
+	})
+
+	t.Run("revokes immediately without a body", func(t *testing.T) {
+		// Arrange
+		gotGrace := time.Minute
+		mockService := &MockAPIKeyService{
+			RotateFn: func(ctx context.Context, id string, gracePeriod time.Duration) (model.APIKey, string, error) {
+				gotGrace = gracePeriod
+				return model.APIKey{ID: "key-2"}, "ak_key-2_secret", nil
+			},
+		}
+		router := setupAPIKeyTestRouter(mockService)
+
+		req, _ := http.NewRequest("POST", "/admin/keys/key-1/rotate", nil)
+		w := httptest.NewRecorder()
+
+		// Act
+		router.ServeHTTP(w, req)
+
+		// Assert
+		assert.Equal(t, 201, w.Code)
+		assert.Equal(t, time.Duration(0), gotGrace)
+	})
+
+	t.Run("returns 404 for unknown key", func(t *testing.T) {
+		// Arrange
+		mockService := &MockAPIKeyService{
+			RotateFn: func(ctx context.Context, id string, gracePeriod time.Duration) (model.APIKey, string, error) {
+				return model.APIKey{}, "", repository.ErrAPIKeyNotFound
+			},
+		}
+		router := setupAPIKeyTestRouter(mockService)
+
+		req, _ := http.NewRequest("POST", "/admin/keys/missing/rotate", nil)
+		w := httptest.NewRecorder()
+
+		// Act
+		router.ServeHTTP(w, req)
+
+		// Assert
+		assert.Equal(t, 404, w.Code)
+	})
+
+	t.Run("returns 400 with negative grace period", func(t *testing.T) {
+		// Arrange
+		router := setupAPIKeyTestRouter(&MockAPIKeyService{})
+
+		req, _ := http.NewRequest("POST", "/admin/keys/key-1/rotate", bytes.NewBufferString(`{"gracePeriodSeconds": -1}`))
+		w := httptest.NewRecorder()
+
+		// Act
+		router.ServeHTTP(w, req)
+
+		// Assert
+		assert.Equal(t, 400, w.Code)
+	})
+}
+
+func TestRevokeAPIKey(t *testing.T) {
+	t.Run("returns 204 when revoked", func(t *testing.T) {
+		// Arrange
+		var gotID string
+		mockService := &MockAPIKeyService{
+			RevokeFn: func(ctx context.Context, id string) error {
+				gotID = id
+				return nil
+			},
+		}
+		router := setupAPIKeyTestRouter(mockService)
+
+		req, _ := http.NewRequest("DELETE", "/admin/keys/key-1", nil)
+		w := httptest.NewRecorder()
+
+		// Act
+		router.ServeHTTP(w, req)
+
+		// Assert
+		assert.Equal(t, 204, w.Code)
+		assert.Equal(t, "key-1", gotID)
+	})
+
+	t.Run("returns 404 for unknown key", func(t *testing.T) {
+		// Arrange
+		mockService := &MockAPIKeyService{
+			RevokeFn: func(ctx context.Context, id string) error {
+				return repository.ErrAPIKeyNotFound
+			},
+		}
+		router := setupAPIKeyTestRouter(mockService)
+
+		req, _ := http.NewRequest("DELETE", "/admin/keys/missing", nil)
+		w := httptest.NewRecorder()
+
+		// Act
+		router.ServeHTTP(w, req)
+
+		// Assert
+		assert.Equal(t, 404, w.Code)
+	})
+}
+
+func TestListAPIKeys(t *testing.T) {
+	t.Run("returns keys without hashes", func(t *testing.T) {
+		// Arrange
+		mockService := &MockAPIKeyService{
+			ListFn: func(ctx context.Context) ([]model.APIKey, error) {
+				return []model.APIKey{{ID: "key-1", Name: "dashboard", Hash: "hash", Scopes: []string{model.ScopeStatsRead}}}, nil
+			},
+		}
+		router := setupAPIKeyTestRouter(mockService)
+
+		req, _ := http.NewRequest("GET", "/admin/keys", nil)
+		w := httptest.NewRecorder()
+
+		// Act
+		router.ServeHTTP(w, req)
+
+		// Assert
+		assert.Equal(t, 200, w.Code)
+		assert.NotContains(t, w.Body.String(), "hash")
+		assert.Contains(t, w.Body.String(), "dashboard")
+	})
+}
@@ -0,0 +1,154 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"admin-statistics-api/internal/logging"
+)
+
+// HealthCheck checks that a dependency is reachable
+type HealthCheck struct {
+	Name     string
+	Required bool // Whether the API is not ready without it; optional dependencies only degrade it
+	Check    func(ctx context.Context) error
+}
+
+// DependencyStatus is the result of one HealthCheck
+type DependencyStatus struct {
+	Status    string `json:"status"` // "up" or "down"
+	Required  bool   `json:"required"`
+	LatencyMs int64  `json:"latencyMs"`
+	Error     string `json:"error,omitempty"` // Why it is down, without the underlying error
+}
+
+// VersionInfo describes the running build
+type VersionInfo struct {
+	Version    string `json:"version"`
+	GoVersion  string `json:"goVersion"`
+	Commit     string `json:"commit,omitempty"`
+	CommitTime string `json:"commitTime,omitempty"`
+	Modified   bool   `json:"modified"` // Built from a working tree with uncommitted changes
+}
+
+// HealthHandler handles the unauthenticated liveness, readiness and version endpoints
+type HealthHandler struct {
+	checks  []HealthCheck
+	timeout time.Duration
+	version VersionInfo
+}
+
+// NewHealthHandler creates a new HealthHandler that runs checks within timeout
+func NewHealthHandler(checks []HealthCheck, timeout time.Duration, version VersionInfo) *HealthHandler {
+	return &HealthHandler{
+		checks:  checks,
+		timeout: timeout,
+		version: version,
+	}
+}
+
+// ReadVersionInfo reads the build information embedded by the Go toolchain. commit,
+// when set at build time, takes precedence over the VCS revision, which is missing from
+// builds outside a git checkout.
+func ReadVersionInfo(commit string) VersionInfo {
+	info := VersionInfo{Version: "(devel)", Commit: commit}
+
+	build, ok := debug.ReadBuildInfo()
+	if !ok {
+		return info
+	}
+	info.GoVersion = build.GoVersion
+	if build.Main.Version != "" {
+		info.Version = build.Main.Version
+	}
+
+	for _, setting := range build.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			if info.Commit == "" {
+				info.Commit = setting.Value
+			}
+		case "vcs.time":
+			info.CommitTime = setting.Value
+		case "vcs.modified":
+			info.Modified = setting.Value == "true"
+		}
+	}
+
+	return info
+}
+
+// Healthz handles GET /healthz, reporting that the process is alive
+func (h *HealthHandler) Healthz(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// Readyz handles GET /readyz, checking every dependency concurrently within the timeout.
+// It responds 503 when a required dependency is down, and reports "degraded" when only
+// optional ones are.
+func (h *HealthHandler) Readyz(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c, h.timeout)
+	defer cancel()
+
+	results := make([]DependencyStatus, len(h.checks))
+	var wg sync.WaitGroup
+	for i, check := range h.checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = runHealthCheck(ctx, check)
+		}()
+	}
+	wg.Wait()
+
+	status, code := "ready", http.StatusOK
+	dependencies := make(map[string]DependencyStatus, len(h.checks))
+	for i, check := range h.checks {
+		dependencies[check.Name] = results[i]
+		if results[i].Status == "up" {
+			continue
+		}
+		if check.Required {
+			status, code = "not ready", http.StatusServiceUnavailable
+		} else if code == http.StatusOK {
+			status = "degraded"
+		}
+	}
+
+	c.JSON(code, gin.H{
+		"status":       status,
+		"dependencies": dependencies,
+	})
+}
+
+// Version handles GET /version
+func (h *HealthHandler) Version(c *gin.Context) {
+	c.JSON(http.StatusOK, h.version)
+}
+
+// runHealthCheck runs check and times it. The error of a failed check is logged rather
+// than returned, as /readyz is unauthenticated and driver errors name hosts and ports.
+func runHealthCheck(ctx context.Context, check HealthCheck) DependencyStatus {
+	start := time.Now()
+	err := check.Check(ctx)
+
+	result := DependencyStatus{
+		Status:    "up",
+		Required:  check.Required,
+		LatencyMs: time.Since(start).Milliseconds(),
+	}
+	if err != nil {
+		result.Status = "down"
+		result.Error = "unreachable"
+		if errors.Is(err, context.DeadlineExceeded) {
+			result.Error = "timed out"
+		}
+		logging.FromContext(ctx).Warn("Health check failed", "dependency", check.Name, "error", err)
+	}
+	return result
+}
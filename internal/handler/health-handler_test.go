@@ -0,0 +1,114 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupHealthRouter(checks []HealthCheck) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+
+	handler := NewHealthHandler(checks, 50*time.Millisecond, VersionInfo{Version: "v1.2.3", Commit: "abc123"})
+	router.GET("/healthz", handler.Healthz)
+	router.GET("/readyz", handler.Readyz)
+	router.GET("/version", handler.Version)
+
+	return router
+}
+
+func TestReadyz(t *testing.T) {
+	up := func(ctx context.Context) error { return nil }
+	down := func(ctx context.Context) error { return errors.New("dial tcp 127.0.0.1:6379: connect: connection refused") }
+	hang := func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	request := func(checks []HealthCheck) (int, map[string]interface{}) {
+		w := httptest.NewRecorder()
+		setupHealthRouter(checks).ServeHTTP(w, httptest.NewRequest("GET", "/readyz", nil))
+
+		var response map[string]interface{}
+		_ = json.Unmarshal(w.Body.Bytes(), &response)
+		return w.Code, response
+	}
+
+	t.Run("returns 200 when every dependency is up", func(t *testing.T) {
+		// Act
+		code, response := request([]HealthCheck{
+			{Name: "mongodb", Required: true, Check: up},
+			{Name: "redis", Check: up},
+		})
+
+		// Assert
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, "ready", response["status"])
+		dependencies := response["dependencies"].(map[string]interface{})
+		assert.Equal(t, "up", dependencies["mongodb"].(map[string]interface{})["status"])
+		assert.Equal(t, "up", dependencies["redis"].(map[string]interface{})["status"])
+	})
+
+	t.Run("returns 200 degraded when an optional dependency is down", func(t *testing.T) {
+		code, response := request([]HealthCheck{
+			{Name: "mongodb", Required: true, Check: up},
+			{Name: "redis", Check: down},
+		})
+
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, "degraded", response["status"])
+		redis := response["dependencies"].(map[string]interface{})["redis"].(map[string]interface{})
+		assert.Equal(t, "down", redis["status"])
+		assert.Equal(t, "unreachable", redis["error"], "The check's error is logged, not returned")
+	})
+
+	t.Run("returns 503 when a required dependency is down", func(t *testing.T) {
+		code, response := request([]HealthCheck{
+			{Name: "mongodb", Required: true, Check: down},
+			{Name: "redis", Check: down},
+		})
+
+		assert.Equal(t, http.StatusServiceUnavailable, code)
+		assert.Equal(t, "not ready", response["status"])
+	})
+
+	t.Run("a check that does not answer within the deadline is down", func(t *testing.T) {
+		start := time.Now()
+
+		code, response := request([]HealthCheck{{Name: "mongodb", Required: true, Check: hang}})
+
+		assert.Equal(t, http.StatusServiceUnavailable, code)
+		assert.Less(t, time.Since(start), time.Second)
+		mongodb := response["dependencies"].(map[string]interface{})["mongodb"].(map[string]interface{})
+		assert.Equal(t, "timed out", mongodb["error"])
+	})
+}
+
+func TestHealthzAndVersion(t *testing.T) {
+	router := setupHealthRouter(nil)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/healthz", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/version", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	var version VersionInfo
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &version))
+	assert.Equal(t, "v1.2.3", version.Version)
+	assert.Equal(t, "abc123", version.Commit)
+}
+
+func TestReadVersionInfo(t *testing.T) {
+	assert.Equal(t, "deadbeef", ReadVersionInfo("deadbeef").Commit, "A commit set at build time wins")
+	assert.NotEmpty(t, ReadVersionInfo("").GoVersion)
+}
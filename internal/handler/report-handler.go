@@ -0,0 +1,158 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"admin-statistics-api/internal/apierror"
+	"admin-statistics-api/internal/middleware"
+	"admin-statistics-api/internal/model"
+	"admin-statistics-api/internal/repository"
+	"admin-statistics-api/internal/service"
+)
+
+// reportRetryAfter is how long clients are told to wait when the report queue is full
+const reportRetryAfter = 30 * time.Second
+
+// ReportHandler handles HTTP requests for report jobs
+type ReportHandler struct {
+	service  service.ReportServiceInterface
+	validate *validator.Validate
+}
+
+// NewReportHandler creates a new ReportHandler
+func NewReportHandler(service service.ReportServiceInterface) *ReportHandler {
+	return &ReportHandler{
+		service:  service,
+		validate: newValidator(),
+	}
+}
+
+// CreateReportRequest represents the body of a request to run a report. The parameters
+// are those of the report type's endpoint.
+type CreateReportRequest struct {
+	Type        string    `json:"type" validate:"required,oneof=ggr ggr_series daily_wager_volume user_wager_percentile user_summary leaderboard round_anomalies"`
+	From        time.Time `json:"from" validate:"required"`
+	To          time.Time `json:"to" validate:"required,gtefield=From"`
+	Granularity string    `json:"granularity" validate:"omitempty,oneof=hour day week month"`
+	Timezone    string    `json:"tz" validate:"omitempty,timezone"`
+	UserID      string    `json:"userId"`
+	Metric      string    `json:"metric" validate:"omitempty,oneof=wager payout ggr"`
+	Limit       int       `json:"limit" validate:"omitempty,min=1,max=1000"`
+	Currency    string    `json:"currency" validate:"omitempty,oneof=ETH BTC USDT"`
+}
+
+// CreateReport handles the endpoint queuing a report job
+func (h *ReportHandler) CreateReport(c *gin.Context) {
+	var request CreateReportRequest
+
+	// Parse request body
+	if err := c.ShouldBindJSON(&request); err != nil {
+		apierror.Abort(c, bodyError(err))
+		return
+	}
+
+	// Validate request
+	if err := h.validate.Struct(request); err != nil {
+		apierror.Abort(c, validationError(err))
+		return
+	}
+
+	// A report needs the scope of its endpoint
+	principal, _ := middleware.PrincipalFromContext(c)
+	if scope := model.ReportScopes[request.Type]; !principal.HasScope(scope) {
+		apierror.Abort(c, apierror.Forbidden("Credentials lack required scope "+scope))
+		return
+	}
+
+	params, apiErr := request.params()
+	if apiErr != nil {
+		apierror.Abort(c, apiErr)
+		return
+	}
+
+	job, err := h.service.CreateReport(c, principal.ID, request.Type, params)
+	if err != nil {
+		respondReportError(c, "Failed to create report", err)
+		return
+	}
+
+	c.Header("Location", "/reports/"+job.ID)
+	c.JSON(http.StatusAccepted, gin.H{"data": job})
+}
+
+// GetReport handles the endpoint returning a report job's status, and its result once it succeeded
+func (h *ReportHandler) GetReport(c *gin.Context) {
+	principal, _ := middleware.PrincipalFromContext(c)
+	job, err := h.service.GetReport(c, principal.ID, c.Param("id"))
+	if err != nil {
+		respondReportError(c, "Failed to get report", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": job})
+}
+
+// CancelReport handles the endpoint cancelling a queued or running report job
+func (h *ReportHandler) CancelReport(c *gin.Context) {
+	principal, _ := middleware.PrincipalFromContext(c)
+	job, err := h.service.CancelReport(c, principal.ID, c.Param("id"))
+	if err != nil {
+		respondReportError(c, "Failed to cancel report", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": job})
+}
+
+// params checks the parameters the report type needs and applies its endpoint's defaults
+func (r CreateReportRequest) params() (model.ReportParams, *apierror.Error) {
+	params := model.ReportParams{From: r.From, To: r.To}
+
+	switch r.Type {
+	case model.ReportTypeGGR, model.ReportTypeGGRSeries, model.ReportTypeDailyWagerVolume:
+		params.Granularity = r.Granularity
+		params.Timezone = r.Timezone
+		if r.Type != model.ReportTypeGGR && params.Granularity == "" {
+			params.Granularity = model.GranularityDay
+		}
+		// A time zone only affects bucket boundaries
+		if params.Timezone != "" && params.Granularity == "" {
+			return params, apierror.Validation("Validation failed", apierror.Detail{Field: "tz", Message: "requires granularity"})
+		}
+	case model.ReportTypeUserWagerPercentile, model.ReportTypeUserSummary:
+		if r.UserID == "" {
+			return params, apierror.Validation("Validation failed", apierror.Detail{Field: "userId", Message: "is required for " + r.Type + " reports"})
+		}
+		params.UserID = r.UserID
+	case model.ReportTypeLeaderboard:
+		params.Metric = r.Metric
+		params.Currency = r.Currency
+		params.Limit = r.Limit
+		if params.Metric == "" {
+			params.Metric = defaultLeaderboardMetric
+		}
+		if params.Limit == 0 {
+			params.Limit = defaultLeaderboardLimit
+		}
+	}
+	return params, nil
+}
+
+// respondReportError maps report service errors to API errors, reporting any other
+// failure with message
+func respondReportError(c *gin.Context, message string, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidReportRequest):
+		apierror.Abort(c, apierror.Validation("Validation failed", apierror.Detail{Message: err.Error()}))
+	case errors.Is(err, service.ErrReportQueueFull):
+		apierror.Abort(c, apierror.RateLimited("Too many reports are queued, retry later", reportRetryAfter))
+	case errors.Is(err, repository.ErrReportNotFound):
+		apierror.Abort(c, apierror.NotFound("Report not found"))
+	default:
+		apierror.Abort(c, apierror.From(err, message))
+	}
+}
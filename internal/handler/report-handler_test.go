@@ -0,0 +1,258 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"admin-statistics-api/internal/middleware"
+	"admin-statistics-api/internal/model"
+	"admin-statistics-api/internal/repository"
+	"admin-statistics-api/internal/service"
+)
+
+// MockReportService implements service.ReportServiceInterface for testing
+type MockReportService struct {
+	CreateFn func(ctx context.Context, owner, reportType string, params model.ReportParams) (model.ReportJob, error)
+	GetFn    func(ctx context.Context, owner, id string) (model.ReportJob, error)
+	CancelFn func(ctx context.Context, owner, id string) (model.ReportJob, error)
+}
+
+// Make sure MockReportService implements the interface
+var _ service.ReportServiceInterface = (*MockReportService)(nil)
+
+// CreateReport implements service.ReportServiceInterface
+func (m *MockReportService) CreateReport(ctx context.Context, owner, reportType string, params model.ReportParams) (model.ReportJob, error) {
+	if m.CreateFn != nil {
+		return m.CreateFn(ctx, owner, reportType, params)
+	}
+	return model.ReportJob{}, errors.New("not implemented")
+}
+
+// GetReport implements service.ReportServiceInterface
+func (m *MockReportService) GetReport(ctx context.Context, owner, id string) (model.ReportJob, error) {
+	if m.GetFn != nil {
+		return m.GetFn(ctx, owner, id)
+	}
+	return model.ReportJob{}, errors.New("not implemented")
+}
+
+// CancelReport implements service.ReportServiceInterface
+func (m *MockReportService) CancelReport(ctx context.Context, owner, id string) (model.ReportJob, error) {
+	if m.CancelFn != nil {
+		return m.CancelFn(ctx, owner, id)
+	}
+	return model.ReportJob{}, errors.New("not implemented")
+}
+
+// Setup the report test router, authenticated as a principal with scopes
+func setupReportTestRouter(mockService service.ReportServiceInterface, scopes ...string) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set(middleware.PrincipalContextKey, model.Principal{ID: "key-1", Scopes: scopes})
+	})
+
+	handler := NewReportHandler(mockService)
+	router.POST("/reports", handler.CreateReport)
+	router.GET("/reports/:id", handler.GetReport)
+	router.DELETE("/reports/:id", handler.CancelReport)
+
+	return router
+}
+
+func TestCreateReport(t *testing.T) {
+	t.Run("returns 202 with the queued job", func(t *testing.T) {
+		// Arrange
+		var gotOwner, gotType string
+		var gotParams model.ReportParams
+		mockService := &MockReportService{
+			CreateFn: func(ctx context.Context, owner, reportType string, params model.ReportParams) (model.ReportJob, error) {
+				gotOwner, gotType, gotParams = owner, reportType, params
+				return model.ReportJob{ID: "01HRMD5HGTZB3TW3PGYXRD07CQ", Type: reportType, Params: params, Owner: owner, Status: model.ReportStatusQueued}, nil
+			},
+		}
+		router := setupReportTestRouter(mockService, model.ScopeStatsRead)
+
+		body := `{"type": "daily_wager_volume", "from": "2023-01-01T00:00:00Z", "to": "2024-01-01T00:00:00Z"}`
+		req, _ := http.NewRequest("POST", "/reports", bytes.NewBufferString(body))
+		w := httptest.NewRecorder()
+
+		// Act
+		router.ServeHTTP(w, req)
+
+		// Assert
+		assert.Equal(t, http.StatusAccepted, w.Code)
+		assert.Equal(t, "/reports/01HRMD5HGTZB3TW3PGYXRD07CQ", w.Header().Get("Location"))
+		assert.Equal(t, "key-1", gotOwner)
+		assert.Equal(t, model.ReportTypeDailyWagerVolume, gotType)
+		assert.Equal(t, model.GranularityDay, gotParams.Granularity, "Granularity defaults as on the endpoint")
+
+		var response map[string]interface{}
+		err := json.Unmarshal(w.Body.Bytes(), &response)
+		assert.NoError(t, err)
+		data := response["data"].(map[string]interface{})
+		assert.Equal(t, "queued", data["status"])
+		assert.NotContains(t, data, "owner")
+	})
+
+	t.Run("applies the leaderboard defaults", func(t *testing.T) {
+		// Arrange
+		var gotParams model.ReportParams
+		mockService := &MockReportService{
+			CreateFn: func(ctx context.Context, owner, reportType string, params model.ReportParams) (model.ReportJob, error) {
+				gotParams = params
+				return model.ReportJob{ID: "job-1"}, nil
+			},
+		}
+		router := setupReportTestRouter(mockService, model.ScopeUserRead)
+
+		body := `{"type": "leaderboard", "from": "2023-01-01T00:00:00Z", "to": "2024-01-01T00:00:00Z", "granularity": "day"}`
+		req, _ := http.NewRequest("POST", "/reports", bytes.NewBufferString(body))
+		w := httptest.NewRecorder()
+
+		// Act
+		router.ServeHTTP(w, req)
+
+		// Assert
+		assert.Equal(t, http.StatusAccepted, w.Code)
+		assert.Equal(t, model.ReportParams{From: gotParams.From, To: gotParams.To, Metric: "wager", Limit: 10}, gotParams)
+	})
+
+	t.Run("returns 403 without the scope of the report's endpoint", func(t *testing.T) {
+		// Arrange
+		router := setupReportTestRouter(&MockReportService{}, model.ScopeStatsRead)
+
+		body := `{"type": "user_summary", "userId": "01HRMD5HGTZB3TW3PGYXRD07CQ", "from": "2023-01-01T00:00:00Z", "to": "2024-01-01T00:00:00Z"}`
+		req, _ := http.NewRequest("POST", "/reports", bytes.NewBufferString(body))
+		w := httptest.NewRecorder()
+
+		// Act
+		router.ServeHTTP(w, req)
+
+		// Assert
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		assert.Contains(t, w.Body.String(), "Credentials lack required scope user:read")
+	})
+
+	t.Run("returns 400 with invalid requests", func(t *testing.T) {
+		bodies := map[string]string{
+			"unknown type":    `{"type": "revenue", "from": "2023-01-01T00:00:00Z", "to": "2024-01-01T00:00:00Z"}`,
+			"missing from":    `{"type": "ggr", "to": "2024-01-01T00:00:00Z"}`,
+			"reversed range":  `{"type": "ggr", "from": "2024-01-01T00:00:00Z", "to": "2023-01-01T00:00:00Z"}`,
+			"malformed date":  `{"type": "ggr", "from": "2023-01-01", "to": "2024-01-01T00:00:00Z"}`,
+			"missing user ID": `{"type": "user_summary", "from": "2023-01-01T00:00:00Z", "to": "2024-01-01T00:00:00Z"}`,
+			"tz alone":        `{"type": "ggr", "from": "2023-01-01T00:00:00Z", "to": "2024-01-01T00:00:00Z", "tz": "Europe/London"}`,
+			"invalid json":    `{"type":`,
+		}
+
+		for name, body := range bodies {
+			t.Run(name, func(t *testing.T) {
+				// Arrange
+				router := setupReportTestRouter(&MockReportService{}, model.ScopeStatsRead, model.ScopeUserRead)
+
+				req, _ := http.NewRequest("POST", "/reports", bytes.NewBufferString(body))
+				w := httptest.NewRecorder()
+
+				// Act
+				router.ServeHTTP(w, req)
+
+				// Assert
+				assert.Equal(t, http.StatusBadRequest, w.Code)
+			})
+		}
+	})
+
+	t.Run("returns 429 when the queue is full", func(t *testing.T) {
+		// Arrange
+		mockService := &MockReportService{
+			CreateFn: func(ctx context.Context, owner, reportType string, params model.ReportParams) (model.ReportJob, error) {
+				return model.ReportJob{}, service.ErrReportQueueFull
+			},
+		}
+		router := setupReportTestRouter(mockService, model.ScopeStatsRead)
+
+		body := `{"type": "ggr", "from": "2023-01-01T00:00:00Z", "to": "2024-01-01T00:00:00Z"}`
+		req, _ := http.NewRequest("POST", "/reports", bytes.NewBufferString(body))
+		w := httptest.NewRecorder()
+
+		// Act
+		router.ServeHTTP(w, req)
+
+		// Assert
+		assert.Equal(t, http.StatusTooManyRequests, w.Code)
+		assert.Equal(t, "30", w.Header().Get("Retry-After"))
+	})
+}
+
+func TestGetReport(t *testing.T) {
+	t.Run("returns the job of the principal", func(t *testing.T) {
+		// Arrange
+		var gotOwner, gotID string
+		mockService := &MockReportService{
+			GetFn: func(ctx context.Context, owner, id string) (model.ReportJob, error) {
+				gotOwner, gotID = owner, id
+				return model.ReportJob{ID: id, Status: model.ReportStatusSucceeded, Result: json.RawMessage(`[{"currency":"ETH"}]`)}, nil
+			},
+		}
+		router := setupReportTestRouter(mockService)
+
+		req, _ := http.NewRequest("GET", "/reports/job-1", nil)
+		w := httptest.NewRecorder()
+
+		// Act
+		router.ServeHTTP(w, req)
+
+		// Assert
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "key-1", gotOwner)
+		assert.Equal(t, "job-1", gotID)
+		assert.JSONEq(t, `{"data": {"id": "job-1", "type": "", "params": {"from": "0001-01-01T00:00:00Z", "to": "0001-01-01T00:00:00Z"}, "status": "succeeded",
+			"result": [{"currency": "ETH"}], "createdAt": "0001-01-01T00:00:00Z", "expiresAt": "0001-01-01T00:00:00Z"}}`, w.Body.String())
+	})
+
+	t.Run("returns 404 for unknown jobs", func(t *testing.T) {
+		// Arrange
+		mockService := &MockReportService{
+			GetFn: func(ctx context.Context, owner, id string) (model.ReportJob, error) {
+				return model.ReportJob{}, repository.ErrReportNotFound
+			},
+		}
+		router := setupReportTestRouter(mockService)
+
+		req, _ := http.NewRequest("GET", "/reports/missing", nil)
+		w := httptest.NewRecorder()
+
+		// Act
+		router.ServeHTTP(w, req)
+
+		// Assert
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
+
+func TestCancelReport(t *testing.T) {
+	// Arrange
+	mockService := &MockReportService{
+		CancelFn: func(ctx context.Context, owner, id string) (model.ReportJob, error) {
+			return model.ReportJob{ID: id, Status: model.ReportStatusCancelled}, nil
+		},
+	}
+	router := setupReportTestRouter(mockService)
+
+	req, _ := http.NewRequest("DELETE", "/reports/job-1", nil)
+	w := httptest.NewRecorder()
+
+	// Act
+	router.ServeHTTP(w, req)
+
+	// Assert
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"status":"cancelled"`)
+}
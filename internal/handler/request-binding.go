@@ -0,0 +1,188 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"admin-statistics-api/internal/apierror"
+	"admin-statistics-api/internal/service"
+)
+
+// newValidator creates a validator that names fields by their query parameter or JSON
+// key, so validation errors refer to what the client sent
+func newValidator() *validator.Validate {
+	validate := validator.New()
+	validate.RegisterTagNameFunc(func(field reflect.StructField) string {
+		for _, tag := range []string{"form", "json"} {
+			if name, _, _ := strings.Cut(field.Tag.Get(tag), ","); name != "" && name != "-" {
+				return name
+			}
+		}
+		return field.Name
+	})
+	return validate
+}
+
+// bindQuery parses the query parameters into params, a pointer to a struct with form
+// tags, and validates them. Each malformed or invalid parameter is reported as a detail
+// of the returned error.
+func (h *TransactionHandler) bindQuery(c *gin.Context, params interface{}) *apierror.Error {
+	// Gin does not say which parameter it failed to parse, so check them first
+	if details := checkQuery(c.Request.URL.Query(), reflect.TypeOf(params).Elem()); len(details) > 0 {
+		return apierror.Validation("Invalid query parameters", details...)
+	}
+	if err := c.ShouldBindQuery(params); err != nil {
+		return apierror.Validation("Invalid query parameters", apierror.Detail{Message: err.Error()})
+	}
+
+	if err := h.validate.Struct(params); err != nil {
+		return validationError(err)
+	}
+
+	// Results for a named range are cached under its name, so the service must know it
+	if timeframe, ok := params.(interface{ resolveRange(now time.Time) string }); ok {
+		if name := timeframe.resolveRange(time.Now()); name != "" {
+			c.Request = c.Request.WithContext(service.WithNamedRange(c.Request.Context(), name))
+		}
+	}
+	return nil
+}
+
+// bindStatsQuery binds the query parameters of a stats endpoint like bindQuery, then
+// picks the response format from the format parameter or Accept header
+func (h *TransactionHandler) bindStatsQuery(c *gin.Context, params interface{}) (string, *apierror.Error) {
+	if apiErr := h.bindQuery(c, params); apiErr != nil {
+		return "", apiErr
+	}
+	return responseFormat(c)
+}
+
+// checkQuery reports the parameters in values that cannot be parsed as the type of the
+// struct field they bind to
+func checkQuery(values url.Values, t reflect.Type) []apierror.Detail {
+	var details []apierror.Detail
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			details = append(details, checkQuery(values, field.Type)...)
+			continue
+		}
+
+		name := field.Tag.Get("form")
+		value := values.Get(name)
+		if name == "" || value == "" {
+			continue
+		}
+
+		switch {
+		case field.Type == reflect.TypeOf(time.Time{}):
+			if _, err := time.Parse(time.RFC3339, value); err != nil {
+				details = append(details, apierror.Detail{Field: name, Message: "must be an ISO 8601 date and time (YYYY-MM-DDThh:mm:ssZ)"})
+			}
+		case field.Type.Kind() == reflect.Int:
+			if _, err := strconv.Atoi(value); err != nil {
+				details = append(details, apierror.Detail{Field: name, Message: "must be a whole number"})
+			}
+		}
+	}
+	return details
+}
+
+// bodyError converts an error from binding a JSON request body
+func bodyError(err error) *apierror.Error {
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	var timeErr *time.ParseError
+	var validationErrs validator.ValidationErrors
+	switch {
+	case errors.Is(err, io.EOF):
+		return apierror.Validation("Request body is required")
+	case errors.As(err, &syntaxErr) || errors.Is(err, io.ErrUnexpectedEOF):
+		return apierror.Validation("Request body is not valid JSON", apierror.Detail{Message: err.Error()})
+	case errors.As(err, &typeErr):
+		return apierror.Validation("Invalid request body", apierror.Detail{
+			Field:   typeErr.Field,
+			Message: fmt.Sprintf("must be %s, got %s", jsonType(typeErr.Type), typeErr.Value),
+		})
+	case errors.As(err, &timeErr):
+		// encoding/json does not say which field held the time
+		return apierror.Validation("Invalid request body", apierror.Detail{Message: "dates must be ISO 8601 date and times (YYYY-MM-DDThh:mm:ssZ)"})
+	case errors.As(err, &validationErrs):
+		return validationError(err)
+	default:
+		return apierror.Validation("Invalid request body", apierror.Detail{Message: err.Error()})
+	}
+}
+
+// jsonType describes the JSON value that decodes into t
+func jsonType(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	case reflect.Struct, reflect.Map:
+		return "an object"
+	case reflect.String:
+		return "a string"
+	case reflect.Bool:
+		return "a boolean"
+	default:
+		return "a number"
+	}
+}
+
+// validationError converts an error from the validator, with a detail per failed field
+func validationError(err error) *apierror.Error {
+	var validationErrs validator.ValidationErrors
+	if !errors.As(err, &validationErrs) {
+		return apierror.Validation("Validation failed", apierror.Detail{Message: err.Error()})
+	}
+
+	details := make([]apierror.Detail, 0, len(validationErrs))
+	for _, fieldErr := range validationErrs {
+		details = append(details, apierror.Detail{Field: fieldErr.Field(), Message: ruleMessage(fieldErr)})
+	}
+	return apierror.Validation("Validation failed", details...)
+}
+
+// ruleMessage describes the validation rule a field failed
+func ruleMessage(fieldErr validator.FieldError) string {
+	param := fieldErr.Param()
+	switch fieldErr.Tag() {
+	case "required":
+		return "is required"
+	case "gtefield":
+		return "must not be before " + strings.ToLower(param)
+	case "required_without":
+		return "is required unless " + strings.ToLower(param) + " is given"
+	case "excluded_with":
+		return "must not be given with " + strings.ToLower(param)
+	case "oneof":
+		return "must be one of " + strings.ReplaceAll(param, " ", ", ")
+	case "timezone":
+		return "must be an IANA time zone, e.g. Europe/London"
+	case "min", "max":
+		bound := "at least"
+		if fieldErr.Tag() == "max" {
+			bound = "at most"
+		}
+		switch fieldErr.Kind() {
+		case reflect.String:
+			return fmt.Sprintf("must be %s %s characters long", bound, param)
+		case reflect.Slice, reflect.Array, reflect.Map:
+			return fmt.Sprintf("must have %s %s entries", bound, param)
+		default:
+			return fmt.Sprintf("must be %s %s", bound, param)
+		}
+	default:
+		return "failed the " + fieldErr.Tag() + " check"
+	}
+}
@@ -0,0 +1,135 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"admin-statistics-api/internal/apierror"
+)
+
+func TestRequestBinding_ErrorDetails(t *testing.T) {
+	timeframe := "from=2023-01-01T00:00:00Z&to=2023-01-31T00:00:00Z"
+
+	tests := []struct {
+		name            string
+		method          string
+		url             string
+		body            string
+		expectedMessage string
+		expectedDetails []apierror.Detail
+	}{
+		{
+			name:            "non-numeric limit",
+			method:          "GET",
+			url:             "/leaderboard?limit=ten&" + timeframe,
+			expectedMessage: "Invalid query parameters",
+			expectedDetails: []apierror.Detail{{Field: "limit", Message: "must be a whole number"}},
+		},
+		{
+			name:            "every malformed date",
+			method:          "GET",
+			url:             "/gross_gaming_rev?from=yesterday&to=2023-01-31",
+			expectedMessage: "Invalid query parameters",
+			expectedDetails: []apierror.Detail{
+				{Field: "from", Message: "must be an ISO 8601 date and time (YYYY-MM-DDThh:mm:ssZ)"},
+				{Field: "to", Message: "must be an ISO 8601 date and time (YYYY-MM-DDThh:mm:ssZ)"},
+			},
+		},
+		{
+			name:            "missing date",
+			method:          "GET",
+			url:             "/rounds/anomalies?to=2023-01-31T00:00:00Z",
+			expectedMessage: "Validation failed",
+			expectedDetails: []apierror.Detail{{Field: "from", Message: "is required unless range is given"}},
+		},
+		{
+			name:            "range ending before it starts",
+			method:          "GET",
+			url:             "/gross_gaming_rev?from=2023-02-01T00:00:00Z&to=2023-01-01T00:00:00Z",
+			expectedMessage: "Validation failed",
+			expectedDetails: []apierror.Detail{{Field: "to", Message: "must not be before from"}},
+		},
+		{
+			name:            "unknown metric and limit out of range",
+			method:          "GET",
+			url:             "/leaderboard?metric=bets&limit=5000&" + timeframe,
+			expectedMessage: "Validation failed",
+			expectedDetails: []apierror.Detail{
+				{Field: "metric", Message: "must be one of wager, payout, ggr"},
+				{Field: "limit", Message: "must be at most 1000"},
+			},
+		},
+		{
+			name:            "range with from and to",
+			method:          "GET",
+			url:             "/gross_gaming_rev?range=last_24h&" + timeframe,
+			expectedMessage: "Validation failed",
+			expectedDetails: []apierror.Detail{
+				{Field: "from", Message: "must not be given with range"},
+				{Field: "to", Message: "must not be given with range"},
+			},
+		},
+		{
+			name:            "unknown range",
+			method:          "GET",
+			url:             "/gross_gaming_rev?range=last_week",
+			expectedMessage: "Validation failed",
+			expectedDetails: []apierror.Detail{{Field: "range", Message: "must be one of last_24h, month_to_date"}},
+		},
+		{
+			name:            "time zone without granularity",
+			method:          "GET",
+			url:             "/gross_gaming_rev?tz=Europe/London&" + timeframe,
+			expectedMessage: "Validation failed",
+			expectedDetails: []apierror.Detail{{Field: "tz", Message: "requires granularity"}},
+		},
+		{
+			name:            "unparsable JSON",
+			method:          "POST",
+			url:             "/transactions",
+			body:            `{"userId": `,
+			expectedMessage: "Request body is not valid JSON",
+			expectedDetails: []apierror.Detail{{Message: "unexpected EOF"}},
+		},
+		{
+			name:            "wrongly typed JSON field",
+			method:          "POST",
+			url:             "/transactions",
+			body:            `{"userId": 42}`,
+			expectedMessage: "Invalid request body",
+			expectedDetails: []apierror.Detail{{Field: "userId", Message: "must be a string, got number"}},
+		},
+		{
+			name:            "object instead of a batch",
+			method:          "POST",
+			url:             "/transactions/batch",
+			body:            `{"userId": "01HRMD5HGTZB3TW3PGYXRD07CQ"}`,
+			expectedMessage: "Invalid request body",
+			expectedDetails: []apierror.Detail{{Message: "must be an array, got object"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			router := setupTestRouter(&MockTransactionService{})
+			req, _ := http.NewRequest(tt.method, tt.url, strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+
+			// Act
+			router.ServeHTTP(w, req)
+
+			// Assert
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+			var response apierror.Envelope
+			assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+			assert.Equal(t, apierror.CodeValidation, response.Code)
+			assert.Equal(t, tt.expectedMessage, response.Message)
+			assert.Equal(t, tt.expectedDetails, response.Details)
+		})
+	}
+}
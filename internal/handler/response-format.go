@@ -0,0 +1,322 @@
+package handler
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"admin-statistics-api/internal/apierror"
+	"admin-statistics-api/internal/model"
+	"admin-statistics-api/internal/repository"
+	"admin-statistics-api/internal/service"
+)
+
+// Formats a stats endpoint can respond in
+const (
+	formatJSON   = "json"
+	formatCSV    = "csv"
+	formatNDJSON = "ndjson"
+)
+
+// Media types of the CSV and NDJSON formats. NDJSON is also accepted as application/ndjson.
+const (
+	mimeCSV         = "text/csv"
+	mimeNDJSON      = "application/x-ndjson"
+	mimeNDJSONAlias = "application/ndjson"
+)
+
+// flushRows is how many CSV or NDJSON rows are written between flushes, so a large
+// result reaches the client in chunks as it is read rather than all at the end
+const flushRows = 500
+
+// utf8BOM starts a CSV, so that spreadsheets such as Excel read it as UTF-8
+const utf8BOM = "\xEF\xBB\xBF"
+
+// responseFormat picks the format of a stats response: the format query parameter if
+// given, or else the first of JSON, CSV and NDJSON that the Accept header allows. JSON
+// is the default. The response varies with Accept, so shared caches are told so.
+func responseFormat(c *gin.Context) (string, *apierror.Error) {
+	c.Writer.Header().Add("Vary", "Accept")
+
+	switch format := c.Query("format"); format {
+	case formatJSON, formatCSV, formatNDJSON:
+		return format, nil
+	case "":
+	default:
+		return "", apierror.Validation("Invalid query parameters",
+			apierror.Detail{Field: "format", Message: "must be one of json, csv, ndjson"})
+	}
+
+	switch c.NegotiateFormat(gin.MIMEJSON, mimeCSV, mimeNDJSON, mimeNDJSONAlias) {
+	case mimeCSV:
+		return formatCSV, nil
+	case mimeNDJSON, mimeNDJSONAlias:
+		return formatNDJSON, nil
+	default:
+		return formatJSON, nil
+	}
+}
+
+// respond writes a stats result in format: response as JSON, or rows, a slice of
+// structs, as CSV or NDJSON. CSV is offered as a download named after name.
+func respond(c *gin.Context, format, name string, response gin.H, rows interface{}) {
+	if format == formatJSON {
+		c.JSON(http.StatusOK, response)
+		return
+	}
+	writeRows(c, format, name, newSliceRows(rows))
+}
+
+// streamRows answers a CSV or NDJSON request for a report type with its rows, written
+// as they are read from the database rather than after the whole result is loaded.
+// Each row is decoded as a T. Failing to start the query is answered with an error
+// response; a failure once rows are being sent can only cut the response short.
+func streamRows[T any](c *gin.Context, svc service.TransactionServiceInterface, format, name, reportType string, params model.ReportParams, failure string) {
+	cursor, err := svc.StreamRows(withCacheStatus(c), reportType, params)
+	if err != nil {
+		apierror.Abort(c, apierror.From(err, failure))
+		return
+	}
+	// Close even when the client went away, so the server-side cursor is released
+	defer cursor.Close(context.WithoutCancel(c.Request.Context()))
+
+	writeRows(c, format, name, &cursorRows[T]{ctx: c.Request.Context(), cursor: cursor})
+}
+
+// rowReader reads the rows of a CSV or NDJSON response one at a time
+type rowReader interface {
+	// rowType is the struct type of the rows
+	rowType() reflect.Type
+	// next moves to the next row, returning false when there are none left or reading failed
+	next() bool
+	// row returns the current row
+	row() reflect.Value
+	// err returns the error that stopped reading, if any
+	err() error
+}
+
+// sliceRows reads the rows of a slice of structs
+type sliceRows struct {
+	values reflect.Value
+	index  int
+}
+
+// newSliceRows creates a rowReader over rows, a slice of structs
+func newSliceRows(rows interface{}) *sliceRows {
+	return &sliceRows{values: reflect.ValueOf(rows), index: -1}
+}
+
+func (r *sliceRows) rowType() reflect.Type {
+	return r.values.Type().Elem()
+}
+
+func (r *sliceRows) next() bool {
+	r.index++
+	return r.index < r.values.Len()
+}
+
+func (r *sliceRows) row() reflect.Value {
+	return r.values.Index(r.index)
+}
+
+func (r *sliceRows) err() error {
+	return nil
+}
+
+// cursorRows reads rows from a repository cursor, decoding each as a T
+type cursorRows[T any] struct {
+	ctx       context.Context
+	cursor    repository.RowCursor
+	current   T
+	decodeErr error
+}
+
+func (r *cursorRows[T]) rowType() reflect.Type {
+	return reflect.TypeOf(r.current)
+}
+
+func (r *cursorRows[T]) row() reflect.Value {
+	return reflect.ValueOf(r.current)
+}
+
+func (r *cursorRows[T]) next() bool {
+	if r.decodeErr != nil || !r.cursor.Next(r.ctx) {
+		return false
+	}
+	var row T
+	if r.decodeErr = r.cursor.Decode(&row); r.decodeErr != nil {
+		return false
+	}
+	r.current = row
+	return true
+}
+
+func (r *cursorRows[T]) err() error {
+	if r.decodeErr != nil {
+		return r.decodeErr
+	}
+	return r.cursor.Err()
+}
+
+// writeRows writes rows as CSV or NDJSON. A failure to read or write a row stops the
+// response and is recorded on the request, which logs it.
+func writeRows(c *gin.Context, format, name string, rows rowReader) {
+	var err error
+	if format == formatCSV {
+		err = writeCSV(c, name, rows)
+	} else {
+		err = writeNDJSON(c, rows)
+	}
+	if err != nil {
+		c.Error(err)
+	}
+}
+
+// exportName names the file of an export for a timeframe, e.g.
+// "daily_wager_volume_2023-01-01_2023-01-31". Characters that are not safe in a file
+// name, such as those of a user ID from the path, are replaced with underscores.
+func exportName(prefix string, params TimeframeParams) string {
+	name := prefix + "_" + params.From.UTC().Format(time.DateOnly) + "_" + params.To.UTC().Format(time.DateOnly)
+	return strings.Map(func(r rune) rune {
+		if r == '-' || r == '_' || r == '.' || ('0' <= r && r <= '9') || ('a' <= r && r <= 'z') || ('A' <= r && r <= 'Z') {
+			return r
+		}
+		return '_'
+	}, name)
+}
+
+// writeCSV writes rows as CSV with a header row of their JSON field names. The file
+// starts with a UTF-8 byte order mark, lines end with CRLF and decimals are written
+// without an exponent, as spreadsheets expect.
+func writeCSV(c *gin.Context, name string, rows rowReader) error {
+	c.Header("Content-Type", mimeCSV+"; charset=utf-8")
+	c.Header("Content-Disposition", `attachment; filename="`+name+`.csv"`)
+	c.Status(http.StatusOK)
+
+	columns := csvColumns(rows.rowType())
+
+	c.Writer.WriteString(utf8BOM)
+	w := csv.NewWriter(c.Writer)
+	w.UseCRLF = true
+
+	header := make([]string, len(columns))
+	for i, column := range columns {
+		header[i] = column.name
+	}
+	w.Write(header)
+
+	record := make([]string, len(columns))
+	for i := 1; rows.next(); i++ {
+		row := rows.row()
+		for j, column := range columns {
+			record[j] = csvValue(row.Field(column.index))
+		}
+		w.Write(record)
+
+		if i%flushRows == 0 {
+			w.Flush()
+			c.Writer.Flush()
+		}
+	}
+
+	w.Flush()
+	if err := rows.err(); err != nil {
+		return err
+	}
+	return w.Error()
+}
+
+// writeNDJSON writes rows as one JSON object per line
+func writeNDJSON(c *gin.Context, rows rowReader) error {
+	c.Header("Content-Type", mimeNDJSON)
+	c.Status(http.StatusOK)
+
+	encoder := json.NewEncoder(c.Writer)
+	for i := 1; rows.next(); i++ {
+		if err := encoder.Encode(rows.row().Interface()); err != nil {
+			return err
+		}
+
+		if i%flushRows == 0 {
+			c.Writer.Flush()
+		}
+	}
+	return rows.err()
+}
+
+// csvColumn is a struct field written as a CSV column
+type csvColumn struct {
+	name  string
+	index int
+}
+
+// csvColumns returns the columns for a row type, named and ordered like its JSON fields
+func csvColumns(t reflect.Type) []csvColumn {
+	columns := make([]csvColumn, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if !field.IsExported() || name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		columns = append(columns, csvColumn{name: name, index: i})
+	}
+	return columns
+}
+
+// csvValue formats a field for a CSV cell. Missing values are empty and lists are
+// joined with semicolons.
+func csvValue(v reflect.Value) string {
+	switch value := v.Interface().(type) {
+	case model.Decimal:
+		return value.PlainString()
+	case *model.Decimal:
+		if value == nil {
+			return ""
+		}
+		return value.PlainString()
+	case time.Time:
+		if value.IsZero() {
+			return ""
+		}
+		return value.UTC().Format(time.RFC3339)
+	case *time.Time:
+		if value == nil {
+			return ""
+		}
+		return value.UTC().Format(time.RFC3339)
+	case string:
+		return csvText(value)
+	case []string:
+		texts := make([]string, len(value))
+		for i, s := range value {
+			texts[i] = csvText(s)
+		}
+		return strings.Join(texts, ";")
+	case int:
+		return strconv.Itoa(value)
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	default:
+		return fmt.Sprint(value)
+	}
+}
+
+// csvText guards a text cell against spreadsheets running it as a formula, such as a
+// round ID sent as "=HYPERLINK(...)", by prefixing it with a quote
+func csvText(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
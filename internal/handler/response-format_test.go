@@ -0,0 +1,247 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"admin-statistics-api/internal/model"
+	"admin-statistics-api/internal/repository"
+)
+
+func TestResponseFormat(t *testing.T) {
+	timeframe := "from=2023-01-01T00:00:00Z&to=2023-01-31T00:00:00Z"
+	mockService := &MockTransactionService{
+		DailyWagerVolumeFn: func(ctx context.Context, from, to time.Time, bucket model.TimeBucket) ([]model.DailyWagerRow, error) {
+			return []model.DailyWagerRow{
+				{Date: "2023-01-01", Currency: "BTC", WagerAmount: model.MustParseDecimal("1E-8"), WagerUSDAmount: model.MustParseDecimal("0.00042")},
+				{Date: "2023-01-02", Currency: "ETH", WagerAmount: model.MustParseDecimal("150.50"), WagerUSDAmount: model.MustParseDecimal("301000.00")},
+			}, nil
+		},
+	}
+
+	csvBody := utf8BOM + "date,currency,wagerAmount,wagerUSDAmount\r\n" +
+		"2023-01-01,BTC,0.00000001,0.00042\r\n" +
+		"2023-01-02,ETH,150.50,301000.00\r\n"
+
+	tests := []struct {
+		name                string
+		query               string
+		accept              string
+		expectedContentType string
+	}{
+		{"JSON by default", "", "", "application/json; charset=utf-8"},
+		{"JSON for browsers", "", "text/html,application/xhtml+xml,*/*;q=0.8", "application/json; charset=utf-8"},
+		{"CSV from the Accept header", "", "text/csv", "text/csv; charset=utf-8"},
+		{"NDJSON from the Accept header", "", "application/x-ndjson", "application/x-ndjson"},
+		{"format parameter over the Accept header", "&format=csv", "application/json", "text/csv; charset=utf-8"},
+		{"NDJSON from the format parameter", "&format=ndjson", "", "application/x-ndjson"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			router := setupTestRouter(mockService)
+			req, _ := http.NewRequest("GET", "/daily_wager_volume?"+timeframe+tt.query, nil)
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+			w := httptest.NewRecorder()
+
+			// Act
+			router.ServeHTTP(w, req)
+
+			// Assert
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Equal(t, tt.expectedContentType, w.Header().Get("Content-Type"))
+			assert.Equal(t, "Accept", w.Header().Get("Vary"))
+		})
+	}
+
+	t.Run("writes CSV rows with plain decimals", func(t *testing.T) {
+		// Arrange
+		router := setupTestRouter(mockService)
+		req, _ := http.NewRequest("GET", "/daily_wager_volume?format=csv&"+timeframe, nil)
+		w := httptest.NewRecorder()
+
+		// Act
+		router.ServeHTTP(w, req)
+
+		// Assert
+		assert.Equal(t, csvBody, w.Body.String())
+		assert.Equal(t, `attachment; filename="daily_wager_volume_2023-01-01_2023-01-31.csv"`, w.Header().Get("Content-Disposition"))
+	})
+
+	t.Run("writes one JSON object per NDJSON line", func(t *testing.T) {
+		// Arrange
+		router := setupTestRouter(mockService)
+		req, _ := http.NewRequest("GET", "/daily_wager_volume?format=ndjson&"+timeframe, nil)
+		w := httptest.NewRecorder()
+
+		// Act
+		router.ServeHTTP(w, req)
+
+		// Assert
+		lines := strings.Split(strings.TrimSuffix(w.Body.String(), "\n"), "\n")
+		if assert.Len(t, lines, 2) {
+			assert.JSONEq(t, `{"date":"2023-01-01","currency":"BTC","wagerAmount":"1E-8","wagerUSDAmount":"0.00042"}`, lines[0])
+			assert.JSONEq(t, `{"date":"2023-01-02","currency":"ETH","wagerAmount":"150.50","wagerUSDAmount":"301000.00"}`, lines[1])
+		}
+	})
+
+	t.Run("streams from the database without the cache", func(t *testing.T) {
+		// Arrange
+		var streamed model.ReportParams
+		cursor := repository.NewMockRowCursor(model.DailyWagerRow{Date: "2023-01-01", Currency: "BTC", WagerAmount: model.MustParseDecimal("1")})
+		router := setupTestRouter(&MockTransactionService{
+			StreamRowsFn: func(ctx context.Context, reportType string, params model.ReportParams) (repository.RowCursor, error) {
+				assert.Equal(t, model.ReportTypeDailyWagerVolume, reportType)
+				streamed = params
+				return cursor, nil
+			},
+		})
+		req, _ := http.NewRequest("GET", "/daily_wager_volume?format=ndjson&tz=Europe/Malta&"+timeframe, nil)
+		w := httptest.NewRecorder()
+
+		// Act
+		router.ServeHTTP(w, req)
+
+		// Assert
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, model.GranularityDay, streamed.Granularity)
+		assert.Equal(t, "Europe/Malta", streamed.Timezone)
+		assert.Equal(t, 1, strings.Count(w.Body.String(), "\n"))
+		assert.True(t, cursor.Closed)
+	})
+
+	t.Run("answers with an error when the query cannot start", func(t *testing.T) {
+		// Arrange
+		router := setupTestRouter(&MockTransactionService{
+			StreamRowsFn: func(ctx context.Context, reportType string, params model.ReportParams) (repository.RowCursor, error) {
+				return nil, errors.New("connection refused")
+			},
+		})
+		req, _ := http.NewRequest("GET", "/daily_wager_volume?format=csv&"+timeframe, nil)
+		w := httptest.NewRecorder()
+
+		// Act
+		router.ServeHTTP(w, req)
+
+		// Assert
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+		assert.Contains(t, w.Header().Get("Content-Type"), "application/json")
+	})
+
+	t.Run("cuts the response short when reading fails", func(t *testing.T) {
+		// Arrange
+		cursor := repository.NewMockRowCursor(model.DailyWagerRow{Date: "2023-01-01", Currency: "BTC"})
+		cursor.Error = errors.New("cursor killed")
+		router := setupTestRouter(&MockTransactionService{
+			StreamRowsFn: func(ctx context.Context, reportType string, params model.ReportParams) (repository.RowCursor, error) {
+				return cursor, nil
+			},
+		})
+		req, _ := http.NewRequest("GET", "/daily_wager_volume?format=csv&"+timeframe, nil)
+		w := httptest.NewRecorder()
+
+		// Act
+		router.ServeHTTP(w, req)
+
+		// Assert
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, utf8BOM+"date,currency,wagerAmount,wagerUSDAmount\r\n2023-01-01,BTC,0,0\r\n", w.Body.String())
+		assert.True(t, cursor.Closed)
+	})
+
+	t.Run("rejects an unknown format before querying", func(t *testing.T) {
+		// Arrange
+		router := setupTestRouter(&MockTransactionService{})
+		req, _ := http.NewRequest("GET", "/daily_wager_volume?format=xlsx&"+timeframe, nil)
+		w := httptest.NewRecorder()
+
+		// Act
+		router.ServeHTTP(w, req)
+
+		// Assert
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		var response map[string]interface{}
+		_ = json.Unmarshal(w.Body.Bytes(), &response)
+		assert.Equal(t, []interface{}{map[string]interface{}{"field": "format", "message": "must be one of json, csv, ndjson"}}, response["details"])
+	})
+}
+
+func TestResponseFormat_CSVValues(t *testing.T) {
+	timeframe := "from=2023-01-01T00:00:00Z&to=2023-01-31T00:00:00Z"
+
+	t.Run("joins lists, leaves missing values empty and guards formulas", func(t *testing.T) {
+		// Arrange
+		payoutAt := time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)
+		mockService := &MockTransactionService{
+			RoundAnomaliesFn: func(ctx context.Context, from, to time.Time) ([]model.RoundAnomaly, error) {
+				return []model.RoundAnomaly{{
+					RoundID:       "=HYPERLINK(\"http://example.com\")",
+					UserIDs:       []string{"01HRMD5HGTZB3TW3PGYXRD07CQ", "01HRMD5HGTZB3TW3PGYXRD07CR"},
+					Currencies:    []string{"ETH"},
+					PayoutCount:   1,
+					FirstPayoutAt: &payoutAt,
+					Anomalies:     []string{model.RoundAnomalyPayoutWithoutWager},
+				}}, nil
+			},
+		}
+		router := setupTestRouter(mockService)
+		req, _ := http.NewRequest("GET", "/rounds/anomalies?format=csv&"+timeframe, nil)
+		w := httptest.NewRecorder()
+
+		// Act
+		router.ServeHTTP(w, req)
+
+		// Assert
+		assert.Equal(t, utf8BOM+"roundId,userIds,currencies,wagerCount,payoutCount,firstWagerAt,firstPayoutAt,anomalies\r\n"+
+			`"'=HYPERLINK(""http://example.com"")",01HRMD5HGTZB3TW3PGYXRD07CQ;01HRMD5HGTZB3TW3PGYXRD07CR,ETH,0,1,,2023-01-02T03:04:05Z,payout_without_wager`+"\r\n",
+			w.Body.String())
+	})
+
+	t.Run("writes negative decimals as numbers", func(t *testing.T) {
+		// Arrange
+		mockService := &MockTransactionService{
+			GGRFn: func(ctx context.Context, from, to time.Time, bucket model.TimeBucket) ([]model.GGRRow, error) {
+				return []model.GGRRow{{Currency: "USDT", GGR: model.MustParseDecimal("-12.50"), GGRUSD: model.MustParseDecimal("-12.49")}}, nil
+			},
+		}
+		router := setupTestRouter(mockService)
+		req, _ := http.NewRequest("GET", "/gross_gaming_rev?"+timeframe, nil)
+		req.Header.Set("Accept", "text/csv")
+		w := httptest.NewRecorder()
+
+		// Act
+		router.ServeHTTP(w, req)
+
+		// Assert
+		assert.Equal(t, utf8BOM+"date,currency,ggr,ggrUSD\r\n,USDT,-12.50,-12.49\r\n", w.Body.String())
+	})
+
+	t.Run("names a user's export after a safe form of the user ID", func(t *testing.T) {
+		// Arrange
+		mockService := &MockTransactionService{
+			UserPercentileFn: func(ctx context.Context, userID string, from, to time.Time) (float64, error) {
+				return 87.5, nil
+			},
+		}
+		router := setupTestRouter(mockService)
+		req, _ := http.NewRequest("GET", "/user/a%22b/wager_percentile?format=csv&"+timeframe, nil)
+		w := httptest.NewRecorder()
+
+		// Act
+		router.ServeHTTP(w, req)
+
+		// Assert
+		assert.Equal(t, utf8BOM+"userID,percentile\r\n\"a\"\"b\",87.5\r\n", w.Body.String())
+		assert.Equal(t, `attachment; filename="wager_percentile_a_b_2023-01-01_2023-01-31.csv"`, w.Header().Get("Content-Disposition"))
+	})
+}
@@ -1,11 +1,15 @@
 package handler
 
 import (
+	"context"
+	"errors"
 	"net/http"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/go-playground/validator/v10"
+	"admin-statistics-api/internal/apierror"
+	"admin-statistics-api/internal/model"
 	"admin-statistics-api/internal/service"
 )
 
@@ -19,71 +23,195 @@ type TransactionHandler struct {
 func NewTransactionHandler(service service.TransactionServiceInterface) *TransactionHandler {
 	return &TransactionHandler{
 		service:  service,
-		validate: validator.New(),
+		validate: newValidator(),
 	}
 }
 
-// TimeframeParams represents query parameters for date range
+// TimeframeParams represents query parameters for date range, given by from and to or
+// by the name of a range such as last_24h
 type TimeframeParams struct {
-	From time.Time `form:"from" validate:"required"`
-	To   time.Time `form:"to" validate:"required,gtefield=From"`
+	From  time.Time `form:"from" validate:"required_without=Range,excluded_with=Range"`
+	To    time.Time `form:"to" validate:"required_without=Range,excluded_with=Range,gtefield=From"`
+	Range string    `form:"range" validate:"omitempty,oneof=last_24h month_to_date"`
 }
 
+// resolveRange sets From and To to the bounds of the named range at now, if one was
+// requested, and returns its name
+func (p *TimeframeParams) resolveRange(now time.Time) string {
+	if from, to, ok := model.ResolveRange(p.Range, now); ok {
+		p.From, p.To = from, to
+		return p.Range
+	}
+	return ""
+}
+
+// BucketParams represents query parameters for grouping results over time
+type BucketParams struct {
+	Granularity string `form:"granularity" validate:"omitempty,oneof=hour day week month"`
+	Timezone    string `form:"tz" validate:"omitempty,timezone"`
+}
+
+// TimeBucket converts the parameters to a model.TimeBucket
+func (p BucketParams) TimeBucket() model.TimeBucket {
+	return model.TimeBucket{Granularity: p.Granularity, Timezone: p.Timezone}
+}
+
+// TimeSeriesParams represents query parameters for a date range grouped over time
+type TimeSeriesParams struct {
+	TimeframeParams
+	BucketParams
+}
+
+// LeaderboardParams represents query parameters for the leaderboard
+type LeaderboardParams struct {
+	TimeframeParams
+	Metric   string `form:"metric" validate:"omitempty,oneof=wager payout ggr"`
+	Limit    int    `form:"limit" validate:"omitempty,min=1,max=1000"`
+	Currency string `form:"currency" validate:"omitempty,oneof=ETH BTC USDT"`
+}
+
+// userPercentileRow is a user's wager percentile as a CSV or NDJSON row
+type userPercentileRow struct {
+	UserID     string  `json:"userID"`
+	Percentile float64 `json:"percentile"`
+}
+
+// cacheStatusHeader tells clients whether a result was fresh or stale from the cache, or a miss
+const cacheStatusHeader = "X-Cache-Status"
+
+// withCacheStatus returns a context for a service call that sets the cache status header
+// on the response. The header is set before the handler writes the body.
+func withCacheStatus(c *gin.Context) context.Context {
+	return service.WithCacheStatusReporter(c, func(status service.CacheStatus) {
+		c.Header(cacheStatusHeader, string(status))
+	})
+}
+
+// Leaderboard defaults applied when the query parameters are omitted
+const (
+	defaultLeaderboardMetric = model.LeaderboardMetricWager
+	defaultLeaderboardLimit  = 10
+)
+
 // GetGrossGamingRevenue handles the GGR endpoint
 func (h *TransactionHandler) GetGrossGamingRevenue(c *gin.Context) {
-	var params TimeframeParams
+	var params TimeSeriesParams
+
+	// Parse and validate query parameters and the response format
+	format, apiErr := h.bindStatsQuery(c, &params)
+	if apiErr != nil {
+		apierror.Abort(c, apiErr)
+		return
+	}
 
-	// Parse query parameters
-	if err := c.ShouldBindQuery(&params); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date format. Use ISO 8601 (YYYY-MM-DDThh:mm:ssZ)"})
+	// A time zone only affects bucket boundaries
+	if params.Timezone != "" && params.Granularity == "" {
+		apierror.Abort(c, apierror.Validation("Validation failed", apierror.Detail{Field: "tz", Message: "requires granularity"}))
 		return
 	}
 
-	// Validate parameters
-	if err := h.validate.Struct(params); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Validation error: " + err.Error()})
+	// Stream CSV and NDJSON from the database rather than loading the whole result
+	if format != formatJSON {
+		streamRows[model.GGRRow](c, h.service, format, exportName("gross_gaming_rev", params.TimeframeParams), model.ReportTypeGGR,
+			model.ReportParams{From: params.From, To: params.To, Granularity: params.Granularity, Timezone: params.Timezone},
+			"Failed to calculate GGR")
 		return
 	}
 
 	// Call service to get GGR
-	results, err := h.service.CalculateGGR(c, params.From, params.To)
+	results, err := h.service.CalculateGGR(withCacheStatus(c), params.From, params.To, params.TimeBucket())
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to calculate GGR: " + err.Error()})
+		apierror.Abort(c, apierror.From(err, "Failed to calculate GGR"))
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
+	response := gin.H{
 		"timeframe": gin.H{"from": params.From, "to": params.To},
 		"data":      results,
+	}
+	if params.Granularity != "" {
+		response["granularity"] = params.Granularity
+		response["tz"] = params.TimeBucket().Location()
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// GetGrossGamingRevenueSeries handles the GGR time series endpoint, bucketed by UTC day unless granularity and tz are given
+func (h *TransactionHandler) GetGrossGamingRevenueSeries(c *gin.Context) {
+	var params TimeSeriesParams
+
+	// Parse and validate query parameters and the response format
+	format, apiErr := h.bindStatsQuery(c, &params)
+	if apiErr != nil {
+		apierror.Abort(c, apiErr)
+		return
+	}
+
+	bucket := params.TimeBucket()
+	if bucket.Granularity == "" {
+		bucket.Granularity = model.GranularityDay
+	}
+
+	// Stream CSV and NDJSON from the database rather than loading the whole result
+	if format != formatJSON {
+		streamRows[model.GGRSeriesRow](c, h.service, format, exportName("gross_gaming_rev_series", params.TimeframeParams), model.ReportTypeGGRSeries,
+			model.ReportParams{From: params.From, To: params.To, Granularity: bucket.Granularity, Timezone: bucket.Timezone},
+			"Failed to calculate GGR series")
+		return
+	}
+
+	// Call service to get the GGR series
+	results, err := h.service.CalculateGGRSeries(withCacheStatus(c), params.From, params.To, bucket)
+	if err != nil {
+		apierror.Abort(c, apierror.From(err, "Failed to calculate GGR series"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"timeframe":   gin.H{"from": params.From, "to": params.To},
+		"granularity": bucket.Granularity,
+		"tz":          bucket.Location(),
+		"data":        results,
 	})
 }
 
-// GetDailyWagerVolume handles the daily wager volume endpoint
+// GetDailyWagerVolume handles the wager volume endpoint, bucketed by UTC day unless granularity and tz are given
 func (h *TransactionHandler) GetDailyWagerVolume(c *gin.Context) {
-	var params TimeframeParams
+	var params TimeSeriesParams
 
-	// Parse query parameters
-	if err := c.ShouldBindQuery(&params); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date format. Use ISO 8601 (YYYY-MM-DDThh:mm:ssZ)"})
+	// Parse and validate query parameters and the response format
+	format, apiErr := h.bindStatsQuery(c, &params)
+	if apiErr != nil {
+		apierror.Abort(c, apiErr)
 		return
 	}
 
-	// Validate parameters
-	if err := h.validate.Struct(params); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Validation error: " + err.Error()})
+	bucket := params.TimeBucket()
+	if bucket.Granularity == "" {
+		bucket.Granularity = model.GranularityDay
+	}
+
+	// Stream CSV and NDJSON from the database rather than loading the whole result
+	if format != formatJSON {
+		streamRows[model.DailyWagerRow](c, h.service, format, exportName("daily_wager_volume", params.TimeframeParams), model.ReportTypeDailyWagerVolume,
+			model.ReportParams{From: params.From, To: params.To, Granularity: bucket.Granularity, Timezone: bucket.Timezone},
+			"Failed to calculate daily wager volume")
 		return
 	}
 
-	// Call service to get daily wager volume
-	results, err := h.service.CalculateDailyWagerVolume(c, params.From, params.To)
+	// Call service to get wager volume
+	results, err := h.service.CalculateDailyWagerVolume(withCacheStatus(c), params.From, params.To, bucket)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to calculate daily wager volume: " + err.Error()})
+		apierror.Abort(c, apierror.From(err, "Failed to calculate daily wager volume"))
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"timeframe": gin.H{"from": params.From, "to": params.To},
-		"data":      results,
+		"timeframe":   gin.H{"from": params.From, "to": params.To},
+		"granularity": bucket.Granularity,
+		"tz":          bucket.Location(),
+		"data":        results,
 	})
 }
 
@@ -94,32 +222,184 @@ func (h *TransactionHandler) GetUserWagerPercentile(c *gin.Context) {
 	// Get user ID from path
 	userID := c.Param("user_id")
 	if userID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "User ID is required"})
-		return
-	}
-
-	// Parse query parameters
-	if err := c.ShouldBindQuery(&params); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date format. Use ISO 8601 (YYYY-MM-DDThh:mm:ssZ)"})
+		apierror.Abort(c, apierror.Validation("User ID is required"))
 		return
 	}
 
-	// Validate parameters
-	if err := h.validate.Struct(params); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Validation error: " + err.Error()})
+	// Parse and validate query parameters and the response format
+	format, apiErr := h.bindStatsQuery(c, &params)
+	if apiErr != nil {
+		apierror.Abort(c, apiErr)
 		return
 	}
 
 	// Call service to get user wager percentile
-	percentile, err := h.service.CalculateUserWagerPercentile(c, userID, params.From, params.To)
+	percentile, err := h.service.CalculateUserWagerPercentile(withCacheStatus(c), userID, params.From, params.To)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to calculate user wager percentile: " + err.Error()})
+		apierror.Abort(c, apierror.From(err, "Failed to calculate user wager percentile"))
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
+	respond(c, format, exportName("wager_percentile_"+userID, params), gin.H{
 		"userID":     userID,
 		"percentile": percentile,
 		"timeframe":  gin.H{"from": params.From, "to": params.To},
+	}, []userPercentileRow{{UserID: userID, Percentile: percentile}})
+}
+
+// GetUserSummary handles the user summary endpoint
+func (h *TransactionHandler) GetUserSummary(c *gin.Context) {
+	var params TimeframeParams
+
+	// Get user ID from path
+	userID := c.Param("user_id")
+	if userID == "" {
+		apierror.Abort(c, apierror.Validation("User ID is required"))
+		return
+	}
+
+	// Parse and validate query parameters and the response format
+	format, apiErr := h.bindStatsQuery(c, &params)
+	if apiErr != nil {
+		apierror.Abort(c, apiErr)
+		return
+	}
+
+	// Call service to get user summary
+	summary, err := h.service.CalculateUserSummary(withCacheStatus(c), userID, params.From, params.To)
+	if err != nil {
+		apierror.Abort(c, apierror.From(err, "Failed to calculate user summary"))
+		return
+	}
+
+	// CSV and NDJSON list the per-currency rows; the USD total is only in JSON
+	respond(c, format, exportName("summary_"+userID, params), gin.H{
+		"userID":    userID,
+		"timeframe": gin.H{"from": params.From, "to": params.To},
+		"data":      summary,
+	}, summary.Currencies)
+}
+
+// GetLeaderboard handles the leaderboard endpoint
+func (h *TransactionHandler) GetLeaderboard(c *gin.Context) {
+	var params LeaderboardParams
+
+	// Parse and validate query parameters and the response format
+	format, apiErr := h.bindStatsQuery(c, &params)
+	if apiErr != nil {
+		apierror.Abort(c, apiErr)
+		return
+	}
+
+	// Apply defaults
+	if params.Metric == "" {
+		params.Metric = defaultLeaderboardMetric
+	}
+	if params.Limit == 0 {
+		params.Limit = defaultLeaderboardLimit
+	}
+
+	// Stream CSV and NDJSON from the database rather than loading the whole result
+	if format != formatJSON {
+		streamRows[model.LeaderboardRow](c, h.service, format, exportName("leaderboard_"+params.Metric, params.TimeframeParams), model.ReportTypeLeaderboard,
+			model.ReportParams{From: params.From, To: params.To, Metric: params.Metric, Currency: params.Currency, Limit: params.Limit},
+			"Failed to calculate leaderboard")
+		return
+	}
+
+	// Call service to get leaderboard
+	results, err := h.service.CalculateLeaderboard(withCacheStatus(c), params.Metric, params.Currency, params.Limit, params.From, params.To)
+	if err != nil {
+		apierror.Abort(c, apierror.From(err, "Failed to calculate leaderboard"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"timeframe": gin.H{"from": params.From, "to": params.To},
+		"metric":    params.Metric,
+		"currency":  params.Currency,
+		"data":      results,
+	})
+}
+
+// GetRoundAnomalies handles the round reconciliation report endpoint
+func (h *TransactionHandler) GetRoundAnomalies(c *gin.Context) {
+	var params TimeframeParams
+
+	// Parse and validate query parameters and the response format
+	format, apiErr := h.bindStatsQuery(c, &params)
+	if apiErr != nil {
+		apierror.Abort(c, apiErr)
+		return
+	}
+
+	// Stream CSV and NDJSON from the database rather than loading the whole result
+	if format != formatJSON {
+		streamRows[model.RoundAnomaly](c, h.service, format, exportName("round_anomalies", params), model.ReportTypeRoundAnomalies,
+			model.ReportParams{From: params.From, To: params.To},
+			"Failed to find round anomalies")
+		return
+	}
+
+	// Call service to get round anomalies
+	results, err := h.service.FindRoundAnomalies(withCacheStatus(c), params.From, params.To)
+	if err != nil {
+		apierror.Abort(c, apierror.From(err, "Failed to find round anomalies"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"timeframe": gin.H{"from": params.From, "to": params.To},
+		"data":      results,
+	})
+}
+
+// CreateTransaction handles ingestion of a single transaction
+func (h *TransactionHandler) CreateTransaction(c *gin.Context) {
+	var transaction model.Transaction
+
+	// Parse request body
+	if err := c.ShouldBindJSON(&transaction); err != nil {
+		apierror.Abort(c, bodyError(err))
+		return
+	}
+
+	h.createTransactions(c, []model.Transaction{transaction})
+}
+
+// CreateTransactionBatch handles ingestion of a batch of transactions
+func (h *TransactionHandler) CreateTransactionBatch(c *gin.Context) {
+	var transactions []model.Transaction
+
+	// Parse request body
+	if err := c.ShouldBindJSON(&transactions); err != nil {
+		apierror.Abort(c, bodyError(err))
+		return
+	}
+
+	h.createTransactions(c, transactions)
+}
+
+// createTransactions stores transactions through the service and writes the ingestion summary
+func (h *TransactionHandler) createTransactions(c *gin.Context, transactions []model.Transaction) {
+	inserted, err := h.service.CreateTransactions(c, transactions)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidTransaction) {
+			apierror.Abort(c, apierror.Validation("Validation failed", apierror.Detail{Message: err.Error()}))
+			return
+		}
+		apierror.Abort(c, apierror.From(err, "Failed to store transactions"))
+		return
+	}
+
+	// Replayed transactions are reported as duplicates so retries can be told apart from new writes
+	status := http.StatusCreated
+	if inserted == 0 {
+		status = http.StatusOK
+	}
+
+	c.JSON(status, gin.H{
+		"inserted":   inserted,
+		"duplicates": len(transactions) - inserted,
 	})
-}
\ No newline at end of file
+}
@@ -4,39 +4,57 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/go-playground/validator/v10"
 	"github.com/stretchr/testify/assert"
+	"admin-statistics-api/internal/model"
+	"admin-statistics-api/internal/repository"
 	"admin-statistics-api/internal/service"
 )
 
 // MockTransactionService implements service.TransactionServiceInterface for testing
 type MockTransactionService struct {
-	GGRFn               func(ctx context.Context, from, to time.Time) ([]map[string]interface{}, error)
-	DailyWagerVolumeFn  func(ctx context.Context, from, to time.Time) ([]map[string]interface{}, error)
+	GGRFn               func(ctx context.Context, from, to time.Time, bucket model.TimeBucket) ([]model.GGRRow, error)
+	GGRSeriesFn         func(ctx context.Context, from, to time.Time, bucket model.TimeBucket) ([]model.GGRSeriesRow, error)
+	DailyWagerVolumeFn  func(ctx context.Context, from, to time.Time, bucket model.TimeBucket) ([]model.DailyWagerRow, error)
 	UserPercentileFn    func(ctx context.Context, userID string, from, to time.Time) (float64, error)
+	UserSummaryFn       func(ctx context.Context, userID string, from, to time.Time) (model.UserSummary, error)
+	LeaderboardFn       func(ctx context.Context, metric, currency string, limit int, from, to time.Time) ([]model.LeaderboardRow, error)
+	RoundAnomaliesFn    func(ctx context.Context, from, to time.Time) ([]model.RoundAnomaly, error)
+	CreateTransactionsFn func(ctx context.Context, transactions []model.Transaction) (int, error)
+	StreamRowsFn         func(ctx context.Context, reportType string, params model.ReportParams) (repository.RowCursor, error)
 }
 
 // Make sure MockTransactionService implements the interface
 var _ service.TransactionServiceInterface = (*MockTransactionService)(nil)
 
 // CalculateGGR implements service.TransactionServiceInterface
-func (m *MockTransactionService) CalculateGGR(ctx context.Context, from, to time.Time) ([]map[string]interface{}, error) {
+func (m *MockTransactionService) CalculateGGR(ctx context.Context, from, to time.Time, bucket model.TimeBucket) ([]model.GGRRow, error) {
 	if m.GGRFn != nil {
-		return m.GGRFn(ctx, from, to)
+		return m.GGRFn(ctx, from, to, bucket)
+	}
+	return nil, errors.New("not implemented")
+}
+
+// CalculateGGRSeries implements service.TransactionServiceInterface
+func (m *MockTransactionService) CalculateGGRSeries(ctx context.Context, from, to time.Time, bucket model.TimeBucket) ([]model.GGRSeriesRow, error) {
+	if m.GGRSeriesFn != nil {
+		return m.GGRSeriesFn(ctx, from, to, bucket)
 	}
 	return nil, errors.New("not implemented")
 }
 
 // CalculateDailyWagerVolume implements service.TransactionServiceInterface
-func (m *MockTransactionService) CalculateDailyWagerVolume(ctx context.Context, from, to time.Time) ([]map[string]interface{}, error) {
+func (m *MockTransactionService) CalculateDailyWagerVolume(ctx context.Context, from, to time.Time, bucket model.TimeBucket) ([]model.DailyWagerRow, error) {
 	if m.DailyWagerVolumeFn != nil {
-		return m.DailyWagerVolumeFn(ctx, from, to)
+		return m.DailyWagerVolumeFn(ctx, from, to, bucket)
 	}
 	return nil, errors.New("not implemented")
 }
@@ -49,19 +67,93 @@ func (m *MockTransactionService) CalculateUserWagerPercentile(ctx context.Contex
 	return 0, errors.New("not implemented")
 }
 
+// CalculateUserSummary implements service.TransactionServiceInterface
+func (m *MockTransactionService) CalculateUserSummary(ctx context.Context, userID string, from, to time.Time) (model.UserSummary, error) {
+	if m.UserSummaryFn != nil {
+		return m.UserSummaryFn(ctx, userID, from, to)
+	}
+	return model.UserSummary{}, errors.New("not implemented")
+}
+
+// CalculateLeaderboard implements service.TransactionServiceInterface
+func (m *MockTransactionService) CalculateLeaderboard(ctx context.Context, metric, currency string, limit int, from, to time.Time) ([]model.LeaderboardRow, error) {
+	if m.LeaderboardFn != nil {
+		return m.LeaderboardFn(ctx, metric, currency, limit, from, to)
+	}
+	return nil, errors.New("not implemented")
+}
+
+// FindRoundAnomalies implements service.TransactionServiceInterface
+func (m *MockTransactionService) FindRoundAnomalies(ctx context.Context, from, to time.Time) ([]model.RoundAnomaly, error) {
+	if m.RoundAnomaliesFn != nil {
+		return m.RoundAnomaliesFn(ctx, from, to)
+	}
+	return nil, errors.New("not implemented")
+}
+
+// StreamRows implements service.TransactionServiceInterface. Without StreamRowsFn it
+// streams the rows the function for the report type returns.
+func (m *MockTransactionService) StreamRows(ctx context.Context, reportType string, params model.ReportParams) (repository.RowCursor, error) {
+	if m.StreamRowsFn != nil {
+		return m.StreamRowsFn(ctx, reportType, params)
+	}
+
+	var rows interface{}
+	var err error
+	switch reportType {
+	case model.ReportTypeGGR:
+		rows, err = m.CalculateGGR(ctx, params.From, params.To, params.TimeBucket())
+	case model.ReportTypeGGRSeries:
+		rows, err = m.CalculateGGRSeries(ctx, params.From, params.To, params.TimeBucket())
+	case model.ReportTypeDailyWagerVolume:
+		rows, err = m.CalculateDailyWagerVolume(ctx, params.From, params.To, params.TimeBucket())
+	case model.ReportTypeLeaderboard:
+		rows, err = m.CalculateLeaderboard(ctx, params.Metric, params.Currency, params.Limit, params.From, params.To)
+	case model.ReportTypeRoundAnomalies:
+		rows, err = m.FindRoundAnomalies(ctx, params.From, params.To)
+	default:
+		return nil, repository.ErrNotStreamable
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	values := reflect.ValueOf(rows)
+	items := make([]interface{}, values.Len())
+	for i := range items {
+		items[i] = values.Index(i).Interface()
+	}
+	return repository.NewMockRowCursor(items...), nil
+}
+
+// CreateTransactions implements service.TransactionServiceInterface
+func (m *MockTransactionService) CreateTransactions(ctx context.Context, transactions []model.Transaction) (int, error) {
+	if m.CreateTransactionsFn != nil {
+		return m.CreateTransactionsFn(ctx, transactions)
+	}
+	return 0, errors.New("not implemented")
+}
+
 // Setup the test router
 func setupTestRouter(mockService service.TransactionServiceInterface) *gin.Engine {
 	gin.SetMode(gin.TestMode)
 	router := gin.New()
+	router.ContextWithFallback = true
 
 	handler := &TransactionHandler{
 		service:  mockService,
-		validate: validator.New(), // Initialize validator properly
+		validate: newValidator(),
 	}
 
 	router.GET("/gross_gaming_rev", handler.GetGrossGamingRevenue)
+	router.GET("/gross_gaming_rev/series", handler.GetGrossGamingRevenueSeries)
 	router.GET("/daily_wager_volume", handler.GetDailyWagerVolume)
 	router.GET("/user/:user_id/wager_percentile", handler.GetUserWagerPercentile)
+	router.GET("/user/:user_id/summary", handler.GetUserSummary)
+	router.GET("/leaderboard", handler.GetLeaderboard)
+	router.GET("/rounds/anomalies", handler.GetRoundAnomalies)
+	router.POST("/transactions", handler.CreateTransaction)
+	router.POST("/transactions/batch", handler.CreateTransactionBatch)
 
 	return router
 }
@@ -71,12 +163,12 @@ func TestGetGrossGamingRevenue(t *testing.T) {
 	t.Run("returns 200 with valid data", func(t *testing.T) {
 		// Arrange
 		mockService := &MockTransactionService{
-			GGRFn: func(ctx context.Context, from, to time.Time) ([]map[string]interface{}, error) {
-				return []map[string]interface{}{
+			GGRFn: func(ctx context.Context, from, to time.Time, bucket model.TimeBucket) ([]model.GGRRow, error) {
+				return []model.GGRRow{
 					{
-						"currency": "BTC",
-						"ggr":      "10.50",
-						"ggrUSD":   "525000.00",
+						Currency: "BTC",
+						GGR:      model.MustParseDecimal("10.50"),
+						GGRUSD:   model.MustParseDecimal("525000.00"),
 					},
 				}, nil
 			},
@@ -102,6 +194,7 @@ func TestGetGrossGamingRevenue(t *testing.T) {
 		assert.Len(t, data, 1)
 		firstItem := data[0].(map[string]interface{})
 		assert.Equal(t, "BTC", firstItem["currency"])
+		assert.Equal(t, "10.50", firstItem["ggr"], "Decimals should be serialized as strings")
 	})
 
 	t.Run("returns 400 with invalid date format", func(t *testing.T) {
@@ -120,14 +213,18 @@ func TestGetGrossGamingRevenue(t *testing.T) {
 		assert.Equal(t, 400, w.Code)
 		var response map[string]interface{}
 		_ = json.Unmarshal(w.Body.Bytes(), &response)
-		assert.Contains(t, response, "error")
-		assert.Contains(t, response["error"].(string), "Invalid date format")
+		assert.Equal(t, "validation_error", response["code"])
+		assert.Equal(t, "Invalid query parameters", response["message"])
+		details := response["details"].([]interface{})
+		if assert.Len(t, details, 1) {
+			assert.Equal(t, "from", details[0].(map[string]interface{})["field"])
+		}
 	})
 
 	t.Run("returns 500 when service returns error", func(t *testing.T) {
 		// Arrange
 		mockService := &MockTransactionService{
-			GGRFn: func(ctx context.Context, from, to time.Time) ([]map[string]interface{}, error) {
+			GGRFn: func(ctx context.Context, from, to time.Time, bucket model.TimeBucket) ([]model.GGRRow, error) {
 				return nil, errors.New("service error")
 			},
 		}
@@ -146,8 +243,254 @@ func TestGetGrossGamingRevenue(t *testing.T) {
 		assert.Equal(t, 500, w.Code)
 		var response map[string]interface{}
 		_ = json.Unmarshal(w.Body.Bytes(), &response)
-		assert.Contains(t, response, "error")
-		assert.Contains(t, response["error"].(string), "Failed to calculate GGR")
+		assert.Equal(t, "internal_error", response["code"])
+		assert.Equal(t, "Failed to calculate GGR", response["message"])
+		assert.NotContains(t, w.Body.String(), "service error", "Internal details stay out of the response")
+	})
+	t.Run("returns 503 with Retry-After while the circuit breaker is open", func(t *testing.T) {
+		// Arrange
+		mockService := &MockTransactionService{
+			GGRFn: func(ctx context.Context, from, to time.Time, bucket model.TimeBucket) ([]model.GGRRow, error) {
+				return nil, &repository.CircuitOpenError{Name: "mongodb", OpenFor: 1500 * time.Millisecond}
+			},
+		}
+		router := setupTestRouter(mockService)
+		req, _ := http.NewRequest("GET", "/gross_gaming_rev?from=2023-01-01T00:00:00Z&to=2023-01-31T00:00:00Z", nil)
+		w := httptest.NewRecorder()
+
+		// Act
+		router.ServeHTTP(w, req)
+
+		// Assert
+		assert.Equal(t, 503, w.Code)
+		assert.Equal(t, "2", w.Header().Get("Retry-After"))
+		assert.Contains(t, w.Body.String(), `"code":"upstream_unavailable"`)
+		assert.NotContains(t, w.Body.String(), "mongodb", "Internal details stay out of the response")
+	})
+}
+
+func TestGetGrossGamingRevenue_Bucketing(t *testing.T) {
+	timeframe := "from=2023-01-01T00:00:00Z&to=2023-01-31T00:00:00Z"
+
+	t.Run("passes granularity and time zone to service", func(t *testing.T) {
+		// Arrange
+		var gotBucket model.TimeBucket
+		mockService := &MockTransactionService{
+			GGRFn: func(ctx context.Context, from, to time.Time, bucket model.TimeBucket) ([]model.GGRRow, error) {
+				gotBucket = bucket
+				return []model.GGRRow{{Date: "2023-01-01T08:00", Currency: "BTC"}}, nil
+			},
+		}
+		router := setupTestRouter(mockService)
+
+		req, _ := http.NewRequest("GET", "/gross_gaming_rev?granularity=hour&tz=Asia/Singapore&"+timeframe, nil)
+		w := httptest.NewRecorder()
+
+		// Act
+		router.ServeHTTP(w, req)
+
+		// Assert
+		assert.Equal(t, 200, w.Code)
+		assert.Equal(t, model.TimeBucket{Granularity: "hour", Timezone: "Asia/Singapore"}, gotBucket)
+		var response map[string]interface{}
+		_ = json.Unmarshal(w.Body.Bytes(), &response)
+		assert.Equal(t, "hour", response["granularity"])
+		assert.Equal(t, "Asia/Singapore", response["tz"])
+		firstItem := response["data"].([]interface{})[0].(map[string]interface{})
+		assert.Equal(t, "2023-01-01T08:00", firstItem["date"])
+	})
+
+	t.Run("returns one total per currency without granularity", func(t *testing.T) {
+		// Arrange
+		var gotBucket model.TimeBucket
+		mockService := &MockTransactionService{
+			GGRFn: func(ctx context.Context, from, to time.Time, bucket model.TimeBucket) ([]model.GGRRow, error) {
+				gotBucket = bucket
+				return []model.GGRRow{{Currency: "BTC"}}, nil
+			},
+		}
+		router := setupTestRouter(mockService)
+
+		req, _ := http.NewRequest("GET", "/gross_gaming_rev?"+timeframe, nil)
+		w := httptest.NewRecorder()
+
+		// Act
+		router.ServeHTTP(w, req)
+
+		// Assert
+		assert.Equal(t, 200, w.Code)
+		assert.True(t, gotBucket.IsZero())
+		var response map[string]interface{}
+		_ = json.Unmarshal(w.Body.Bytes(), &response)
+		assert.NotContains(t, response, "granularity")
+		assert.NotContains(t, response["data"].([]interface{})[0], "date")
+	})
+
+	t.Run("resolves a named range", func(t *testing.T) {
+		// Arrange
+		var gotFrom, gotTo time.Time
+		mockService := &MockTransactionService{
+			GGRFn: func(ctx context.Context, from, to time.Time, bucket model.TimeBucket) ([]model.GGRRow, error) {
+				gotFrom, gotTo = from, to
+				return []model.GGRRow{}, nil
+			},
+		}
+		router := setupTestRouter(mockService)
+
+		req, _ := http.NewRequest("GET", "/gross_gaming_rev?range=month_to_date", nil)
+		w := httptest.NewRecorder()
+
+		// Act
+		before := time.Now()
+		router.ServeHTTP(w, req)
+
+		// Assert
+		assert.Equal(t, 200, w.Code)
+		assert.Equal(t, time.Date(gotTo.Year(), gotTo.Month(), 1, 0, 0, 0, 0, time.UTC), gotFrom)
+		assert.False(t, gotTo.Before(before))
+	})
+
+	t.Run("returns 400 with invalid bucketing", func(t *testing.T) {
+		queries := map[string]string{
+			"unknown granularity":     "granularity=minute&" + timeframe,
+			"unknown time zone":       "granularity=day&tz=Mars/Olympus&" + timeframe,
+			"tz without granularity":  "tz=Asia/Singapore&" + timeframe,
+		}
+
+		for name, query := range queries {
+			t.Run(name, func(t *testing.T) {
+				// Arrange
+				mockService := &MockTransactionService{}
+				router := setupTestRouter(mockService)
+
+				req, _ := http.NewRequest("GET", "/gross_gaming_rev?"+query, nil)
+				w := httptest.NewRecorder()
+
+				// Act
+				router.ServeHTTP(w, req)
+
+				// Assert
+				assert.Equal(t, 400, w.Code)
+			})
+		}
+	})
+}
+
+func TestGetGrossGamingRevenueSeries(t *testing.T) {
+	timeframe := "from=2023-01-01T00:00:00Z&to=2023-01-31T00:00:00Z"
+
+	t.Run("returns 200 with valid data", func(t *testing.T) {
+		// Arrange
+		holdPercent := model.MustParseDecimal("4.00")
+		mockService := &MockTransactionService{
+			GGRSeriesFn: func(ctx context.Context, from, to time.Time, bucket model.TimeBucket) ([]model.GGRSeriesRow, error) {
+				return []model.GGRSeriesRow{
+					{
+						Date:        "2023-01-01",
+						Currency:    "ETH",
+						Wager:       model.MustParseDecimal("150.00"),
+						Payout:      model.MustParseDecimal("144.00"),
+						GGR:         model.MustParseDecimal("6.00"),
+						WagerUSD:    model.MustParseDecimal("300000.00"),
+						PayoutUSD:   model.MustParseDecimal("288000.00"),
+						GGRUSD:      model.MustParseDecimal("12000.00"),
+						HoldPercent: &holdPercent,
+					},
+					{
+						Date:     "2023-01-01",
+						Currency: "BTC",
+						Payout:   model.MustParseDecimal("0.5"),
+						GGR:      model.MustParseDecimal("-0.5"),
+					},
+				}, nil
+			},
+		}
+		router := setupTestRouter(mockService)
+
+		req, _ := http.NewRequest("GET", "/gross_gaming_rev/series?"+timeframe, nil)
+		w := httptest.NewRecorder()
+
+		// Act
+		router.ServeHTTP(w, req)
+
+		// Assert
+		assert.Equal(t, 200, w.Code)
+
+		var response map[string]interface{}
+		err := json.Unmarshal(w.Body.Bytes(), &response)
+		assert.NoError(t, err)
+		assert.Equal(t, "day", response["granularity"])
+		assert.Equal(t, "UTC", response["tz"])
+
+		data := response["data"].([]interface{})
+		assert.Len(t, data, 2)
+		firstItem := data[0].(map[string]interface{})
+		assert.Equal(t, "2023-01-01", firstItem["date"])
+		assert.Equal(t, "150.00", firstItem["wager"])
+		assert.Equal(t, "144.00", firstItem["payout"])
+		assert.Equal(t, "6.00", firstItem["ggr"])
+		assert.Equal(t, "12000.00", firstItem["ggrUSD"])
+		assert.Equal(t, "4.00", firstItem["holdPercent"])
+
+		secondItem := data[1].(map[string]interface{})
+		assert.Contains(t, secondItem, "holdPercent")
+		assert.Nil(t, secondItem["holdPercent"])
+	})
+
+	t.Run("passes granularity and time zone to service", func(t *testing.T) {
+		// Arrange
+		var gotBucket model.TimeBucket
+		mockService := &MockTransactionService{
+			GGRSeriesFn: func(ctx context.Context, from, to time.Time, bucket model.TimeBucket) ([]model.GGRSeriesRow, error) {
+				gotBucket = bucket
+				return []model.GGRSeriesRow{}, nil
+			},
+		}
+		router := setupTestRouter(mockService)
+
+		req, _ := http.NewRequest("GET", "/gross_gaming_rev/series?granularity=month&tz=Europe/London&"+timeframe, nil)
+		w := httptest.NewRecorder()
+
+		// Act
+		router.ServeHTTP(w, req)
+
+		// Assert
+		assert.Equal(t, 200, w.Code)
+		assert.Equal(t, model.TimeBucket{Granularity: "month", Timezone: "Europe/London"}, gotBucket)
+	})
+
+	t.Run("returns 400 with invalid granularity", func(t *testing.T) {
+		// Arrange
+		mockService := &MockTransactionService{}
+		router := setupTestRouter(mockService)
+
+		req, _ := http.NewRequest("GET", "/gross_gaming_rev/series?granularity=year&"+timeframe, nil)
+		w := httptest.NewRecorder()
+
+		// Act
+		router.ServeHTTP(w, req)
+
+		// Assert
+		assert.Equal(t, 400, w.Code)
+	})
+
+	t.Run("returns 500 when service fails", func(t *testing.T) {
+		// Arrange
+		mockService := &MockTransactionService{
+			GGRSeriesFn: func(ctx context.Context, from, to time.Time, bucket model.TimeBucket) ([]model.GGRSeriesRow, error) {
+				return nil, errors.New("database error")
+			},
+		}
+		router := setupTestRouter(mockService)
+
+		req, _ := http.NewRequest("GET", "/gross_gaming_rev/series?"+timeframe, nil)
+		w := httptest.NewRecorder()
+
+		// Act
+		router.ServeHTTP(w, req)
+
+		// Assert
+		assert.Equal(t, 500, w.Code)
 	})
 }
 
@@ -156,13 +499,13 @@ func TestGetDailyWagerVolume(t *testing.T) {
 	t.Run("returns 200 with valid data", func(t *testing.T) {
 		// Arrange
 		mockService := &MockTransactionService{
-			DailyWagerVolumeFn: func(ctx context.Context, from, to time.Time) ([]map[string]interface{}, error) {
-				return []map[string]interface{}{
+			DailyWagerVolumeFn: func(ctx context.Context, from, to time.Time, bucket model.TimeBucket) ([]model.DailyWagerRow, error) {
+				return []model.DailyWagerRow{
 					{
-						"date":           "2023-01-01",
-						"currency":       "ETH",
-						"wagerAmount":    "150.75",
-						"wagerUSDAmount": "301500.00",
+						Date:           "2023-01-01",
+						Currency:       "ETH",
+						WagerAmount:    model.MustParseDecimal("150.75"),
+						WagerUSDAmount: model.MustParseDecimal("301500.00"),
 					},
 				}, nil
 			},
@@ -188,6 +531,54 @@ func TestGetDailyWagerVolume(t *testing.T) {
 		firstItem := data[0].(map[string]interface{})
 		assert.Equal(t, "2023-01-01", firstItem["date"])
 	})
+
+	t.Run("defaults to UTC days", func(t *testing.T) {
+		// Arrange
+		var gotBucket model.TimeBucket
+		mockService := &MockTransactionService{
+			DailyWagerVolumeFn: func(ctx context.Context, from, to time.Time, bucket model.TimeBucket) ([]model.DailyWagerRow, error) {
+				gotBucket = bucket
+				return []model.DailyWagerRow{}, nil
+			},
+		}
+		router := setupTestRouter(mockService)
+
+		req, _ := http.NewRequest("GET", "/daily_wager_volume?from=2023-01-01T00:00:00Z&to=2023-01-31T00:00:00Z", nil)
+		w := httptest.NewRecorder()
+
+		// Act
+		router.ServeHTTP(w, req)
+
+		// Assert
+		assert.Equal(t, 200, w.Code)
+		assert.Equal(t, model.TimeBucket{Granularity: model.GranularityDay}, gotBucket)
+		var response map[string]interface{}
+		_ = json.Unmarshal(w.Body.Bytes(), &response)
+		assert.Equal(t, "day", response["granularity"])
+		assert.Equal(t, "UTC", response["tz"])
+	})
+
+	t.Run("passes granularity and time zone to service", func(t *testing.T) {
+		// Arrange
+		var gotBucket model.TimeBucket
+		mockService := &MockTransactionService{
+			DailyWagerVolumeFn: func(ctx context.Context, from, to time.Time, bucket model.TimeBucket) ([]model.DailyWagerRow, error) {
+				gotBucket = bucket
+				return []model.DailyWagerRow{}, nil
+			},
+		}
+		router := setupTestRouter(mockService)
+
+		req, _ := http.NewRequest("GET", "/daily_wager_volume?granularity=week&tz=Australia/Sydney&from=2023-01-01T00:00:00Z&to=2023-01-31T00:00:00Z", nil)
+		w := httptest.NewRecorder()
+
+		// Act
+		router.ServeHTTP(w, req)
+
+		// Assert
+		assert.Equal(t, 200, w.Code)
+		assert.Equal(t, model.TimeBucket{Granularity: "week", Timezone: "Australia/Sydney"}, gotBucket)
+	})
 }
 
 func TestGetUserWagerPercentile(t *testing.T) {
@@ -238,8 +629,8 @@ func TestGetUserWagerPercentile(t *testing.T) {
 		assert.Equal(t, 400, w.Code) // Handler returns 400 Bad Request when user ID is empty
 		var response map[string]interface{}
 		_ = json.Unmarshal(w.Body.Bytes(), &response)
-		assert.Contains(t, response, "error")
-		assert.Contains(t, response["error"].(string), "User ID is required")
+		assert.Equal(t, "validation_error", response["code"])
+		assert.Equal(t, "User ID is required", response["message"])
 	})
 
 	t.Run("returns 500 when service returns error", func(t *testing.T) {
@@ -265,7 +656,491 @@ func TestGetUserWagerPercentile(t *testing.T) {
 		assert.Equal(t, 500, w.Code)
 		var response map[string]interface{}
 		_ = json.Unmarshal(w.Body.Bytes(), &response)
-		assert.Contains(t, response, "error")
-		assert.Contains(t, response["error"].(string), "Failed to calculate user wager percentile")
+		assert.Equal(t, "internal_error", response["code"])
+		assert.Contains(t, response["message"].(string), "Failed to calculate user wager percentile")
+	})
+}
+
+func TestGetUserSummary(t *testing.T) {
+	t.Run("returns 200 with valid data", func(t *testing.T) {
+		// Arrange
+		var receivedUserID string
+		mockService := &MockTransactionService{
+			UserSummaryFn: func(ctx context.Context, userID string, from, to time.Time) (model.UserSummary, error) {
+				receivedUserID = userID
+				return model.UserSummary{
+					Currencies: []model.UserCurrencySummary{
+						{Currency: "BTC", Wagered: model.MustParseDecimal("1.5"), Rounds: 3},
+					},
+					Total: &model.UserTotalSummary{WageredUSD: model.MustParseDecimal("75000"), Rounds: 3},
+				}, nil
+			},
+		}
+		router := setupTestRouter(mockService)
+
+		// Setup request
+		userID := "01HRMD5HGTZB3TW3PGYXRD07CQ"
+		req, _ := http.NewRequest("GET", "/user/"+userID+"/summary?from=2023-01-01T00:00:00Z&to=2023-01-31T00:00:00Z", nil)
+		w := httptest.NewRecorder()
+
+		// Act
+		router.ServeHTTP(w, req)
+
+		// Assert
+		assert.Equal(t, 200, w.Code)
+		assert.Equal(t, userID, receivedUserID)
+		var response map[string]interface{}
+		_ = json.Unmarshal(w.Body.Bytes(), &response)
+		assert.Equal(t, userID, response["userID"])
+		assert.Contains(t, response, "timeframe")
+		data := response["data"].(map[string]interface{})
+		assert.Len(t, data["currencies"], 1)
+		assert.Equal(t, float64(3), data["total"].(map[string]interface{})["rounds"])
+	})
+
+	t.Run("returns 400 with invalid date format", func(t *testing.T) {
+		// Arrange
+		mockService := &MockTransactionService{}
+		router := setupTestRouter(mockService)
+
+		req, _ := http.NewRequest("GET", "/user/01HRMD5HGTZB3TW3PGYXRD07CQ/summary?from=yesterday&to=2023-01-31T00:00:00Z", nil)
+		w := httptest.NewRecorder()
+
+		// Act
+		router.ServeHTTP(w, req)
+
+		// Assert
+		assert.Equal(t, 400, w.Code)
 	})
-}
\ No newline at end of file
+
+	t.Run("returns 500 when service returns error", func(t *testing.T) {
+		// Arrange
+		mockService := &MockTransactionService{
+			UserSummaryFn: func(ctx context.Context, userID string, from, to time.Time) (model.UserSummary, error) {
+				return model.UserSummary{}, errors.New("service error")
+			},
+		}
+		router := setupTestRouter(mockService)
+
+		req, _ := http.NewRequest("GET", "/user/01HRMD5HGTZB3TW3PGYXRD07CQ/summary?from=2023-01-01T00:00:00Z&to=2023-01-31T00:00:00Z", nil)
+		w := httptest.NewRecorder()
+
+		// Act
+		router.ServeHTTP(w, req)
+
+		// Assert
+		assert.Equal(t, 500, w.Code)
+		var response map[string]interface{}
+		_ = json.Unmarshal(w.Body.Bytes(), &response)
+		assert.Contains(t, response["message"].(string), "Failed to calculate user summary")
+	})
+}
+
+func TestGetLeaderboard(t *testing.T) {
+	timeframe := "from=2023-01-01T00:00:00Z&to=2023-01-31T00:00:00Z"
+
+	t.Run("applies defaults when metric and limit are omitted", func(t *testing.T) {
+		// Arrange
+		var gotMetric, gotCurrency string
+		var gotLimit int
+		mockService := &MockTransactionService{
+			LeaderboardFn: func(ctx context.Context, metric, currency string, limit int, from, to time.Time) ([]model.LeaderboardRow, error) {
+				gotMetric, gotCurrency, gotLimit = metric, currency, limit
+				return []model.LeaderboardRow{
+					{UserID: "01HRMD5HGTZB3TW3PGYXRD07CQ", Rank: 1, Percentile: 100.0},
+				}, nil
+			},
+		}
+		router := setupTestRouter(mockService)
+
+		req, _ := http.NewRequest("GET", "/leaderboard?"+timeframe, nil)
+		w := httptest.NewRecorder()
+
+		// Act
+		router.ServeHTTP(w, req)
+
+		// Assert
+		assert.Equal(t, 200, w.Code)
+		assert.Equal(t, model.LeaderboardMetricWager, gotMetric)
+		assert.Equal(t, "", gotCurrency)
+		assert.Equal(t, 10, gotLimit)
+		var response map[string]interface{}
+		_ = json.Unmarshal(w.Body.Bytes(), &response)
+		assert.Equal(t, "wager", response["metric"])
+		data := response["data"].([]interface{})
+		assert.Len(t, data, 1)
+		assert.Equal(t, float64(1), data[0].(map[string]interface{})["rank"])
+		assert.NotContains(t, data[0], "wager", "Native amounts are omitted across currencies")
+	})
+
+	t.Run("passes metric, currency and limit to service", func(t *testing.T) {
+		// Arrange
+		var gotMetric, gotCurrency string
+		var gotLimit int
+		mockService := &MockTransactionService{
+			LeaderboardFn: func(ctx context.Context, metric, currency string, limit int, from, to time.Time) ([]model.LeaderboardRow, error) {
+				gotMetric, gotCurrency, gotLimit = metric, currency, limit
+				return []model.LeaderboardRow{}, nil
+			},
+		}
+		router := setupTestRouter(mockService)
+
+		req, _ := http.NewRequest("GET", "/leaderboard?metric=ggr&currency=BTC&limit=25&"+timeframe, nil)
+		w := httptest.NewRecorder()
+
+		// Act
+		router.ServeHTTP(w, req)
+
+		// Assert
+		assert.Equal(t, 200, w.Code)
+		assert.Equal(t, "ggr", gotMetric)
+		assert.Equal(t, "BTC", gotCurrency)
+		assert.Equal(t, 25, gotLimit)
+	})
+
+	t.Run("returns 400 with invalid parameters", func(t *testing.T) {
+		queries := map[string]string{
+			"unknown metric":   "metric=deposits&" + timeframe,
+			"unknown currency": "currency=DOGE&" + timeframe,
+			"limit too large":  "limit=5000&" + timeframe,
+			"non-numeric":      "limit=ten&" + timeframe,
+			"missing dates":    "metric=wager",
+		}
+
+		for name, query := range queries {
+			t.Run(name, func(t *testing.T) {
+				// Arrange
+				mockService := &MockTransactionService{}
+				router := setupTestRouter(mockService)
+
+				req, _ := http.NewRequest("GET", "/leaderboard?"+query, nil)
+				w := httptest.NewRecorder()
+
+				// Act
+				router.ServeHTTP(w, req)
+
+				// Assert
+				assert.Equal(t, 400, w.Code)
+			})
+		}
+	})
+
+	t.Run("returns 500 when service returns error", func(t *testing.T) {
+		// Arrange
+		mockService := &MockTransactionService{
+			LeaderboardFn: func(ctx context.Context, metric, currency string, limit int, from, to time.Time) ([]model.LeaderboardRow, error) {
+				return nil, errors.New("service error")
+			},
+		}
+		router := setupTestRouter(mockService)
+
+		req, _ := http.NewRequest("GET", "/leaderboard?"+timeframe, nil)
+		w := httptest.NewRecorder()
+
+		// Act
+		router.ServeHTTP(w, req)
+
+		// Assert
+		assert.Equal(t, 500, w.Code)
+		var response map[string]interface{}
+		_ = json.Unmarshal(w.Body.Bytes(), &response)
+		assert.Contains(t, response["message"].(string), "Failed to calculate leaderboard")
+	})
+}
+
+func TestGetRoundAnomalies(t *testing.T) {
+	t.Run("returns 200 with valid data", func(t *testing.T) {
+		// Arrange
+		mockService := &MockTransactionService{
+			RoundAnomaliesFn: func(ctx context.Context, from, to time.Time) ([]model.RoundAnomaly, error) {
+				return []model.RoundAnomaly{
+					{
+						RoundID:   "round-1",
+						Anomalies: []string{model.RoundAnomalyPayoutWithoutWager},
+					},
+				}, nil
+			},
+		}
+		router := setupTestRouter(mockService)
+
+		// Setup request
+		req, _ := http.NewRequest("GET", "/rounds/anomalies?from=2023-01-01T00:00:00Z&to=2023-01-31T00:00:00Z", nil)
+		w := httptest.NewRecorder()
+
+		// Act
+		router.ServeHTTP(w, req)
+
+		// Assert
+		assert.Equal(t, 200, w.Code)
+		var response map[string]interface{}
+		_ = json.Unmarshal(w.Body.Bytes(), &response)
+		assert.Contains(t, response, "timeframe")
+		data := response["data"].([]interface{})
+		assert.Len(t, data, 1)
+		firstItem := data[0].(map[string]interface{})
+		assert.Equal(t, "round-1", firstItem["roundId"])
+	})
+
+	t.Run("returns 400 when to is before from", func(t *testing.T) {
+		// Arrange
+		mockService := &MockTransactionService{}
+		router := setupTestRouter(mockService)
+
+		req, _ := http.NewRequest("GET", "/rounds/anomalies?from=2023-02-01T00:00:00Z&to=2023-01-01T00:00:00Z", nil)
+		w := httptest.NewRecorder()
+
+		// Act
+		router.ServeHTTP(w, req)
+
+		// Assert
+		assert.Equal(t, 400, w.Code)
+	})
+
+	t.Run("returns 500 when service returns error", func(t *testing.T) {
+		// Arrange
+		mockService := &MockTransactionService{
+			RoundAnomaliesFn: func(ctx context.Context, from, to time.Time) ([]model.RoundAnomaly, error) {
+				return nil, errors.New("service error")
+			},
+		}
+		router := setupTestRouter(mockService)
+
+		req, _ := http.NewRequest("GET", "/rounds/anomalies?from=2023-01-01T00:00:00Z&to=2023-01-31T00:00:00Z", nil)
+		w := httptest.NewRecorder()
+
+		// Act
+		router.ServeHTTP(w, req)
+
+		// Assert
+		assert.Equal(t, 500, w.Code)
+		var response map[string]interface{}
+		_ = json.Unmarshal(w.Body.Bytes(), &response)
+		assert.Contains(t, response["message"].(string), "Failed to find round anomalies")
+	})
+}
+
+func TestCacheStatusHeader(t *testing.T) {
+	// Setup with a real service so the cache status is reported
+	transactionService := service.NewTransactionService(repository.NewMockTransactionRepository(), repository.NewMockCache())
+	router := setupTestRouter(transactionService)
+
+	request := func(path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("reports a miss, then a fresh hit", func(t *testing.T) {
+		path := "/gross_gaming_rev?from=2023-01-01T00:00:00Z&to=2023-01-31T00:00:00Z"
+
+		miss := request(path)
+		hit := request(path)
+
+		assert.Equal(t, http.StatusOK, miss.Code)
+		assert.Equal(t, "miss", miss.Header().Get("X-Cache-Status"))
+		assert.Equal(t, "fresh", hit.Header().Get("X-Cache-Status"))
+	})
+
+	t.Run("is set on every cached endpoint", func(t *testing.T) {
+		paths := []string{
+			"/gross_gaming_rev/series?from=2023-01-01T00:00:00Z&to=2023-01-31T00:00:00Z",
+			"/daily_wager_volume?from=2023-01-01T00:00:00Z&to=2023-01-31T00:00:00Z",
+			"/user/01HRMD5HGTZB3TW3PGYXRD07CQ/wager_percentile?from=2023-01-01T00:00:00Z&to=2023-01-31T00:00:00Z",
+			"/user/01HRMD5HGTZB3TW3PGYXRD07CQ/summary?from=2023-01-01T00:00:00Z&to=2023-01-31T00:00:00Z",
+			"/leaderboard?from=2023-01-01T00:00:00Z&to=2023-01-31T00:00:00Z",
+			"/rounds/anomalies?from=2023-01-01T00:00:00Z&to=2023-01-31T00:00:00Z",
+		}
+
+		for _, path := range paths {
+			w := request(path)
+
+			assert.Equal(t, http.StatusOK, w.Code, path)
+			assert.Equal(t, "miss", w.Header().Get("X-Cache-Status"), path)
+		}
+	})
+
+	t.Run("reports a fresh hit for a named range ending later", func(t *testing.T) {
+		miss := request("/daily_wager_volume?range=last_24h")
+		hit := request("/daily_wager_volume?range=last_24h")
+
+		assert.Equal(t, http.StatusOK, miss.Code)
+		assert.Equal(t, "miss", miss.Header().Get("X-Cache-Status"))
+		assert.Equal(t, "fresh", hit.Header().Get("X-Cache-Status"))
+	})
+
+	t.Run("is not set when validation fails", func(t *testing.T) {
+		w := request("/gross_gaming_rev?from=invalid")
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Empty(t, w.Header().Get("X-Cache-Status"))
+	})
+}
+
+func TestCreateTransaction(t *testing.T) {
+	body := `{
+		"id": "01HRMD5HGTZB3TW3PGYXRD07CQ",
+		"createdAt": "2023-01-01T00:00:00Z",
+		"userId": "01HRMD5HGTZB3TW3PGYXRD07CR",
+		"roundId": "01HRMD5HGTZB3TW3PGYXRD07CS",
+		"type": "Wager",
+		"amount": "10.50",
+		"currency": "BTC",
+		"usdAmount": "525000.00"
+	}`
+
+	t.Run("returns 201 when transaction is inserted", func(t *testing.T) {
+		// Arrange
+		var received []model.Transaction
+		mockService := &MockTransactionService{
+			CreateTransactionsFn: func(ctx context.Context, transactions []model.Transaction) (int, error) {
+				received = transactions
+				return len(transactions), nil
+			},
+		}
+		router := setupTestRouter(mockService)
+
+		req, _ := http.NewRequest("POST", "/transactions", strings.NewReader(body))
+		w := httptest.NewRecorder()
+
+		// Act
+		router.ServeHTTP(w, req)
+
+		// Assert
+		assert.Equal(t, 201, w.Code)
+		assert.Len(t, received, 1)
+		assert.Equal(t, "01HRMD5HGTZB3TW3PGYXRD07CQ", received[0].ID)
+		assert.Equal(t, "10.50", received[0].Amount.String())
+		var response map[string]interface{}
+		_ = json.Unmarshal(w.Body.Bytes(), &response)
+		assert.Equal(t, float64(1), response["inserted"])
+		assert.Equal(t, float64(0), response["duplicates"])
+	})
+
+	t.Run("returns 200 when transaction was already stored", func(t *testing.T) {
+		// Arrange
+		mockService := &MockTransactionService{
+			CreateTransactionsFn: func(ctx context.Context, transactions []model.Transaction) (int, error) {
+				return 0, nil
+			},
+		}
+		router := setupTestRouter(mockService)
+
+		req, _ := http.NewRequest("POST", "/transactions", strings.NewReader(body))
+		w := httptest.NewRecorder()
+
+		// Act
+		router.ServeHTTP(w, req)
+
+		// Assert
+		assert.Equal(t, 200, w.Code)
+		var response map[string]interface{}
+		_ = json.Unmarshal(w.Body.Bytes(), &response)
+		assert.Equal(t, float64(0), response["inserted"])
+		assert.Equal(t, float64(1), response["duplicates"])
+	})
+
+	t.Run("returns 400 with malformed body", func(t *testing.T) {
+		// Arrange
+		mockService := &MockTransactionService{}
+		router := setupTestRouter(mockService)
+
+		req, _ := http.NewRequest("POST", "/transactions", strings.NewReader(`{"amount": 10}`))
+		w := httptest.NewRecorder()
+
+		// Act
+		router.ServeHTTP(w, req)
+
+		// Assert
+		assert.Equal(t, 400, w.Code)
+		var response map[string]interface{}
+		_ = json.Unmarshal(w.Body.Bytes(), &response)
+		assert.Equal(t, "validation_error", response["code"])
+	})
+
+	t.Run("returns 400 when service rejects transaction", func(t *testing.T) {
+		// Arrange
+		mockService := &MockTransactionService{
+			CreateTransactionsFn: func(ctx context.Context, transactions []model.Transaction) (int, error) {
+				return 0, fmt.Errorf("%w: unknown currency", service.ErrInvalidTransaction)
+			},
+		}
+		router := setupTestRouter(mockService)
+
+		req, _ := http.NewRequest("POST", "/transactions", strings.NewReader(body))
+		w := httptest.NewRecorder()
+
+		// Act
+		router.ServeHTTP(w, req)
+
+		// Assert
+		assert.Equal(t, 400, w.Code)
+		var response map[string]interface{}
+		_ = json.Unmarshal(w.Body.Bytes(), &response)
+		assert.Equal(t, "validation_error", response["code"])
+		assert.Contains(t, w.Body.String(), "unknown currency")
+	})
+
+	t.Run("returns 500 when service returns error", func(t *testing.T) {
+		// Arrange
+		mockService := &MockTransactionService{
+			CreateTransactionsFn: func(ctx context.Context, transactions []model.Transaction) (int, error) {
+				return 0, errors.New("service error")
+			},
+		}
+		router := setupTestRouter(mockService)
+
+		req, _ := http.NewRequest("POST", "/transactions", strings.NewReader(body))
+		w := httptest.NewRecorder()
+
+		// Act
+		router.ServeHTTP(w, req)
+
+		// Assert
+		assert.Equal(t, 500, w.Code)
+	})
+}
+
+func TestCreateTransactionBatch(t *testing.T) {
+	t.Run("reports inserted and duplicate counts", func(t *testing.T) {
+		// Arrange
+		mockService := &MockTransactionService{
+			CreateTransactionsFn: func(ctx context.Context, transactions []model.Transaction) (int, error) {
+				return 1, nil
+			},
+		}
+		router := setupTestRouter(mockService)
+
+		body := `[
+			{"id": "01HRMD5HGTZB3TW3PGYXRD07CQ", "type": "Wager", "amount": "1", "currency": "ETH"},
+			{"id": "01HRMD5HGTZB3TW3PGYXRD07CS", "type": "Payout", "amount": "2", "currency": "ETH"}
+		]`
+		req, _ := http.NewRequest("POST", "/transactions/batch", strings.NewReader(body))
+		w := httptest.NewRecorder()
+
+		// Act
+		router.ServeHTTP(w, req)
+
+		// Assert
+		assert.Equal(t, 201, w.Code)
+		var response map[string]interface{}
+		_ = json.Unmarshal(w.Body.Bytes(), &response)
+		assert.Equal(t, float64(1), response["inserted"])
+		assert.Equal(t, float64(1), response["duplicates"])
+	})
+
+	t.Run("returns 400 when body is not an array", func(t *testing.T) {
+		// Arrange
+		mockService := &MockTransactionService{}
+		router := setupTestRouter(mockService)
+
+		req, _ := http.NewRequest("POST", "/transactions/batch", strings.NewReader(`{"id": "x"}`))
+		w := httptest.NewRecorder()
+
+		// Act
+		router.ServeHTTP(w, req)
+
+		// Assert
+		assert.Equal(t, 400, w.Code)
+	})
+}
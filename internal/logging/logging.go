@@ -0,0 +1,130 @@
+// Package logging creates the API's structured JSON logger and carries a request's
+// logger, ID and log fields through its context.
+package logging
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+)
+
+// New creates a logger that writes JSON lines to w, dropping records below level
+func New(w io.Writer, level slog.Leveler) *slog.Logger {
+	return slog.New(slog.NewJSONHandler(w, &slog.HandlerOptions{Level: level}))
+}
+
+// ParseLevel parses a level name such as "debug", "info", "warn" or "error"
+func ParseLevel(name string) (slog.Level, error) {
+	var level slog.Level
+	err := level.UnmarshalText([]byte(strings.TrimSpace(name)))
+	return level, err
+}
+
+type loggerKey struct{}
+type requestIDKey struct{}
+type fieldsKey struct{}
+
+// ContextWithLogger returns a copy of ctx holding logger
+func ContextWithLogger(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, logger)
+}
+
+// FromContext returns the logger in ctx, or the default logger
+func FromContext(ctx context.Context) *slog.Logger {
+	if logger, ok := ctx.Value(loggerKey{}).(*slog.Logger); ok {
+		return logger
+	}
+	return slog.Default()
+}
+
+// ContextWithRequestID returns a copy of ctx holding the ID of the request it serves
+func ContextWithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestIDFromContext returns the request ID in ctx, or an empty string
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// Fields collects attributes that the layers serving a request add to its log line
+type Fields struct {
+	mu        sync.Mutex
+	attrs     []slog.Attr
+	durations []durationField
+}
+
+// durationField is a duration summed over calls to AddDuration
+type durationField struct {
+	key   string
+	total time.Duration
+}
+
+// ContextWithFields returns a copy of ctx to which AddAttrs and AddDuration add fields
+func ContextWithFields(ctx context.Context, fields *Fields) context.Context {
+	return context.WithValue(ctx, fieldsKey{}, fields)
+}
+
+// Attrs returns the fields added so far, with durations in milliseconds
+func (f *Fields) Attrs() []slog.Attr {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	attrs := make([]slog.Attr, 0, len(f.attrs)+len(f.durations))
+	attrs = append(attrs, f.attrs...)
+	for _, d := range f.durations {
+		attrs = append(attrs, slog.Float64(d.key, float64(d.total.Microseconds())/1000))
+	}
+	return attrs
+}
+
+// AddAttrs adds attrs to the log line of the request served with ctx, if it has one
+func AddAttrs(ctx context.Context, attrs ...slog.Attr) {
+	fields, ok := ctx.Value(fieldsKey{}).(*Fields)
+	if !ok {
+		return
+	}
+	fields.mu.Lock()
+	defer fields.mu.Unlock()
+
+	fields.attrs = append(fields.attrs, attrs...)
+}
+
+// AddDuration adds d to the duration logged under key for the request served with ctx,
+// if it has one, so that a request running several queries logs their total
+func AddDuration(ctx context.Context, key string, d time.Duration) {
+	fields, ok := ctx.Value(fieldsKey{}).(*Fields)
+	if !ok {
+		return
+	}
+	fields.mu.Lock()
+	defer fields.mu.Unlock()
+
+	for i := range fields.durations {
+		if fields.durations[i].key == key {
+			fields.durations[i].total += d
+			return
+		}
+	}
+	fields.durations = append(fields.durations, durationField{key: key, total: d})
+}
+
+// CopyContext returns a copy of ctx holding the logger, request ID and fields of from.
+// Work that outlives a request runs under a new context and uses it to keep logging
+// for the request.
+func CopyContext(ctx, from context.Context) context.Context {
+	if logger, ok := from.Value(loggerKey{}).(*slog.Logger); ok {
+		ctx = ContextWithLogger(ctx, logger)
+	}
+	if id, ok := from.Value(requestIDKey{}).(string); ok {
+		ctx = ContextWithRequestID(ctx, id)
+	}
+	if fields, ok := from.Value(fieldsKey{}).(*Fields); ok {
+		ctx = ContextWithFields(ctx, fields)
+	}
+	return ctx
+}
@@ -0,0 +1,96 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseLevel(t *testing.T) {
+	for name, want := range map[string]slog.Level{
+		"debug": slog.LevelDebug,
+		"INFO":  slog.LevelInfo,
+		"warn ": slog.LevelWarn,
+		"error": slog.LevelError,
+	} {
+		level, err := ParseLevel(name)
+
+		assert.NoError(t, err, name)
+		assert.Equal(t, want, level, name)
+	}
+
+	_, err := ParseLevel("verbose")
+	assert.Error(t, err)
+}
+
+func TestNew(t *testing.T) {
+	// Arrange
+	var out bytes.Buffer
+	logger := New(&out, slog.LevelWarn)
+
+	// Act
+	logger.Info("Dropped")
+	logger.Warn("Kept", "key", "ggr:2023")
+
+	// Assert
+	var line map[string]interface{}
+	assert.NoError(t, json.Unmarshal(out.Bytes(), &line), "Only the warning is written, as one JSON line")
+	assert.Equal(t, "WARN", line["level"])
+	assert.Equal(t, "Kept", line["msg"])
+	assert.Equal(t, "ggr:2023", line["key"])
+}
+
+func TestFromContext(t *testing.T) {
+	t.Run("returns the default logger without one in the context", func(t *testing.T) {
+		assert.Same(t, slog.Default(), FromContext(context.Background()))
+	})
+
+	t.Run("returns the logger in the context", func(t *testing.T) {
+		logger := New(&bytes.Buffer{}, slog.LevelInfo)
+
+		assert.Same(t, logger, FromContext(ContextWithLogger(context.Background(), logger)))
+	})
+}
+
+func TestFields(t *testing.T) {
+	t.Run("collects attributes and sums durations", func(t *testing.T) {
+		// Arrange
+		fields := &Fields{}
+		ctx := ContextWithFields(context.Background(), fields)
+
+		// Act
+		AddAttrs(ctx, slog.String("cache_key", "ggr:2023"))
+		AddDuration(ctx, "mongo_ms", 1500*time.Microsecond)
+		AddDuration(ctx, "mongo_ms", 500*time.Microsecond)
+
+		// Assert
+		assert.Equal(t, []slog.Attr{slog.String("cache_key", "ggr:2023"), slog.Float64("mongo_ms", 2)}, fields.Attrs())
+	})
+
+	t.Run("are ignored without fields in the context", func(t *testing.T) {
+		AddAttrs(context.Background(), slog.String("cache_key", "ggr:2023"))
+		AddDuration(context.Background(), "mongo_ms", time.Second)
+	})
+}
+
+func TestCopyContext(t *testing.T) {
+	// Arrange
+	logger := New(&bytes.Buffer{}, slog.LevelInfo)
+	fields := &Fields{}
+	request := ContextWithFields(ContextWithRequestID(ContextWithLogger(context.Background(), logger), "req-1"), fields)
+
+	// Act
+	ctx := CopyContext(context.Background(), request)
+	AddAttrs(ctx, slog.String("cache", "miss"))
+
+	// Assert
+	assert.Same(t, logger, FromContext(ctx))
+	assert.Equal(t, "req-1", RequestIDFromContext(ctx))
+	assert.Equal(t, []slog.Attr{slog.String("cache", "miss")}, fields.Attrs())
+	assert.Empty(t, RequestIDFromContext(CopyContext(context.Background(), context.Background())))
+}
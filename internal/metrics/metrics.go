@@ -0,0 +1,81 @@
+// Package metrics defines the API's Prometheus metrics
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Metrics are the metrics the API records
+type Metrics struct {
+	Registry *prometheus.Registry
+
+	HTTPRequests *prometheus.CounterVec   // route, method, status
+	HTTPDuration *prometheus.HistogramVec // route, method, status
+	HTTPInFlight *prometheus.GaugeVec     // route
+
+	CacheHits   *prometheus.CounterVec // prefix
+	CacheMisses *prometheus.CounterVec // prefix
+	CacheErrors *prometheus.CounterVec // prefix, operation
+
+	MongoDuration *prometheus.HistogramVec // method, status
+	MongoInFlight *prometheus.GaugeVec     // method
+}
+
+// New creates the API's metrics in a new registry, along with the Go runtime and
+// process metrics
+func New() *Metrics {
+	r := prometheus.NewRegistry()
+	r.MustRegister(collectors.NewGoCollector(), collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}))
+	factory := promauto.With(r)
+
+	return &Metrics{
+		Registry: r,
+
+		HTTPRequests: factory.NewCounterVec(prometheus.CounterOpts{
+			Name: "http_requests_total",
+			Help: "HTTP requests by route pattern, method and status code.",
+		}, []string{"route", "method", "status"}),
+		HTTPDuration: factory.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "http_request_duration_seconds",
+			Help:    "HTTP request latency by route pattern, method and status code.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"route", "method", "status"}),
+		HTTPInFlight: factory.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "http_requests_in_flight",
+			Help: "HTTP requests being served by route pattern.",
+		}, []string{"route"}),
+
+		CacheHits: factory.NewCounterVec(prometheus.CounterOpts{
+			Name: "cache_hits_total",
+			Help: "Cache lookups that found a value, by key prefix.",
+		}, []string{"prefix"}),
+		CacheMisses: factory.NewCounterVec(prometheus.CounterOpts{
+			Name: "cache_misses_total",
+			Help: "Cache lookups that found no value, by key prefix.",
+		}, []string{"prefix"}),
+		CacheErrors: factory.NewCounterVec(prometheus.CounterOpts{
+			Name: "cache_errors_total",
+			Help: "Failed cache operations by key prefix and operation.",
+		}, []string{"prefix", "operation"}),
+
+		MongoDuration: factory.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "mongodb_query_duration_seconds",
+			Help:    "MongoDB query latency by repository method and status.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"method", "status"}),
+		MongoInFlight: factory.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "mongodb_queries_in_flight",
+			Help: "MongoDB queries running by repository method.",
+		}, []string{"method"}),
+	}
+}
+
+// Handler serves the metrics in the Prometheus exposition format
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.Registry, promhttp.HandlerOpts{Registry: m.Registry})
+}
@@ -0,0 +1,26 @@
+package metrics
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetrics_Handler(t *testing.T) {
+	// Arrange
+	m := New()
+	m.HTTPRequests.WithLabelValues("/leaderboard", "GET", "200").Inc()
+	m.MongoDuration.WithLabelValues("CalculateGGR", "ok").Observe(0.2)
+
+	// Act
+	w := httptest.NewRecorder()
+	m.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+
+	// Assert
+	assert.Equal(t, 200, w.Code)
+	assert.Contains(t, w.Header().Get("Content-Type"), "text/plain")
+	assert.Contains(t, w.Body.String(), `http_requests_total{method="GET",route="/leaderboard",status="200"} 1`)
+	assert.Contains(t, w.Body.String(), `mongodb_query_duration_seconds_bucket{method="CalculateGGR",status="ok",le="0.25"} 1`)
+	assert.Contains(t, w.Body.String(), "go_goroutines ", "Go runtime metrics are included")
+}
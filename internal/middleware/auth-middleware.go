@@ -1,31 +1,101 @@
 package middleware
 
 import (
-	"net/http"
+	"context"
+	"errors"
+	"strings"
 
 	"github.com/gin-gonic/gin"
-	"admin-statistics-api/internal/config"
+	"admin-statistics-api/internal/apierror"
+	"admin-statistics-api/internal/logging"
+	"admin-statistics-api/internal/model"
+	"admin-statistics-api/internal/service"
 )
 
-// AuthMiddleware provides a middleware function for validating API keys.
-// This middleware checks the incoming request's "Authorization" header against the
-// expected API key configured in the application. If the key does not match,
-// the middleware will abort the request with an Unauthorized status, ensuring
-// that only authorized requests can access protected routes.
-func AuthMiddleware(cfg *config.Config) gin.HandlerFunc {
+// PrincipalContextKey is the gin context key holding the authenticated model.Principal
+const PrincipalContextKey = "principal"
+
+// errInvalidAPIKey is the response message for any key that does not authenticate
+const errInvalidAPIKey = "Invalid or missing API key"
+
+// bearerPrefix starts an Authorization header carrying a JWT
+const bearerPrefix = "Bearer "
+
+// Authenticator resolves the secret key sent by a client to its API key
+type Authenticator interface {
+	Authenticate(ctx context.Context, secret string) (model.APIKey, error)
+}
+
+// TokenVerifier validates a bearer token and returns the principal it identifies
+type TokenVerifier interface {
+	VerifyToken(ctx context.Context, token string) (model.Principal, error)
+}
+
+// AuthMiddleware provides a middleware function for validating API keys and JWTs.
+// An "Authorization: Bearer <token>" header is validated by tokens, which may be nil
+// when JWTs are not accepted. Any other "Authorization" header is passed to keys,
+// which compares it against the stored API keys in constant time. If neither
+// authenticates the request, the middleware will abort it with an Unauthorized
+// status, ensuring that only authorized requests can access protected routes.
+// The authenticated principal is stored in the context under PrincipalContextKey
+// for RequireScope.
+func AuthMiddleware(keys Authenticator, tokens TokenVerifier) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// Retrieve the API key from the request header
+		// Retrieve the API key or token from the request header
 		authHeader := c.GetHeader("Authorization")
 
-		// If the API key is invalid or missing, respond with an error and stop processing
-		if authHeader != cfg.Auth.APIKey {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
-				"error": "Invalid or missing API key",
-			})
+		var principal model.Principal
+		var err error
+		if token, isBearer := strings.CutPrefix(authHeader, bearerPrefix); isBearer && tokens != nil {
+			principal, err = tokens.VerifyToken(c, token)
+		} else {
+			var key model.APIKey
+			key, err = keys.Authenticate(c, authHeader)
+			principal = key.Principal()
+		}
+
+		if err != nil {
+			// Store failures are logged but reported like any other rejected credential
+			if !errors.Is(err, service.ErrInvalidAPIKey) && !errors.Is(err, service.ErrInvalidToken) {
+				logging.FromContext(c).Error("Authentication failed", "error", err)
+			}
+
+			// If the credential is invalid or missing, respond with an error and stop processing
+			apierror.Abort(c, apierror.Unauthorized(errInvalidAPIKey))
+			return
+		}
+
+		// If the credential is valid, continue to the next middleware/handler
+		c.Set(PrincipalContextKey, principal)
+		c.Next()
+	}
+}
+
+// RequireScope provides a middleware function that only lets through requests whose
+// authenticated principal was granted scope. It must run after AuthMiddleware.
+func RequireScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal, ok := PrincipalFromContext(c)
+		if !ok {
+			apierror.Abort(c, apierror.Unauthorized(errInvalidAPIKey))
+			return
+		}
+
+		if !principal.HasScope(scope) {
+			apierror.Abort(c, apierror.Forbidden("Credentials lack required scope "+scope))
 			return
 		}
 
-		// If the API key is valid, continue to the next middleware/handler
 		c.Next()
 	}
 }
+
+// PrincipalFromContext returns the principal authenticated by AuthMiddleware
+func PrincipalFromContext(c *gin.Context) (model.Principal, bool) {
+	value, ok := c.Get(PrincipalContextKey)
+	if !ok {
+		return model.Principal{}, false
+	}
+	principal, ok := value.(model.Principal)
+	return principal, ok
+}
@@ -1,71 +1,278 @@
 package middleware
 
 import (
+	"context"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
-	"admin-statistics-api/internal/config"
+	"admin-statistics-api/internal/model"
+	"admin-statistics-api/internal/repository"
+	"admin-statistics-api/internal/service"
 )
 
-func TestAuthMiddleware(t *testing.T) {
-	// Setup
+// setupAuthRouter creates a router with one route per scope, grouped the same way as the API
+func setupAuthRouter(auth Authenticator) *gin.Engine {
 	gin.SetMode(gin.TestMode)
-	
-	// Create a test config
-	cfg := &config.Config{
-		Auth: config.AuthConfig{
-			APIKey: "test-api-key",
-		},
-	}
 
-	// Create a test router
 	router := gin.New()
-	router.Use(AuthMiddleware(cfg))
-	
-	// Add a test route
-	router.GET("/test", func(c *gin.Context) {
+	router.Use(AuthMiddleware(auth, nil))
+
+	ok := func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{"status": "success"})
-	})
+	}
+	router.GET("/test", ok)
+	router.GET("/stats", RequireScope(model.ScopeStatsRead), ok)
+	router.GET("/user", RequireScope(model.ScopeUserRead), ok)
+	router.POST("/transactions", RequireScope(model.ScopeTransactionsWrite), ok)
+	router.POST("/admin", RequireScope(model.ScopeKeysAdmin), ok)
+
+	return router
+}
+
+// scopeRoutes maps each scope to the test route that requires it
+var scopeRoutes = map[string]struct{ Method, Path string }{
+	model.ScopeStatsRead:         {"GET", "/stats"},
+	model.ScopeUserRead:          {"GET", "/user"},
+	model.ScopeTransactionsWrite: {"POST", "/transactions"},
+	model.ScopeKeysAdmin:         {"POST", "/admin"},
+}
+
+func TestAuthMiddleware(t *testing.T) {
+	// Setup
+	ctx := context.Background()
+	store := repository.NewMockAPIKeyStore()
+	apiKeyService := service.NewAPIKeyService(store, "test-api-key")
+	router := setupAuthRouter(apiKeyService)
+
+	request := func(method, path, key string) int {
+		req := httptest.NewRequest(method, path, nil)
+		if key != "" {
+			req.Header.Set("Authorization", key)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
 
 	// Test cases
 	t.Run("allows request with valid API key", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, request("GET", "/test", "test-api-key"))
+	})
+
+	t.Run("blocks request with invalid API key", func(t *testing.T) {
+		assert.Equal(t, http.StatusUnauthorized, request("GET", "/test", "invalid-key"))
+	})
+
+	t.Run("blocks request with missing API key", func(t *testing.T) {
+		assert.Equal(t, http.StatusUnauthorized, request("GET", "/test", ""))
+	})
+
+	t.Run("default API key has every scope", func(t *testing.T) {
+		for scope, route := range scopeRoutes {
+			assert.Equal(t, http.StatusOK, request(route.Method, route.Path, "test-api-key"), scope)
+		}
+	})
+
+	for scope := range scopeRoutes {
+		scope := scope
+		t.Run("key with only "+scope, func(t *testing.T) {
+			// Arrange
+			_, secret, err := apiKeyService.CreateAPIKey(ctx, "only "+scope, []string{scope}, nil)
+			assert.NoError(t, err)
+
+			for routeScope, route := range scopeRoutes {
+				// Act
+				code := request(route.Method, route.Path, secret)
+
+				// Assert
+				if routeScope == scope {
+					assert.Equal(t, http.StatusOK, code, "%s should be allowed", route.Path)
+				} else {
+					assert.Equal(t, http.StatusForbidden, code, "%s should be forbidden", route.Path)
+				}
+			}
+		})
+	}
+
+	t.Run("blocks request with wrong secret for a known key", func(t *testing.T) {
 		// Arrange
-		req := httptest.NewRequest("GET", "/test", nil)
-		req.Header.Set("Authorization", "test-api-key")
-		w := httptest.NewRecorder()
-		
+		key, secret, err := apiKeyService.CreateAPIKey(ctx, "reader", []string{model.ScopeStatsRead}, nil)
+		assert.NoError(t, err)
+		forged := "ak_" + key.ID + "_" + "00000000000000000000000000000000"
+
+		// Act & Assert
+		assert.Equal(t, http.StatusOK, request("GET", "/stats", secret))
+		assert.Equal(t, http.StatusUnauthorized, request("GET", "/stats", forged))
+	})
+
+	t.Run("blocks request with expired API key", func(t *testing.T) {
+		// Arrange
+		key, secret, err := apiKeyService.CreateAPIKey(ctx, "short lived", []string{model.ScopeStatsRead}, timePtr(time.Now().Add(time.Hour)))
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, request("GET", "/stats", secret))
+
 		// Act
-		router.ServeHTTP(w, req)
-		
+		key.ExpiresAt = timePtr(time.Now().Add(-time.Second))
+		store.Keys[key.ID] = key
+
 		// Assert
-		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, http.StatusUnauthorized, request("GET", "/stats", secret))
 	})
 
-	t.Run("blocks request with invalid API key", func(t *testing.T) {
+	t.Run("blocks request with revoked API key", func(t *testing.T) {
 		// Arrange
-		req := httptest.NewRequest("GET", "/test", nil)
-		req.Header.Set("Authorization", "invalid-key")
-		w := httptest.NewRecorder()
-		
+		key, secret, err := apiKeyService.CreateAPIKey(ctx, "revoked", []string{model.ScopeStatsRead}, nil)
+		assert.NoError(t, err)
+
 		// Act
+		assert.NoError(t, apiKeyService.RevokeAPIKey(ctx, key.ID))
+
+		// Assert
+		assert.Equal(t, http.StatusUnauthorized, request("GET", "/stats", secret))
+	})
+
+	t.Run("blocks request when the key store fails", func(t *testing.T) {
+		// Arrange
+		_, secret, err := apiKeyService.CreateAPIKey(ctx, "unreachable", []string{model.ScopeStatsRead}, nil)
+		assert.NoError(t, err)
+		store.Err = errors.New("database error")
+		defer func() { store.Err = nil }()
+
+		// Act & Assert
+		assert.Equal(t, http.StatusUnauthorized, request("GET", "/stats", secret))
+	})
+}
+
+// fakeTokenVerifier accepts the tokens in its map and rejects everything else
+type fakeTokenVerifier map[string]model.Principal
+
+func (f fakeTokenVerifier) VerifyToken(ctx context.Context, token string) (model.Principal, error) {
+	principal, ok := f[token]
+	if !ok {
+		return model.Principal{}, service.ErrInvalidToken
+	}
+	return principal, nil
+}
+
+func TestAuthMiddleware_BearerToken(t *testing.T) {
+	// Setup
+	gin.SetMode(gin.TestMode)
+	apiKeyService := service.NewAPIKeyService(repository.NewMockAPIKeyStore(), "test-api-key")
+	tokens := fakeTokenVerifier{
+		"analyst-token": {ID: "jwt:alice", Name: "alice", Roles: []string{"analyst"}, Scopes: []string{model.ScopeStatsRead, model.ScopeUserRead}},
+		"viewer-token":  {ID: "jwt:bob", Name: "bob", Roles: []string{"viewer"}, Scopes: []string{model.ScopeStatsRead}},
+	}
+
+	newRouter := func(tokens TokenVerifier) *gin.Engine {
+		router := gin.New()
+		router.Use(AuthMiddleware(apiKeyService, tokens))
+		ok := func(c *gin.Context) {
+			principal, _ := PrincipalFromContext(c)
+			c.JSON(http.StatusOK, gin.H{"id": principal.ID})
+		}
+		router.GET("/stats", RequireScope(model.ScopeStatsRead), ok)
+		router.GET("/user", RequireScope(model.ScopeUserRead), ok)
+		return router
+	}
+
+	request := func(router *gin.Engine, path, header string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		req.Header.Set("Authorization", header)
+		w := httptest.NewRecorder()
 		router.ServeHTTP(w, req)
-		
+		return w
+	}
+
+	t.Run("allows request with a valid token", func(t *testing.T) {
+		// Act
+		w := request(newRouter(tokens), "/user", "Bearer analyst-token")
+
 		// Assert
-		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `{"id":"jwt:alice"}`, w.Body.String())
 	})
 
-	t.Run("blocks request with missing API key", func(t *testing.T) {
+	t.Run("forbids a role without the required scope", func(t *testing.T) {
+		router := newRouter(tokens)
+
+		assert.Equal(t, http.StatusOK, request(router, "/stats", "Bearer viewer-token").Code)
+		assert.Equal(t, http.StatusForbidden, request(router, "/user", "Bearer viewer-token").Code)
+	})
+
+	t.Run("blocks request with an invalid token", func(t *testing.T) {
+		assert.Equal(t, http.StatusUnauthorized, request(newRouter(tokens), "/stats", "Bearer forged-token").Code)
+	})
+
+	t.Run("still accepts API keys when tokens are enabled", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, request(newRouter(tokens), "/user", "test-api-key").Code)
+	})
+
+	t.Run("rejects tokens when JWT auth is disabled", func(t *testing.T) {
+		assert.Equal(t, http.StatusUnauthorized, request(newRouter(nil), "/stats", "Bearer analyst-token").Code)
+	})
+}
+
+func TestRequireScope(t *testing.T) {
+	t.Run("blocks request without an authenticated key", func(t *testing.T) {
 		// Arrange
-		req := httptest.NewRequest("GET", "/test", nil)
+		gin.SetMode(gin.TestMode)
+		router := gin.New()
+		router.GET("/stats", RequireScope(model.ScopeStatsRead), func(c *gin.Context) {
+			c.Status(http.StatusOK)
+		})
+
+		req := httptest.NewRequest("GET", "/stats", nil)
 		w := httptest.NewRecorder()
-		
+
 		// Act
 		router.ServeHTTP(w, req)
-		
+
 		// Assert
 		assert.Equal(t, http.StatusUnauthorized, w.Code)
 	})
-}
\ No newline at end of file
+}
+
+func timePtr(t time.Time) *time.Time {
+	return &t
+}
+
+func TestAuthMiddleware_ErrorEnvelope(t *testing.T) {
+	// Setup
+	ctx := context.Background()
+	apiKeyService := service.NewAPIKeyService(repository.NewMockAPIKeyStore(), "test-api-key")
+	router := setupAuthRouter(apiKeyService)
+	_, secret, err := apiKeyService.CreateAPIKey(ctx, "reader", []string{model.ScopeStatsRead}, nil)
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		key      string
+		path     string
+		expected string
+	}{
+		{"missing credentials", "", "/stats", `{"code":"unauthorized","message":"Invalid or missing API key","details":[],"requestId":""}`},
+		{"missing scope", secret, "/user", `{"code":"forbidden","message":"Credentials lack required scope user:read","details":[],"requestId":""}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			req := httptest.NewRequest("GET", tt.path, nil)
+			if tt.key != "" {
+				req.Header.Set("Authorization", tt.key)
+			}
+			w := httptest.NewRecorder()
+
+			// Act
+			router.ServeHTTP(w, req)
+
+			// Assert
+			assert.JSONEq(t, tt.expected, w.Body.String())
+		})
+	}
+}
@@ -0,0 +1,36 @@
+package middleware
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// degradedHeader lists the dependencies the API is currently running without
+const degradedHeader = "X-Degraded"
+
+// DegradedMiddleware provides a middleware function that reports which dependencies
+// are unavailable in the X-Degraded header, e.g. "cache,mongodb". The header is left
+// out while every dependency's check reports it healthy.
+func DegradedMiddleware(checks map[string]func() bool) gin.HandlerFunc {
+	names := make([]string, 0, len(checks))
+	for name := range checks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return func(c *gin.Context) {
+		var degraded []string
+		for _, name := range names {
+			if !checks[name]() {
+				degraded = append(degraded, name)
+			}
+		}
+		if len(degraded) > 0 {
+			c.Header(degradedHeader, strings.Join(degraded, ","))
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,40 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDegradedMiddleware(t *testing.T) {
+	request := func(checks map[string]func() bool) *httptest.ResponseRecorder {
+		gin.SetMode(gin.TestMode)
+		router := gin.New()
+		router.Use(DegradedMiddleware(checks))
+		router.GET("/stats", func(c *gin.Context) {
+			c.Status(http.StatusOK)
+		})
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", "/stats", nil))
+		return w
+	}
+	healthy := func() bool { return true }
+	unhealthy := func() bool { return false }
+
+	t.Run("lists unavailable dependencies in order", func(t *testing.T) {
+		w := request(map[string]func() bool{"mongodb": unhealthy, "cache": unhealthy, "other": healthy})
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "cache,mongodb", w.Header().Get("X-Degraded"))
+	})
+
+	t.Run("omits the header while everything is healthy", func(t *testing.T) {
+		w := request(map[string]func() bool{"cache": healthy})
+
+		assert.NotContains(t, w.Header(), "X-Degraded")
+	})
+}
@@ -0,0 +1,75 @@
+package middleware
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"admin-statistics-api/internal/apierror"
+	"admin-statistics-api/internal/logging"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// LoggingMiddleware provides a middleware function that logs one line per request
+// with its route, status and duration, plus the fields the service and repository add
+// while serving it, such as the cache key, cache status and MongoDB time.
+//
+// Handlers and the layers below them log through logging.FromContext, which returns
+// logger with the request's ID and route. Like the span of TracingMiddleware, the
+// request's context only reaches the service when the engine has ContextWithFallback set.
+func LoggingMiddleware(logger *slog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		route := c.FullPath()
+		if route == "" {
+			route = unmatchedRoute
+		}
+
+		ctx := c.Request.Context()
+		requestLogger := logger.With(
+			slog.String("request_id", logging.RequestIDFromContext(ctx)),
+			slog.String("method", c.Request.Method),
+			slog.String("route", route),
+		)
+		if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+			requestLogger = requestLogger.With(slog.String("trace_id", sc.TraceID().String()))
+		}
+
+		fields := &logging.Fields{}
+		ctx = logging.ContextWithFields(logging.ContextWithLogger(ctx, requestLogger), fields)
+		c.Request = c.Request.WithContext(ctx)
+
+		start := time.Now()
+		c.Next()
+
+		status := c.Writer.Status()
+		level := slog.LevelInfo
+		if status >= http.StatusInternalServerError {
+			level = slog.LevelError
+		}
+
+		attrs := []slog.Attr{
+			slog.String("path", c.Request.URL.Path),
+			slog.Int("status", status),
+			slog.Float64("duration_ms", float64(time.Since(start).Microseconds())/1000),
+			slog.String("client_ip", c.ClientIP()),
+		}
+		attrs = append(attrs, fields.Attrs()...)
+		if errs := c.Errors.String(); errs != "" {
+			attrs = append(attrs, slog.String("error", errs))
+		}
+		requestLogger.LogAttrs(ctx, level, "Request served", attrs...)
+	}
+}
+
+// RecoveryMiddleware provides a middleware function that turns a panic in a handler
+// into an internal_error response, logging it with its stack through the request's logger
+func RecoveryMiddleware() gin.HandlerFunc {
+	return gin.CustomRecoveryWithWriter(io.Discard, func(c *gin.Context, err any) {
+		logging.FromContext(c.Request.Context()).Error("Handler panicked",
+			slog.Any("panic", err), slog.String("stack", string(debug.Stack())))
+		apierror.Abort(c, apierror.Internal("Internal server error", nil))
+	})
+}
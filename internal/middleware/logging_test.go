@@ -0,0 +1,128 @@
+package middleware
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"admin-statistics-api/internal/logging"
+)
+
+// logLines decodes the JSON lines written to out
+func logLines(t *testing.T, out *bytes.Buffer) []map[string]interface{} {
+	var lines []map[string]interface{}
+	scanner := bufio.NewScanner(out)
+	for scanner.Scan() {
+		var line map[string]interface{}
+		assert.NoError(t, json.Unmarshal(scanner.Bytes(), &line))
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// setupLoggingRouter creates a router that logs to out
+func setupLoggingRouter(out *bytes.Buffer) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.ContextWithFallback = true
+	router.Use(RequestIDMiddleware())
+	router.Use(LoggingMiddleware(logging.New(out, slog.LevelDebug)))
+	router.Use(RecoveryMiddleware())
+	return router
+}
+
+func TestLoggingMiddleware(t *testing.T) {
+	t.Run("logs the request with the fields added while serving it", func(t *testing.T) {
+		// Arrange
+		var out bytes.Buffer
+		router := setupLoggingRouter(&out)
+		router.GET("/daily_wager_volume", func(c *gin.Context) {
+			logging.FromContext(c).Debug("Querying")
+			logging.AddAttrs(c, slog.String("cache_key", "daily_wager:2023"), slog.String("cache", "miss"))
+			logging.AddDuration(c, "mongo_ms", 12*time.Millisecond)
+			c.Status(http.StatusOK)
+		})
+
+		req := httptest.NewRequest("GET", "/daily_wager_volume?from=2023-01-01T00:00:00Z", nil)
+		req.Header.Set(RequestIDHeader, "req-1")
+
+		// Act
+		router.ServeHTTP(httptest.NewRecorder(), req)
+
+		// Assert
+		lines := logLines(t, &out)
+		assert.Len(t, lines, 2)
+
+		debug, request := lines[0], lines[1]
+		assert.Equal(t, "Querying", debug["msg"])
+		assert.Equal(t, "req-1", debug["request_id"], "Handlers log with the request's logger")
+		assert.Equal(t, "/daily_wager_volume", debug["route"])
+
+		assert.Equal(t, "INFO", request["level"])
+		assert.Equal(t, "req-1", request["request_id"])
+		assert.Equal(t, "GET", request["method"])
+		assert.Equal(t, "/daily_wager_volume", request["route"])
+		assert.Equal(t, 200.0, request["status"])
+		assert.Equal(t, "daily_wager:2023", request["cache_key"])
+		assert.Equal(t, "miss", request["cache"])
+		assert.Equal(t, 12.0, request["mongo_ms"])
+		assert.Contains(t, request, "duration_ms")
+	})
+
+	t.Run("logs server errors at error level", func(t *testing.T) {
+		var out bytes.Buffer
+		router := setupLoggingRouter(&out)
+		router.GET("/fail", func(c *gin.Context) {
+			c.Status(http.StatusServiceUnavailable)
+		})
+
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/fail", nil))
+
+		lines := logLines(t, &out)
+		assert.Len(t, lines, 1)
+		assert.Equal(t, "ERROR", lines[0]["level"])
+		assert.Equal(t, 503.0, lines[0]["status"])
+	})
+
+	t.Run("logs unmatched routes without their path as the route", func(t *testing.T) {
+		var out bytes.Buffer
+		router := setupLoggingRouter(&out)
+
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/missing", nil))
+
+		lines := logLines(t, &out)
+		assert.Len(t, lines, 1)
+		assert.Equal(t, unmatchedRoute, lines[0]["route"])
+		assert.Equal(t, "/missing", lines[0]["path"])
+	})
+}
+
+func TestRecoveryMiddleware(t *testing.T) {
+	// Arrange
+	var out bytes.Buffer
+	router := setupLoggingRouter(&out)
+	router.GET("/panic", func(c *gin.Context) {
+		panic("boom")
+	})
+	w := httptest.NewRecorder()
+
+	// Act
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/panic", nil))
+
+	// Assert
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+
+	lines := logLines(t, &out)
+	assert.Len(t, lines, 2)
+	assert.Equal(t, "Handler panicked", lines[0]["msg"])
+	assert.Equal(t, "boom", lines[0]["panic"])
+	assert.Equal(t, lines[0]["request_id"], lines[1]["request_id"])
+	assert.Equal(t, 500.0, lines[1]["status"])
+}
@@ -0,0 +1,35 @@
+package middleware
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"admin-statistics-api/internal/metrics"
+)
+
+// unmatchedRoute labels requests that matched no route, so that arbitrary paths do not
+// each become a series
+const unmatchedRoute = "unmatched"
+
+// MetricsMiddleware provides a middleware function that counts requests and records
+// their latency by route pattern, method and status, and tracks how many are in flight
+func MetricsMiddleware(m *metrics.Metrics) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		route := c.FullPath()
+		if route == "" {
+			route = unmatchedRoute
+		}
+
+		inFlight := m.HTTPInFlight.WithLabelValues(route)
+		inFlight.Inc()
+		defer inFlight.Dec()
+
+		start := time.Now()
+		c.Next()
+
+		status := strconv.Itoa(c.Writer.Status())
+		m.HTTPRequests.WithLabelValues(route, c.Request.Method, status).Inc()
+		m.HTTPDuration.WithLabelValues(route, c.Request.Method, status).Observe(time.Since(start).Seconds())
+	}
+}
@@ -0,0 +1,47 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"admin-statistics-api/internal/metrics"
+)
+
+func TestMetricsMiddleware(t *testing.T) {
+	// Arrange
+	gin.SetMode(gin.TestMode)
+	m := metrics.New()
+	router := gin.New()
+	router.Use(MetricsMiddleware(m))
+
+	var inFlight float64
+	router.GET("/user/:user_id/summary", func(c *gin.Context) {
+		inFlight = testutil.ToFloat64(m.HTTPInFlight.WithLabelValues("/user/:user_id/summary"))
+		c.Status(http.StatusOK)
+	})
+
+	// Act
+	for _, path := range []string{"/user/1/summary", "/user/2/summary", "/missing"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	}
+
+	// Assert
+	assert.Equal(t, 2.0, testutil.ToFloat64(m.HTTPRequests.WithLabelValues("/user/:user_id/summary", "GET", "200")), "Requests are labeled by route pattern")
+	assert.Equal(t, uint64(2), histogramCount(t, m.HTTPDuration, "/user/:user_id/summary", "GET", "200"))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.HTTPRequests.WithLabelValues("unmatched", "GET", "404")))
+	assert.Equal(t, 1.0, inFlight)
+	assert.Equal(t, 0.0, testutil.ToFloat64(m.HTTPInFlight.WithLabelValues("/user/:user_id/summary")))
+}
+
+// histogramCount returns how many values the series of h for labels has recorded
+func histogramCount(t *testing.T, h *prometheus.HistogramVec, labels ...string) uint64 {
+	var metric dto.Metric
+	assert.NoError(t, h.WithLabelValues(labels...).(prometheus.Metric).Write(&metric))
+	return metric.GetHistogram().GetSampleCount()
+}
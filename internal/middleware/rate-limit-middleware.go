@@ -0,0 +1,96 @@
+package middleware
+
+import (
+	"math"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"admin-statistics-api/internal/apierror"
+	"admin-statistics-api/internal/logging"
+	"admin-statistics-api/internal/model"
+	"admin-statistics-api/internal/repository"
+)
+
+// RateLimits holds the limits applied by RateLimitMiddleware, which can be replaced
+// while serving
+type RateLimits struct {
+	limits atomic.Pointer[rateLimits]
+}
+
+type rateLimits struct {
+	defaultLimit model.RateLimit
+	routeLimits  map[string]model.RateLimit
+}
+
+// NewRateLimits creates RateLimits applying the route's limit from routeLimits (keyed
+// by the route pattern, e.g. "/user/:user_id/summary") or defaultLimit otherwise.
+// A zero limit disables limiting for the route.
+func NewRateLimits(defaultLimit model.RateLimit, routeLimits map[string]model.RateLimit) *RateLimits {
+	l := &RateLimits{}
+	l.Set(defaultLimit, routeLimits)
+	return l
+}
+
+// Set replaces the limits. Requests already counted against a route stay counted.
+func (l *RateLimits) Set(defaultLimit model.RateLimit, routeLimits map[string]model.RateLimit) {
+	l.limits.Store(&rateLimits{defaultLimit: defaultLimit, routeLimits: routeLimits})
+}
+
+// Disable stops limiting every route until Set is called
+func (l *RateLimits) Disable() {
+	l.limits.Store(&rateLimits{})
+}
+
+// For returns the limit for a route pattern
+func (l *RateLimits) For(route string) model.RateLimit {
+	limits := l.limits.Load()
+	if limit, ok := limits.routeLimits[route]; ok {
+		return limit
+	}
+	return limits.defaultLimit
+}
+
+// RateLimitMiddleware provides a middleware function that limits how often each
+// authenticated principal can call each route. Every principal has a separate bucket
+// per route, limited as limits sets for the route. It must run after AuthMiddleware.
+//
+// Responses carry X-RateLimit-Limit, X-RateLimit-Remaining and X-RateLimit-Reset
+// (seconds until the full limit is available). Rejected requests get 429 Too Many
+// Requests with a Retry-After header. If the limiter fails the request is let through.
+func RateLimitMiddleware(limiter repository.RateLimiter, limits *RateLimits) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		route := c.FullPath()
+		limit := limits.For(route)
+
+		principal, authenticated := PrincipalFromContext(c)
+		if !authenticated || limit.IsZero() {
+			c.Next()
+			return
+		}
+
+		result, err := limiter.Allow(c, principal.ID+":"+c.Request.Method+":"+route, limit)
+		if err != nil {
+			logging.FromContext(c).Warn("Rate limiter failed, allowing request", "error", err)
+			c.Next()
+			return
+		}
+
+		c.Header("X-RateLimit-Limit", strconv.Itoa(result.Limit))
+		c.Header("X-RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		c.Header("X-RateLimit-Reset", strconv.Itoa(ceilSeconds(result.ResetAfter)))
+
+		if !result.Allowed {
+			apierror.Abort(c, apierror.RateLimited("Rate limit exceeded, retry later", result.RetryAfter))
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// ceilSeconds rounds a duration up to whole seconds for HTTP headers
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,187 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"admin-statistics-api/internal/model"
+	"admin-statistics-api/internal/repository"
+	"admin-statistics-api/internal/service"
+)
+
+// failingRateLimiter is a RateLimiter whose backend is always unavailable
+type failingRateLimiter struct{}
+
+func (failingRateLimiter) Allow(ctx context.Context, key string, limit model.RateLimit) (repository.RateLimitResult, error) {
+	return repository.RateLimitResult{}, errors.New("limiter unavailable")
+}
+
+// setupRateLimitRouter creates a router limiting every route to two requests a minute,
+// except the percentile route which allows one
+func setupRateLimitRouter(limiter repository.RateLimiter) (*gin.Engine, *service.APIKeyService) {
+	routeLimits := map[string]model.RateLimit{
+		"/user/:user_id/wager_percentile": {Requests: 1, Period: time.Minute},
+		"/unlimited":                      {},
+	}
+	return setupRateLimitRouterWithLimits(limiter, NewRateLimits(model.RateLimit{Requests: 2, Period: time.Minute}, routeLimits))
+}
+
+// setupRateLimitRouterWithLimits creates a router limited by limits
+func setupRateLimitRouterWithLimits(limiter repository.RateLimiter, limits *RateLimits) (*gin.Engine, *service.APIKeyService) {
+	gin.SetMode(gin.TestMode)
+
+	apiKeyService := service.NewAPIKeyService(repository.NewMockAPIKeyStore(), "test-api-key")
+
+	router := gin.New()
+	router.Use(AuthMiddleware(apiKeyService, nil))
+	router.Use(RateLimitMiddleware(limiter, limits))
+
+	ok := func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	}
+	router.GET("/stats", ok)
+	router.GET("/leaderboard", ok)
+	router.GET("/user/:user_id/wager_percentile", ok)
+	router.GET("/unlimited", ok)
+
+	return router, apiKeyService
+}
+
+func TestRateLimitMiddleware(t *testing.T) {
+	request := func(router *gin.Engine, path, key string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		req.Header.Set("Authorization", key)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("sets rate limit headers on allowed requests", func(t *testing.T) {
+		// Arrange
+		router, _ := setupRateLimitRouter(repository.NewMemoryRateLimiter())
+
+		// Act
+		w := request(router, "/stats", "test-api-key")
+
+		// Assert
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "2", w.Header().Get("X-RateLimit-Limit"))
+		assert.Equal(t, "1", w.Header().Get("X-RateLimit-Remaining"))
+		assert.Equal(t, "30", w.Header().Get("X-RateLimit-Reset"))
+		assert.Empty(t, w.Header().Get("Retry-After"))
+	})
+
+	t.Run("returns 429 with Retry-After over the limit", func(t *testing.T) {
+		// Arrange
+		router, _ := setupRateLimitRouter(repository.NewMemoryRateLimiter())
+		request(router, "/stats", "test-api-key")
+		request(router, "/stats", "test-api-key")
+
+		// Act
+		w := request(router, "/stats", "test-api-key")
+
+		// Assert
+		assert.Equal(t, http.StatusTooManyRequests, w.Code)
+		assert.Equal(t, "30", w.Header().Get("Retry-After"))
+		assert.Equal(t, "0", w.Header().Get("X-RateLimit-Remaining"))
+		assert.Equal(t, "60", w.Header().Get("X-RateLimit-Reset"))
+		assert.JSONEq(t, `{"code":"rate_limited","message":"Rate limit exceeded, retry later","details":[],"requestId":""}`, w.Body.String())
+	})
+
+	t.Run("applies route limits", func(t *testing.T) {
+		router, _ := setupRateLimitRouter(repository.NewMemoryRateLimiter())
+
+		assert.Equal(t, http.StatusOK, request(router, "/user/u1/wager_percentile", "test-api-key").Code)
+		assert.Equal(t, http.StatusTooManyRequests, request(router, "/user/u2/wager_percentile", "test-api-key").Code)
+	})
+
+	t.Run("limits each route separately", func(t *testing.T) {
+		router, _ := setupRateLimitRouter(repository.NewMemoryRateLimiter())
+		request(router, "/user/u1/wager_percentile", "test-api-key")
+
+		assert.Equal(t, http.StatusOK, request(router, "/leaderboard", "test-api-key").Code)
+	})
+
+	t.Run("limits each API key separately", func(t *testing.T) {
+		// Arrange
+		router, apiKeyService := setupRateLimitRouter(repository.NewMemoryRateLimiter())
+		_, secret, err := apiKeyService.CreateAPIKey(context.Background(), "dashboard", []string{model.ScopeUserRead}, nil)
+		assert.NoError(t, err)
+		request(router, "/user/u1/wager_percentile", "test-api-key")
+
+		// Act
+		w := request(router, "/user/u1/wager_percentile", secret)
+
+		// Assert
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("skips routes with a zero limit", func(t *testing.T) {
+		router, _ := setupRateLimitRouter(repository.NewMemoryRateLimiter())
+
+		for i := 0; i < 5; i++ {
+			w := request(router, "/unlimited", "test-api-key")
+
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Empty(t, w.Header().Get("X-RateLimit-Limit"))
+		}
+	})
+
+	t.Run("allows requests when the limiter fails", func(t *testing.T) {
+		router, _ := setupRateLimitRouter(failingRateLimiter{})
+
+		assert.Equal(t, http.StatusOK, request(router, "/stats", "test-api-key").Code)
+	})
+
+	t.Run("rejects unauthenticated requests before limiting", func(t *testing.T) {
+		router, _ := setupRateLimitRouter(repository.NewMemoryRateLimiter())
+
+		w := request(router, "/stats", "invalid-key")
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Empty(t, w.Header().Get("X-RateLimit-Limit"))
+	})
+
+	t.Run("applies replaced limits to the next requests", func(t *testing.T) {
+		// Arrange
+		limits := NewRateLimits(model.RateLimit{Requests: 1, Period: time.Minute}, nil)
+		router, _ := setupRateLimitRouterWithLimits(repository.NewMemoryRateLimiter(), limits)
+		request(router, "/stats", "test-api-key")
+
+		// Act
+		limits.Set(model.RateLimit{Requests: 1, Period: time.Minute}, map[string]model.RateLimit{"/stats": {}})
+		w := request(router, "/stats", "test-api-key")
+
+		// Assert
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Empty(t, w.Header().Get("X-RateLimit-Limit"), "The route is no longer limited")
+	})
+
+	t.Run("stops limiting while disabled and limits again once set", func(t *testing.T) {
+		// Arrange
+		limit := model.RateLimit{Requests: 1, Period: time.Minute}
+		limits := NewRateLimits(limit, nil)
+		limits.Disable()
+		router, _ := setupRateLimitRouterWithLimits(repository.NewMemoryRateLimiter(), limits)
+
+		// Act
+		first := request(router, "/stats", "test-api-key")
+		second := request(router, "/stats", "test-api-key")
+		limits.Set(limit, nil)
+		third := request(router, "/stats", "test-api-key")
+		fourth := request(router, "/stats", "test-api-key")
+
+		// Assert
+		assert.Equal(t, http.StatusOK, first.Code)
+		assert.Equal(t, http.StatusOK, second.Code)
+		assert.Empty(t, second.Header().Get("X-RateLimit-Limit"))
+		assert.Equal(t, http.StatusOK, third.Code)
+		assert.Equal(t, http.StatusTooManyRequests, fourth.Code)
+	})
+}
@@ -0,0 +1,64 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"admin-statistics-api/internal/logging"
+)
+
+func TestRequestIDMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	// serve returns the response to a request with the X-Request-ID header set to id,
+	// and the ID the handler saw in its context
+	serve := func(id string) (*httptest.ResponseRecorder, string) {
+		var seen string
+		router := gin.New()
+		router.Use(RequestIDMiddleware())
+		router.GET("/test", func(c *gin.Context) {
+			seen = logging.RequestIDFromContext(c.Request.Context())
+			c.Status(http.StatusOK)
+		})
+
+		req := httptest.NewRequest("GET", "/test", nil)
+		if id != "" {
+			req.Header.Set(RequestIDHeader, id)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w, seen
+	}
+
+	t.Run("keeps the caller's request ID", func(t *testing.T) {
+		// Act
+		w, seen := serve("checkout-7f3a")
+
+		// Assert
+		assert.Equal(t, "checkout-7f3a", w.Header().Get(RequestIDHeader))
+		assert.Equal(t, "checkout-7f3a", seen)
+	})
+
+	t.Run("generates a request ID when there is none", func(t *testing.T) {
+		w, seen := serve("")
+
+		assert.Len(t, seen, 32)
+		assert.Equal(t, seen, w.Header().Get(RequestIDHeader))
+
+		_, other := serve("")
+		assert.NotEqual(t, seen, other, "Each request gets its own ID")
+	})
+
+	t.Run("replaces unusable request IDs", func(t *testing.T) {
+		for _, id := range []string{"has spaces", "café", strings.Repeat("a", maxRequestIDLength+1)} {
+			w, seen := serve(id)
+
+			assert.NotEqual(t, id, seen)
+			assert.Len(t, w.Header().Get(RequestIDHeader), 32)
+		}
+	})
+}
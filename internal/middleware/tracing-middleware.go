@@ -0,0 +1,38 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+// TracingMiddleware provides a middleware function that records a server span for each
+// request through otelgin, continuing the caller's trace when the request has a W3C
+// traceparent header. The span is stored in the request's context, so handlers only pass
+// it on to the service when the engine has ContextWithFallback set.
+//
+// Spans are named after the method and route, like "GET /daily_wager_volume".
+func TracingMiddleware(service string, opts ...otelgin.Option) gin.HandlerFunc {
+	opts = append([]otelgin.Option{otelgin.WithSpanNameFormatter(spanName)}, opts...)
+	return otelgin.Middleware(service, opts...)
+}
+
+// TraceparentMiddleware provides a middleware function that sets a traceparent response
+// header for the request's span, so a slow request can be looked up by its trace ID.
+// It must come after TracingMiddleware.
+func TraceparentMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		otel.GetTextMapPropagator().Inject(c.Request.Context(), propagation.HeaderCarrier(c.Writer.Header()))
+		c.Next()
+	}
+}
+
+// spanName names a request's span after its method and route
+func spanName(c *gin.Context) string {
+	route := c.FullPath()
+	if route == "" {
+		route = unmatchedRoute
+	}
+	return c.Request.Method + " " + route
+}
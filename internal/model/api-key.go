@@ -0,0 +1,53 @@
+package model
+
+import (
+	"time"
+)
+
+// API key scopes
+const (
+	ScopeStatsRead         = "stats:read"         // Aggregate statistics
+	ScopeUserRead          = "user:read"          // Per-user statistics
+	ScopeTransactionsWrite = "transactions:write" // Transaction ingestion
+	ScopeKeysAdmin         = "keys:admin"         // API key management
+)
+
+// AllScopes lists every API key scope
+var AllScopes = []string{ScopeStatsRead, ScopeUserRead, ScopeTransactionsWrite, ScopeKeysAdmin}
+
+// APIKey is a named API key. Only a hash of the secret key is stored.
+type APIKey struct {
+	ID        string     `bson:"_id" json:"id"` // ULID string, also embedded in the secret key
+	Name      string     `bson:"name" json:"name"`
+	Hash      string     `bson:"hash" json:"-"` // Hex SHA-256 of the secret key
+	Scopes    []string   `bson:"scopes" json:"scopes"`
+	CreatedAt time.Time  `bson:"createdAt" json:"createdAt"`
+	ExpiresAt *time.Time `bson:"expiresAt,omitempty" json:"expiresAt,omitempty"`
+	RevokedAt *time.Time `bson:"revokedAt,omitempty" json:"revokedAt,omitempty"` // May be in the future while a rotated key winds down
+}
+
+// HasScope reports whether the key grants scope
+func (k APIKey) HasScope(scope string) bool {
+	for _, s := range k.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// Principal returns the principal authenticated by the key
+func (k APIKey) Principal() Principal {
+	return Principal{ID: k.ID, Name: k.Name, Scopes: k.Scopes}
+}
+
+// ActiveAt reports whether the key is neither expired nor revoked at the given time
+func (k APIKey) ActiveAt(at time.Time) bool {
+	if k.ExpiresAt != nil && !at.Before(*k.ExpiresAt) {
+		return false
+	}
+	if k.RevokedAt != nil && !at.Before(*k.RevokedAt) {
+		return false
+	}
+	return true
+}
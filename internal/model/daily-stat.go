@@ -0,0 +1,26 @@
+package model
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// DailyStat is the rollup of one currency and transaction type over one UTC day
+type DailyStat struct {
+	ID        DailyStatKey         `bson:"_id"`
+	Day       time.Time            `bson:"day"` // Midnight UTC
+	Currency  string               `bson:"currency"`
+	Type      string               `bson:"type"`
+	Amount    primitive.Decimal128 `bson:"amount"`    // Sum of amount
+	USDAmount primitive.Decimal128 `bson:"usdAmount"` // Sum of usdAmount
+	Count     int                  `bson:"count"`     // Number of transactions
+}
+
+// DailyStatKey identifies a DailyStat. Field order matters because MongoDB compares
+// embedded documents field by field, so it must match the rollup backfill pipeline.
+type DailyStatKey struct {
+	Day      time.Time `bson:"day"`
+	Currency string    `bson:"currency"`
+	Type     string    `bson:"type"`
+}
@@ -10,16 +10,16 @@ import (
 
 // Transaction represents a user transaction in the system
 type Transaction struct {
-	ID        string               `bson:"_id"`        // ULID string
-	CreatedAt time.Time            `bson:"createdAt"`
+	ID        string               `bson:"_id" json:"id"`               // ULID string
+	CreatedAt time.Time            `bson:"createdAt" json:"createdAt"`
 
-	UserID    string               `bson:"userId"`     // ULID string
-	RoundID   string               `bson:"roundId"`
+	UserID    string               `bson:"userId" json:"userId"`       // ULID string
+	RoundID   string               `bson:"roundId" json:"roundId"`
 
-	Type      string               `bson:"type"`       // Either "Wager" or "Payout"
-	Amount    primitive.Decimal128 `bson:"amount"`     // Should always be >= 0
-	Currency  string               `bson:"currency"`   // Either "ETH", "BTC", or "USDT"
-	USDAmount primitive.Decimal128 `bson:"usdAmount"`  // The USD value of the `amount` and `currency`
+	Type      string               `bson:"type" json:"type"`           // Either "Wager" or "Payout"
+	Amount    primitive.Decimal128 `bson:"amount" json:"amount"`       // Should always be >= 0
+	Currency  string               `bson:"currency" json:"currency"`   // Either "ETH", "BTC", or "USDT"
+	USDAmount primitive.Decimal128 `bson:"usdAmount" json:"usdAmount"` // The USD value of the `amount` and `currency`
 }
 
 // Transaction types
@@ -35,6 +35,21 @@ const (
 	CurrencyUSDT = "USDT"
 )
 
+// Round anomaly types reported by the reconciliation report
+const (
+	RoundAnomalyPayoutWithoutWager = "payout_without_wager"
+	RoundAnomalyMultipleWagers     = "multiple_wagers"
+	RoundAnomalyCurrencyMismatch   = "currency_mismatch"
+	RoundAnomalyPayoutBeforeWager  = "payout_before_wager"
+)
+
+// Leaderboard metrics
+const (
+	LeaderboardMetricWager  = "wager"
+	LeaderboardMetricPayout = "payout"
+	LeaderboardMetricGGR    = "ggr"
+)
+
 // GenerateULID generates a new ULID string
 func GenerateULID() string {
 	// Create entropy source for ULID
@@ -0,0 +1,157 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/x/bsonx/bsoncore"
+)
+
+// Decimal is an exact decimal amount used in aggregation results.
+// It is serialized as a JSON string (e.g. "15.23") so no precision is lost,
+// and it decodes any BSON numeric type because MongoDB returns an integer
+// when a $sum or $cond falls back to a literal 0.
+type Decimal struct {
+	value primitive.Decimal128
+}
+
+// plainZero is the Decimal128 encoding of "0", which is stored as the zero value
+// so that a zero Decimal compares equal to one that was parsed or decoded
+var plainZero, _ = primitive.ParseDecimal128("0")
+
+// NewDecimal wraps a Decimal128 value
+func NewDecimal(d primitive.Decimal128) Decimal {
+	if d == plainZero {
+		return Decimal{}
+	}
+	return Decimal{value: d}
+}
+
+// ParseDecimal parses a decimal string such as "15.23"
+func ParseDecimal(s string) (Decimal, error) {
+	d, err := primitive.ParseDecimal128(s)
+	if err != nil {
+		return Decimal{}, err
+	}
+	return NewDecimal(d), nil
+}
+
+// MustParseDecimal parses a decimal string and panics if it is invalid
+func MustParseDecimal(s string) Decimal {
+	d, err := ParseDecimal(s)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+// Decimal128 returns the underlying Decimal128 value
+func (d Decimal) Decimal128() primitive.Decimal128 {
+	if d.value.IsZero() {
+		return plainZero
+	}
+	return d.value
+}
+
+// String returns the decimal in its exact string form
+func (d Decimal) String() string {
+	// The zero value of Decimal128 encodes 0E-6176
+	if d.value.IsZero() {
+		return "0"
+	}
+	return d.value.String()
+}
+
+// PlainString returns the decimal without an exponent, e.g. "0.00000001" where String
+// returns "1E-8", for spreadsheets that would otherwise round it
+func (d Decimal) PlainString() string {
+	coefficient, exp, err := d.Decimal128().BigInt()
+	if err != nil {
+		return d.String() // NaN or Infinity
+	}
+
+	digits := coefficient.String()
+	sign := ""
+	if strings.HasPrefix(digits, "-") {
+		sign, digits = "-", digits[1:]
+	}
+
+	if exp >= 0 {
+		return sign + digits + strings.Repeat("0", exp)
+	}
+	scale := -exp
+	if len(digits) <= scale {
+		digits = strings.Repeat("0", scale-len(digits)+1) + digits
+	}
+	return sign + digits[:len(digits)-scale] + "." + digits[len(digits)-scale:]
+}
+
+// MarshalJSON writes the decimal as a JSON string
+func (d Decimal) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+// UnmarshalJSON reads a decimal from a JSON string or number
+func (d *Decimal) UnmarshalJSON(b []byte) error {
+	if string(b) == "null" {
+		return nil
+	}
+
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		// Accept bare numbers as well, keeping their literal digits
+		var n json.Number
+		if err := json.Unmarshal(b, &n); err != nil {
+			return fmt.Errorf("decimal must be a string or number: %s", b)
+		}
+		s = n.String()
+	}
+
+	parsed, err := ParseDecimal(s)
+	if err != nil {
+		return err
+	}
+	*d = parsed
+	return nil
+}
+
+// MarshalBSONValue stores the decimal as a BSON Decimal128
+func (d Decimal) MarshalBSONValue() (bsontype.Type, []byte, error) {
+	return bsontype.Decimal128, bsoncore.AppendDecimal128(nil, d.Decimal128()), nil
+}
+
+// UnmarshalBSONValue reads a decimal from any BSON numeric type
+func (d *Decimal) UnmarshalBSONValue(t bsontype.Type, data []byte) error {
+	value := bsoncore.Value{Type: t, Data: data}
+
+	switch t {
+	case bsontype.Decimal128:
+		*d = NewDecimal(value.Decimal128())
+		return nil
+	case bsontype.Int32:
+		return d.parse(strconv.FormatInt(int64(value.Int32()), 10))
+	case bsontype.Int64:
+		return d.parse(strconv.FormatInt(value.Int64(), 10))
+	case bsontype.Double:
+		return d.parse(strconv.FormatFloat(value.Double(), 'f', -1, 64))
+	case bsontype.Null:
+		*d = Decimal{}
+		return nil
+	default:
+		return fmt.Errorf("cannot decode BSON %s into Decimal", t)
+	}
+}
+
+// parse replaces the decimal's value with the parsed string
+func (d *Decimal) parse(s string) error {
+	parsed, err := ParseDecimal(s)
+	if err != nil {
+		return err
+	}
+	*d = parsed
+	return nil
+}
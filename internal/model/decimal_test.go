@@ -0,0 +1,116 @@
+package model
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestDecimal_JSON(t *testing.T) {
+	t.Run("marshals as string", func(t *testing.T) {
+		data, err := json.Marshal(MustParseDecimal("15.23"))
+
+		assert.NoError(t, err)
+		assert.Equal(t, `"15.23"`, string(data))
+	})
+
+	t.Run("zero value marshals as 0", func(t *testing.T) {
+		data, err := json.Marshal(Decimal{})
+
+		assert.NoError(t, err)
+		assert.Equal(t, `"0"`, string(data))
+	})
+
+	t.Run("round trips exactly", func(t *testing.T) {
+		original := MustParseDecimal("761500.0010")
+
+		data, _ := json.Marshal(original)
+		var decoded Decimal
+		err := json.Unmarshal(data, &decoded)
+
+		assert.NoError(t, err)
+		assert.Equal(t, original, decoded)
+	})
+
+	t.Run("accepts numbers", func(t *testing.T) {
+		var decoded Decimal
+		err := json.Unmarshal([]byte(`12.5`), &decoded)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "12.5", decoded.String())
+	})
+
+	t.Run("rejects non-numeric strings", func(t *testing.T) {
+		var decoded Decimal
+		err := json.Unmarshal([]byte(`"abc"`), &decoded)
+
+		assert.Error(t, err)
+	})
+}
+
+func TestDecimal_BSON(t *testing.T) {
+	type row struct {
+		Amount Decimal `bson:"amount"`
+	}
+
+	cases := map[string]struct {
+		value    interface{}
+		expected string
+	}{
+		"decimal128": {MustParseDecimal("10.50").Decimal128(), "10.50"},
+		"int32":      {int32(0), "0"},
+		"int64":      {int64(42), "42"},
+		"double":     {2.5, "2.5"},
+		"null":       {nil, "0"},
+	}
+
+	for name, tc := range cases {
+		t.Run("decodes "+name, func(t *testing.T) {
+			data, err := bson.Marshal(bson.M{"amount": tc.value})
+			assert.NoError(t, err)
+
+			var decoded row
+			err = bson.Unmarshal(data, &decoded)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, decoded.Amount.String())
+		})
+	}
+
+	t.Run("encodes as decimal128", func(t *testing.T) {
+		data, err := bson.Marshal(row{Amount: MustParseDecimal("1.23")})
+		assert.NoError(t, err)
+
+		var raw bson.M
+		_ = bson.Unmarshal(data, &raw)
+
+		assert.Equal(t, MustParseDecimal("1.23").Decimal128(), raw["amount"])
+	})
+
+	t.Run("rejects non-numeric types", func(t *testing.T) {
+		data, _ := bson.Marshal(bson.M{"amount": "10"})
+
+		var decoded row
+		err := bson.Unmarshal(data, &decoded)
+
+		assert.Error(t, err)
+	})
+}
+
+func TestDecimal_PlainString(t *testing.T) {
+	tests := map[string]string{
+		"15.23":   "15.23",
+		"1E-8":    "0.00000001",
+		"-2.5E-7": "-0.00000025",
+		"1.5E+3":  "1500",
+		"0.00":    "0.00",
+		"-10.50":  "-10.50",
+	}
+
+	for input, expected := range tests {
+		assert.Equal(t, expected, MustParseDecimal(input).PlainString(), input)
+	}
+	assert.Equal(t, "0", Decimal{}.PlainString())
+}
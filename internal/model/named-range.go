@@ -0,0 +1,23 @@
+package model
+
+import "time"
+
+// Named ranges a stats endpoint can be asked for instead of from and to
+const (
+	RangeLast24h     = "last_24h"
+	RangeMonthToDate = "month_to_date"
+)
+
+// ResolveRange returns the bounds of the named range at now, in UTC. It reports false
+// for an unknown name.
+func ResolveRange(name string, now time.Time) (from, to time.Time, ok bool) {
+	to = now.UTC()
+	switch name {
+	case RangeLast24h:
+		return to.Add(-24 * time.Hour), to, true
+	case RangeMonthToDate:
+		return time.Date(to.Year(), to.Month(), 1, 0, 0, 0, 0, time.UTC), to, true
+	default:
+		return time.Time{}, time.Time{}, false
+	}
+}
@@ -0,0 +1,19 @@
+package model
+
+// Principal is an authenticated client, identified by an API key or a JWT
+type Principal struct {
+	ID     string   `json:"id"`              // API key ID, or "jwt:" followed by the token subject
+	Name   string   `json:"name"`            // API key name, or the token subject
+	Scopes []string `json:"scopes"`          // Scopes granted to the client
+	Roles  []string `json:"roles,omitempty"` // Token roles the scopes were mapped from; empty for API keys
+}
+
+// HasScope reports whether the principal was granted scope
+func (p Principal) HasScope(scope string) bool {
+	for _, s := range p.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,49 @@
+package model
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RateLimit allows Requests requests per Period. Up to Requests may be sent at once,
+// after which capacity refills evenly over the period.
+type RateLimit struct {
+	Requests int
+	Period   time.Duration
+}
+
+// IsZero reports whether no limit is set
+func (l RateLimit) IsZero() bool {
+	return l.Requests <= 0 || l.Period <= 0
+}
+
+// Interval returns the time it takes to regain capacity for one request
+func (l RateLimit) Interval() time.Duration {
+	return l.Period / time.Duration(l.Requests)
+}
+
+// String returns the limit in the form accepted by ParseRateLimit
+func (l RateLimit) String() string {
+	return fmt.Sprintf("%d/%s", l.Requests, l.Period)
+}
+
+// ParseRateLimit parses a limit written as "<requests>/<period>", such as "100/1m"
+func ParseRateLimit(s string) (RateLimit, error) {
+	requests, period, found := strings.Cut(strings.TrimSpace(s), "/")
+	if !found {
+		return RateLimit{}, fmt.Errorf("rate limit %q must be <requests>/<period>", s)
+	}
+
+	n, err := strconv.Atoi(strings.TrimSpace(requests))
+	if err != nil || n <= 0 {
+		return RateLimit{}, fmt.Errorf("rate limit %q must allow a positive number of requests", s)
+	}
+	d, err := time.ParseDuration(strings.TrimSpace(period))
+	if err != nil || d <= 0 {
+		return RateLimit{}, fmt.Errorf("rate limit %q must have a positive period", s)
+	}
+
+	return RateLimit{Requests: n, Period: d}, nil
+}
@@ -0,0 +1,26 @@
+package model
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRateLimit(t *testing.T) {
+	t.Run("parses requests per period", func(t *testing.T) {
+		limit, err := ParseRateLimit(" 100 / 1m ")
+
+		assert.NoError(t, err)
+		assert.Equal(t, RateLimit{Requests: 100, Period: time.Minute}, limit)
+		assert.Equal(t, 600*time.Millisecond, limit.Interval())
+	})
+
+	t.Run("rejects invalid limits", func(t *testing.T) {
+		for _, value := range []string{"", "100", "0/1m", "-1/1m", "ten/1m", "100/", "100/0s", "100/minute"} {
+			_, err := ParseRateLimit(value)
+
+			assert.Error(t, err, value)
+		}
+	})
+}
@@ -0,0 +1,77 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Report types, one per stats endpoint
+const (
+	ReportTypeGGR                 = "ggr"
+	ReportTypeGGRSeries           = "ggr_series"
+	ReportTypeDailyWagerVolume    = "daily_wager_volume"
+	ReportTypeUserWagerPercentile = "user_wager_percentile"
+	ReportTypeUserSummary         = "user_summary"
+	ReportTypeLeaderboard         = "leaderboard"
+	ReportTypeRoundAnomalies      = "round_anomalies"
+)
+
+// ReportScopes maps each report type to the scope needed to request it, which is the
+// scope of its endpoint
+var ReportScopes = map[string]string{
+	ReportTypeGGR:                 ScopeStatsRead,
+	ReportTypeGGRSeries:           ScopeStatsRead,
+	ReportTypeDailyWagerVolume:    ScopeStatsRead,
+	ReportTypeRoundAnomalies:      ScopeStatsRead,
+	ReportTypeUserWagerPercentile: ScopeUserRead,
+	ReportTypeUserSummary:         ScopeUserRead,
+	ReportTypeLeaderboard:         ScopeUserRead,
+}
+
+// Report job statuses. Succeeded, failed and cancelled jobs are finished.
+const (
+	ReportStatusQueued    = "queued"
+	ReportStatusRunning   = "running"
+	ReportStatusSucceeded = "succeeded"
+	ReportStatusFailed    = "failed"
+	ReportStatusCancelled = "cancelled"
+)
+
+// ReportParams are the parameters of a report, those of its endpoint. Only the ones the
+// report type uses are set.
+type ReportParams struct {
+	From        time.Time `bson:"from" json:"from"`
+	To          time.Time `bson:"to" json:"to"`
+	Granularity string    `bson:"granularity,omitempty" json:"granularity,omitempty"`
+	Timezone    string    `bson:"tz,omitempty" json:"tz,omitempty"`
+	UserID      string    `bson:"userId,omitempty" json:"userId,omitempty"`
+	Metric      string    `bson:"metric,omitempty" json:"metric,omitempty"`
+	Currency    string    `bson:"currency,omitempty" json:"currency,omitempty"`
+	Limit       int       `bson:"limit,omitempty" json:"limit,omitempty"`
+}
+
+// TimeBucket returns the bucketing of the report's results
+func (p ReportParams) TimeBucket() TimeBucket {
+	return TimeBucket{Granularity: p.Granularity, Timezone: p.Timezone}
+}
+
+// ReportJob is a report run in the background. Its result is the "data" its endpoint
+// would have returned, kept until ExpiresAt.
+type ReportJob struct {
+	ID         string          `bson:"_id" json:"id"` // ULID string
+	Type       string          `bson:"type" json:"type"`
+	Params     ReportParams    `bson:"params" json:"params"`
+	Owner      string          `bson:"owner" json:"-"` // ID of the principal that requested it
+	Status     string          `bson:"status" json:"status"`
+	Error      string          `bson:"error,omitempty" json:"error,omitempty"` // Why a failed job failed
+	Result     json.RawMessage `bson:"result,omitempty" json:"result,omitempty"`
+	CreatedAt  time.Time       `bson:"createdAt" json:"createdAt"`
+	StartedAt  *time.Time      `bson:"startedAt,omitempty" json:"startedAt,omitempty"`
+	FinishedAt *time.Time      `bson:"finishedAt,omitempty" json:"finishedAt,omitempty"`
+	ExpiresAt  time.Time       `bson:"expiresAt" json:"expiresAt"`
+}
+
+// Finished reports whether the job has stopped, successfully or not
+func (j ReportJob) Finished() bool {
+	return j.Status == ReportStatusSucceeded || j.Status == ReportStatusFailed || j.Status == ReportStatusCancelled
+}
@@ -0,0 +1,92 @@
+package model
+
+import (
+	"time"
+)
+
+// GGRRow is the Gross Gaming Revenue for one currency, optionally within one time bucket
+type GGRRow struct {
+	Date     string  `bson:"date,omitempty" json:"date,omitempty"` // Bucket label; empty for a single total
+	Currency string  `bson:"currency" json:"currency"`
+	GGR      Decimal `bson:"ggr" json:"ggr"`
+	GGRUSD   Decimal `bson:"ggrUSD" json:"ggrUSD"`
+}
+
+// GGRSeriesRow is the wagers, payouts and GGR for one currency in one time bucket
+type GGRSeriesRow struct {
+	Date        string   `bson:"date" json:"date"` // Bucket label, e.g. "2023-01-01" for a day
+	Currency    string   `bson:"currency" json:"currency"`
+	Wager       Decimal  `bson:"wager" json:"wager"`
+	Payout      Decimal  `bson:"payout" json:"payout"`
+	GGR         Decimal  `bson:"ggr" json:"ggr"`
+	WagerUSD    Decimal  `bson:"wagerUSD" json:"wagerUSD"`
+	PayoutUSD   Decimal  `bson:"payoutUSD" json:"payoutUSD"`
+	GGRUSD      Decimal  `bson:"ggrUSD" json:"ggrUSD"`
+	HoldPercent *Decimal `bson:"holdPercent" json:"holdPercent"` // GGR / wager * 100; nil when there were no wagers
+}
+
+// DailyWagerRow is the wager volume for one currency in one time bucket
+type DailyWagerRow struct {
+	Date           string  `bson:"date" json:"date"` // Bucket label, e.g. "2023-01-01" for a day
+	Currency       string  `bson:"currency" json:"currency"`
+	WagerAmount    Decimal `bson:"wagerAmount" json:"wagerAmount"`
+	WagerUSDAmount Decimal `bson:"wagerUSDAmount" json:"wagerUSDAmount"`
+}
+
+// UserSummary is a user's activity for a time period
+type UserSummary struct {
+	Currencies []UserCurrencySummary `bson:"currencies" json:"currencies"`
+	Total      *UserTotalSummary     `bson:"total,omitempty" json:"total,omitempty"` // Nil when the user has no transactions
+}
+
+// UserCurrencySummary is a user's activity in one currency
+type UserCurrencySummary struct {
+	Currency      string    `bson:"currency" json:"currency"`
+	Wagered       Decimal   `bson:"wagered" json:"wagered"`
+	WageredUSD    Decimal   `bson:"wageredUSD" json:"wageredUSD"`
+	PaidOut       Decimal   `bson:"paidOut" json:"paidOut"`
+	PaidOutUSD    Decimal   `bson:"paidOutUSD" json:"paidOutUSD"`
+	Net           Decimal   `bson:"net" json:"net"`
+	NetUSD        Decimal   `bson:"netUSD" json:"netUSD"`
+	Rounds        int       `bson:"rounds" json:"rounds"`
+	AverageBet    Decimal   `bson:"averageBet" json:"averageBet"`
+	AverageBetUSD Decimal   `bson:"averageBetUSD" json:"averageBetUSD"`
+	FirstSeen     time.Time `bson:"firstSeen" json:"firstSeen"`
+	LastSeen      time.Time `bson:"lastSeen" json:"lastSeen"`
+}
+
+// UserTotalSummary is a user's activity across all currencies in USD
+type UserTotalSummary struct {
+	WageredUSD    Decimal   `bson:"wageredUSD" json:"wageredUSD"`
+	PaidOutUSD    Decimal   `bson:"paidOutUSD" json:"paidOutUSD"`
+	NetUSD        Decimal   `bson:"netUSD" json:"netUSD"`
+	Rounds        int       `bson:"rounds" json:"rounds"`
+	AverageBetUSD Decimal   `bson:"averageBetUSD" json:"averageBetUSD"`
+	FirstSeen     time.Time `bson:"firstSeen" json:"firstSeen"`
+	LastSeen      time.Time `bson:"lastSeen" json:"lastSeen"`
+}
+
+// LeaderboardRow is one ranked user on the leaderboard
+type LeaderboardRow struct {
+	UserID     string   `bson:"userId" json:"userId"`
+	Rank       int      `bson:"rank" json:"rank"`
+	Percentile float64  `bson:"percentile" json:"percentile"`
+	Wager      *Decimal `bson:"wager,omitempty" json:"wager,omitempty"`   // Only set for a single currency
+	Payout     *Decimal `bson:"payout,omitempty" json:"payout,omitempty"` // Only set for a single currency
+	GGR        *Decimal `bson:"ggr,omitempty" json:"ggr,omitempty"`       // Only set for a single currency
+	WagerUSD   Decimal  `bson:"wagerUSD" json:"wagerUSD"`
+	PayoutUSD  Decimal  `bson:"payoutUSD" json:"payoutUSD"`
+	GGRUSD     Decimal  `bson:"ggrUSD" json:"ggrUSD"`
+}
+
+// RoundAnomaly is a round whose wagers and payouts do not reconcile
+type RoundAnomaly struct {
+	RoundID       string     `bson:"roundId" json:"roundId"`
+	UserIDs       []string   `bson:"userIds" json:"userIds"`
+	Currencies    []string   `bson:"currencies" json:"currencies"`
+	WagerCount    int        `bson:"wagerCount" json:"wagerCount"`
+	PayoutCount   int        `bson:"payoutCount" json:"payoutCount"`
+	FirstWagerAt  *time.Time `bson:"firstWagerAt,omitempty" json:"firstWagerAt,omitempty"`
+	FirstPayoutAt *time.Time `bson:"firstPayoutAt,omitempty" json:"firstPayoutAt,omitempty"`
+	Anomalies     []string   `bson:"anomalies" json:"anomalies"`
+}
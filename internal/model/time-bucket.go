@@ -0,0 +1,42 @@
+package model
+
+// Bucket granularities for time series results
+const (
+	GranularityHour  = "hour"
+	GranularityDay   = "day"
+	GranularityWeek  = "week"
+	GranularityMonth = "month"
+)
+
+// TimeBucket controls how aggregation results are grouped over time.
+// Bucket boundaries are computed in Timezone, so a "day" starts at local midnight.
+type TimeBucket struct {
+	Granularity string // One of the Granularity constants; empty means no grouping by time
+	Timezone    string // IANA time zone name such as "Asia/Singapore"; empty means UTC
+}
+
+// IsZero reports whether no time grouping was requested
+func (b TimeBucket) IsZero() bool {
+	return b.Granularity == ""
+}
+
+// Location returns the time zone name, defaulting to UTC
+func (b TimeBucket) Location() string {
+	if b.Timezone == "" {
+		return "UTC"
+	}
+	return b.Timezone
+}
+
+// LabelFormat returns the $dateToString format used to label a bucket by its start time
+func (b TimeBucket) LabelFormat() string {
+	switch b.Granularity {
+	case GranularityHour:
+		return "%Y-%m-%dT%H:00"
+	case GranularityMonth:
+		return "%Y-%m"
+	default:
+		// Days, and weeks labelled by their first day (Monday)
+		return "%Y-%m-%d"
+	}
+}
@@ -0,0 +1,244 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"admin-statistics-api/internal/model"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MongoAPIKeyStore stores API keys in a MongoDB collection
+type MongoAPIKeyStore struct {
+	collection *mongo.Collection
+}
+
+// NewMongoAPIKeyStore creates a new MongoAPIKeyStore
+func NewMongoAPIKeyStore(db *mongo.Database, collectionName string) *MongoAPIKeyStore {
+	return &MongoAPIKeyStore{
+		collection: db.Collection(collectionName),
+	}
+}
+
+// FindAPIKey finds an API key by ID
+func (s *MongoAPIKeyStore) FindAPIKey(ctx context.Context, id string) (model.APIKey, error) {
+	var key model.APIKey
+	err := s.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&key)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return model.APIKey{}, ErrAPIKeyNotFound
+	}
+	return key, err
+}
+
+// ListAPIKeys lists every API key, oldest first
+func (s *MongoAPIKeyStore) ListAPIKeys(ctx context.Context) ([]model.APIKey, error) {
+	cursor, err := s.collection.Find(ctx, bson.M{}, options.Find().SetSort(bson.D{{Key: "createdAt", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	keys := make([]model.APIKey, 0)
+	if err = cursor.All(ctx, &keys); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// CreateAPIKey stores a new API key
+func (s *MongoAPIKeyStore) CreateAPIKey(ctx context.Context, key model.APIKey) error {
+	_, err := s.collection.InsertOne(ctx, key)
+	return err
+}
+
+// RevokeAPIKey revokes an API key from the given time
+func (s *MongoAPIKeyStore) RevokeAPIKey(ctx context.Context, id string, at time.Time) error {
+	result, err := s.collection.UpdateByID(ctx, id, bson.M{"$set": bson.M{"revokedAt": at}})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrAPIKeyNotFound
+	}
+	return nil
+}
+
+// FileAPIKeyStore stores API keys in a JSON file, which is rewritten on every change
+type FileAPIKeyStore struct {
+	path string
+	keys map[string]model.APIKey
+	mu   sync.RWMutex
+}
+
+// fileAPIKey is the file representation of an API key, which unlike the API keeps the hash
+type fileAPIKey struct {
+	model.APIKey
+	Hash string `json:"hash"`
+}
+
+// NewFileAPIKeyStore creates a FileAPIKeyStore, loading any keys already in the file
+func NewFileAPIKeyStore(path string) (*FileAPIKeyStore, error) {
+	keys, err := readAPIKeyFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return &FileAPIKeyStore{path: path, keys: keys}, nil
+}
+
+// Reload reads the file again, picking up keys edited outside the API. The keys are
+// kept as they were if the file cannot be read.
+func (s *FileAPIKeyStore) Reload() error {
+	keys, err := readAPIKeyFile(s.path)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.keys = keys
+	return nil
+}
+
+// FindAPIKey finds an API key by ID
+func (s *FileAPIKeyStore) FindAPIKey(ctx context.Context, id string) (model.APIKey, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	key, ok := s.keys[id]
+	if !ok {
+		return model.APIKey{}, ErrAPIKeyNotFound
+	}
+	return key, nil
+}
+
+// ListAPIKeys lists every API key, oldest first
+func (s *FileAPIKeyStore) ListAPIKeys(ctx context.Context) ([]model.APIKey, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.sortedKeys(), nil
+}
+
+// CreateAPIKey stores a new API key
+func (s *FileAPIKeyStore) CreateAPIKey(ctx context.Context, key model.APIKey) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.keys[key.ID]; ok {
+		return errors.New("api key already exists")
+	}
+
+	s.keys[key.ID] = key
+	if err := s.save(); err != nil {
+		delete(s.keys, key.ID)
+		return err
+	}
+	return nil
+}
+
+// RevokeAPIKey revokes an API key from the given time
+func (s *FileAPIKeyStore) RevokeAPIKey(ctx context.Context, id string, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key, ok := s.keys[id]
+	if !ok {
+		return ErrAPIKeyNotFound
+	}
+
+	previous := key
+	key.RevokedAt = &at
+	s.keys[id] = key
+	if err := s.save(); err != nil {
+		s.keys[id] = previous
+		return err
+	}
+	return nil
+}
+
+// readAPIKeyFile reads the keys from a file; a missing file holds no keys
+func readAPIKeyFile(path string) (map[string]model.APIKey, error) {
+	keys := make(map[string]model.APIKey)
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return keys, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var stored []fileAPIKey
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return nil, err
+	}
+	for _, key := range stored {
+		key.APIKey.Hash = key.Hash
+		keys[key.ID] = key.APIKey
+	}
+	return keys, nil
+}
+
+// save writes the keys to a temporary file and renames it over the original,
+// so that a crash never leaves a partially written file behind
+func (s *FileAPIKeyStore) save() error {
+	keys := s.sortedKeys()
+	stored := make([]fileAPIKey, len(keys))
+	for i, key := range keys {
+		stored[i] = fileAPIKey{APIKey: key, Hash: key.Hash}
+	}
+
+	data, err := json.MarshalIndent(stored, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(0o600); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path)
+}
+
+// sortedKeys returns the keys ordered by creation time, then ID
+func (s *FileAPIKeyStore) sortedKeys() []model.APIKey {
+	keys := make([]model.APIKey, 0, len(s.keys))
+	for _, key := range s.keys {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if !keys[i].CreatedAt.Equal(keys[j].CreatedAt) {
+			return keys[i].CreatedAt.Before(keys[j].CreatedAt)
+		}
+		return keys[i].ID < keys[j].ID
+	})
+	return keys
+}
+
+// Ensure both stores implement APIKeyStore
+var (
+	_ APIKeyStore = (*MongoAPIKeyStore)(nil)
+	_ APIKeyStore = (*FileAPIKeyStore)(nil)
+)
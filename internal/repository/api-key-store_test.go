@@ -0,0 +1,143 @@
+package repository
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"admin-statistics-api/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileAPIKeyStore(t *testing.T) {
+	ctx := context.Background()
+	createdAt := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	key := model.APIKey{
+		ID:        model.GenerateULID(),
+		Name:      "dashboard",
+		Hash:      "5e884898da28047151d0e56f8dc6292773603d0d6aabbdd62a11ef721d1542d8",
+		Scopes:    []string{model.ScopeStatsRead},
+		CreatedAt: createdAt,
+	}
+
+	t.Run("starts empty without a file", func(t *testing.T) {
+		// Arrange
+		store, err := NewFileAPIKeyStore(filepath.Join(t.TempDir(), "api-keys.json"))
+		assert.NoError(t, err)
+
+		// Act
+		keys, err := store.ListAPIKeys(ctx)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Empty(t, keys)
+	})
+
+	t.Run("persists keys with their hash across reloads", func(t *testing.T) {
+		// Arrange
+		path := filepath.Join(t.TempDir(), "api-keys.json")
+		store, err := NewFileAPIKeyStore(path)
+		assert.NoError(t, err)
+
+		// Act
+		assert.NoError(t, store.CreateAPIKey(ctx, key))
+		reloaded, err := NewFileAPIKeyStore(path)
+		assert.NoError(t, err)
+		found, err := reloaded.FindAPIKey(ctx, key.ID)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, key, found)
+
+		info, err := os.Stat(path)
+		assert.NoError(t, err)
+		assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+	})
+
+	t.Run("revokes keys", func(t *testing.T) {
+		// Arrange
+		path := filepath.Join(t.TempDir(), "api-keys.json")
+		store, err := NewFileAPIKeyStore(path)
+		assert.NoError(t, err)
+		assert.NoError(t, store.CreateAPIKey(ctx, key))
+		revokedAt := createdAt.Add(time.Hour)
+
+		// Act
+		err = store.RevokeAPIKey(ctx, key.ID, revokedAt)
+
+		// Assert
+		assert.NoError(t, err)
+		reloaded, err := NewFileAPIKeyStore(path)
+		assert.NoError(t, err)
+		found, err := reloaded.FindAPIKey(ctx, key.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, revokedAt, *found.RevokedAt)
+	})
+
+	t.Run("reports unknown and duplicate keys", func(t *testing.T) {
+		// Arrange
+		store, err := NewFileAPIKeyStore(filepath.Join(t.TempDir(), "api-keys.json"))
+		assert.NoError(t, err)
+		assert.NoError(t, store.CreateAPIKey(ctx, key))
+
+		// Act
+		_, findErr := store.FindAPIKey(ctx, "missing")
+		revokeErr := store.RevokeAPIKey(ctx, "missing", createdAt)
+		createErr := store.CreateAPIKey(ctx, key)
+
+		// Assert
+		assert.ErrorIs(t, findErr, ErrAPIKeyNotFound)
+		assert.ErrorIs(t, revokeErr, ErrAPIKeyNotFound)
+		assert.Error(t, createErr)
+	})
+
+	t.Run("reloads keys written to the file by another process", func(t *testing.T) {
+		// Arrange
+		path := filepath.Join(t.TempDir(), "api-keys.json")
+		store, err := NewFileAPIKeyStore(path)
+		assert.NoError(t, err)
+		writer, err := NewFileAPIKeyStore(path)
+		assert.NoError(t, err)
+		assert.NoError(t, writer.CreateAPIKey(ctx, key))
+
+		// Act
+		err = store.Reload()
+
+		// Assert
+		assert.NoError(t, err)
+		found, err := store.FindAPIKey(ctx, key.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, key, found)
+	})
+
+	t.Run("keeps its keys when reloading a corrupt file", func(t *testing.T) {
+		// Arrange
+		path := filepath.Join(t.TempDir(), "api-keys.json")
+		store, err := NewFileAPIKeyStore(path)
+		assert.NoError(t, err)
+		assert.NoError(t, store.CreateAPIKey(ctx, key))
+		assert.NoError(t, os.WriteFile(path, []byte("not json"), 0o600))
+
+		// Act
+		err = store.Reload()
+
+		// Assert
+		assert.Error(t, err)
+		_, err = store.FindAPIKey(ctx, key.ID)
+		assert.NoError(t, err)
+	})
+
+	t.Run("fails on a corrupt file", func(t *testing.T) {
+		// Arrange
+		path := filepath.Join(t.TempDir(), "api-keys.json")
+		assert.NoError(t, os.WriteFile(path, []byte("not json"), 0o600))
+
+		// Act
+		_, err := NewFileAPIKeyStore(path)
+
+		// Assert
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,20 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"admin-statistics-api/internal/model"
+)
+
+// ErrAPIKeyNotFound is returned when no API key has the requested ID
+var ErrAPIKeyNotFound = errors.New("api key not found")
+
+// APIKeyStore defines the interface for API key storage
+type APIKeyStore interface {
+	FindAPIKey(ctx context.Context, id string) (model.APIKey, error)
+	ListAPIKeys(ctx context.Context) ([]model.APIKey, error)
+	CreateAPIKey(ctx context.Context, key model.APIKey) error
+	RevokeAPIKey(ctx context.Context, id string, at time.Time) error
+}
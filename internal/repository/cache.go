@@ -1,25 +1,72 @@
 package repository
 
 import (
+	"context"
+	"container/list"
+	"encoding/json"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
-// MemoryCache is a simple in-memory cache
+// memoryCacheCleanupInterval is how often expired items are removed from a MemoryCache
+const memoryCacheCleanupInterval = 5 * time.Minute
+
+// MemoryCache is an in-memory cache. It can be bounded by entry count and by size,
+// in which case the least recently used entries are evicted first.
 type MemoryCache struct {
-	items map[string]cacheItem
-	mu    sync.RWMutex
+	items      map[string]*list.Element
+	recency    *list.List // Most recently used at the front
+	mu         sync.Mutex
+	maxEntries int   // Zero for no limit
+	maxBytes   int64 // Zero for no limit
+	bytes      int64
+
+	hits      atomic.Uint64
+	misses    atomic.Uint64
+	evictions atomic.Uint64
+
+	stop      chan struct{}
+	closeOnce sync.Once
+}
+
+// CacheStats are the counters of a MemoryCache
+type CacheStats struct {
+	Hits      uint64 `json:"hits"`
+	Misses    uint64 `json:"misses"`
+	Evictions uint64 `json:"evictions"` // Entries removed to stay within the bounds
+	Entries   int    `json:"entries"`
+	Bytes     int64  `json:"bytes"` // Only tracked when the cache has a byte budget
 }
 
 type cacheItem struct {
+	key        string
 	value      interface{}
+	size       int64
 	expiration time.Time
+	from, to   time.Time // Range the value was computed over; zero when it has none
 }
 
-// NewMemoryCache creates a new MemoryCache
+// covers reports whether the item was computed over a range that includes t
+func (i *cacheItem) covers(t time.Time) bool {
+	return !i.from.IsZero() && !t.Before(i.from) && !t.After(i.to)
+}
+
+// NewMemoryCache creates a new MemoryCache without bounds
 func NewMemoryCache() *MemoryCache {
+	return NewBoundedMemoryCache(0, 0)
+}
+
+// NewBoundedMemoryCache creates a new MemoryCache that holds at most maxEntries entries
+// and maxBytes bytes, where zero means no limit. The size of an entry is estimated from
+// its key and JSON encoding.
+func NewBoundedMemoryCache(maxEntries int, maxBytes int64) *MemoryCache {
 	cache := &MemoryCache{
-		items: make(map[string]cacheItem),
+		items:      make(map[string]*list.Element),
+		recency:    list.New(),
+		maxEntries: maxEntries,
+		maxBytes:   maxBytes,
+		stop:       make(chan struct{}),
 	}
 
 	// Start a cleanup goroutine
@@ -29,55 +76,169 @@ func NewMemoryCache() *MemoryCache {
 }
 
 // Get retrieves a value from the cache
-func (c *MemoryCache) Get(key string) (interface{}, bool) {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
+func (c *MemoryCache) Get(ctx context.Context, key string) (interface{}, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 
-	item, found := c.items[key]
+	elem, found := c.items[key]
 	if !found {
+		c.misses.Add(1)
 		return nil, false
 	}
 
 	// Check if the item has expired
+	item := elem.Value.(*cacheItem)
 	if time.Now().After(item.expiration) {
+		c.remove(elem)
+		c.misses.Add(1)
 		return nil, false
 	}
 
+	c.recency.MoveToFront(elem)
+	c.hits.Add(1)
 	return item.value, true
 }
 
 // Set adds a value to the cache
-func (c *MemoryCache) Set(key string, value interface{}, expiration time.Duration) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
+func (c *MemoryCache) Set(ctx context.Context, key string, value interface{}, expiration time.Duration) {
+	c.add(&cacheItem{
+		key:        key,
+		value:      value,
+		expiration: time.Now().Add(expiration),
+	})
+}
 
-	c.items[key] = cacheItem{
+// SetRange adds a value to the cache that was computed over [from, to]
+func (c *MemoryCache) SetRange(ctx context.Context, key string, value interface{}, expiration time.Duration, from, to time.Time) {
+	c.add(&cacheItem{
+		key:        key,
 		value:      value,
 		expiration: time.Now().Add(expiration),
+		from:       from,
+		to:         to,
+	})
+}
+
+// InvalidateTimes removes every entry whose range covers one of times
+func (c *MemoryCache) InvalidateTimes(ctx context.Context, times ...time.Time) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	removed := 0
+	for _, elem := range c.items {
+		item := elem.Value.(*cacheItem)
+		for _, t := range times {
+			if item.covers(t) {
+				c.remove(elem)
+				removed++
+				break
+			}
+		}
 	}
+	return removed
 }
 
 // Delete removes a value from the cache
-func (c *MemoryCache) Delete(key string) {
+func (c *MemoryCache) Delete(ctx context.Context, key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, found := c.items[key]; found {
+		c.remove(elem)
+	}
+}
+
+// Stats returns the cache's counters
+func (c *MemoryCache) Stats() CacheStats {
+	c.mu.Lock()
+	entries, bytes := len(c.items), c.bytes
+	c.mu.Unlock()
+
+	return CacheStats{
+		Hits:      c.hits.Load(),
+		Misses:    c.misses.Load(),
+		Evictions: c.evictions.Load(),
+		Entries:   entries,
+		Bytes:     bytes,
+	}
+}
+
+// Close stops the cleanup goroutine. The cache can still be used afterwards, but
+// expired items are then only removed when they are read.
+func (c *MemoryCache) Close() error {
+	c.closeOnce.Do(func() {
+		close(c.stop)
+	})
+	return nil
+}
+
+// add stores item, replacing any item with the same key, and evicts the least recently
+// used items until the cache is within its bounds again
+func (c *MemoryCache) add(item *cacheItem) {
+	if c.maxBytes > 0 {
+		item.size = entrySize(item.key, item.value)
+	}
+
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	delete(c.items, key)
+	if elem, found := c.items[item.key]; found {
+		c.remove(elem)
+	}
+
+	// An item larger than the whole budget would only evict everything else
+	if c.maxBytes > 0 && item.size > c.maxBytes {
+		c.evictions.Add(1)
+		return
+	}
+
+	c.items[item.key] = c.recency.PushFront(item)
+	c.bytes += item.size
+
+	for (c.maxEntries > 0 && len(c.items) > c.maxEntries) || (c.maxBytes > 0 && c.bytes > c.maxBytes) {
+		c.remove(c.recency.Back())
+		c.evictions.Add(1)
+	}
+}
+
+// remove deletes elem from the cache; the caller must hold c.mu
+func (c *MemoryCache) remove(elem *list.Element) {
+	item := c.recency.Remove(elem).(*cacheItem)
+	delete(c.items, item.key)
+	c.bytes -= item.size
+}
+
+// entrySize estimates how many bytes an entry takes from its key and JSON encoding
+func entrySize(key string, value interface{}) int64 {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return int64(len(key))
+	}
+	return int64(len(key) + len(data))
 }
 
-// cleanup periodically removes expired items from the cache
+// cleanup periodically removes expired items from the cache until it is closed
 func (c *MemoryCache) cleanup() {
-	ticker := time.NewTicker(5 * time.Minute)
+	ticker := time.NewTicker(memoryCacheCleanupInterval)
 	defer ticker.Stop()
 
-	for range ticker.C {
+	for {
+		select {
+		case <-c.stop:
+			return
+		case <-ticker.C:
+		}
+
 		c.mu.Lock()
 		now := time.Now()
-		for key, item := range c.items {
-			if now.After(item.expiration) {
-				delete(c.items, key)
+		for _, elem := range c.items {
+			if now.After(elem.Value.(*cacheItem).expiration) {
+				c.remove(elem)
 			}
 		}
 		c.mu.Unlock()
 	}
-}
\ No newline at end of file
+}
+
+// Ensure MemoryCache implements RangeCache
+var _ RangeCache = (*MemoryCache)(nil)
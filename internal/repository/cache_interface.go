@@ -1,12 +1,42 @@
 package repository
 
 import (
+	"context"
 	"time"
 )
 
 // Cache interface for caching responses
 type Cache interface {
-	Get(key string) (interface{}, bool)
-	Set(key string, value interface{}, expiration time.Duration)
-	Delete(key string)
-}
\ No newline at end of file
+	Get(ctx context.Context, key string) (interface{}, bool)
+	Set(ctx context.Context, key string, value interface{}, expiration time.Duration)
+	Delete(ctx context.Context, key string)
+}
+
+// RangeCache is a Cache that also indexes entries by the time range they were computed
+// over, so that the entries affected by new transactions can be invalidated
+type RangeCache interface {
+	Cache
+
+	// SetRange adds a value to the cache that was computed over [from, to]
+	SetRange(ctx context.Context, key string, value interface{}, expiration time.Duration, from, to time.Time)
+
+	// InvalidateTimes removes every entry whose range covers one of times and returns how many were removed
+	InvalidateTimes(ctx context.Context, times ...time.Time) int
+}
+
+// CacheEntry is a cached value with how long it stays cached and the range it was
+// computed over
+type CacheEntry struct {
+	Value    interface{}
+	TTL      time.Duration // Time left until the entry expires; zero when it does not expire
+	From, To time.Time     // Zero when the entry was set without a range
+}
+
+// EntryCache is a Cache that can also return an entry's remaining TTL and range, so
+// that another cache can copy it without losing either
+type EntryCache interface {
+	Cache
+
+	// GetEntry retrieves a value from the cache with its TTL and range
+	GetEntry(ctx context.Context, key string) (CacheEntry, bool)
+}
@@ -0,0 +1,159 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryCache_InvalidateTimes(t *testing.T) {
+	ctx := context.Background()
+	january := [2]time.Time{time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2023, 1, 31, 0, 0, 0, 0, time.UTC)}
+	february := [2]time.Time{time.Date(2023, 2, 1, 0, 0, 0, 0, time.UTC), time.Date(2023, 2, 28, 0, 0, 0, 0, time.UTC)}
+
+	setup := func() *MemoryCache {
+		cache := NewMemoryCache()
+		cache.SetRange(ctx, "january", "jan", time.Minute, january[0], january[1])
+		cache.SetRange(ctx, "february", "feb", time.Minute, february[0], february[1])
+		cache.Set(ctx, "unranged", "value", time.Minute)
+		return cache
+	}
+
+	t.Run("removes entries whose range covers the time", func(t *testing.T) {
+		// Arrange
+		cache := setup()
+
+		// Act
+		removed := cache.InvalidateTimes(ctx, time.Date(2023, 1, 15, 12, 0, 0, 0, time.UTC))
+
+		// Assert
+		assert.Equal(t, 1, removed)
+		_, foundJanuary := cache.Get(ctx, "january")
+		_, foundFebruary := cache.Get(ctx, "february")
+		_, foundUnranged := cache.Get(ctx, "unranged")
+		assert.False(t, foundJanuary)
+		assert.True(t, foundFebruary)
+		assert.True(t, foundUnranged, "Entries without a range are never invalidated")
+	})
+
+	t.Run("includes both ends of the range", func(t *testing.T) {
+		cache := setup()
+
+		assert.Equal(t, 2, cache.InvalidateTimes(ctx, january[0], february[1]))
+	})
+
+	t.Run("keeps entries outside every time", func(t *testing.T) {
+		cache := setup()
+
+		removed := cache.InvalidateTimes(ctx, time.Date(2022, 12, 31, 0, 0, 0, 0, time.UTC), time.Date(2023, 1, 31, 0, 0, 0, 1, time.UTC))
+
+		assert.Equal(t, 0, removed)
+	})
+
+	t.Run("a plain Set clears the range", func(t *testing.T) {
+		cache := setup()
+		cache.Set(ctx, "january", "jan", time.Minute)
+
+		assert.Equal(t, 0, cache.InvalidateTimes(ctx, january[0]))
+	})
+}
+
+func TestMemoryCache_Bounds(t *testing.T) {
+	ctx := context.Background()
+	t.Run("evicts the least recently used entry past the entry limit", func(t *testing.T) {
+		// Arrange
+		cache := NewBoundedMemoryCache(2, 0)
+		defer cache.Close()
+		cache.Set(ctx, "a", 1, time.Minute)
+		cache.Set(ctx, "b", 2, time.Minute)
+
+		// Act
+		cache.Get(ctx, "a")
+		cache.Set(ctx, "c", 3, time.Minute)
+
+		// Assert
+		_, foundA := cache.Get(ctx, "a")
+		_, foundB := cache.Get(ctx, "b")
+		_, foundC := cache.Get(ctx, "c")
+		assert.True(t, foundA, "Reading an entry makes it recently used")
+		assert.False(t, foundB)
+		assert.True(t, foundC)
+		assert.Equal(t, uint64(1), cache.Stats().Evictions)
+	})
+
+	t.Run("evicts entries past the byte budget", func(t *testing.T) {
+		// Each entry takes 1 byte of key and 10 of JSON
+		cache := NewBoundedMemoryCache(0, 25)
+		defer cache.Close()
+
+		cache.Set(ctx, "a", "12345678", time.Minute)
+		cache.Set(ctx, "b", "12345678", time.Minute)
+		cache.Set(ctx, "c", "12345678", time.Minute)
+
+		stats := cache.Stats()
+		assert.Equal(t, 2, stats.Entries)
+		assert.Equal(t, int64(22), stats.Bytes)
+		_, foundA := cache.Get(ctx, "a")
+		assert.False(t, foundA)
+	})
+
+	t.Run("does not store an entry larger than the byte budget", func(t *testing.T) {
+		cache := NewBoundedMemoryCache(0, 5)
+		defer cache.Close()
+		cache.Set(ctx, "a", 1, time.Minute)
+
+		cache.Set(ctx, "b", "too large", time.Minute)
+
+		_, foundA := cache.Get(ctx, "a")
+		_, foundB := cache.Get(ctx, "b")
+		assert.True(t, foundA)
+		assert.False(t, foundB)
+	})
+
+	t.Run("replacing an entry updates its size", func(t *testing.T) {
+		cache := NewBoundedMemoryCache(0, 100)
+		defer cache.Close()
+
+		cache.Set(ctx, "a", "12345678", time.Minute)
+		cache.Set(ctx, "a", 1, time.Minute)
+
+		assert.Equal(t, int64(2), cache.Stats().Bytes)
+		assert.Equal(t, 1, cache.Stats().Entries)
+	})
+}
+
+func TestMemoryCache_Stats(t *testing.T) {
+	ctx := context.Background()
+	// Arrange
+	cache := NewMemoryCache()
+	defer cache.Close()
+	cache.Set(ctx, "a", 1, time.Minute)
+	cache.Set(ctx, "expired", 2, -time.Second)
+
+	// Act
+	cache.Get(ctx, "a")
+	cache.Get(ctx, "a")
+	cache.Get(ctx, "missing")
+	cache.Get(ctx, "expired")
+
+	// Assert
+	stats := cache.Stats()
+	assert.Equal(t, uint64(2), stats.Hits)
+	assert.Equal(t, uint64(2), stats.Misses)
+	assert.Equal(t, uint64(0), stats.Evictions, "Expired entries are not evictions")
+	assert.Equal(t, 1, stats.Entries)
+}
+
+func TestMemoryCache_Close(t *testing.T) {
+	ctx := context.Background()
+	cache := NewMemoryCache()
+
+	assert.NoError(t, cache.Close())
+	assert.NoError(t, cache.Close(), "Closing twice is allowed")
+
+	cache.Set(ctx, "a", 1, time.Minute)
+	_, found := cache.Get(ctx, "a")
+	assert.True(t, found, "A closed cache can still be used")
+}
@@ -0,0 +1,114 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"admin-statistics-api/internal/model"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// CircuitBreakerRepository wraps a repository with a CircuitBreaker, so that once MongoDB
+// keeps timing out or dropping connections, calls fail fast with a *CircuitOpenError
+type CircuitBreakerRepository struct {
+	repo    TransactionRepositoryInterface
+	breaker *CircuitBreaker
+}
+
+// NewCircuitBreakerRepository creates a CircuitBreakerRepository that opens after
+// threshold consecutive timeouts or network errors and stays open for openFor
+func NewCircuitBreakerRepository(repo TransactionRepositoryInterface, threshold int, openFor time.Duration) *CircuitBreakerRepository {
+	return &CircuitBreakerRepository{
+		repo:    repo,
+		breaker: NewCircuitBreaker("mongodb", threshold, openFor, isMongoUnavailable),
+	}
+}
+
+// Healthy reports whether calls are going through to MongoDB
+func (r *CircuitBreakerRepository) Healthy() bool {
+	return r.breaker.Healthy()
+}
+
+// isMongoUnavailable reports whether err means MongoDB could not answer, as opposed to
+// rejecting the query
+func isMongoUnavailable(err error) bool {
+	return mongo.IsTimeout(err) || mongo.IsNetworkError(err)
+}
+
+// withBreaker runs call through the breaker
+func withBreaker[T any](b *CircuitBreaker, call func() (T, error)) (T, error) {
+	var result T
+	err := b.Do(func() error {
+		var err error
+		result, err = call()
+		return err
+	})
+	return result, err
+}
+
+// CalculateGGR calls the wrapped repository unless the breaker is open
+func (r *CircuitBreakerRepository) CalculateGGR(ctx context.Context, from, to time.Time, bucket model.TimeBucket) ([]model.GGRRow, error) {
+	return withBreaker(r.breaker, func() ([]model.GGRRow, error) {
+		return r.repo.CalculateGGR(ctx, from, to, bucket)
+	})
+}
+
+// CalculateGGRSeries calls the wrapped repository unless the breaker is open
+func (r *CircuitBreakerRepository) CalculateGGRSeries(ctx context.Context, from, to time.Time, bucket model.TimeBucket) ([]model.GGRSeriesRow, error) {
+	return withBreaker(r.breaker, func() ([]model.GGRSeriesRow, error) {
+		return r.repo.CalculateGGRSeries(ctx, from, to, bucket)
+	})
+}
+
+// CalculateDailyWagerVolume calls the wrapped repository unless the breaker is open
+func (r *CircuitBreakerRepository) CalculateDailyWagerVolume(ctx context.Context, from, to time.Time, bucket model.TimeBucket) ([]model.DailyWagerRow, error) {
+	return withBreaker(r.breaker, func() ([]model.DailyWagerRow, error) {
+		return r.repo.CalculateDailyWagerVolume(ctx, from, to, bucket)
+	})
+}
+
+// CalculateUserWagerPercentile calls the wrapped repository unless the breaker is open
+func (r *CircuitBreakerRepository) CalculateUserWagerPercentile(ctx context.Context, userID string, from, to time.Time) (float64, error) {
+	return withBreaker(r.breaker, func() (float64, error) {
+		return r.repo.CalculateUserWagerPercentile(ctx, userID, from, to)
+	})
+}
+
+// CalculateUserSummary calls the wrapped repository unless the breaker is open
+func (r *CircuitBreakerRepository) CalculateUserSummary(ctx context.Context, userID string, from, to time.Time) (model.UserSummary, error) {
+	return withBreaker(r.breaker, func() (model.UserSummary, error) {
+		return r.repo.CalculateUserSummary(ctx, userID, from, to)
+	})
+}
+
+// CalculateLeaderboard calls the wrapped repository unless the breaker is open
+func (r *CircuitBreakerRepository) CalculateLeaderboard(ctx context.Context, metric, currency string, limit int, from, to time.Time) ([]model.LeaderboardRow, error) {
+	return withBreaker(r.breaker, func() ([]model.LeaderboardRow, error) {
+		return r.repo.CalculateLeaderboard(ctx, metric, currency, limit, from, to)
+	})
+}
+
+// FindRoundAnomalies calls the wrapped repository unless the breaker is open
+func (r *CircuitBreakerRepository) FindRoundAnomalies(ctx context.Context, from, to time.Time) ([]model.RoundAnomaly, error) {
+	return withBreaker(r.breaker, func() ([]model.RoundAnomaly, error) {
+		return r.repo.FindRoundAnomalies(ctx, from, to)
+	})
+}
+
+// StreamRows opens a cursor on the wrapped repository unless the breaker is open. Only
+// opening the cursor goes through the breaker, not reading the rows.
+func (r *CircuitBreakerRepository) StreamRows(ctx context.Context, reportType string, params model.ReportParams) (RowCursor, error) {
+	return withBreaker(r.breaker, func() (RowCursor, error) {
+		return r.repo.StreamRows(ctx, reportType, params)
+	})
+}
+
+// InsertTransactions calls the wrapped repository unless the breaker is open
+func (r *CircuitBreakerRepository) InsertTransactions(ctx context.Context, transactions []model.Transaction) (int, error) {
+	return withBreaker(r.breaker, func() (int, error) {
+		return r.repo.InsertTransactions(ctx, transactions)
+	})
+}
+
+// Ensure CircuitBreakerRepository implements TransactionRepositoryInterface
+var _ TransactionRepositoryInterface = (*CircuitBreakerRepository)(nil)
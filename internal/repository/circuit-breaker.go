@@ -0,0 +1,155 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is wrapped by the errors a CircuitBreaker returns instead of making a call
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// CircuitOpenError is returned instead of calling a dependency that keeps failing
+type CircuitOpenError struct {
+	Name    string        // The dependency, e.g. "mongodb"
+	OpenFor time.Duration // How long until the breaker lets a call through again
+}
+
+func (e *CircuitOpenError) Error() string {
+	return fmt.Sprintf("%s is unavailable, retry in %s", e.Name, e.OpenFor.Round(time.Second))
+}
+
+// RetryAfter returns how long until the breaker lets a call through again, which
+// clients are told to wait
+func (e *CircuitOpenError) RetryAfter() time.Duration {
+	return e.OpenFor
+}
+
+func (e *CircuitOpenError) Unwrap() error {
+	return ErrCircuitOpen
+}
+
+type circuitState int
+
+const (
+	circuitClosed   circuitState = iota // Calls go through
+	circuitOpen                         // Calls fail fast until openFor has passed
+	circuitHalfOpen                     // One trial call decides whether to close or reopen
+)
+
+// CircuitBreaker stops calling a dependency after threshold consecutive failures, so
+// callers fail fast instead of waiting on it. After openFor it lets one trial call
+// through, and closes again if that call succeeds.
+type CircuitBreaker struct {
+	name      string
+	threshold int
+	openFor   time.Duration
+	isFailure func(error) bool // Errors that count towards opening, e.g. timeouts
+	now       func() time.Time
+
+	mu       sync.Mutex
+	state    circuitState
+	failures int
+	openedAt time.Time
+	trial    bool // Whether the half-open trial call is running
+}
+
+// NewCircuitBreaker creates a CircuitBreaker for the named dependency. Only errors for
+// which isFailure returns true count as failures; other errors pass through.
+func NewCircuitBreaker(name string, threshold int, openFor time.Duration, isFailure func(error) bool) *CircuitBreaker {
+	return &CircuitBreaker{
+		name:      name,
+		threshold: threshold,
+		openFor:   openFor,
+		isFailure: isFailure,
+		now:       time.Now,
+	}
+}
+
+// Do calls fn unless the breaker is open, in which case it returns a *CircuitOpenError
+func (b *CircuitBreaker) Do(fn func() error) error {
+	if err := b.allow(); err != nil {
+		return err
+	}
+
+	err := fn()
+	b.record(err)
+	return err
+}
+
+// Healthy reports whether the breaker is closed
+func (b *CircuitBreaker) Healthy() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.state == circuitClosed
+}
+
+// allow reports whether a call may go through, moving an open breaker to half-open
+// once openFor has passed
+func (b *CircuitBreaker) allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case circuitOpen:
+		elapsed := b.now().Sub(b.openedAt)
+		if elapsed < b.openFor {
+			return b.openError(b.openFor - elapsed)
+		}
+		b.state = circuitHalfOpen
+		b.trial = true
+		return nil
+	case circuitHalfOpen:
+		if b.trial {
+			return b.openError(0)
+		}
+		b.trial = true
+		return nil
+	default:
+		return nil
+	}
+}
+
+// record updates the breaker with the outcome of a call
+func (b *CircuitBreaker) record(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	failed := err != nil && b.isFailure(err)
+
+	switch b.state {
+	case circuitHalfOpen:
+		b.trial = false
+		if failed {
+			b.open()
+		} else if err == nil {
+			b.state = circuitClosed
+			b.failures = 0
+			slog.Info("Circuit breaker closed", "name", b.name)
+		}
+	case circuitClosed:
+		if failed {
+			b.failures++
+			if b.failures >= b.threshold {
+				b.open()
+			}
+		} else if err == nil {
+			b.failures = 0
+		}
+	}
+}
+
+// open starts rejecting calls; the caller must hold b.mu
+func (b *CircuitBreaker) open() {
+	b.state = circuitOpen
+	b.openedAt = b.now()
+	slog.Warn("Circuit breaker opened", "name", b.name, "open_for", b.openFor.String(), "failures", max(b.failures, 1))
+}
+
+// openError reports when a call may be made again, at least a second from now
+func (b *CircuitBreaker) openError(retryAfter time.Duration) *CircuitOpenError {
+	return &CircuitOpenError{Name: b.name, OpenFor: max(retryAfter, time.Second)}
+}
@@ -0,0 +1,134 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"admin-statistics-api/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+var errUnavailable = errors.New("unavailable")
+
+// newTestBreaker creates a breaker that opens after two failures, with a controllable clock
+func newTestBreaker() (*CircuitBreaker, *time.Time) {
+	now := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	breaker := NewCircuitBreaker("test", 2, 10*time.Second, func(err error) bool {
+		return errors.Is(err, errUnavailable)
+	})
+	breaker.now = func() time.Time { return now }
+	return breaker, &now
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	fail := func() error { return errUnavailable }
+	succeed := func() error { return nil }
+
+	t.Run("opens after consecutive failures", func(t *testing.T) {
+		// Arrange
+		breaker, _ := newTestBreaker()
+		breaker.Do(fail)
+		breaker.Do(fail)
+
+		// Act
+		called := false
+		err := breaker.Do(func() error {
+			called = true
+			return nil
+		})
+
+		// Assert
+		assert.False(t, called)
+		var openErr *CircuitOpenError
+		assert.ErrorAs(t, err, &openErr)
+		assert.ErrorIs(t, err, ErrCircuitOpen)
+		assert.Equal(t, 10*time.Second, openErr.RetryAfter())
+		assert.False(t, breaker.Healthy())
+	})
+
+	t.Run("a success resets the failure count", func(t *testing.T) {
+		breaker, _ := newTestBreaker()
+
+		breaker.Do(fail)
+		breaker.Do(succeed)
+		breaker.Do(fail)
+
+		assert.True(t, breaker.Healthy())
+	})
+
+	t.Run("other errors do not count", func(t *testing.T) {
+		breaker, _ := newTestBreaker()
+		invalid := errors.New("invalid query")
+
+		for i := 0; i < 3; i++ {
+			assert.Equal(t, invalid, breaker.Do(func() error { return invalid }))
+		}
+
+		assert.True(t, breaker.Healthy())
+	})
+
+	t.Run("closes when the trial call succeeds", func(t *testing.T) {
+		breaker, now := newTestBreaker()
+		breaker.Do(fail)
+		breaker.Do(fail)
+		*now = now.Add(10 * time.Second)
+
+		assert.NoError(t, breaker.Do(succeed))
+
+		assert.True(t, breaker.Healthy())
+	})
+
+	t.Run("reopens when the trial call fails", func(t *testing.T) {
+		breaker, now := newTestBreaker()
+		breaker.Do(fail)
+		breaker.Do(fail)
+		*now = now.Add(10 * time.Second)
+
+		assert.Equal(t, errUnavailable, breaker.Do(fail))
+
+		assert.ErrorIs(t, breaker.Do(succeed), ErrCircuitOpen)
+	})
+
+	t.Run("rejects other calls during the trial", func(t *testing.T) {
+		breaker, now := newTestBreaker()
+		breaker.Do(fail)
+		breaker.Do(fail)
+		*now = now.Add(10 * time.Second)
+
+		var duringTrial error
+		breaker.Do(func() error {
+			duringTrial = breaker.Do(succeed)
+			return nil
+		})
+
+		var openErr *CircuitOpenError
+		assert.ErrorAs(t, duringTrial, &openErr)
+		assert.Equal(t, time.Second, openErr.RetryAfter())
+	})
+}
+
+func TestCircuitBreakerRepository(t *testing.T) {
+	// Arrange
+	mockRepo := &MockTransactionRepository{
+		CalculateGGRFn: func(ctx context.Context, from, to time.Time, bucket model.TimeBucket) ([]model.GGRRow, error) {
+			return nil, context.DeadlineExceeded
+		},
+	}
+	repo := NewCircuitBreakerRepository(mockRepo, 2, time.Minute)
+	from := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2023, 1, 31, 0, 0, 0, 0, time.UTC)
+
+	// Act
+	for i := 0; i < 3; i++ {
+		repo.CalculateGGR(context.Background(), from, to, model.TimeBucket{})
+	}
+	_, err := repo.CalculateGGRSeries(context.Background(), from, to, model.TimeBucket{})
+
+	// Assert
+	assert.Len(t, mockRepo.CalculateGGRCalls, 2, "Calls stop once the breaker opens")
+	assert.Empty(t, mockRepo.CalculateGGRSeriesCalls, "The breaker covers every method")
+	assert.ErrorIs(t, err, ErrCircuitOpen)
+	assert.False(t, repo.Healthy())
+}
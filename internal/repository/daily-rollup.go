@@ -0,0 +1,340 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"admin-statistics-api/internal/logging"
+	"admin-statistics-api/internal/model"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// day is the length of one rollup period
+const day = 24 * time.Hour
+
+// dirtyRollupsSuffix names the collection of days whose rollups need a repair, after the
+// rollup collection
+const dirtyRollupsSuffix = "_dirty"
+
+// dirtyRollupDay is a UTC day whose rollups may not match its raw transactions. Version
+// counts the inserts into the day, so that a repair only clears the mark when no insert
+// raced with it.
+type dirtyRollupDay struct {
+	Day     time.Time `bson:"_id"`
+	Version int64     `bson:"version"`
+}
+
+// WithDailyRollups keeps the named collection of model.DailyStat documents up to date on
+// every insert. When readRollups is true, GGR and wager volume queries read whole UTC days
+// from the rollups and only scan raw transactions for the partial days at either end of
+// the range. Enable reads only once BackfillDailyRollups has covered existing transactions.
+func (r *TransactionRepository) WithDailyRollups(collectionName string, readRollups bool) *TransactionRepository {
+	r.rollups = r.collection.Database().Collection(collectionName)
+	r.readRollups = readRollups
+	return r
+}
+
+// EnsureDailyRollupIndexes creates the index that rollup reads and backfills use to find
+// the rollups of a range of days
+func (r *TransactionRepository) EnsureDailyRollupIndexes(ctx context.Context) error {
+	if r.rollups == nil {
+		return errors.New("daily rollups are not configured")
+	}
+
+	_, err := r.rollups.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "day", Value: 1}, {Key: "type", Value: 1}},
+	})
+	return err
+}
+
+// BackfillDailyRollups recomputes the rollups for every UTC day touched by [from, to] from
+// the raw transactions, replacing any existing rollup for those days and removing those
+// left without transactions. Zero times leave that end of the range unbounded.
+func (r *TransactionRepository) BackfillDailyRollups(ctx context.Context, from, to time.Time) error {
+	if r.rollups == nil {
+		return errors.New("daily rollups are not configured")
+	}
+
+	// Widen the range to whole days so that no day is rolled up partially
+	createdAt := bson.M{}
+	if !from.IsZero() {
+		createdAt["$gte"] = from.UTC().Truncate(day)
+	}
+	if !to.IsZero() {
+		createdAt["$lt"] = to.UTC().Truncate(day).Add(day)
+	}
+	match := bson.M{}
+	if len(createdAt) > 0 {
+		match["createdAt"] = createdAt
+	}
+
+	// Every rollup this run writes is tagged with it, so that older ones can be removed.
+	// Object IDs start with their creation time, so a later concurrent run's are kept.
+	run := primitive.NewObjectID()
+
+	pipeline := mongo.Pipeline{
+		// Match transactions within the given days
+		{
+			{Key: "$match", Value: match},
+		},
+		// Group by day, currency and type, in the same field order as model.DailyStatKey
+		{
+			{Key: "$group", Value: bson.M{
+				"_id": bson.D{
+					{Key: "day", Value: bson.M{"$dateTrunc": bson.M{"date": "$createdAt", "unit": "day"}}},
+					{Key: "currency", Value: "$currency"},
+					{Key: "type", Value: "$type"},
+				},
+				"amount":    bson.M{"$sum": "$amount"},
+				"usdAmount": bson.M{"$sum": "$usdAmount"},
+				"count":     bson.M{"$sum": 1},
+			}},
+		},
+		// Copy the key fields to the top level for querying
+		{
+			{Key: "$addFields", Value: bson.M{
+				"day":         "$_id.day",
+				"currency":    "$_id.currency",
+				"type":        "$_id.type",
+				"backfillRun": run,
+			}},
+		},
+		// Write the rollups
+		{
+			{Key: "$merge", Value: bson.M{
+				"into":           r.rollups.Name(),
+				"on":             "_id",
+				"whenMatched":    "replace",
+				"whenNotMatched": "insert",
+			}},
+		},
+	}
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return err
+	}
+	if err := cursor.Close(ctx); err != nil {
+		return err
+	}
+
+	// Remove the rollups of days, currencies and types that no longer have transactions
+	stale := bson.M{"$or": bson.A{
+		bson.M{"backfillRun": bson.M{"$lt": run}},
+		bson.M{"backfillRun": bson.M{"$exists": false}},
+	}}
+	if len(createdAt) > 0 {
+		stale["day"] = createdAt
+	}
+	_, err = r.rollups.DeleteMany(ctx, stale)
+	return err
+}
+
+// RepairDailyRollups backfills every day marked dirty after an insert and returns how many
+// days it repaired. A day that receives another insert meanwhile stays marked.
+func (r *TransactionRepository) RepairDailyRollups(ctx context.Context) (int, error) {
+	if r.rollups == nil {
+		return 0, errors.New("daily rollups are not configured")
+	}
+
+	cursor, err := r.dirtyRollups().Find(ctx, bson.M{})
+	if err != nil {
+		return 0, err
+	}
+	var days []dirtyRollupDay
+	if err := cursor.All(ctx, &days); err != nil {
+		return 0, err
+	}
+
+	repaired := 0
+	for _, dirty := range days {
+		if err := r.BackfillDailyRollups(ctx, dirty.Day, dirty.Day); err != nil {
+			return repaired, err
+		}
+		if _, err := r.dirtyRollups().DeleteOne(ctx, bson.M{"_id": dirty.Day, "version": dirty.Version}); err != nil {
+			return repaired, err
+		}
+		repaired++
+	}
+	return repaired, nil
+}
+
+// RunRollupRepair repairs the days marked dirty every interval until ctx is cancelled
+func (r *TransactionRepository) RunRollupRepair(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			repaired, err := r.RepairDailyRollups(ctx)
+			if err != nil && ctx.Err() == nil {
+				logging.FromContext(ctx).Warn("Failed to repair daily rollups", "error", err)
+			}
+			if repaired > 0 {
+				logging.FromContext(ctx).Debug("Repaired daily rollups", "days", repaired)
+			}
+		}
+	}
+}
+
+// closedRollupDays returns the transactions created before the current UTC day, whose
+// days a backfill may be recomputing while they are added to the rollups
+func closedRollupDays(transactions []model.Transaction, now time.Time) []model.Transaction {
+	today := now.UTC().Truncate(day)
+	closed := make([]model.Transaction, 0)
+	for _, transaction := range transactions {
+		if transaction.CreatedAt.Before(today) {
+			closed = append(closed, transaction)
+		}
+	}
+	return closed
+}
+
+// markRollupsDirty marks the days of transactions for RepairDailyRollups
+func (r *TransactionRepository) markRollupsDirty(ctx context.Context, transactions []model.Transaction) error {
+	if r.rollups == nil || len(transactions) == 0 {
+		return nil
+	}
+
+	days := make(map[time.Time]struct{})
+	models := make([]mongo.WriteModel, 0, 1)
+	for _, transaction := range transactions {
+		d := transaction.CreatedAt.UTC().Truncate(day)
+		if _, ok := days[d]; ok {
+			continue
+		}
+		days[d] = struct{}{}
+		models = append(models, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"_id": d}).
+			SetUpdate(bson.M{"$inc": bson.M{"version": 1}}).
+			SetUpsert(true))
+	}
+
+	_, err := r.dirtyRollups().BulkWrite(ctx, models, options.BulkWrite().SetOrdered(false))
+	return err
+}
+
+// dirtyRollups returns the collection of days marked for RepairDailyRollups
+func (r *TransactionRepository) dirtyRollups() *mongo.Collection {
+	return r.rollups.Database().Collection(r.rollups.Name() + dirtyRollupsSuffix)
+}
+
+// addToDailyRollups adds newly inserted transactions to their daily rollups
+func (r *TransactionRepository) addToDailyRollups(ctx context.Context, transactions []model.Transaction) error {
+	if r.rollups == nil || len(transactions) == 0 {
+		return nil
+	}
+
+	models := make([]mongo.WriteModel, len(transactions))
+	for i, transaction := range transactions {
+		key := model.DailyStatKey{
+			Day:      transaction.CreatedAt.UTC().Truncate(day),
+			Currency: transaction.Currency,
+			Type:     transaction.Type,
+		}
+		models[i] = mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"_id": key}).
+			SetUpdate(bson.M{
+				"$setOnInsert": bson.M{
+					"day":      key.Day,
+					"currency": key.Currency,
+					"type":     key.Type,
+				},
+				"$inc": bson.M{
+					"amount":    transaction.Amount,
+					"usdAmount": transaction.USDAmount,
+					"count":     1,
+				},
+			}).
+			SetUpsert(true)
+	}
+
+	_, err := r.rollups.BulkWrite(ctx, models, options.BulkWrite().SetOrdered(false))
+	return err
+}
+
+// transactionSource returns the collection to aggregate and the leading stages that produce
+// the transactions in [from, to] matching filter. When rollups can serve the bucket, whole
+// days are read from the rollups, shaped like transactions with createdAt at midnight, and
+// only the partial days at either end are read from raw transactions.
+func (r *TransactionRepository) transactionSource(from, to time.Time, bucket model.TimeBucket, filter bson.M) (*mongo.Collection, mongo.Pipeline) {
+	match := func(createdAt bson.M) bson.M {
+		m := bson.M{"createdAt": createdAt}
+		for key, value := range filter {
+			m[key] = value
+		}
+		return m
+	}
+
+	firstDay, endDay, ok := closedDays(from, to)
+	if !ok || !r.canReadRollups(bucket) {
+		return r.collection, mongo.Pipeline{
+			{{Key: "$match", Value: match(bson.M{"$gte": from, "$lte": to})}},
+		}
+	}
+
+	rollupMatch := bson.M{"day": bson.M{"$gte": firstDay, "$lt": endDay}}
+	for key, value := range filter {
+		rollupMatch[key] = value
+	}
+	rollupStages := mongo.Pipeline{
+		{{Key: "$match", Value: rollupMatch}},
+		{{Key: "$project", Value: bson.M{
+			"createdAt": "$day",
+			"currency":  1,
+			"type":      1,
+			"amount":    1,
+			"usdAmount": 1,
+			"_id":       0,
+		}}},
+	}
+
+	// Partial days at either end of the range
+	edges := bson.A{}
+	if from.Before(firstDay) {
+		edges = append(edges, match(bson.M{"$gte": from, "$lt": firstDay}))
+	}
+	if !endDay.After(to) {
+		edges = append(edges, match(bson.M{"$gte": endDay, "$lte": to}))
+	}
+	if len(edges) == 0 {
+		return r.rollups, rollupStages
+	}
+
+	return r.collection, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"$or": edges}}},
+		{{Key: "$unionWith", Value: bson.M{"coll": r.rollups.Name(), "pipeline": rollupStages}}},
+	}
+}
+
+// canReadRollups reports whether a bucket can be built from whole UTC days
+func (r *TransactionRepository) canReadRollups(bucket model.TimeBucket) bool {
+	if r.rollups == nil || !r.readRollups {
+		return false
+	}
+	return bucket.IsZero() || (bucket.Granularity != model.GranularityHour && bucket.Location() == "UTC")
+}
+
+// closedDays returns the UTC days [firstDay, endDay) that lie entirely within [from, to],
+// or false if there are none. MongoDB stores dates with millisecond precision, so the
+// bounds are truncated to milliseconds the same way the driver encodes them.
+func closedDays(from, to time.Time) (firstDay, endDay time.Time, ok bool) {
+	from = from.UTC().Truncate(time.Millisecond)
+	to = to.UTC().Truncate(time.Millisecond)
+
+	firstDay = from.Truncate(day)
+	if firstDay.Before(from) {
+		firstDay = firstDay.Add(day)
+	}
+	// The last day is closed when its final millisecond is within the range
+	endDay = to.Add(time.Millisecond).Truncate(day)
+
+	return firstDay, endDay, firstDay.Before(endDay)
+}
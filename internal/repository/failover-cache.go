@@ -0,0 +1,183 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"admin-statistics-api/internal/logging"
+)
+
+const (
+	// failoverPingTimeout bounds each check of the primary cache
+	failoverPingTimeout = 1 * time.Second
+
+	// maxMissedInvalidations bounds the invalidations kept for the primary cache while it
+	// is unreachable
+	maxMissedInvalidations = 100000
+)
+
+// FailoverCache uses a primary cache such as Redis while it is reachable, and a fallback
+// cache such as a MemoryCache otherwise. The primary is pinged in the background, so
+// requests do not wait on an unreachable server, and used again once it recovers.
+//
+// Invalidations made while the primary is unreachable are applied to it when it comes
+// back, so it does not serve results that miss transactions ingested meanwhile.
+type FailoverCache struct {
+	primary  Cache
+	fallback Cache
+	ping     func(ctx context.Context) error
+	healthy  atomic.Bool
+
+	mu     sync.Mutex // Guards missed and changes of healthy, so no invalidation is lost between them
+	missed []time.Time
+
+	stop      chan struct{}
+	closeOnce sync.Once
+}
+
+// NewFailoverCache creates a FailoverCache that checks the primary with ping every interval
+func NewFailoverCache(primary, fallback Cache, ping func(ctx context.Context) error, interval time.Duration) *FailoverCache {
+	cache := &FailoverCache{
+		primary:  primary,
+		fallback: fallback,
+		ping:     ping,
+		stop:     make(chan struct{}),
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), failoverPingTimeout)
+	defer cancel()
+	if err := ping(ctx); err != nil {
+		logging.FromContext(ctx).Warn("Cache unreachable, using the fallback cache until it recovers", "error", err)
+	} else {
+		cache.healthy.Store(true)
+	}
+
+	go cache.monitor(interval)
+
+	return cache
+}
+
+// Healthy reports whether the primary cache is in use
+func (c *FailoverCache) Healthy() bool {
+	return c.healthy.Load()
+}
+
+// Get retrieves a value from the cache in use
+func (c *FailoverCache) Get(ctx context.Context, key string) (interface{}, bool) {
+	return c.current().Get(ctx, key)
+}
+
+// Set adds a value to the cache in use
+func (c *FailoverCache) Set(ctx context.Context, key string, value interface{}, expiration time.Duration) {
+	c.current().Set(ctx, key, value, expiration)
+}
+
+// SetRange adds a value to the cache in use that was computed over [from, to]
+func (c *FailoverCache) SetRange(ctx context.Context, key string, value interface{}, expiration time.Duration, from, to time.Time) {
+	current := c.current()
+	if rangeCache, ok := current.(RangeCache); ok {
+		rangeCache.SetRange(ctx, key, value, expiration, from, to)
+	} else {
+		current.Set(ctx, key, value, expiration)
+	}
+}
+
+// InvalidateTimes removes every entry whose range covers one of times from both caches,
+// or from the fallback only while the primary is unreachable
+func (c *FailoverCache) InvalidateTimes(ctx context.Context, times ...time.Time) int {
+	removed := 0
+	if rangeCache, ok := c.fallback.(RangeCache); ok {
+		removed += rangeCache.InvalidateTimes(ctx, times...)
+	}
+
+	rangeCache, ok := c.primary.(RangeCache)
+	if !ok {
+		return removed
+	}
+
+	c.mu.Lock()
+	if !c.healthy.Load() {
+		if len(c.missed)+len(times) > maxMissedInvalidations {
+			logging.FromContext(ctx).Warn("Too many invalidations while the cache is unreachable; some cached results may miss new transactions until they expire")
+		} else {
+			c.missed = append(c.missed, times...)
+		}
+		c.mu.Unlock()
+		return removed
+	}
+	c.mu.Unlock()
+
+	return removed + rangeCache.InvalidateTimes(ctx, times...)
+}
+
+// Delete removes a value from both caches
+func (c *FailoverCache) Delete(ctx context.Context, key string) {
+	if c.healthy.Load() {
+		c.primary.Delete(ctx, key)
+	}
+	c.fallback.Delete(ctx, key)
+}
+
+// Close stops checking the primary cache
+func (c *FailoverCache) Close() error {
+	c.closeOnce.Do(func() {
+		close(c.stop)
+	})
+	return nil
+}
+
+// current returns the cache to use
+func (c *FailoverCache) current() Cache {
+	if c.healthy.Load() {
+		return c.primary
+	}
+	return c.fallback
+}
+
+// monitor pings the primary cache every interval until the cache is closed
+func (c *FailoverCache) monitor(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.stop:
+			return
+		case <-ticker.C:
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), failoverPingTimeout)
+		err := c.ping(ctx)
+		cancel()
+
+		switch {
+		case err != nil && c.healthy.Load():
+			c.mu.Lock()
+			c.healthy.Store(false)
+			c.mu.Unlock()
+			logging.FromContext(ctx).Warn("Cache unreachable, using the fallback cache until it recovers", "error", err)
+		case err == nil && !c.healthy.Load():
+			c.recover(context.Background())
+			logging.FromContext(ctx).Info("Cache reachable again")
+		}
+	}
+}
+
+// recover switches back to the primary cache and applies the invalidations it missed
+func (c *FailoverCache) recover(ctx context.Context) {
+	c.mu.Lock()
+	c.healthy.Store(true)
+	missed := c.missed
+	c.missed = nil
+	c.mu.Unlock()
+
+	if len(missed) > 0 {
+		removed := c.primary.(RangeCache).InvalidateTimes(ctx, missed...)
+		logging.FromContext(ctx).Info("Invalidated cached results for transactions ingested while the cache was unreachable", "removed", removed, "transactions", len(missed))
+	}
+}
+
+// Ensure FailoverCache implements RangeCache
+var _ RangeCache = (*FailoverCache)(nil)
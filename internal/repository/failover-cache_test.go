@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFailoverCache(t *testing.T) {
+	ctx := context.Background()
+	// setup creates a cache whose primary is reachable while down is false
+	setup := func(t *testing.T, down bool) (*FailoverCache, *MemoryCache, *MemoryCache, *atomic.Bool) {
+		primary, fallback := NewMemoryCache(), NewMemoryCache()
+		unreachable := &atomic.Bool{}
+		unreachable.Store(down)
+		ping := func(ctx context.Context) error {
+			if unreachable.Load() {
+				return errors.New("connection refused")
+			}
+			return nil
+		}
+
+		cache := NewFailoverCache(primary, fallback, ping, 5*time.Millisecond)
+		t.Cleanup(func() {
+			cache.Close()
+			primary.Close()
+			fallback.Close()
+		})
+		return cache, primary, fallback, unreachable
+	}
+
+	t.Run("uses the primary while it is reachable", func(t *testing.T) {
+		// Arrange
+		cache, primary, fallback, _ := setup(t, false)
+
+		// Act
+		cache.Set(ctx, "key", "value", time.Minute)
+
+		// Assert
+		assert.True(t, cache.Healthy())
+		_, inPrimary := primary.Get(ctx, "key")
+		_, inFallback := fallback.Get(ctx, "key")
+		assert.True(t, inPrimary)
+		assert.False(t, inFallback)
+	})
+
+	t.Run("starts on the fallback when the primary is unreachable", func(t *testing.T) {
+		cache, primary, fallback, _ := setup(t, true)
+
+		cache.Set(ctx, "key", "value", time.Minute)
+
+		assert.False(t, cache.Healthy())
+		_, inPrimary := primary.Get(ctx, "key")
+		_, inFallback := fallback.Get(ctx, "key")
+		assert.False(t, inPrimary)
+		assert.True(t, inFallback)
+	})
+
+	t.Run("switches over and back as the primary goes down and recovers", func(t *testing.T) {
+		cache, _, _, unreachable := setup(t, false)
+
+		unreachable.Store(true)
+		assert.Eventually(t, func() bool { return !cache.Healthy() }, time.Second, time.Millisecond)
+
+		unreachable.Store(false)
+		assert.Eventually(t, cache.Healthy, time.Second, time.Millisecond)
+	})
+
+	t.Run("applies invalidations the primary missed once it recovers", func(t *testing.T) {
+		cache, primary, _, unreachable := setup(t, false)
+		from := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+		to := time.Date(2023, 1, 31, 0, 0, 0, 0, time.UTC)
+		cache.SetRange(ctx, "ggr:january", "jan", time.Minute, from, to)
+		unreachable.Store(true)
+		assert.Eventually(t, func() bool { return !cache.Healthy() }, time.Second, time.Millisecond)
+
+		cache.InvalidateTimes(ctx, time.Date(2023, 1, 15, 0, 0, 0, 0, time.UTC))
+		_, stillCached := primary.Get(ctx, "ggr:january")
+		assert.True(t, stillCached, "The primary is not written while unreachable")
+
+		unreachable.Store(false)
+		assert.Eventually(t, cache.Healthy, time.Second, time.Millisecond)
+		_, found := cache.Get(ctx, "ggr:january")
+		assert.False(t, found)
+	})
+}
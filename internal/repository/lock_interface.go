@@ -0,0 +1,13 @@
+package repository
+
+import (
+	"context"
+	"time"
+)
+
+// Locker hands out short lived locks shared between API instances
+type Locker interface {
+	// TryLock takes the lock for key if nobody holds it, without waiting. The lock is
+	// released by calling unlock, or when ttl passes if the holder never does.
+	TryLock(ctx context.Context, key string, ttl time.Duration) (unlock func(), acquired bool, err error)
+}
@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"admin-statistics-api/internal/metrics"
+)
+
+// MetricsCache wraps a cache and counts its hits and misses by key prefix, such as
+// "ggr" for "ggr:2023-01-01T00:00:00Z:..."
+type MetricsCache struct {
+	cache   Cache
+	metrics *metrics.Metrics
+}
+
+// NewMetricsCache creates a MetricsCache around cache
+func NewMetricsCache(cache Cache, m *metrics.Metrics) *MetricsCache {
+	return &MetricsCache{
+		cache:   cache,
+		metrics: m,
+	}
+}
+
+// cacheKeyPrefix returns the part of key before the first colon
+func cacheKeyPrefix(key string) string {
+	prefix, _, _ := strings.Cut(key, ":")
+	return prefix
+}
+
+// RecordError counts a failed cache operation. It can be passed to
+// RedisCache.WithErrorHandler.
+func (c *MetricsCache) RecordError(operation, key string, err error) {
+	c.metrics.CacheErrors.WithLabelValues(cacheKeyPrefix(key), operation).Inc()
+}
+
+// Get retrieves a value from the wrapped cache and counts the hit or miss
+func (c *MetricsCache) Get(ctx context.Context, key string) (interface{}, bool) {
+	value, found := c.cache.Get(ctx, key)
+	if found {
+		c.metrics.CacheHits.WithLabelValues(cacheKeyPrefix(key)).Inc()
+	} else {
+		c.metrics.CacheMisses.WithLabelValues(cacheKeyPrefix(key)).Inc()
+	}
+	return value, found
+}
+
+// Set adds a value to the wrapped cache
+func (c *MetricsCache) Set(ctx context.Context, key string, value interface{}, expiration time.Duration) {
+	c.cache.Set(ctx, key, value, expiration)
+}
+
+// SetRange adds a value to the wrapped cache that was computed over [from, to]
+func (c *MetricsCache) SetRange(ctx context.Context, key string, value interface{}, expiration time.Duration, from, to time.Time) {
+	if rangeCache, ok := c.cache.(RangeCache); ok {
+		rangeCache.SetRange(ctx, key, value, expiration, from, to)
+	} else {
+		c.cache.Set(ctx, key, value, expiration)
+	}
+}
+
+// InvalidateTimes removes every entry whose range covers one of times from the wrapped cache
+func (c *MetricsCache) InvalidateTimes(ctx context.Context, times ...time.Time) int {
+	if rangeCache, ok := c.cache.(RangeCache); ok {
+		return rangeCache.InvalidateTimes(ctx, times...)
+	}
+	return 0
+}
+
+// Delete removes a value from the wrapped cache
+func (c *MetricsCache) Delete(ctx context.Context, key string) {
+	c.cache.Delete(ctx, key)
+}
+
+// Ensure MetricsCache implements RangeCache
+var _ RangeCache = (*MetricsCache)(nil)
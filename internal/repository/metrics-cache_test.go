@@ -0,0 +1,63 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"admin-statistics-api/internal/metrics"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestMetricsCache(t *testing.T) {
+	ctx := context.Background()
+	// Arrange
+	m := metrics.New()
+	memoryCache := NewMemoryCache()
+	defer memoryCache.Close()
+	cache := NewMetricsCache(memoryCache, m)
+	cache.Set(ctx, "ggr:2023-01-01:2023-01-31", "value", time.Minute)
+
+	// Act
+	cache.Get(ctx, "ggr:2023-01-01:2023-01-31")
+	cache.Get(ctx, "ggr:2023-02-01:2023-02-28")
+	cache.Get(ctx, "percentile:user:2023-01-01:2023-01-31")
+
+	// Assert
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.CacheHits.WithLabelValues("ggr")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.CacheMisses.WithLabelValues("ggr")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.CacheMisses.WithLabelValues("percentile")))
+}
+
+func TestMetricsCache_CountsRedisErrors(t *testing.T) {
+	ctx := context.Background()
+	// Arrange
+	s, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("Failed to start miniredis: %v", err)
+	}
+	defer s.Close()
+
+	redisCache, err := NewRedisCache("redis://" + s.Addr())
+	assert.NoError(t, err)
+	defer redisCache.Close()
+
+	m := metrics.New()
+	cache := NewMetricsCache(redisCache, m)
+	redisCache.WithErrorHandler(cache.RecordError)
+
+	// Act
+	cache.Get(ctx, "ggr:missing")
+	s.SetError("server unavailable")
+	cache.Get(ctx, "ggr:key")
+	cache.Set(ctx, "daily_wager:key", "value", time.Minute)
+	cache.InvalidateTimes(ctx, time.Now())
+
+	// Assert
+	assert.Equal(t, 2.0, testutil.ToFloat64(m.CacheMisses.WithLabelValues("ggr")), "Failed lookups are also misses")
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.CacheErrors.WithLabelValues("ggr", "get")), "A missing key is not an error")
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.CacheErrors.WithLabelValues("daily_wager", "set")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.CacheErrors.WithLabelValues("invalidate", "invalidate")), "Invalidation spans many keys")
+}
@@ -0,0 +1,108 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"admin-statistics-api/internal/metrics"
+	"admin-statistics-api/internal/model"
+)
+
+// MetricsRepository wraps a repository and records how long each method's MongoDB
+// queries take and how many are running
+type MetricsRepository struct {
+	repo    TransactionRepositoryInterface
+	metrics *metrics.Metrics
+}
+
+// NewMetricsRepository creates a MetricsRepository around repo
+func NewMetricsRepository(repo TransactionRepositoryInterface, m *metrics.Metrics) *MetricsRepository {
+	return &MetricsRepository{
+		repo:    repo,
+		metrics: m,
+	}
+}
+
+// observe runs call and records its duration under method
+func observe[T any](m *metrics.Metrics, method string, call func() (T, error)) (T, error) {
+	inFlight := m.MongoInFlight.WithLabelValues(method)
+	inFlight.Inc()
+	defer inFlight.Dec()
+
+	start := time.Now()
+	result, err := call()
+
+	status := "ok"
+	if err != nil {
+		status = "error"
+	}
+	m.MongoDuration.WithLabelValues(method, status).Observe(time.Since(start).Seconds())
+
+	return result, err
+}
+
+// CalculateGGR calls the wrapped repository and records its duration
+func (r *MetricsRepository) CalculateGGR(ctx context.Context, from, to time.Time, bucket model.TimeBucket) ([]model.GGRRow, error) {
+	return observe(r.metrics, "CalculateGGR", func() ([]model.GGRRow, error) {
+		return r.repo.CalculateGGR(ctx, from, to, bucket)
+	})
+}
+
+// CalculateGGRSeries calls the wrapped repository and records its duration
+func (r *MetricsRepository) CalculateGGRSeries(ctx context.Context, from, to time.Time, bucket model.TimeBucket) ([]model.GGRSeriesRow, error) {
+	return observe(r.metrics, "CalculateGGRSeries", func() ([]model.GGRSeriesRow, error) {
+		return r.repo.CalculateGGRSeries(ctx, from, to, bucket)
+	})
+}
+
+// CalculateDailyWagerVolume calls the wrapped repository and records its duration
+func (r *MetricsRepository) CalculateDailyWagerVolume(ctx context.Context, from, to time.Time, bucket model.TimeBucket) ([]model.DailyWagerRow, error) {
+	return observe(r.metrics, "CalculateDailyWagerVolume", func() ([]model.DailyWagerRow, error) {
+		return r.repo.CalculateDailyWagerVolume(ctx, from, to, bucket)
+	})
+}
+
+// CalculateUserWagerPercentile calls the wrapped repository and records its duration
+func (r *MetricsRepository) CalculateUserWagerPercentile(ctx context.Context, userID string, from, to time.Time) (float64, error) {
+	return observe(r.metrics, "CalculateUserWagerPercentile", func() (float64, error) {
+		return r.repo.CalculateUserWagerPercentile(ctx, userID, from, to)
+	})
+}
+
+// CalculateUserSummary calls the wrapped repository and records its duration
+func (r *MetricsRepository) CalculateUserSummary(ctx context.Context, userID string, from, to time.Time) (model.UserSummary, error) {
+	return observe(r.metrics, "CalculateUserSummary", func() (model.UserSummary, error) {
+		return r.repo.CalculateUserSummary(ctx, userID, from, to)
+	})
+}
+
+// CalculateLeaderboard calls the wrapped repository and records its duration
+func (r *MetricsRepository) CalculateLeaderboard(ctx context.Context, metric, currency string, limit int, from, to time.Time) ([]model.LeaderboardRow, error) {
+	return observe(r.metrics, "CalculateLeaderboard", func() ([]model.LeaderboardRow, error) {
+		return r.repo.CalculateLeaderboard(ctx, metric, currency, limit, from, to)
+	})
+}
+
+// FindRoundAnomalies calls the wrapped repository and records its duration
+func (r *MetricsRepository) FindRoundAnomalies(ctx context.Context, from, to time.Time) ([]model.RoundAnomaly, error) {
+	return observe(r.metrics, "FindRoundAnomalies", func() ([]model.RoundAnomaly, error) {
+		return r.repo.FindRoundAnomalies(ctx, from, to)
+	})
+}
+
+// StreamRows calls the wrapped repository and records how long opening the cursor takes
+func (r *MetricsRepository) StreamRows(ctx context.Context, reportType string, params model.ReportParams) (RowCursor, error) {
+	return observe(r.metrics, "StreamRows", func() (RowCursor, error) {
+		return r.repo.StreamRows(ctx, reportType, params)
+	})
+}
+
+// InsertTransactions calls the wrapped repository and records its duration
+func (r *MetricsRepository) InsertTransactions(ctx context.Context, transactions []model.Transaction) (int, error) {
+	return observe(r.metrics, "InsertTransactions", func() (int, error) {
+		return r.repo.InsertTransactions(ctx, transactions)
+	})
+}
+
+// Ensure MetricsRepository implements TransactionRepositoryInterface
+var _ TransactionRepositoryInterface = (*MetricsRepository)(nil)
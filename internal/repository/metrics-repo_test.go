@@ -0,0 +1,51 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"admin-statistics-api/internal/metrics"
+	"admin-statistics-api/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func TestMetricsRepository(t *testing.T) {
+	// Arrange
+	m := metrics.New()
+	var inFlight float64
+	mockRepo := &MockTransactionRepository{
+		CalculateGGRFn: func(ctx context.Context, from, to time.Time, bucket model.TimeBucket) ([]model.GGRRow, error) {
+			inFlight = testutil.ToFloat64(m.MongoInFlight.WithLabelValues("CalculateGGR"))
+			return nil, nil
+		},
+		CalculateUserWagerPercentileFn: func(ctx context.Context, userID string, from, to time.Time) (float64, error) {
+			return 0, errors.New("aggregation failed")
+		},
+	}
+	repo := NewMetricsRepository(mockRepo, m)
+	from := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2023, 1, 31, 0, 0, 0, 0, time.UTC)
+
+	// Act
+	repo.CalculateGGR(context.Background(), from, to, model.TimeBucket{})
+	_, err := repo.CalculateUserWagerPercentile(context.Background(), "user", from, to)
+
+	// Assert
+	assert.EqualError(t, err, "aggregation failed")
+	assert.Equal(t, uint64(1), histogramCount(t, m.MongoDuration, "CalculateGGR", "ok"))
+	assert.Equal(t, uint64(1), histogramCount(t, m.MongoDuration, "CalculateUserWagerPercentile", "error"))
+	assert.Equal(t, 1.0, inFlight)
+	assert.Equal(t, 0.0, testutil.ToFloat64(m.MongoInFlight.WithLabelValues("CalculateGGR")))
+}
+
+// histogramCount returns how many values the series of h for labels has recorded
+func histogramCount(t *testing.T, h *prometheus.HistogramVec, labels ...string) uint64 {
+	var metric dto.Metric
+	assert.NoError(t, h.WithLabelValues(labels...).(prometheus.Metric).Write(&metric))
+	return metric.GetHistogram().GetSampleCount()
+}
@@ -0,0 +1,106 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"admin-statistics-api/internal/model"
+)
+
+// MockAPIKeyStore is an in-memory implementation of the APIKeyStore interface for testing
+type MockAPIKeyStore struct {
+	Keys map[string]model.APIKey
+	mu   sync.RWMutex
+
+	// Err, when set, is returned by every method
+	Err error
+
+	// Track function calls
+	FindAPIKeyCalls   []string
+	CreateAPIKeyCalls []model.APIKey
+	RevokeAPIKeyCalls []struct {
+		ID string
+		At time.Time
+	}
+}
+
+// NewMockAPIKeyStore creates a new MockAPIKeyStore holding the given keys
+func NewMockAPIKeyStore(keys ...model.APIKey) *MockAPIKeyStore {
+	store := &MockAPIKeyStore{Keys: make(map[string]model.APIKey)}
+	for _, key := range keys {
+		store.Keys[key.ID] = key
+	}
+	return store
+}
+
+// FindAPIKey mocks the FindAPIKey method
+func (s *MockAPIKeyStore) FindAPIKey(ctx context.Context, id string) (model.APIKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.FindAPIKeyCalls = append(s.FindAPIKeyCalls, id)
+	if s.Err != nil {
+		return model.APIKey{}, s.Err
+	}
+
+	key, ok := s.Keys[id]
+	if !ok {
+		return model.APIKey{}, ErrAPIKeyNotFound
+	}
+	return key, nil
+}
+
+// ListAPIKeys mocks the ListAPIKeys method
+func (s *MockAPIKeyStore) ListAPIKeys(ctx context.Context) ([]model.APIKey, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.Err != nil {
+		return nil, s.Err
+	}
+
+	keys := make([]model.APIKey, 0, len(s.Keys))
+	for _, key := range s.Keys {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].ID < keys[j].ID })
+	return keys, nil
+}
+
+// CreateAPIKey mocks the CreateAPIKey method
+func (s *MockAPIKeyStore) CreateAPIKey(ctx context.Context, key model.APIKey) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.CreateAPIKeyCalls = append(s.CreateAPIKeyCalls, key)
+	if s.Err != nil {
+		return s.Err
+	}
+
+	s.Keys[key.ID] = key
+	return nil
+}
+
+// RevokeAPIKey mocks the RevokeAPIKey method
+func (s *MockAPIKeyStore) RevokeAPIKey(ctx context.Context, id string, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.RevokeAPIKeyCalls = append(s.RevokeAPIKeyCalls, struct {
+		ID string
+		At time.Time
+	}{id, at})
+	if s.Err != nil {
+		return s.Err
+	}
+
+	key, ok := s.Keys[id]
+	if !ok {
+		return ErrAPIKeyNotFound
+	}
+	key.RevokedAt = &at
+	s.Keys[id] = key
+	return nil
+}
@@ -1,6 +1,7 @@
 package repository
 
 import (
+	"context"
 	"sync"
 	"time"
 )
@@ -14,6 +15,8 @@ type MockCache struct {
 	DeleteCalls      []string
 	GetShouldFail    bool
 	GetCustomResults map[string]interface{}
+	Ranges           map[string][2]time.Time // Range of each entry set with SetRange
+	InvalidateCalls  [][]time.Time
 }
 
 // NewMockCache creates a new MockCache
@@ -22,11 +25,12 @@ func NewMockCache() *MockCache {
 		items:            make(map[string]interface{}),
 		SetCalls:         make(map[string]interface{}),
 		GetCustomResults: make(map[string]interface{}),
+		Ranges:           make(map[string][2]time.Time),
 	}
 }
 
 // Get retrieves a value from the cache
-func (c *MockCache) Get(key string) (interface{}, bool) {
+func (c *MockCache) Get(ctx context.Context, key string) (interface{}, bool) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
@@ -47,7 +51,7 @@ func (c *MockCache) Get(key string) (interface{}, bool) {
 }
 
 // Set adds a value to the cache
-func (c *MockCache) Set(key string, value interface{}, expiration time.Duration) {
+func (c *MockCache) Set(ctx context.Context, key string, value interface{}, expiration time.Duration) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
@@ -56,10 +60,44 @@ func (c *MockCache) Set(key string, value interface{}, expiration time.Duration)
 }
 
 // Delete removes a value from the cache
-func (c *MockCache) Delete(key string) {
+func (c *MockCache) Delete(ctx context.Context, key string) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
 	c.DeleteCalls = append(c.DeleteCalls, key)
 	delete(c.items, key)
-}
\ No newline at end of file
+}
+
+// SetRange adds a value to the cache and records its range
+func (c *MockCache) SetRange(ctx context.Context, key string, value interface{}, expiration time.Duration, from, to time.Time) {
+	c.Set(ctx, key, value, expiration)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.Ranges[key] = [2]time.Time{from, to}
+}
+
+// InvalidateTimes removes every entry whose recorded range covers one of times
+func (c *MockCache) InvalidateTimes(ctx context.Context, times ...time.Time) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.InvalidateCalls = append(c.InvalidateCalls, times)
+
+	removed := 0
+	for key, r := range c.Ranges {
+		for _, t := range times {
+			if !t.Before(r[0]) && !t.After(r[1]) {
+				delete(c.items, key)
+				delete(c.Ranges, key)
+				removed++
+				break
+			}
+		}
+	}
+	return removed
+}
+
+// Ensure MockCache implements RangeCache
+var _ RangeCache = (*MockCache)(nil)
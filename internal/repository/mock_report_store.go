@@ -0,0 +1,109 @@
+package repository
+
+import (
+	"context"
+	"slices"
+	"sync"
+
+	"admin-statistics-api/internal/model"
+)
+
+// MockReportStore is an in-memory implementation of the ReportStore interface for testing
+type MockReportStore struct {
+	Reports map[string]model.ReportJob
+	mu      sync.Mutex
+
+	// Err, when set, is returned by every method
+	Err error
+	// UpdateReportFn, when set, is called with each job to update and its error returned
+	// in place of storing the job
+	UpdateReportFn func(job model.ReportJob) error
+
+	// Track function calls
+	CreateReportCalls []model.ReportJob
+	DeleteReportCalls []string
+}
+
+// NewMockReportStore creates a new MockReportStore
+func NewMockReportStore() *MockReportStore {
+	return &MockReportStore{Reports: make(map[string]model.ReportJob)}
+}
+
+// CreateReport mocks the CreateReport method
+func (s *MockReportStore) CreateReport(ctx context.Context, job model.ReportJob) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.CreateReportCalls = append(s.CreateReportCalls, job)
+	if s.Err != nil {
+		return s.Err
+	}
+
+	s.Reports[job.ID] = job
+	return nil
+}
+
+// FindReport mocks the FindReport method
+func (s *MockReportStore) FindReport(ctx context.Context, id string) (model.ReportJob, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.Err != nil {
+		return model.ReportJob{}, s.Err
+	}
+
+	job, ok := s.Reports[id]
+	if !ok {
+		return model.ReportJob{}, ErrReportNotFound
+	}
+	return job, nil
+}
+
+// UpdateReport mocks the UpdateReport method
+func (s *MockReportStore) UpdateReport(ctx context.Context, job model.ReportJob, from ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.Err != nil {
+		return s.Err
+	}
+	if s.UpdateReportFn != nil {
+		if err := s.UpdateReportFn(job); err != nil {
+			return err
+		}
+	}
+
+	stored, ok := s.Reports[job.ID]
+	if !ok {
+		return ErrReportNotFound
+	}
+	if !slices.Contains(from, stored.Status) {
+		return ErrReportStatusChanged
+	}
+
+	s.Reports[job.ID] = job
+	return nil
+}
+
+// DeleteReport mocks the DeleteReport method
+func (s *MockReportStore) DeleteReport(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.DeleteReportCalls = append(s.DeleteReportCalls, id)
+	if s.Err != nil {
+		return s.Err
+	}
+
+	delete(s.Reports, id)
+	return nil
+}
+
+// Report returns the stored job with the given ID, for assertions
+func (s *MockReportStore) Report(id string) (model.ReportJob, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job, ok := s.Reports[id]
+	return job, ok
+}
@@ -2,60 +2,205 @@ package repository
 
 import (
 	"context"
+	"fmt"
+	"reflect"
+	"sync"
 	"time"
 
-	"go.mongodb.org/mongo-driver/bson"
+	"admin-statistics-api/internal/model"
 )
 
 // MockTransactionRepository is a mock implementation of the transaction repository for testing
 type MockTransactionRepository struct {
-	CalculateGGRFn                 func(ctx context.Context, from, to time.Time) ([]bson.M, error)
-	CalculateDailyWagerVolumeFn    func(ctx context.Context, from, to time.Time) ([]bson.M, error)
+	CalculateGGRFn                 func(ctx context.Context, from, to time.Time, bucket model.TimeBucket) ([]model.GGRRow, error)
+	CalculateGGRSeriesFn           func(ctx context.Context, from, to time.Time, bucket model.TimeBucket) ([]model.GGRSeriesRow, error)
+	CalculateDailyWagerVolumeFn    func(ctx context.Context, from, to time.Time, bucket model.TimeBucket) ([]model.DailyWagerRow, error)
 	CalculateUserWagerPercentileFn func(ctx context.Context, userID string, from, to time.Time) (float64, error)
+	CalculateUserSummaryFn         func(ctx context.Context, userID string, from, to time.Time) (model.UserSummary, error)
+	CalculateLeaderboardFn         func(ctx context.Context, metric, currency string, limit int, from, to time.Time) ([]model.LeaderboardRow, error)
+	FindRoundAnomaliesFn           func(ctx context.Context, from, to time.Time) ([]model.RoundAnomaly, error)
+	StreamRowsFn                   func(ctx context.Context, reportType string, params model.ReportParams) (RowCursor, error)
+	InsertTransactionsFn           func(ctx context.Context, transactions []model.Transaction) (int, error)
 	
 	// Track function calls
-	CalculateGGRCalls                []struct{From, To time.Time}
-	CalculateDailyWagerVolumeCalls   []struct{From, To time.Time}
+	mu                               sync.Mutex
+	CalculateGGRCalls                []struct{From, To time.Time; Bucket model.TimeBucket}
+	CalculateGGRSeriesCalls          []struct{From, To time.Time; Bucket model.TimeBucket}
+	CalculateDailyWagerVolumeCalls   []struct{From, To time.Time; Bucket model.TimeBucket}
 	CalculateUserWagerPercentileCalls []struct{UserID string; From, To time.Time}
+	CalculateUserSummaryCalls         []struct{UserID string; From, To time.Time}
+	CalculateLeaderboardCalls         []struct{Metric, Currency string; Limit int; From, To time.Time}
+	FindRoundAnomaliesCalls           []struct{From, To time.Time}
+	StreamRowsCalls                   []struct{ReportType string; Params model.ReportParams}
+	InsertTransactionsCalls           [][]model.Transaction
 }
 
 // NewMockTransactionRepository creates a new MockTransactionRepository
 func NewMockTransactionRepository() *MockTransactionRepository {
 	return &MockTransactionRepository{
-		CalculateGGRCalls:                make([]struct{From, To time.Time}, 0),
-		CalculateDailyWagerVolumeCalls:   make([]struct{From, To time.Time}, 0),
+		CalculateGGRCalls:                make([]struct{From, To time.Time; Bucket model.TimeBucket}, 0),
+		CalculateGGRSeriesCalls:          make([]struct{From, To time.Time; Bucket model.TimeBucket}, 0),
+		CalculateDailyWagerVolumeCalls:   make([]struct{From, To time.Time; Bucket model.TimeBucket}, 0),
 		CalculateUserWagerPercentileCalls: make([]struct{UserID string; From, To time.Time}, 0),
+		CalculateUserSummaryCalls:         make([]struct{UserID string; From, To time.Time}, 0),
+		CalculateLeaderboardCalls:         make([]struct{Metric, Currency string; Limit int; From, To time.Time}, 0),
+		FindRoundAnomaliesCalls:           make([]struct{From, To time.Time}, 0),
+		StreamRowsCalls:                   make([]struct{ReportType string; Params model.ReportParams}, 0),
+		InsertTransactionsCalls:           make([][]model.Transaction, 0),
 		
 		// Default implementations return empty results
-		CalculateGGRFn: func(ctx context.Context, from, to time.Time) ([]bson.M, error) {
-			return []bson.M{}, nil
+		CalculateGGRFn: func(ctx context.Context, from, to time.Time, bucket model.TimeBucket) ([]model.GGRRow, error) {
+			return []model.GGRRow{}, nil
 		},
-		CalculateDailyWagerVolumeFn: func(ctx context.Context, from, to time.Time) ([]bson.M, error) {
-			return []bson.M{}, nil
+		CalculateGGRSeriesFn: func(ctx context.Context, from, to time.Time, bucket model.TimeBucket) ([]model.GGRSeriesRow, error) {
+			return []model.GGRSeriesRow{}, nil
+		},
+		CalculateDailyWagerVolumeFn: func(ctx context.Context, from, to time.Time, bucket model.TimeBucket) ([]model.DailyWagerRow, error) {
+			return []model.DailyWagerRow{}, nil
 		},
 		CalculateUserWagerPercentileFn: func(ctx context.Context, userID string, from, to time.Time) (float64, error) {
 			return 0, nil
 		},
+		CalculateUserSummaryFn: func(ctx context.Context, userID string, from, to time.Time) (model.UserSummary, error) {
+			return model.UserSummary{Currencies: []model.UserCurrencySummary{}}, nil
+		},
+		CalculateLeaderboardFn: func(ctx context.Context, metric, currency string, limit int, from, to time.Time) ([]model.LeaderboardRow, error) {
+			return []model.LeaderboardRow{}, nil
+		},
+		FindRoundAnomaliesFn: func(ctx context.Context, from, to time.Time) ([]model.RoundAnomaly, error) {
+			return []model.RoundAnomaly{}, nil
+		},
+		StreamRowsFn: func(ctx context.Context, reportType string, params model.ReportParams) (RowCursor, error) {
+			return NewMockRowCursor(), nil
+		},
+		InsertTransactionsFn: func(ctx context.Context, transactions []model.Transaction) (int, error) {
+			return len(transactions), nil
+		},
 	}
 }
 
 // CalculateGGR mocks the CalculateGGR method
-func (r *MockTransactionRepository) CalculateGGR(ctx context.Context, from, to time.Time) ([]bson.M, error) {
-	r.CalculateGGRCalls = append(r.CalculateGGRCalls, struct{From, To time.Time}{from, to})
-	return r.CalculateGGRFn(ctx, from, to)
+func (r *MockTransactionRepository) CalculateGGR(ctx context.Context, from, to time.Time, bucket model.TimeBucket) ([]model.GGRRow, error) {
+	r.mu.Lock()
+	r.CalculateGGRCalls = append(r.CalculateGGRCalls, struct{From, To time.Time; Bucket model.TimeBucket}{from, to, bucket})
+	r.mu.Unlock()
+	return r.CalculateGGRFn(ctx, from, to, bucket)
+}
+
+// CalculateGGRSeries mocks the CalculateGGRSeries method
+func (r *MockTransactionRepository) CalculateGGRSeries(ctx context.Context, from, to time.Time, bucket model.TimeBucket) ([]model.GGRSeriesRow, error) {
+	r.mu.Lock()
+	r.CalculateGGRSeriesCalls = append(r.CalculateGGRSeriesCalls, struct{From, To time.Time; Bucket model.TimeBucket}{from, to, bucket})
+	r.mu.Unlock()
+	return r.CalculateGGRSeriesFn(ctx, from, to, bucket)
 }
 
 // CalculateDailyWagerVolume mocks the CalculateDailyWagerVolume method
-func (r *MockTransactionRepository) CalculateDailyWagerVolume(ctx context.Context, from, to time.Time) ([]bson.M, error) {
-	r.CalculateDailyWagerVolumeCalls = append(r.CalculateDailyWagerVolumeCalls, struct{From, To time.Time}{from, to})
-	return r.CalculateDailyWagerVolumeFn(ctx, from, to)
+func (r *MockTransactionRepository) CalculateDailyWagerVolume(ctx context.Context, from, to time.Time, bucket model.TimeBucket) ([]model.DailyWagerRow, error) {
+	r.mu.Lock()
+	r.CalculateDailyWagerVolumeCalls = append(r.CalculateDailyWagerVolumeCalls, struct{From, To time.Time; Bucket model.TimeBucket}{from, to, bucket})
+	r.mu.Unlock()
+	return r.CalculateDailyWagerVolumeFn(ctx, from, to, bucket)
 }
 
 // CalculateUserWagerPercentile mocks the CalculateUserWagerPercentile method
 func (r *MockTransactionRepository) CalculateUserWagerPercentile(ctx context.Context, userID string, from, to time.Time) (float64, error) {
+	r.mu.Lock()
 	r.CalculateUserWagerPercentileCalls = append(r.CalculateUserWagerPercentileCalls, struct{UserID string; From, To time.Time}{userID, from, to})
+	r.mu.Unlock()
 	return r.CalculateUserWagerPercentileFn(ctx, userID, from, to)
 }
 
+// CalculateUserSummary mocks the CalculateUserSummary method
+func (r *MockTransactionRepository) CalculateUserSummary(ctx context.Context, userID string, from, to time.Time) (model.UserSummary, error) {
+	r.mu.Lock()
+	r.CalculateUserSummaryCalls = append(r.CalculateUserSummaryCalls, struct{UserID string; From, To time.Time}{userID, from, to})
+	r.mu.Unlock()
+	return r.CalculateUserSummaryFn(ctx, userID, from, to)
+}
+
+// CalculateLeaderboard mocks the CalculateLeaderboard method
+func (r *MockTransactionRepository) CalculateLeaderboard(ctx context.Context, metric, currency string, limit int, from, to time.Time) ([]model.LeaderboardRow, error) {
+	r.mu.Lock()
+	r.CalculateLeaderboardCalls = append(r.CalculateLeaderboardCalls, struct{Metric, Currency string; Limit int; From, To time.Time}{metric, currency, limit, from, to})
+	r.mu.Unlock()
+	return r.CalculateLeaderboardFn(ctx, metric, currency, limit, from, to)
+}
+
+// FindRoundAnomalies mocks the FindRoundAnomalies method
+func (r *MockTransactionRepository) FindRoundAnomalies(ctx context.Context, from, to time.Time) ([]model.RoundAnomaly, error) {
+	r.mu.Lock()
+	r.FindRoundAnomaliesCalls = append(r.FindRoundAnomaliesCalls, struct{From, To time.Time}{from, to})
+	r.mu.Unlock()
+	return r.FindRoundAnomaliesFn(ctx, from, to)
+}
+
+// StreamRows mocks the StreamRows method
+func (r *MockTransactionRepository) StreamRows(ctx context.Context, reportType string, params model.ReportParams) (RowCursor, error) {
+	r.mu.Lock()
+	r.StreamRowsCalls = append(r.StreamRowsCalls, struct{ReportType string; Params model.ReportParams}{reportType, params})
+	r.mu.Unlock()
+	return r.StreamRowsFn(ctx, reportType, params)
+}
+
+// InsertTransactions mocks the InsertTransactions method
+func (r *MockTransactionRepository) InsertTransactions(ctx context.Context, transactions []model.Transaction) (int, error) {
+	r.mu.Lock()
+	r.InsertTransactionsCalls = append(r.InsertTransactionsCalls, transactions)
+	r.mu.Unlock()
+	return r.InsertTransactionsFn(ctx, transactions)
+}
+
+// Verify implementation of interface
+var _ TransactionRepositoryInterface = (*MockTransactionRepository)(nil)
+
+// MockRowCursor is a RowCursor over rows held in memory. Each row is decoded by assigning
+// it to the value Decode is given, so it must have that value's type.
+type MockRowCursor struct {
+	rows   []interface{}
+	next   int
+	Error  error // Returned by Err once the rows run out
+	Closed bool  // Whether Close was called
+}
+
+// NewMockRowCursor creates a MockRowCursor over rows
+func NewMockRowCursor(rows ...interface{}) *MockRowCursor {
+	return &MockRowCursor{rows: rows}
+}
+
+// Next moves to the next row, returning false when there are none left
+func (c *MockRowCursor) Next(ctx context.Context) bool {
+	if c.next >= len(c.rows) {
+		return false
+	}
+	c.next++
+	return true
+}
+
+// Decode assigns the current row to val, which must be a pointer
+func (c *MockRowCursor) Decode(val interface{}) error {
+	target := reflect.ValueOf(val).Elem()
+	row := reflect.ValueOf(c.rows[c.next-1])
+	if row.Type() != target.Type() {
+		return fmt.Errorf("cannot decode %s into %s", row.Type(), target.Type())
+	}
+	target.Set(row)
+	return nil
+}
+
+// Err returns Error once every row has been read
+func (c *MockRowCursor) Err() error {
+	if c.next < len(c.rows) {
+		return nil
+	}
+	return c.Error
+}
+
+// Close marks the cursor closed
+func (c *MockRowCursor) Close(ctx context.Context) error {
+	c.Closed = true
+	return nil
+}
+
 // Verify implementation of interface
-var _ TransactionRepositoryInterface = (*MockTransactionRepository)(nil)
\ No newline at end of file
+var _ RowCursor = (*MockRowCursor)(nil)
@@ -0,0 +1,95 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"admin-statistics-api/internal/model"
+)
+
+// rateLimitSweepInterval is how often MemoryRateLimiter drops keys that are back at full capacity
+const rateLimitSweepInterval = time.Minute
+
+// MemoryRateLimiter is a token bucket rate limiter that keeps its state in process memory.
+// Limits are only enforced per instance, so it is meant for a single instance or as a
+// fallback when Redis is unreachable.
+type MemoryRateLimiter struct {
+	mu        sync.Mutex
+	buckets   map[string]time.Time // Theoretical arrival time of the next request, by key
+	nextSweep time.Time
+	now       func() time.Time
+}
+
+// NewMemoryRateLimiter creates a new MemoryRateLimiter
+func NewMemoryRateLimiter() *MemoryRateLimiter {
+	return &MemoryRateLimiter{
+		buckets: make(map[string]time.Time),
+		now:     time.Now,
+	}
+}
+
+// Allow takes one request from the bucket for key
+func (l *MemoryRateLimiter) Allow(ctx context.Context, key string, limit model.RateLimit) (RateLimitResult, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+
+	tat, result := takeToken(l.buckets[key], now, limit)
+	if result.Allowed {
+		l.buckets[key] = tat
+	}
+	return result, nil
+}
+
+// sweep drops buckets that have refilled, since they behave the same as missing ones
+func (l *MemoryRateLimiter) sweep(now time.Time) {
+	if now.Before(l.nextSweep) {
+		return
+	}
+	for key, tat := range l.buckets {
+		if !tat.After(now) {
+			delete(l.buckets, key)
+		}
+	}
+	l.nextSweep = now.Add(rateLimitSweepInterval)
+}
+
+// takeToken applies the generic cell rate algorithm, the token bucket expressed as the
+// theoretical arrival time (TAT) of the next request. An empty bucket has its TAT a full
+// period ahead of now. It returns the TAT to store if the request is allowed.
+func takeToken(tat, now time.Time, limit model.RateLimit) (time.Time, RateLimitResult) {
+	interval := limit.Interval()
+	if tat.Before(now) {
+		tat = now
+	}
+
+	newTAT := tat.Add(interval)
+	allowAt := newTAT.Add(-limit.Period)
+	if now.Before(allowAt) {
+		return tat, RateLimitResult{
+			Allowed:    false,
+			Limit:      limit.Requests,
+			Remaining:  0,
+			RetryAfter: allowAt.Sub(now),
+			ResetAfter: tat.Sub(now),
+		}
+	}
+
+	return newTAT, newRateLimitResult(newTAT.Sub(now), limit)
+}
+
+// newRateLimitResult describes an allowed request that left the bucket resetAfter from full
+func newRateLimitResult(resetAfter time.Duration, limit model.RateLimit) RateLimitResult {
+	return RateLimitResult{
+		Allowed:    true,
+		Limit:      limit.Requests,
+		Remaining:  int((limit.Period - resetAfter) / limit.Interval()),
+		ResetAfter: resetAfter,
+	}
+}
+
+// Ensure MemoryRateLimiter implements RateLimiter
+var _ RateLimiter = (*MemoryRateLimiter)(nil)
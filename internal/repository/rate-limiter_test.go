@@ -0,0 +1,180 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"admin-statistics-api/internal/logging"
+	"admin-statistics-api/internal/model"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// testRateLimiter exercises a limiter whose clock reads *now
+func testRateLimiter(t *testing.T, limiter RateLimiter, now *time.Time) {
+	ctx := context.Background()
+	limit := model.RateLimit{Requests: 3, Period: 3 * time.Second}
+
+	t.Run("allows a burst up to the limit", func(t *testing.T) {
+		for remaining := 2; remaining >= 0; remaining-- {
+			result, err := limiter.Allow(ctx, "burst", limit)
+
+			assert.NoError(t, err)
+			assert.True(t, result.Allowed)
+			assert.Equal(t, 3, result.Limit)
+			assert.Equal(t, remaining, result.Remaining)
+		}
+	})
+
+	t.Run("rejects requests over the limit until a token refills", func(t *testing.T) {
+		// Arrange
+		for i := 0; i < 3; i++ {
+			limiter.Allow(ctx, "refill", limit)
+		}
+
+		// Act
+		rejected, err := limiter.Allow(ctx, "refill", limit)
+		*now = now.Add(500 * time.Millisecond)
+		stillRejected, _ := limiter.Allow(ctx, "refill", limit)
+		*now = now.Add(500 * time.Millisecond)
+		allowed, _ := limiter.Allow(ctx, "refill", limit)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.False(t, rejected.Allowed)
+		assert.Equal(t, 0, rejected.Remaining)
+		assert.Equal(t, time.Second, rejected.RetryAfter)
+		assert.Equal(t, 3*time.Second, rejected.ResetAfter)
+		assert.False(t, stillRejected.Allowed)
+		assert.Equal(t, 500*time.Millisecond, stillRejected.RetryAfter)
+		assert.True(t, allowed.Allowed)
+		assert.Equal(t, 0, allowed.Remaining)
+	})
+
+	t.Run("keeps keys separate", func(t *testing.T) {
+		for i := 0; i < 3; i++ {
+			limiter.Allow(ctx, "first", limit)
+		}
+
+		first, _ := limiter.Allow(ctx, "first", limit)
+		second, _ := limiter.Allow(ctx, "second", limit)
+
+		assert.False(t, first.Allowed)
+		assert.True(t, second.Allowed)
+	})
+
+	t.Run("refills completely after the period", func(t *testing.T) {
+		for i := 0; i < 3; i++ {
+			limiter.Allow(ctx, "reset", limit)
+		}
+		*now = now.Add(limit.Period)
+
+		result, _ := limiter.Allow(ctx, "reset", limit)
+
+		assert.True(t, result.Allowed)
+		assert.Equal(t, 2, result.Remaining)
+	})
+}
+
+func TestMemoryRateLimiter(t *testing.T) {
+	now := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	limiter := NewMemoryRateLimiter()
+	limiter.now = func() time.Time { return now }
+
+	testRateLimiter(t, limiter, &now)
+
+	t.Run("sweeps refilled buckets", func(t *testing.T) {
+		now = now.Add(time.Hour)
+
+		limiter.Allow(context.Background(), "fresh", model.RateLimit{Requests: 1, Period: time.Second})
+
+		assert.Len(t, limiter.buckets, 1)
+	})
+}
+
+func TestRedisRateLimiter_WithMiniRedis(t *testing.T) {
+	// Start a miniredis server
+	s, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("Failed to start miniredis: %v", err)
+	}
+	defer s.Close()
+
+	cache, err := NewRedisCache("redis://" + s.Addr())
+	assert.NoError(t, err)
+	defer cache.Close()
+
+	now := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	limiter := NewRedisRateLimiter(cache)
+	limiter.now = func() time.Time { return now }
+	limiter.fallback.now = limiter.now
+
+	testRateLimiter(t, limiter, &now)
+
+	t.Run("stores state under the rate limit prefix with an expiry", func(t *testing.T) {
+		limiter.Allow(context.Background(), "stored", model.RateLimit{Requests: 10, Period: time.Minute})
+
+		assert.True(t, s.Exists("ratelimit:stored"))
+		assert.Equal(t, 6*time.Second, s.TTL("ratelimit:stored"))
+	})
+
+	t.Run("shares limits between instances", func(t *testing.T) {
+		other := NewRedisRateLimiter(cache)
+		other.now = limiter.now
+		limit := model.RateLimit{Requests: 1, Period: time.Minute}
+
+		first, _ := limiter.Allow(context.Background(), "shared", limit)
+		second, _ := other.Allow(context.Background(), "shared", limit)
+
+		assert.True(t, first.Allowed)
+		assert.False(t, second.Allowed)
+	})
+
+	t.Run("falls back to memory when Redis is down", func(t *testing.T) {
+		// Arrange
+		s.Close()
+		defer s.Restart()
+		limit := model.RateLimit{Requests: 1, Period: time.Minute}
+
+		// Act
+		first, err := limiter.Allow(context.Background(), "fallback", limit)
+		second, _ := limiter.Allow(context.Background(), "fallback", limit)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.True(t, first.Allowed)
+		assert.False(t, second.Allowed)
+	})
+
+	t.Run("logs only when it starts and stops limiting in memory", func(t *testing.T) {
+		// Arrange
+		var out bytes.Buffer
+		ctx := logging.ContextWithLogger(context.Background(), logging.New(&out, slog.LevelInfo))
+		limit := model.RateLimit{Requests: 10, Period: time.Minute}
+		limiter.Allow(context.Background(), "logged", limit)
+
+		// Act
+		s.Close()
+		for i := 0; i < 3; i++ {
+			limiter.Allow(ctx, "logged", limit)
+		}
+		s.Restart()
+		// The client waits before dialling again after failed dials
+		assert.Eventually(t, func() bool {
+			limiter.Allow(ctx, "logged", limit)
+			return !limiter.inMemory.Load()
+		}, 5*time.Second, 50*time.Millisecond)
+		limiter.Allow(ctx, "logged", limit)
+
+		// Assert
+		lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+		if assert.Len(t, lines, 2) {
+			assert.Contains(t, lines[0], "limiting in memory")
+			assert.Contains(t, lines[1], "available again")
+		}
+	})
+}
@@ -0,0 +1,22 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"admin-statistics-api/internal/model"
+)
+
+// RateLimitResult is the outcome of one rate limited request
+type RateLimitResult struct {
+	Allowed    bool
+	Limit      int           // Requests allowed per period
+	Remaining  int           // Requests that could still be sent right now
+	RetryAfter time.Duration // Wait before the request would be allowed; zero when allowed
+	ResetAfter time.Duration // Wait until the full limit is available again
+}
+
+// RateLimiter counts requests against a limit per key
+type RateLimiter interface {
+	Allow(ctx context.Context, key string, limit model.RateLimit) (RateLimitResult, error)
+}
@@ -6,47 +6,115 @@ import (
 	"time"
 
 	"github.com/go-redis/redis/v8"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // RedisCache implements the Cache interface using Redis
 type RedisCache struct {
-	client *redis.Client
+	client  *redis.Client
+	onError func(operation, key string, err error)
 }
 
 // NewRedisCache creates a new Redis cache
 func NewRedisCache(redisURL string) (*RedisCache, error) {
-	opts, err := redis.ParseURL(redisURL)
+	cache, err := DialRedisCache(redisURL)
 	if err != nil {
 		return nil, err
 	}
-
-	client := redis.NewClient(opts)
 	
 	// Test connection
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 	
-	if err := client.Ping(ctx).Err(); err != nil {
+	if err := cache.Ping(ctx); err != nil {
+		cache.Close()
+		return nil, err
+	}
+
+	return cache, nil
+}
+
+// DialRedisCache creates a new Redis cache without checking that Redis is reachable.
+// The client connects when it is first used and reconnects after failures.
+func DialRedisCache(redisURL string) (*RedisCache, error) {
+	opts, err := redis.ParseURL(redisURL)
+	if err != nil {
 		return nil, err
 	}
 
 	return &RedisCache{
-		client: client,
+		client: redis.NewClient(opts),
 	}, nil
 }
 
+// WithErrorHandler sets a function called when a cache operation fails. Failures are
+// otherwise only visible as misses.
+func (c *RedisCache) WithErrorHandler(onError func(operation, key string, err error)) *RedisCache {
+	c.onError = onError
+	return c
+}
+
+// reportError passes a failed operation to the error handler, if any
+func (c *RedisCache) reportError(operation, key string, err error) {
+	if c.onError != nil {
+		c.onError(operation, key, err)
+	}
+}
+
+// startRedisSpan starts the span of a Redis operation, such as "GET", on key. The key
+// is left out for operations on many keys.
+func startRedisSpan(ctx context.Context, operation, key string) (context.Context, trace.Span) {
+	attributes := []attribute.KeyValue{
+		attribute.String("db.system", "redis"),
+		attribute.String("db.operation", operation),
+	}
+	if key != "" {
+		attributes = append(attributes, attribute.String("cache.key", key))
+	}
+	return tracer.Start(ctx, "redis."+operation, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attributes...))
+}
+
+// endRedisSpan ends the span of a Redis operation, recording err unless it is nil or
+// a miss
+func endRedisSpan(span trace.Span, err error) {
+	if err != nil && err != redis.Nil {
+		recordError(span, err)
+	}
+	span.End()
+}
+
+// writeContext returns the context for a write to Redis: one that ends after a second,
+// but not when ctx is cancelled, so a result computed for a request that went away is
+// still cached
+func writeContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.WithoutCancel(ctx), 1*time.Second)
+}
+
+// Ping checks that Redis is reachable
+func (c *RedisCache) Ping(ctx context.Context) error {
+	return c.client.Ping(ctx).Err()
+}
+
 // Get retrieves a value from the cache
-func (c *RedisCache) Get(key string) (interface{}, bool) {
-	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+func (c *RedisCache) Get(ctx context.Context, key string) (interface{}, bool) {
+	ctx, cancel := context.WithTimeout(ctx, 1*time.Second)
 	defer cancel()
 
+	ctx, span := startRedisSpan(ctx, "GET", key)
 	val, err := c.client.Get(ctx, key).Result()
+	span.SetAttributes(attribute.Bool("cache.hit", err == nil))
+	endRedisSpan(span, err)
 	if err != nil {
+		if err != redis.Nil {
+			c.reportError("get", key, err)
+		}
 		return nil, false
 	}
 
 	var result interface{}
 	if err := json.Unmarshal([]byte(val), &result); err != nil {
+		c.reportError("get", key, err)
 		return nil, false
 	}
 
@@ -54,24 +122,35 @@ func (c *RedisCache) Get(key string) (interface{}, bool) {
 }
 
 // Set adds a value to the cache
-func (c *RedisCache) Set(key string, value interface{}, expiration time.Duration) {
-	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+func (c *RedisCache) Set(ctx context.Context, key string, value interface{}, expiration time.Duration) {
+	ctx, cancel := writeContext(ctx)
 	defer cancel()
 
 	data, err := json.Marshal(value)
 	if err != nil {
+		c.reportError("set", key, err)
 		return
 	}
 
-	c.client.Set(ctx, key, data, expiration)
+	ctx, span := startRedisSpan(ctx, "SET", key)
+	err = c.client.Set(ctx, key, data, expiration).Err()
+	endRedisSpan(span, err)
+	if err != nil {
+		c.reportError("set", key, err)
+	}
 }
 
 // Delete removes a value from the cache
-func (c *RedisCache) Delete(key string) {
-	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+func (c *RedisCache) Delete(ctx context.Context, key string) {
+	ctx, cancel := writeContext(ctx)
 	defer cancel()
 
-	c.client.Del(ctx, key)
+	ctx, span := startRedisSpan(ctx, "DEL", key)
+	err := c.client.Del(ctx, key).Err()
+	endRedisSpan(span, err)
+	if err != nil {
+		c.reportError("delete", key, err)
+	}
 }
 
 // Close closes the Redis client connection
@@ -1,6 +1,7 @@
 package repository
 
 import (
+	"context"
 	"os"
 	"testing"
 	"time"
@@ -10,6 +11,7 @@ import (
 )
 
 func TestRedisCache(t *testing.T) {
+	ctx := context.Background()
 	// Skip real tests if INTEGRATION_TESTS environment variable is not set
 	if os.Getenv("INTEGRATION_TESTS") != "true" {
 		t.Skip("Skipping integration tests")
@@ -44,8 +46,8 @@ func TestRedisCache(t *testing.T) {
 		}
 
 		// Act
-		cache.Set(key, value, 10*time.Second)
-		retrievedValue, found := cache.Get(key)
+		cache.Set(ctx, key, value, 10*time.Second)
+		retrievedValue, found := cache.Get(ctx, key)
 
 		// Assert
 		assert.True(t, found)
@@ -62,17 +64,17 @@ func TestRedisCache(t *testing.T) {
 
 		key := "test-delete-key"
 		value := "test-value"
-		cache.Set(key, value, 10*time.Second)
+		cache.Set(ctx, key, value, 10*time.Second)
 
 		// Verify it's there
-		_, found := cache.Get(key)
+		_, found := cache.Get(ctx, key)
 		assert.True(t, found)
 
 		// Act
-		cache.Delete(key)
+		cache.Delete(ctx, key)
 
 		// Assert
-		_, found = cache.Get(key)
+		_, found = cache.Get(ctx, key)
 		assert.False(t, found)
 	})
 
@@ -86,12 +88,12 @@ func TestRedisCache(t *testing.T) {
 		value := "test-value"
 
 		// Act
-		cache.Set(key, value, 1*time.Second) // Very short expiration
-		_, foundBefore := cache.Get(key)
+		cache.Set(ctx, key, value, 1*time.Second) // Very short expiration
+		_, foundBefore := cache.Get(ctx, key)
 		
 		// Wait for expiration
 		time.Sleep(2 * time.Second)
-		_, foundAfter := cache.Get(key)
+		_, foundAfter := cache.Get(ctx, key)
 
 		// Assert
 		assert.True(t, foundBefore)
@@ -101,6 +103,7 @@ func TestRedisCache(t *testing.T) {
 
 // Mock Redis tests using miniredis
 func TestRedisCache_WithMiniRedis(t *testing.T) {
+	ctx := context.Background()
 	// Start a miniredis server
 	s, err := miniredis.Run()
 	if err != nil {
@@ -124,8 +127,8 @@ func TestRedisCache_WithMiniRedis(t *testing.T) {
 		}
 
 		// Act
-		cache.Set(key, value, 10*time.Second)
-		retrievedValue, found := cache.Get(key)
+		cache.Set(ctx, key, value, 10*time.Second)
+		retrievedValue, found := cache.Get(ctx, key)
 
 		// Assert
 		assert.True(t, found)
@@ -144,13 +147,13 @@ func TestRedisCache_WithMiniRedis(t *testing.T) {
 		value := "test-value"
 
 		// Act
-		cache.Set(key, value, 10*time.Second)
-		_, foundBefore := cache.Get(key)
+		cache.Set(ctx, key, value, 10*time.Second)
+		_, foundBefore := cache.Get(ctx, key)
 		
 		// Fast-forward time in miniredis
 		s.FastForward(15 * time.Second)
 		
-		_, foundAfter := cache.Get(key)
+		_, foundAfter := cache.Get(ctx, key)
 
 		// Assert
 		assert.True(t, foundBefore)
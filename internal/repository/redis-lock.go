@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+
+	"admin-statistics-api/internal/logging"
+	"github.com/go-redis/redis/v8"
+)
+
+// lockKeyPrefix namespaces locks among the cached responses
+const lockKeyPrefix = "lock:"
+
+// unlockScript deletes a lock only if it still holds this holder's token, so a holder
+// whose lock expired cannot release a lock taken over by someone else
+var unlockScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+// RedisLocker implements Locker with SET NX locks in Redis
+type RedisLocker struct {
+	client *redis.Client
+}
+
+// NewRedisLocker creates a RedisLocker that uses the cache's Redis client
+func NewRedisLocker(cache *RedisCache) *RedisLocker {
+	return &RedisLocker{
+		client: cache.client,
+	}
+}
+
+// TryLock takes the lock for key if nobody holds it
+func (l *RedisLocker) TryLock(ctx context.Context, key string, ttl time.Duration) (func(), bool, error) {
+	token := make([]byte, 16)
+	if _, err := rand.Read(token); err != nil {
+		return nil, false, err
+	}
+	value := hex.EncodeToString(token)
+
+	lockCtx, cancel := context.WithTimeout(ctx, 1*time.Second)
+	defer cancel()
+
+	acquired, err := l.client.SetNX(lockCtx, lockKeyPrefix+key, value, ttl).Result()
+	if err != nil || !acquired {
+		return nil, false, err
+	}
+
+	logger := logging.FromContext(ctx)
+	unlock := func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+		defer cancel()
+
+		if err := unlockScript.Run(ctx, l.client, []string{lockKeyPrefix + key}, value).Err(); err != nil {
+			logger.Warn("Failed to release lock", "key", key, "error", err)
+		}
+	}
+	return unlock, true, nil
+}
+
+// Ensure RedisLocker implements Locker
+var _ Locker = (*RedisLocker)(nil)
@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedisLocker_WithMiniRedis(t *testing.T) {
+	// Start a miniredis server
+	s, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("Failed to start miniredis: %v", err)
+	}
+	defer s.Close()
+
+	cache, err := NewRedisCache("redis://" + s.Addr())
+	assert.NoError(t, err)
+	defer cache.Close()
+
+	ctx := context.Background()
+	locker := NewRedisLocker(cache)
+
+	t.Run("only one holder gets the lock", func(t *testing.T) {
+		// Act
+		unlock, acquired, err := locker.TryLock(ctx, "single", time.Minute)
+		_, acquiredAgain, errAgain := locker.TryLock(ctx, "single", time.Minute)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.True(t, acquired)
+		assert.NoError(t, errAgain)
+		assert.False(t, acquiredAgain)
+		assert.True(t, s.Exists("lock:single"))
+		unlock()
+	})
+
+	t.Run("unlock releases the lock", func(t *testing.T) {
+		unlock, _, _ := locker.TryLock(ctx, "release", time.Minute)
+
+		unlock()
+		_, acquired, err := locker.TryLock(ctx, "release", time.Minute)
+
+		assert.NoError(t, err)
+		assert.True(t, acquired)
+	})
+
+	t.Run("lock expires after its ttl", func(t *testing.T) {
+		locker.TryLock(ctx, "expire", time.Second)
+
+		s.FastForward(2 * time.Second)
+		_, acquired, _ := locker.TryLock(ctx, "expire", time.Second)
+
+		assert.True(t, acquired)
+	})
+
+	t.Run("expired holder cannot release the next holder's lock", func(t *testing.T) {
+		// Arrange
+		staleUnlock, _, _ := locker.TryLock(ctx, "takeover", time.Second)
+		s.FastForward(2 * time.Second)
+		_, acquired, _ := locker.TryLock(ctx, "takeover", time.Minute)
+		assert.True(t, acquired)
+
+		// Act
+		staleUnlock()
+
+		// Assert
+		assert.True(t, s.Exists("lock:takeover"))
+	})
+
+	t.Run("reports errors when Redis is down", func(t *testing.T) {
+		s.Close()
+		defer s.Restart()
+
+		_, acquired, err := locker.TryLock(ctx, "down", time.Minute)
+
+		assert.Error(t, err)
+		assert.False(t, acquired)
+	})
+}
@@ -0,0 +1,191 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"time"
+
+	"admin-statistics-api/internal/logging"
+	"github.com/go-redis/redis/v8"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Keys of the time range index, which holds every cache entry set with SetRange.
+// The index entries of a cache key are removed when it is invalidated, or by a later
+// SetRange once the cache key has expired.
+const (
+	rangeIndexTo      = "cacheindex:to"      // Sorted set of cache keys scored by the end of their range
+	rangeIndexFrom    = "cacheindex:from"    // Hash of cache key to the start of its range
+	rangeIndexExpires = "cacheindex:expires" // Sorted set of cache keys scored by their expiry
+)
+
+// rangeIndexPruneLimit bounds how many expired index entries one SetRange removes
+const rangeIndexPruneLimit = 100
+
+// setRangeScript sets a cache entry and indexes it by its range, with times in Unix
+// milliseconds. It also prunes index entries of cache keys that have expired.
+// ARGV: value, ttl, from, to, expiresAt, now, prune limit
+var setRangeScript = redis.NewScript(`
+redis.call("SET", KEYS[1], ARGV[1], "PX", ARGV[2])
+redis.call("ZADD", KEYS[2], ARGV[4], KEYS[1])
+redis.call("HSET", KEYS[3], KEYS[1], ARGV[3])
+redis.call("ZADD", KEYS[4], ARGV[5], KEYS[1])
+
+local expired = redis.call("ZRANGEBYSCORE", KEYS[4], "-inf", ARGV[6], "LIMIT", 0, ARGV[7])
+for _, key in ipairs(expired) do
+	redis.call("ZREM", KEYS[2], key)
+	redis.call("HDEL", KEYS[3], key)
+	redis.call("ZREM", KEYS[4], key)
+end
+return #expired
+`)
+
+// invalidateTimesScript deletes the cache entries whose range covers one of the times
+// in ARGV, which are Unix milliseconds in ascending order, and returns how many it deleted
+var invalidateTimesScript = redis.NewScript(`
+local times = {}
+for i, t in ipairs(ARGV) do
+	times[i] = tonumber(t)
+end
+
+-- Only ranges ending at or after the earliest time can cover any of them
+local candidates = redis.call("ZRANGEBYSCORE", KEYS[1], times[1], "+inf", "WITHSCORES")
+local deleted = 0
+for i = 1, #candidates, 2 do
+	local key = candidates[i]
+	local to = tonumber(candidates[i + 1])
+	local from = tonumber(redis.call("HGET", KEYS[2], key))
+
+	-- Binary search for the first time at or after the start of the range
+	local lo, hi = 1, #times + 1
+	while lo < hi do
+		local mid = math.floor((lo + hi) / 2)
+		if from == nil or times[mid] < from then
+			lo = mid + 1
+		else
+			hi = mid
+		end
+	end
+
+	if from ~= nil and lo <= #times and times[lo] <= to then
+		deleted = deleted + redis.call("DEL", key)
+		redis.call("ZREM", KEYS[1], key)
+		redis.call("HDEL", KEYS[2], key)
+		redis.call("ZREM", KEYS[3], key)
+	end
+end
+return deleted
+`)
+
+// SetRange adds a value to the cache that was computed over [from, to]
+func (c *RedisCache) SetRange(ctx context.Context, key string, value interface{}, expiration time.Duration, from, to time.Time) {
+	ctx, cancel := writeContext(ctx)
+	defer cancel()
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		c.reportError("set", key, err)
+		return
+	}
+
+	now := time.Now()
+	keys := []string{key, rangeIndexTo, rangeIndexFrom, rangeIndexExpires}
+	ctx, span := startRedisSpan(ctx, "SET", key)
+	err = setRangeScript.Run(ctx, c.client, keys, data, expiration.Milliseconds(), from.UnixMilli(), to.UnixMilli(),
+		now.Add(expiration).UnixMilli(), now.UnixMilli(), rangeIndexPruneLimit).Err()
+	endRedisSpan(span, err)
+	if err != nil {
+		logging.FromContext(ctx).Warn("Failed to cache result with its range", "key", key, "error", err)
+		c.reportError("set", key, err)
+	}
+}
+
+// GetEntry retrieves a value from the cache with its remaining TTL and, if it was set
+// with SetRange, its range
+func (c *RedisCache) GetEntry(ctx context.Context, key string) (CacheEntry, bool) {
+	ctx, cancel := context.WithTimeout(ctx, 1*time.Second)
+	defer cancel()
+
+	ctx, span := startRedisSpan(ctx, "GET", key)
+	defer span.End()
+
+	var get *redis.StringCmd
+	var ttl *redis.DurationCmd
+	var from *redis.StringCmd
+	var to *redis.FloatCmd
+	_, err := c.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		get = pipe.Get(ctx, key)
+		ttl = pipe.PTTL(ctx, key)
+		from = pipe.HGet(ctx, rangeIndexFrom, key)
+		to = pipe.ZScore(ctx, rangeIndexTo, key)
+		return nil
+	})
+	if err != nil && err != redis.Nil {
+		recordError(span, err)
+		c.reportError("get", key, err)
+		return CacheEntry{}, false
+	}
+
+	val, err := get.Bytes()
+	span.SetAttributes(attribute.Bool("cache.hit", err == nil))
+	if err != nil {
+		return CacheEntry{}, false
+	}
+	var entry CacheEntry
+	if err := json.Unmarshal(val, &entry.Value); err != nil {
+		c.reportError("get", key, err)
+		return CacheEntry{}, false
+	}
+
+	// PTTL is negative for a key without an expiry
+	if remaining := ttl.Val(); remaining > 0 {
+		entry.TTL = remaining
+	}
+	fromMillis, fromErr := from.Int64()
+	toMillis, toErr := to.Result()
+	if fromErr == nil && toErr == nil {
+		entry.From = time.UnixMilli(fromMillis).UTC()
+		entry.To = time.UnixMilli(int64(toMillis)).UTC()
+	}
+	return entry, true
+}
+
+// InvalidateTimes removes every entry whose range covers one of times
+func (c *RedisCache) InvalidateTimes(ctx context.Context, times ...time.Time) int {
+	if len(times) == 0 {
+		return 0
+	}
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+	defer cancel()
+
+	millis := make([]int64, 0, len(times))
+	for _, t := range times {
+		millis = append(millis, t.UnixMilli())
+	}
+	sort.Slice(millis, func(i, j int) bool { return millis[i] < millis[j] })
+
+	args := make([]interface{}, len(millis))
+	for i, ms := range millis {
+		args[i] = ms
+	}
+
+	ctx, span := startRedisSpan(ctx, "invalidate", "")
+	deleted, err := invalidateTimesScript.Run(ctx, c.client, []string{rangeIndexTo, rangeIndexFrom, rangeIndexExpires}, args...).Int()
+	span.SetAttributes(attribute.Int("cache.removed", deleted))
+	endRedisSpan(span, err)
+	if err != nil {
+		logging.FromContext(ctx).Warn("Failed to invalidate cached ranges", "error", err)
+		// No one cache key failed, so the error is counted under the operation's name
+		c.reportError("invalidate", "invalidate", err)
+		return 0
+	}
+	return deleted
+}
+
+// Ensure RedisCache implements RangeCache and EntryCache
+var (
+	_ RangeCache = (*RedisCache)(nil)
+	_ EntryCache = (*RedisCache)(nil)
+)
@@ -0,0 +1,109 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedisCache_RangeIndex_WithMiniRedis(t *testing.T) {
+	ctx := context.Background()
+	// Start a miniredis server
+	s, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("Failed to start miniredis: %v", err)
+	}
+	defer s.Close()
+
+	cache, err := NewRedisCache("redis://" + s.Addr())
+	assert.NoError(t, err)
+	defer cache.Close()
+
+	january := [2]time.Time{time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2023, 1, 31, 0, 0, 0, 0, time.UTC)}
+	february := [2]time.Time{time.Date(2023, 2, 1, 0, 0, 0, 0, time.UTC), time.Date(2023, 2, 28, 0, 0, 0, 0, time.UTC)}
+	year := [2]time.Time{time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2023, 12, 31, 0, 0, 0, 0, time.UTC)}
+
+	setup := func() {
+		s.FlushAll()
+		cache.SetRange(ctx, "ggr:january", []string{"jan"}, time.Minute, january[0], january[1])
+		cache.SetRange(ctx, "ggr:february", []string{"feb"}, time.Minute, february[0], february[1])
+		cache.SetRange(ctx, "ggr:year", []string{"year"}, time.Minute, year[0], year[1])
+		cache.Set(ctx, "unranged", "value", time.Minute)
+	}
+
+	t.Run("set range stores the value like Set", func(t *testing.T) {
+		setup()
+
+		value, found := cache.Get(ctx, "ggr:january")
+
+		assert.True(t, found)
+		assert.Equal(t, []interface{}{"jan"}, value)
+		assert.Equal(t, time.Minute, s.TTL("ggr:january"))
+	})
+
+	t.Run("removes entries whose range covers the time", func(t *testing.T) {
+		// Arrange
+		setup()
+
+		// Act
+		removed := cache.InvalidateTimes(ctx, time.Date(2023, 1, 15, 12, 0, 0, 0, time.UTC))
+
+		// Assert
+		assert.Equal(t, 2, removed)
+		assert.False(t, s.Exists("ggr:january"))
+		assert.False(t, s.Exists("ggr:year"))
+		assert.True(t, s.Exists("ggr:february"))
+		assert.True(t, s.Exists("unranged"))
+	})
+
+	t.Run("checks every time in any order", func(t *testing.T) {
+		setup()
+
+		removed := cache.InvalidateTimes(ctx, time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC), february[1], time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC))
+
+		assert.Equal(t, 2, removed)
+		assert.True(t, s.Exists("ggr:january"))
+	})
+
+	t.Run("includes both ends of the range", func(t *testing.T) {
+		setup()
+
+		assert.Equal(t, 2, cache.InvalidateTimes(ctx, january[1]))
+	})
+
+	t.Run("removes index entries of invalidated keys", func(t *testing.T) {
+		setup()
+
+		cache.InvalidateTimes(ctx, february[0])
+
+		members, err := s.ZMembers(rangeIndexTo)
+		assert.NoError(t, err)
+		assert.ElementsMatch(t, []string{"ggr:january"}, members)
+		assert.Empty(t, s.HGet(rangeIndexFrom, "ggr:year"))
+	})
+
+	t.Run("prunes index entries of expired keys", func(t *testing.T) {
+		// Arrange
+		s.FlushAll()
+		cache.SetRange(ctx, "short", "value", time.Millisecond*10, january[0], january[1])
+		time.Sleep(20 * time.Millisecond)
+		s.FastForward(time.Second)
+
+		// Act
+		cache.SetRange(ctx, "long", "value", time.Minute, january[0], january[1])
+
+		// Assert
+		members, err := s.ZMembers(rangeIndexTo)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"long"}, members)
+	})
+
+	t.Run("does nothing without times", func(t *testing.T) {
+		setup()
+
+		assert.Equal(t, 0, cache.InvalidateTimes(ctx, ))
+	})
+}
@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"admin-statistics-api/internal/logging"
+	"admin-statistics-api/internal/model"
+	"github.com/go-redis/redis/v8"
+)
+
+// rateLimitKeyPrefix namespaces rate limiter state among the cached responses
+const rateLimitKeyPrefix = "ratelimit:"
+
+// takeTokenScript is takeToken run atomically in Redis, with times in microseconds.
+// It returns {allowed, retryAfter, resetAfter} and stores the new TAT until it passes.
+// The TAT is formatted explicitly because Lua writes large numbers in exponent form.
+var takeTokenScript = redis.NewScript(`
+local now = tonumber(ARGV[1])
+local interval = tonumber(ARGV[2])
+local period = tonumber(ARGV[3])
+local tat = tonumber(redis.call("GET", KEYS[1]) or now)
+if tat < now then
+	tat = now
+end
+
+local new_tat = tat + interval
+local allow_at = new_tat - period
+if now < allow_at then
+	return {0, allow_at - now, tat - now}
+end
+
+redis.call("SET", KEYS[1], string.format("%.0f", new_tat), "PX", math.ceil((new_tat - now) / 1000))
+return {1, 0, new_tat - now}
+`)
+
+// RedisRateLimiter is a token bucket rate limiter shared by every instance through Redis.
+// While Redis cannot be reached it falls back to limiting in process memory.
+type RedisRateLimiter struct {
+	client   *redis.Client
+	fallback *MemoryRateLimiter
+	inMemory atomic.Bool // Whether the last request was limited in memory, so only changes are logged
+	now      func() time.Time
+}
+
+// NewRedisRateLimiter creates a RedisRateLimiter that uses the cache's Redis client
+func NewRedisRateLimiter(cache *RedisCache) *RedisRateLimiter {
+	return &RedisRateLimiter{
+		client:   cache.client,
+		fallback: NewMemoryRateLimiter(),
+		now:      time.Now,
+	}
+}
+
+// Allow takes one request from the bucket for key
+func (l *RedisRateLimiter) Allow(ctx context.Context, key string, limit model.RateLimit) (RateLimitResult, error) {
+	ctx, cancel := context.WithTimeout(ctx, 1*time.Second)
+	defer cancel()
+
+	values, err := takeTokenScript.Run(ctx, l.client, []string{rateLimitKeyPrefix + key},
+		l.now().UnixMicro(), limit.Interval().Microseconds(), limit.Period.Microseconds()).Int64Slice()
+	if err != nil || len(values) != 3 {
+		if l.inMemory.CompareAndSwap(false, true) {
+			logging.FromContext(ctx).Warn("Redis rate limiter unavailable, limiting in memory until it recovers", "error", err)
+		}
+		return l.fallback.Allow(ctx, key, limit)
+	}
+	if l.inMemory.CompareAndSwap(true, false) {
+		logging.FromContext(ctx).Info("Redis rate limiter available again")
+	}
+
+	resetAfter := time.Duration(values[2]) * time.Microsecond
+	if values[0] == 0 {
+		return RateLimitResult{
+			Allowed:    false,
+			Limit:      limit.Requests,
+			Remaining:  0,
+			RetryAfter: time.Duration(values[1]) * time.Microsecond,
+			ResetAfter: resetAfter,
+		}, nil
+	}
+	return newRateLimitResult(resetAfter, limit), nil
+}
+
+// Ensure RedisRateLimiter implements RateLimiter
+var _ RateLimiter = (*RedisRateLimiter)(nil)
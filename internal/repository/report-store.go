@@ -0,0 +1,172 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"slices"
+	"time"
+
+	"admin-statistics-api/internal/model"
+	"github.com/go-redis/redis/v8"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MongoReportStore stores report jobs in a MongoDB collection, which a TTL index on
+// expiresAt clears of expired jobs
+type MongoReportStore struct {
+	collection *mongo.Collection
+}
+
+// NewMongoReportStore creates a new MongoReportStore
+func NewMongoReportStore(db *mongo.Database, collectionName string) *MongoReportStore {
+	return &MongoReportStore{
+		collection: db.Collection(collectionName),
+	}
+}
+
+// EnsureIndexes creates the TTL index that removes jobs once they expire
+func (s *MongoReportStore) EnsureIndexes(ctx context.Context) error {
+	_, err := s.collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "expiresAt", Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	})
+	return err
+}
+
+// CreateReport stores a new report job
+func (s *MongoReportStore) CreateReport(ctx context.Context, job model.ReportJob) error {
+	_, err := s.collection.InsertOne(ctx, job)
+	return err
+}
+
+// FindReport finds a report job by ID. MongoDB removes expired jobs only once a
+// minute, so they are filtered out here as well.
+func (s *MongoReportStore) FindReport(ctx context.Context, id string) (model.ReportJob, error) {
+	var job model.ReportJob
+	err := s.collection.FindOne(ctx, bson.M{"_id": id, "expiresAt": bson.M{"$gt": time.Now()}}).Decode(&job)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return model.ReportJob{}, ErrReportNotFound
+	}
+	return job, err
+}
+
+// UpdateReport replaces a report job while its status is one of from
+func (s *MongoReportStore) UpdateReport(ctx context.Context, job model.ReportJob, from ...string) error {
+	result, err := s.collection.ReplaceOne(ctx, bson.M{"_id": job.ID, "status": bson.M{"$in": from}}, job)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount > 0 {
+		return nil
+	}
+
+	// Tell a job in another status apart from a missing one
+	count, err := s.collection.CountDocuments(ctx, bson.M{"_id": job.ID})
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrReportNotFound
+	}
+	return ErrReportStatusChanged
+}
+
+// DeleteReport removes a report job
+func (s *MongoReportStore) DeleteReport(ctx context.Context, id string) error {
+	_, err := s.collection.DeleteOne(ctx, bson.M{"_id": id})
+	return err
+}
+
+// reportKeyPrefix namespaces report jobs among the cached responses
+const reportKeyPrefix = "report:"
+
+// RedisReportStore stores each report job as JSON under a key that expires with the job
+type RedisReportStore struct {
+	client *redis.Client
+}
+
+// redisReport is the stored form of a report job, which unlike the API keeps the owner
+type redisReport struct {
+	model.ReportJob
+	Owner string `json:"owner"`
+}
+
+// NewRedisReportStore creates a RedisReportStore that uses the cache's Redis client
+func NewRedisReportStore(cache *RedisCache) *RedisReportStore {
+	return &RedisReportStore{client: cache.client}
+}
+
+// CreateReport stores a new report job
+func (s *RedisReportStore) CreateReport(ctx context.Context, job model.ReportJob) error {
+	data, err := json.Marshal(redisReport{ReportJob: job, Owner: job.Owner})
+	if err != nil {
+		return err
+	}
+	return s.client.Set(ctx, reportKeyPrefix+job.ID, data, reportExpiration(job)).Err()
+}
+
+// FindReport finds a report job by ID
+func (s *RedisReportStore) FindReport(ctx context.Context, id string) (model.ReportJob, error) {
+	return s.get(ctx, s.client, id)
+}
+
+// UpdateReport replaces a report job while its status is one of from. A job changed
+// by another instance during the update counts as a changed status.
+func (s *RedisReportStore) UpdateReport(ctx context.Context, job model.ReportJob, from ...string) error {
+	key := reportKeyPrefix + job.ID
+	err := s.client.Watch(ctx, func(tx *redis.Tx) error {
+		stored, err := s.get(ctx, tx, job.ID)
+		if err != nil {
+			return err
+		}
+		if !slices.Contains(from, stored.Status) {
+			return ErrReportStatusChanged
+		}
+
+		data, err := json.Marshal(redisReport{ReportJob: job, Owner: job.Owner})
+		if err != nil {
+			return err
+		}
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.Set(ctx, key, data, reportExpiration(job))
+			return nil
+		})
+		return err
+	}, key)
+	if errors.Is(err, redis.TxFailedErr) {
+		return ErrReportStatusChanged
+	}
+	return err
+}
+
+// DeleteReport removes a report job
+func (s *RedisReportStore) DeleteReport(ctx context.Context, id string) error {
+	return s.client.Del(ctx, reportKeyPrefix+id).Err()
+}
+
+// get reads a report job through client, which is the store's client or a transaction
+func (s *RedisReportStore) get(ctx context.Context, client redis.Cmdable, id string) (model.ReportJob, error) {
+	data, err := client.Get(ctx, reportKeyPrefix+id).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return model.ReportJob{}, ErrReportNotFound
+	}
+	if err != nil {
+		return model.ReportJob{}, err
+	}
+
+	var stored redisReport
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return model.ReportJob{}, err
+	}
+	stored.ReportJob.Owner = stored.Owner
+	return stored.ReportJob, nil
+}
+
+// reportExpiration returns how long Redis should keep a job, at least a second so that
+// the key is not stored without an expiry
+func reportExpiration(job model.ReportJob) time.Duration {
+	return max(time.Until(job.ExpiresAt), time.Second)
+}
@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"admin-statistics-api/internal/model"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedisReportStore_WithMiniRedis(t *testing.T) {
+	// Start a miniredis server
+	s, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("Failed to start miniredis: %v", err)
+	}
+	defer s.Close()
+
+	cache, err := NewRedisCache("redis://" + s.Addr())
+	assert.NoError(t, err)
+	defer cache.Close()
+
+	ctx := context.Background()
+	store := NewRedisReportStore(cache)
+
+	newJob := func(id string) model.ReportJob {
+		return model.ReportJob{
+			ID:        id,
+			Type:      model.ReportTypeDailyWagerVolume,
+			Owner:     "default",
+			Status:    model.ReportStatusQueued,
+			CreatedAt: time.Now().UTC().Truncate(time.Second),
+			ExpiresAt: time.Now().Add(time.Hour),
+		}
+	}
+
+	t.Run("finds a created job with its owner", func(t *testing.T) {
+		// Arrange
+		job := newJob("created")
+
+		// Act
+		err := store.CreateReport(ctx, job)
+		found, findErr := store.FindReport(ctx, job.ID)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.NoError(t, findErr)
+		assert.Equal(t, "default", found.Owner)
+		assert.Equal(t, model.ReportStatusQueued, found.Status)
+		assert.True(t, s.Exists("report:created"))
+	})
+
+	t.Run("updates a job only from the expected status", func(t *testing.T) {
+		// Arrange
+		job := newJob("updated")
+		store.CreateReport(ctx, job)
+		job.Status = model.ReportStatusRunning
+
+		// Act
+		err := store.UpdateReport(ctx, job, model.ReportStatusQueued)
+		job.Status = model.ReportStatusSucceeded
+		errAgain := store.UpdateReport(ctx, job, model.ReportStatusQueued)
+		found, _ := store.FindReport(ctx, job.ID)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.ErrorIs(t, errAgain, ErrReportStatusChanged)
+		assert.Equal(t, model.ReportStatusRunning, found.Status)
+	})
+
+	t.Run("reports a missing job", func(t *testing.T) {
+		_, err := store.FindReport(ctx, "missing")
+		updateErr := store.UpdateReport(ctx, newJob("missing"), model.ReportStatusQueued)
+
+		assert.ErrorIs(t, err, ErrReportNotFound)
+		assert.ErrorIs(t, updateErr, ErrReportNotFound)
+	})
+
+	t.Run("job expires at its expiry", func(t *testing.T) {
+		// Arrange
+		job := newJob("expiring")
+		job.ExpiresAt = time.Now().Add(time.Minute)
+		store.CreateReport(ctx, job)
+
+		// Act
+		s.FastForward(2 * time.Minute)
+		_, err := store.FindReport(ctx, job.ID)
+
+		// Assert
+		assert.ErrorIs(t, err, ErrReportNotFound)
+	})
+
+	t.Run("deletes a job", func(t *testing.T) {
+		job := newJob("deleted")
+		store.CreateReport(ctx, job)
+
+		err := store.DeleteReport(ctx, job.ID)
+		_, findErr := store.FindReport(ctx, job.ID)
+
+		assert.NoError(t, err)
+		assert.ErrorIs(t, findErr, ErrReportNotFound)
+	})
+}
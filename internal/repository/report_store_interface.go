@@ -0,0 +1,25 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"admin-statistics-api/internal/model"
+)
+
+// ErrReportNotFound is returned when no report job has the requested ID, or it expired
+var ErrReportNotFound = errors.New("report not found")
+
+// ErrReportStatusChanged is returned when a report job is not in the status an update
+// expected, e.g. because it was cancelled while running
+var ErrReportStatusChanged = errors.New("report status changed")
+
+// ReportStore defines the interface for report job storage. Jobs are removed once their
+// ExpiresAt passes.
+type ReportStore interface {
+	CreateReport(ctx context.Context, job model.ReportJob) error
+	FindReport(ctx context.Context, id string) (model.ReportJob, error)
+	// UpdateReport replaces a job, but only while its stored status is one of from
+	UpdateReport(ctx context.Context, job model.ReportJob, from ...string) error
+	DeleteReport(ctx context.Context, id string) error
+}
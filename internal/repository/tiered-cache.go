@@ -0,0 +1,99 @@
+package repository
+
+import (
+	"context"
+	"time"
+)
+
+// TieredCache is a two-tier cache: a MemoryCache (L1) in front of a shared cache such
+// as Redis (L2). Reads are served from L1 when possible and L2 hits are copied into it.
+//
+// Entries stay in L1 for at most l1TTL, since other instances can only update or
+// invalidate the shared tier.
+type TieredCache struct {
+	l1    *MemoryCache
+	l2    Cache
+	l1TTL time.Duration
+}
+
+// NewTieredCache creates a new TieredCache that keeps entries in l1 for at most l1TTL
+func NewTieredCache(l1 *MemoryCache, l2 Cache, l1TTL time.Duration) *TieredCache {
+	return &TieredCache{
+		l1:    l1,
+		l2:    l2,
+		l1TTL: l1TTL,
+	}
+}
+
+// Get retrieves a value from L1, or from L2 and copies it into L1. The copy keeps the
+// range of the L2 entry, so that InvalidateTimes finds it, and expires with it.
+func (c *TieredCache) Get(ctx context.Context, key string) (interface{}, bool) {
+	if value, found := c.l1.Get(ctx, key); found {
+		return value, true
+	}
+
+	entry, found := c.getL2(ctx, key)
+	if !found {
+		return nil, false
+	}
+
+	ttl := c.l1TTL
+	if entry.TTL > 0 {
+		ttl = min(ttl, entry.TTL)
+	}
+	if entry.From.IsZero() {
+		c.l1.Set(ctx, key, entry.Value, ttl)
+	} else {
+		c.l1.SetRange(ctx, key, entry.Value, ttl, entry.From, entry.To)
+	}
+	return entry.Value, true
+}
+
+// getL2 retrieves an entry from L2, with its TTL and range when L2 keeps them
+func (c *TieredCache) getL2(ctx context.Context, key string) (CacheEntry, bool) {
+	if entryCache, ok := c.l2.(EntryCache); ok {
+		return entryCache.GetEntry(ctx, key)
+	}
+	value, found := c.l2.Get(ctx, key)
+	return CacheEntry{Value: value}, found
+}
+
+// Set adds a value to both tiers
+func (c *TieredCache) Set(ctx context.Context, key string, value interface{}, expiration time.Duration) {
+	c.l2.Set(ctx, key, value, expiration)
+	c.l1.Set(ctx, key, value, min(expiration, c.l1TTL))
+}
+
+// SetRange adds a value to both tiers that was computed over [from, to]
+func (c *TieredCache) SetRange(ctx context.Context, key string, value interface{}, expiration time.Duration, from, to time.Time) {
+	if rangeCache, ok := c.l2.(RangeCache); ok {
+		rangeCache.SetRange(ctx, key, value, expiration, from, to)
+	} else {
+		c.l2.Set(ctx, key, value, expiration)
+	}
+	c.l1.SetRange(ctx, key, value, min(expiration, c.l1TTL), from, to)
+}
+
+// InvalidateTimes removes every entry whose range covers one of times from both tiers
+// and returns how many were removed in total
+func (c *TieredCache) InvalidateTimes(ctx context.Context, times ...time.Time) int {
+	removed := c.l1.InvalidateTimes(ctx, times...)
+	if rangeCache, ok := c.l2.(RangeCache); ok {
+		removed += rangeCache.InvalidateTimes(ctx, times...)
+	}
+	return removed
+}
+
+// Delete removes a value from both tiers
+func (c *TieredCache) Delete(ctx context.Context, key string) {
+	c.l2.Delete(ctx, key)
+	c.l1.Delete(ctx, key)
+}
+
+// Stats returns the counters of the L1 cache
+func (c *TieredCache) Stats() CacheStats {
+	return c.l1.Stats()
+}
+
+// Ensure TieredCache implements RangeCache
+var _ RangeCache = (*TieredCache)(nil)
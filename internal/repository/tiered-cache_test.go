@@ -0,0 +1,137 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTieredCache_WithMiniRedis(t *testing.T) {
+	ctx := context.Background()
+	// Start a miniredis server
+	s, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("Failed to start miniredis: %v", err)
+	}
+	defer s.Close()
+
+	redisCache, err := NewRedisCache("redis://" + s.Addr())
+	assert.NoError(t, err)
+	defer redisCache.Close()
+
+	setup := func() (*TieredCache, *MemoryCache) {
+		s.FlushAll()
+		l1 := NewMemoryCache()
+		t.Cleanup(func() { l1.Close() })
+		return NewTieredCache(l1, redisCache, time.Minute), l1
+	}
+
+	t.Run("set writes both tiers", func(t *testing.T) {
+		// Arrange
+		cache, l1 := setup()
+
+		// Act
+		cache.Set(ctx, "key", "value", time.Hour)
+
+		// Assert
+		_, foundL1 := l1.Get(ctx, "key")
+		_, foundL2 := redisCache.Get(ctx, "key")
+		assert.True(t, foundL1)
+		assert.True(t, foundL2)
+		assert.Equal(t, time.Hour, s.TTL("key"))
+	})
+
+	t.Run("an L2 hit is copied into L1", func(t *testing.T) {
+		cache, l1 := setup()
+		redisCache.Set(ctx, "key", "value", time.Hour)
+
+		value, found := cache.Get(ctx, "key")
+
+		assert.True(t, found)
+		assert.Equal(t, "value", value)
+		_, foundL1 := l1.Get(ctx, "key")
+		assert.True(t, foundL1)
+	})
+
+	t.Run("an L2 hit expires from L1 with the L2 entry", func(t *testing.T) {
+		cache, l1 := setup()
+		redisCache.Set(ctx, "key", "value", 50*time.Millisecond)
+
+		cache.Get(ctx, "key")
+		time.Sleep(100 * time.Millisecond)
+
+		// miniredis only expires keys on FastForward, so L2 still has it
+		_, foundL1 := l1.Get(ctx, "key")
+		_, foundL2 := redisCache.Get(ctx, "key")
+		assert.False(t, foundL1)
+		assert.True(t, foundL2)
+	})
+
+	t.Run("an L2 hit copied into L1 is invalidated by its range", func(t *testing.T) {
+		// Arrange
+		cache, l1 := setup()
+		from := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+		to := time.Date(2023, 1, 31, 0, 0, 0, 0, time.UTC)
+		redisCache.SetRange(ctx, "ggr:january", "jan", time.Hour, from, to)
+		_, promoted := cache.Get(ctx, "ggr:january")
+
+		// Act
+		cache.InvalidateTimes(ctx, time.Date(2023, 1, 15, 0, 0, 0, 0, time.UTC))
+		_, found := cache.Get(ctx, "ggr:january")
+
+		// Assert
+		assert.True(t, promoted)
+		assert.False(t, found)
+		_, foundL1 := l1.Get(ctx, "ggr:january")
+		assert.False(t, foundL1)
+	})
+
+	t.Run("an L1 hit does not read L2", func(t *testing.T) {
+		cache, l1 := setup()
+		l1.Set(ctx, "key", "local", time.Hour)
+		redisCache.Set(ctx, "key", "shared", time.Hour)
+
+		value, found := cache.Get(ctx, "key")
+
+		assert.True(t, found)
+		assert.Equal(t, "local", value)
+	})
+
+	t.Run("a miss in both tiers", func(t *testing.T) {
+		cache, _ := setup()
+
+		_, found := cache.Get(ctx, "missing")
+
+		assert.False(t, found)
+	})
+
+	t.Run("delete removes both tiers", func(t *testing.T) {
+		cache, l1 := setup()
+		cache.Set(ctx, "key", "value", time.Hour)
+
+		cache.Delete(ctx, "key")
+
+		_, foundL1 := l1.Get(ctx, "key")
+		_, foundL2 := redisCache.Get(ctx, "key")
+		assert.False(t, foundL1)
+		assert.False(t, foundL2)
+	})
+
+	t.Run("invalidate times removes ranged entries from both tiers", func(t *testing.T) {
+		cache, l1 := setup()
+		from := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+		to := time.Date(2023, 1, 31, 0, 0, 0, 0, time.UTC)
+		cache.SetRange(ctx, "ggr:january", "jan", time.Hour, from, to)
+
+		removed := cache.InvalidateTimes(ctx, time.Date(2023, 1, 15, 0, 0, 0, 0, time.UTC))
+
+		assert.Equal(t, 2, removed)
+		_, foundL1 := l1.Get(ctx, "ggr:january")
+		_, foundL2 := redisCache.Get(ctx, "ggr:january")
+		assert.False(t, foundL1)
+		assert.False(t, foundL2)
+	})
+}
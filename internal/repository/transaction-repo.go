@@ -2,16 +2,35 @@ package repository
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"time"
 
+	"admin-statistics-api/internal/logging"
 	"admin-statistics-api/internal/model"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// duplicateKeyErrorCode is the MongoDB server error code for a unique index violation
+const duplicateKeyErrorCode = 11000
+
+// tracer records the spans of MongoDB and Redis operations
+var tracer = otel.Tracer("admin-statistics-api/internal/repository")
+
+// holdPercentPlaces is the number of decimal places the hold percentage is rounded to
+const holdPercentPlaces = 2
+
 // TransactionRepository handles transaction data operations
 type TransactionRepository struct {
-	collection *mongo.Collection
+	collection  *mongo.Collection
+	rollups     *mongo.Collection // Daily rollups, nil when disabled
+	readRollups bool              // Whether queries may read from rollups
 }
 
 // NewTransactionRepository creates a new TransactionRepository
@@ -21,39 +40,312 @@ func NewTransactionRepository(db *mongo.Database, collectionName string) *Transa
 	}
 }
 
+// aggregateAll runs pipeline on collection and decodes every result into results,
+// recording a span named after the pipeline
+func aggregateAll(ctx context.Context, collection *mongo.Collection, name string, pipeline interface{}, results interface{}) error {
+	ctx, span := startAggregateSpan(ctx, collection, name)
+	defer span.End()
+	defer logQuery(ctx, "aggregate "+name, time.Now())
+
+	cursor, err := collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		recordError(span, err)
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	if err := cursor.All(ctx, results); err != nil {
+		recordError(span, err)
+		return err
+	}
+	return nil
+}
+
+// aggregateCursor runs pipeline on collection like aggregateAll, but returns a cursor over
+// the results instead of decoding them. The span and the query's logged time last until
+// the cursor is closed.
+func aggregateCursor(ctx context.Context, collection *mongo.Collection, name string, pipeline interface{}) (RowCursor, error) {
+	ctx, span := startAggregateSpan(ctx, collection, name)
+	start := time.Now()
+
+	cursor, err := collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		recordError(span, err)
+		span.End()
+		logQuery(ctx, "aggregate "+name, start)
+		return nil, err
+	}
+
+	return &queryCursor{Cursor: cursor, ctx: ctx, span: span, operation: "aggregate " + name, start: start}, nil
+}
+
+// startAggregateSpan starts the span of an aggregation named after its pipeline
+func startAggregateSpan(ctx context.Context, collection *mongo.Collection, name string) (context.Context, trace.Span) {
+	return tracer.Start(ctx, "mongodb.aggregate "+name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("db.system", "mongodb"),
+			attribute.String("db.operation", "aggregate"),
+			attribute.String("db.mongodb.collection", collection.Name()),
+			attribute.String("db.mongodb.pipeline", name)))
+}
+
+// recordError marks span as failed with err
+func recordError(span trace.Span, err error) {
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
+
+// queryCursor is the cursor of a streamed aggregation, which ends its span and logs the
+// query when closed
+type queryCursor struct {
+	*mongo.Cursor
+	ctx       context.Context
+	span      trace.Span
+	operation string
+	start     time.Time
+}
+
+// Close closes the cursor, then ends its span and logs the query
+func (c *queryCursor) Close(ctx context.Context) error {
+	if err := c.Cursor.Err(); err != nil {
+		recordError(c.span, err)
+	}
+	err := c.Cursor.Close(ctx)
+	c.span.End()
+	logQuery(c.ctx, c.operation, c.start)
+	return err
+}
+
+// logQuery adds the time since start to the MongoDB time logged for the request and
+// logs the query at debug level
+func logQuery(ctx context.Context, operation string, start time.Time) {
+	elapsed := time.Since(start)
+	logging.AddDuration(ctx, "mongo_ms", elapsed)
+	logging.FromContext(ctx).Debug("MongoDB query", "operation", operation,
+		"duration_ms", float64(elapsed.Microseconds())/1000)
+}
+
 // InsertMany inserts multiple transactions
 func (r *TransactionRepository) InsertMany(ctx context.Context, transactions []interface{}) error {
 	_, err := r.collection.InsertMany(ctx, transactions)
 	return err
 }
 
-// CalculateGGR calculates the Gross Gaming Revenue for a given time period
-func (r *TransactionRepository) CalculateGGR(ctx context.Context, from, to time.Time) ([]bson.M, error) {
-	pipeline := mongo.Pipeline{
-		// Match transactions within the given time period
-		{
-			{"$match", bson.M{
-				"createdAt": bson.M{
-					"$gte": from,
-					"$lte": to,
+// InsertTransactions inserts transactions and returns how many were newly written.
+// Transactions whose _id already exists are skipped rather than treated as errors,
+// so callers can safely retry a batch that was partially or fully written before.
+// Newly written transactions are then added to the daily rollups, if enabled. Their days
+// are marked dirty for RepairDailyRollups if that update fails, and days before today are
+// marked in any case, since a backfill of them may race with the update.
+func (r *TransactionRepository) InsertTransactions(ctx context.Context, transactions []model.Transaction) (int, error) {
+	if len(transactions) == 0 {
+		return 0, nil
+	}
+
+	docs := make([]interface{}, len(transactions))
+	for i, transaction := range transactions {
+		docs[i] = transaction
+	}
+
+	// Unordered so that one duplicate does not stop the rest of the batch
+	insertCtx, span := tracer.Start(ctx, "mongodb.insert transactions",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("db.system", "mongodb"),
+			attribute.String("db.operation", "insert"),
+			attribute.String("db.mongodb.collection", r.collection.Name()),
+			attribute.Int("db.mongodb.documents", len(docs))))
+	start := time.Now()
+	_, err := r.collection.InsertMany(insertCtx, docs, options.InsertMany().SetOrdered(false))
+	logQuery(ctx, "insert transactions", start)
+	span.End()
+
+	duplicates := make(map[int]struct{})
+	if err != nil {
+		var bulkErr mongo.BulkWriteException
+		if !errors.As(err, &bulkErr) || bulkErr.WriteConcernError != nil {
+			return 0, err
+		}
+
+		// Only duplicate key errors are expected on retries; anything else is a real failure
+		for _, writeErr := range bulkErr.WriteErrors {
+			if writeErr.Code != duplicateKeyErrorCode {
+				return 0, err
+			}
+			duplicates[writeErr.Index] = struct{}{}
+		}
+	}
+
+	inserted := make([]model.Transaction, 0, len(transactions)-len(duplicates))
+	for i, transaction := range transactions {
+		if _, ok := duplicates[i]; !ok {
+			inserted = append(inserted, transaction)
+		}
+	}
+
+	// The transactions are stored, so a failure here must not make the client retry;
+	// the marks leave the days to RepairDailyRollups
+	dirty := closedRollupDays(inserted, time.Now())
+	if err := r.addToDailyRollups(ctx, inserted); err != nil {
+		logging.FromContext(ctx).Warn("Failed to update daily rollups", "error", err)
+		dirty = inserted
+	}
+	if err := r.markRollupsDirty(ctx, dirty); err != nil {
+		logging.FromContext(ctx).Warn("Failed to mark daily rollups dirty", "error", err)
+	}
+
+	return len(inserted), nil
+}
+
+// CalculateGGR calculates the Gross Gaming Revenue for a given time period.
+// With a zero bucket there is one row per currency for the whole period; otherwise
+// there is one row per time bucket and currency, sorted by bucket.
+func (r *TransactionRepository) CalculateGGR(ctx context.Context, from, to time.Time, bucket model.TimeBucket) ([]model.GGRRow, error) {
+	collection, pipeline := r.ggrPipeline(from, to, bucket)
+
+	results := make([]model.GGRRow, 0)
+	if err := aggregateAll(ctx, collection, "ggr", pipeline, &results); err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// ggrPipeline builds the query of CalculateGGR and returns the collection it runs against
+func (r *TransactionRepository) ggrPipeline(from, to time.Time, bucket model.TimeBucket) (*mongo.Collection, mongo.Pipeline) {
+	collection, pipeline := r.ggrTotalsPipeline(from, to, bucket)
+
+	if bucket.IsZero() {
+		// Calculate GGR (wager - payout)
+		pipeline = append(pipeline, bson.D{
+			{Key: "$project", Value: bson.M{
+				"currency": "$_id",
+				"ggr":      bson.M{"$subtract": bson.A{"$wager", "$payout"}},
+				"ggrUSD":   bson.M{"$subtract": bson.A{"$wagerUSD", "$payoutUSD"}},
+				"_id":      0,
+			}},
+		})
+	} else {
+		// Calculate GGR (wager - payout) per bucket and sort by bucket
+		pipeline = append(pipeline,
+			bson.D{
+				{Key: "$project", Value: bson.M{
+					"date":     "$_id.date",
+					"currency": "$_id.currency",
+					"ggr":      bson.M{"$subtract": bson.A{"$wager", "$payout"}},
+					"ggrUSD":   bson.M{"$subtract": bson.A{"$wagerUSD", "$payoutUSD"}},
+					"_id":      0,
+				}},
+			},
+			bson.D{
+				{Key: "$sort", Value: bson.D{
+					{Key: "date", Value: 1},
+					{Key: "currency", Value: 1},
+				}},
+			},
+		)
+	}
+
+	return collection, pipeline
+}
+
+// CalculateGGRSeries calculates wager, payout, GGR and hold percentage per time bucket and currency,
+// sorted by bucket. A zero bucket defaults to a UTC day.
+func (r *TransactionRepository) CalculateGGRSeries(ctx context.Context, from, to time.Time, bucket model.TimeBucket) ([]model.GGRSeriesRow, error) {
+	collection, pipeline := r.ggrSeriesPipeline(from, to, bucket)
+
+	results := make([]model.GGRSeriesRow, 0)
+	if err := aggregateAll(ctx, collection, "ggr_series", pipeline, &results); err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// ggrSeriesPipeline builds the query of CalculateGGRSeries and returns the collection it
+// runs against
+func (r *TransactionRepository) ggrSeriesPipeline(from, to time.Time, bucket model.TimeBucket) (*mongo.Collection, mongo.Pipeline) {
+	if bucket.IsZero() {
+		bucket.Granularity = model.GranularityDay
+	}
+
+	collection, pipeline := r.ggrTotalsPipeline(from, to, bucket)
+	return collection, append(pipeline,
+		// Calculate GGR (wager - payout) and hold percentage (GGR / wager) per bucket
+		bson.D{
+			{Key: "$project", Value: bson.M{
+				"date":      "$_id.date",
+				"currency":  "$_id.currency",
+				"wager":     1,
+				"payout":    1,
+				"ggr":       bson.M{"$subtract": bson.A{"$wager", "$payout"}},
+				"wagerUSD":  1,
+				"payoutUSD": 1,
+				"ggrUSD":    bson.M{"$subtract": bson.A{"$wagerUSD", "$payoutUSD"}},
+				"holdPercent": bson.M{
+					"$cond": bson.A{
+						bson.M{"$eq": bson.A{"$wager", 0}},
+						nil,
+						bson.M{"$round": bson.A{
+							bson.M{"$multiply": bson.A{
+								bson.M{"$divide": bson.A{
+									bson.M{"$subtract": bson.A{"$wager", "$payout"}},
+									"$wager",
+								}},
+								100,
+							}},
+							holdPercentPlaces,
+						}},
+					},
 				},
+				"_id": 0,
 			}},
 		},
+		bson.D{
+			{Key: "$sort", Value: bson.D{
+				{Key: "date", Value: 1},
+				{Key: "currency", Value: 1},
+			}},
+		},
+	)
+}
+
+// ggrTotalsPipeline matches transactions in the time period and sums wagers and payouts,
+// in native currency and USD, per currency and, unless the bucket is zero, per time bucket.
+// Each output document's _id is the currency, or {currency, date} when bucketed.
+// It returns the collection the pipeline must run against.
+func (r *TransactionRepository) ggrTotalsPipeline(from, to time.Time, bucket model.TimeBucket) (*mongo.Collection, mongo.Pipeline) {
+	// Group keys for each stage, adding the bucket label when a time series was requested
+	typeKey := bson.M{
+		"currency": "$currency",
+		"type":     "$type",
+	}
+	currencyKey := interface{}("$_id.currency")
+	if !bucket.IsZero() {
+		typeKey["date"] = bucketLabel(bucket)
+		currencyKey = bson.M{
+			"currency": "$_id.currency",
+			"date":     "$_id.date",
+		}
+	}
+
+	// Match transactions within the given time period
+	collection, pipeline := r.transactionSource(from, to, bucket, nil)
+
+	return collection, append(pipeline,
 		// Group by currency and type
-		{
-			{"$group", bson.M{
-				"_id": bson.M{
-					"currency": "$currency",
-					"type":     "$type",
-				},
+		bson.D{
+			{Key: "$group", Value: bson.M{
+				"_id":            typeKey,
 				"totalAmount":    bson.M{"$sum": "$amount"},
 				"totalUSDAmount": bson.M{"$sum": "$usdAmount"},
 			}},
 		},
 		// Reshape for wager and payout sums
-		{
-			{"$group", bson.M{
-				"_id": "$_id.currency",
+		bson.D{
+			{Key: "$group", Value: bson.M{
+				"_id": currencyKey,
 				"wager": bson.M{
 					"$sum": bson.M{
 						"$cond": bson.A{
@@ -92,58 +384,41 @@ func (r *TransactionRepository) CalculateGGR(ctx context.Context, from, to time.
 				},
 			}},
 		},
-		// Calculate GGR (wager - payout)
-		{
-			{"$project", bson.M{
-				"currency": "$_id",
-				"ggr":      bson.M{"$subtract": bson.A{"$wager", "$payout"}},
-				"ggrUSD":   bson.M{"$subtract": bson.A{"$wagerUSD", "$payoutUSD"}},
-				"_id":      0,
-			}},
-		},
-	}
+	)
+}
 
-	cursor, err := r.collection.Aggregate(ctx, pipeline)
-	if err != nil {
-		return nil, err
-	}
-	defer cursor.Close(ctx)
+// CalculateDailyWagerVolume calculates wager volume per time bucket, which defaults to a UTC day
+func (r *TransactionRepository) CalculateDailyWagerVolume(ctx context.Context, from, to time.Time, bucket model.TimeBucket) ([]model.DailyWagerRow, error) {
+	collection, pipeline := r.dailyWagerPipeline(from, to, bucket)
 
-	var results []bson.M
-	if err = cursor.All(ctx, &results); err != nil {
+	results := make([]model.DailyWagerRow, 0)
+	if err := aggregateAll(ctx, collection, "daily_wager", pipeline, &results); err != nil {
 		return nil, err
 	}
 
 	return results, nil
 }
 
-// CalculateDailyWagerVolume calculates daily wager volume
-func (r *TransactionRepository) CalculateDailyWagerVolume(ctx context.Context, from, to time.Time) ([]bson.M, error) {
-	pipeline := mongo.Pipeline{
-		// Match wager transactions within the given time period
-		{
-			{"$match", bson.M{
-				"createdAt": bson.M{
-					"$gte": from,
-					"$lte": to,
-				},
-				"type": model.TransactionTypeWager,
-			}},
-		},
-		// Add a date field for grouping by day
-		{
-			{"$addFields", bson.M{
-				"date": bson.M{
-					"$dateToString": bson.M{
-						"format": "%Y-%m-%d",
-						"date":   "$createdAt",
-					},
-				},
+// dailyWagerPipeline builds the query of CalculateDailyWagerVolume and returns the
+// collection it runs against
+func (r *TransactionRepository) dailyWagerPipeline(from, to time.Time, bucket model.TimeBucket) (*mongo.Collection, mongo.Pipeline) {
+	if bucket.IsZero() {
+		bucket.Granularity = model.GranularityDay
+	}
+
+	// Match wager transactions within the given time period
+	collection, pipeline := r.transactionSource(from, to, bucket, bson.M{"type": model.TransactionTypeWager})
+
+	return collection, append(pipeline,
+		// Add a date field for grouping by bucket
+		bson.D{
+			{Key: "$addFields", Value: bson.M{
+				"date": bucketLabel(bucket),
 			}},
 		},
 		// Group by date and currency
-		{
-			{"$group", bson.M{
+		bson.D{
+			{Key: "$group", Value: bson.M{
 				"_id": bson.M{
 					"date":     "$date",
 					"currency": "$currency",
@@ -153,8 +428,8 @@ func (r *TransactionRepository) CalculateDailyWagerVolume(ctx context.Context, f
 			}},
 		},
 		// Reshape for better response format
-		{
-			{"$project", bson.M{
+		bson.D{
+			{Key: "$project", Value: bson.M{
 				"date":           "$_id.date",
 				"currency":       "$_id.currency",
 				"wagerAmount":    1,
@@ -163,34 +438,46 @@ func (r *TransactionRepository) CalculateDailyWagerVolume(ctx context.Context, f
 			}},
 		},
 		// Sort by date
-		{
-			{"$sort", bson.M{
-				"date":     1,
-				"currency": 1,
+		bson.D{
+			{Key: "$sort", Value: bson.D{
+				{Key: "date", Value: 1},
+				{Key: "currency", Value: 1},
 			}},
 		},
-	}
+	)
+}
 
-	cursor, err := r.collection.Aggregate(ctx, pipeline)
-	if err != nil {
-		return nil, err
+// bucketLabel builds the expression that labels a transaction with the start of its
+// time bucket. Buckets are truncated in the bucket's time zone, and weeks start on Monday.
+func bucketLabel(bucket model.TimeBucket) bson.M {
+	truncate := bson.M{
+		"date":     "$createdAt",
+		"unit":     bucket.Granularity,
+		"timezone": bucket.Location(),
 	}
-	defer cursor.Close(ctx)
-
-	var results []bson.M
-	if err = cursor.All(ctx, &results); err != nil {
-		return nil, err
+	if bucket.Granularity == model.GranularityWeek {
+		truncate["startOfWeek"] = "monday"
 	}
 
-	return results, nil
+	return bson.M{
+		"$dateToString": bson.M{
+			"format":   bucket.LabelFormat(),
+			"date":     bson.M{"$dateTrunc": truncate},
+			"timezone": bucket.Location(),
+		},
+	}
 }
 
-// CalculateUserWagerPercentile calculates user's percentile based on total wager amount
+// CalculateUserWagerPercentile calculates user's percentile based on total wager amount.
+// The rank is computed in the database by counting the users whose total is strictly
+// higher than the target user's, so only two numbers are returned to the application
+// regardless of how many users wagered in the period. Users with equal totals share
+// the highest rank among them.
 func (r *TransactionRepository) CalculateUserWagerPercentile(ctx context.Context, userID string, from, to time.Time) (float64, error) {
 	// First, get the user's total wager
 	userWagerPipeline := mongo.Pipeline{
 		{
-			{"$match", bson.M{
+			{Key: "$match", Value: bson.M{
 				"createdAt": bson.M{
 					"$gte": from,
 					"$lte": to,
@@ -200,21 +487,15 @@ func (r *TransactionRepository) CalculateUserWagerPercentile(ctx context.Context
 			}},
 		},
 		{
-			{"$group", bson.M{
+			{Key: "$group", Value: bson.M{
 				"_id":           "$userId",
 				"totalWagerUSD": bson.M{"$sum": "$usdAmount"},
 			}},
 		},
 	}
 
-	userCursor, err := r.collection.Aggregate(ctx, userWagerPipeline)
-	if err != nil {
-		return 0, err
-	}
-	defer userCursor.Close(ctx)
-
 	var userResults []bson.M
-	if err = userCursor.All(ctx, &userResults); err != nil {
+	if err := aggregateAll(ctx, r.collection, "user_wager", userWagerPipeline, &userResults); err != nil {
 		return 0, err
 	}
 
@@ -222,12 +503,12 @@ func (r *TransactionRepository) CalculateUserWagerPercentile(ctx context.Context
 		return 0, nil // User has no wagers in this period
 	}
 
-	//userWagerUSD := userResults[0]["totalWagerUSD"]
+	userWagerUSD := userResults[0]["totalWagerUSD"]
 
-	// Now calculate all users' wagers for ranking
-	allUsersPipeline := mongo.Pipeline{
+	// Now count all users and the users ranked above this one
+	rankPipeline := mongo.Pipeline{
 		{
-			{"$match", bson.M{
+			{Key: "$match", Value: bson.M{
 				"createdAt": bson.M{
 					"$gte": from,
 					"$lte": to,
@@ -236,49 +517,440 @@ func (r *TransactionRepository) CalculateUserWagerPercentile(ctx context.Context
 			}},
 		},
 		{
-			{"$group", bson.M{
+			{Key: "$group", Value: bson.M{
 				"_id":           "$userId",
 				"totalWagerUSD": bson.M{"$sum": "$usdAmount"},
 			}},
 		},
 		{
-			{"$sort", bson.M{
-				"totalWagerUSD": -1, // Higher wagers first
+			{Key: "$facet", Value: bson.M{
+				"total": bson.A{
+					bson.M{"$count": "users"},
+				},
+				"above": bson.A{
+					bson.M{"$match": bson.M{"totalWagerUSD": bson.M{"$gt": userWagerUSD}}},
+					bson.M{"$count": "users"},
+				},
+			}},
+		},
+		{
+			{Key: "$project", Value: bson.M{
+				"totalUsers": bson.M{"$ifNull": bson.A{bson.M{"$arrayElemAt": bson.A{"$total.users", 0}}, 0}},
+				"usersAbove": bson.M{"$ifNull": bson.A{bson.M{"$arrayElemAt": bson.A{"$above.users", 0}}, 0}},
 			}},
 		},
 	}
 
-	allUsersCursor, err := r.collection.Aggregate(ctx, allUsersPipeline)
-	if err != nil {
-		return 0, err
+	var rankResults []struct {
+		TotalUsers int64 `bson:"totalUsers"`
+		UsersAbove int64 `bson:"usersAbove"`
 	}
-	defer allUsersCursor.Close(ctx)
-
-	var allUsersResults []bson.M
-	if err = allUsersCursor.All(ctx, &allUsersResults); err != nil {
+	if err := aggregateAll(ctx, r.collection, "wager_rank", rankPipeline, &rankResults); err != nil {
 		return 0, err
 	}
 
-	totalUsers := len(allUsersResults)
-	if totalUsers == 0 {
+	if len(rankResults) == 0 {
 		return 0, nil
 	}
 
-	// Find user's position
-	userRank := 0
-	for i, result := range allUsersResults {
-		id := result["_id"]
-		if id.(string) == userID {
-			userRank = i + 1
-			break
-		}
+	return wagerPercentile(rankResults[0].UsersAbove, rankResults[0].TotalUsers), nil
+}
+
+// wagerPercentile converts a user's rank into a percentile, where usersAbove is the
+// number of users with a strictly higher total. The top user scores 100.
+func wagerPercentile(usersAbove, totalUsers int64) float64 {
+	if totalUsers == 0 {
+		return 0
 	}
 
 	// Calculate percentile (higher rank = higher percentile)
-	percentile := 100.0 - (float64(userRank-1) / float64(totalUsers) * 100.0)
+	return 100.0 - (float64(usersAbove) / float64(totalUsers) * 100.0)
+}
+
+// CalculateUserSummary calculates a user's activity totals for a given time period.
+// The result holds one row per currency and the USD totals across all currencies;
+// the totals are nil when the user has no transactions.
+func (r *TransactionRepository) CalculateUserSummary(ctx context.Context, userID string, from, to time.Time) (model.UserSummary, error) {
+	sumIfType := func(transactionType string, field interface{}) bson.M {
+		return bson.M{"$sum": bson.M{
+			"$cond": bson.A{
+				bson.M{"$eq": bson.A{"$type", transactionType}},
+				field,
+				0,
+			},
+		}}
+	}
+	averageBet := func(total string) bson.M {
+		return bson.M{"$cond": bson.A{
+			bson.M{"$gt": bson.A{"$wagerCount", 0}},
+			bson.M{"$divide": bson.A{total, "$wagerCount"}},
+			0,
+		}}
+	}
+
+	pipeline := mongo.Pipeline{
+		// Match the user's transactions within the given time period
+		{
+			{Key: "$match", Value: bson.M{
+				"createdAt": bson.M{
+					"$gte": from,
+					"$lte": to,
+				},
+				"userId": userID,
+			}},
+		},
+		// Group by currency
+		{
+			{Key: "$group", Value: bson.M{
+				"_id":        "$currency",
+				"wagered":    sumIfType(model.TransactionTypeWager, "$amount"),
+				"wageredUSD": sumIfType(model.TransactionTypeWager, "$usdAmount"),
+				"paidOut":    sumIfType(model.TransactionTypePayout, "$amount"),
+				"paidOutUSD": sumIfType(model.TransactionTypePayout, "$usdAmount"),
+				"wagerCount": sumIfType(model.TransactionTypeWager, 1),
+				"rounds":     bson.M{"$addToSet": "$roundId"},
+				"firstSeen":  bson.M{"$min": "$createdAt"},
+				"lastSeen":   bson.M{"$max": "$createdAt"},
+			}},
+		},
+		{
+			{Key: "$addFields", Value: bson.M{
+				"rounds": bson.M{"$size": "$rounds"},
+			}},
+		},
+		// Build the per-currency rows and the USD totals in one pass
+		{
+			{Key: "$facet", Value: bson.M{
+				"currencies": bson.A{
+					bson.M{"$project": bson.M{
+						"currency":      "$_id",
+						"wagered":       1,
+						"wageredUSD":    1,
+						"paidOut":       1,
+						"paidOutUSD":    1,
+						"net":           bson.M{"$subtract": bson.A{"$wagered", "$paidOut"}},
+						"netUSD":        bson.M{"$subtract": bson.A{"$wageredUSD", "$paidOutUSD"}},
+						"rounds":        1,
+						"averageBet":    averageBet("$wagered"),
+						"averageBetUSD": averageBet("$wageredUSD"),
+						"firstSeen":     1,
+						"lastSeen":      1,
+						"_id":           0,
+					}},
+					bson.M{"$sort": bson.M{"currency": 1}},
+				},
+				"total": bson.A{
+					bson.M{"$group": bson.M{
+						"_id":        nil,
+						"wageredUSD": bson.M{"$sum": "$wageredUSD"},
+						"paidOutUSD": bson.M{"$sum": "$paidOutUSD"},
+						"wagerCount": bson.M{"$sum": "$wagerCount"},
+						"rounds":     bson.M{"$sum": "$rounds"},
+						"firstSeen":  bson.M{"$min": "$firstSeen"},
+						"lastSeen":   bson.M{"$max": "$lastSeen"},
+					}},
+					bson.M{"$project": bson.M{
+						"wageredUSD":    1,
+						"paidOutUSD":    1,
+						"netUSD":        bson.M{"$subtract": bson.A{"$wageredUSD", "$paidOutUSD"}},
+						"rounds":        1,
+						"averageBetUSD": averageBet("$wageredUSD"),
+						"firstSeen":     1,
+						"lastSeen":      1,
+						"_id":           0,
+					}},
+				},
+			}},
+		},
+		{
+			{Key: "$project", Value: bson.M{
+				"currencies": 1,
+				"total":      bson.M{"$arrayElemAt": bson.A{"$total", 0}},
+			}},
+		},
+	}
+
+	var results []model.UserSummary
+	if err := aggregateAll(ctx, r.collection, "user_summary", pipeline, &results); err != nil {
+		return model.UserSummary{}, err
+	}
+
+	// $facet always produces exactly one document
+	summary := model.UserSummary{}
+	if len(results) > 0 {
+		summary = results[0]
+	}
+	if summary.Currencies == nil {
+		summary.Currencies = []model.UserCurrencySummary{}
+	}
+
+	return summary, nil
+}
+
+// CalculateLeaderboard ranks users by a metric for a given time period and returns the top limit users.
+// Without a currency, users are ranked on USD totals across all currencies; with one, only that
+// currency is considered and users are ranked on its native amounts. Each row carries the user's
+// rank, one more than the number of users strictly above them, and the percentile wagerPercentile
+// gives that count, so tied users share both.
+func (r *TransactionRepository) CalculateLeaderboard(ctx context.Context, metric, currency string, limit int, from, to time.Time) ([]model.LeaderboardRow, error) {
+	results := make([]model.LeaderboardRow, 0)
+	if err := aggregateAll(ctx, r.collection, "leaderboard", leaderboardPipeline(metric, currency, limit, from, to), &results); err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// leaderboardPipeline builds the query of CalculateLeaderboard
+func leaderboardPipeline(metric, currency string, limit int, from, to time.Time) mongo.Pipeline {
+	match := bson.M{
+		"createdAt": bson.M{
+			"$gte": from,
+			"$lte": to,
+		},
+	}
+	if currency != "" {
+		match["currency"] = currency
+	}
+
+	sumIfType := func(transactionType, field string) bson.M {
+		return bson.M{"$sum": bson.M{
+			"$cond": bson.A{
+				bson.M{"$eq": bson.A{"$type", transactionType}},
+				field,
+				0,
+			},
+		}}
+	}
+
+	// Rank on native amounts only when they are comparable, i.e. within one currency
+	sortField := metric + "USD"
+	if currency != "" {
+		sortField = metric
+	}
+
+	pipeline := mongo.Pipeline{
+		// Match transactions within the given time period
+		{
+			{Key: "$match", Value: match},
+		},
+		// Group by user
+		{
+			{Key: "$group", Value: bson.M{
+				"_id":       "$userId",
+				"wager":     sumIfType(model.TransactionTypeWager, "$amount"),
+				"payout":    sumIfType(model.TransactionTypePayout, "$amount"),
+				"wagerUSD":  sumIfType(model.TransactionTypeWager, "$usdAmount"),
+				"payoutUSD": sumIfType(model.TransactionTypePayout, "$usdAmount"),
+			}},
+		},
+		{
+			{Key: "$addFields", Value: bson.M{
+				"ggr":    bson.M{"$subtract": bson.A{"$wager", "$payout"}},
+				"ggrUSD": bson.M{"$subtract": bson.A{"$wagerUSD", "$payoutUSD"}},
+			}},
+		},
+		// Count all ranked users while keeping only the top of the ranking
+		{
+			{Key: "$facet", Value: bson.M{
+				"total": bson.A{
+					bson.M{"$count": "users"},
+				},
+				"top": bson.A{
+					bson.M{"$setWindowFields": bson.M{
+						"sortBy": bson.M{sortField: -1},
+						"output": bson.M{"rank": bson.M{"$rank": bson.M{}}},
+					}},
+					bson.M{"$sort": bson.D{{Key: sortField, Value: -1}, {Key: "_id", Value: 1}}},
+					bson.M{"$limit": limit},
+				},
+			}},
+		},
+		{
+			{Key: "$unwind", Value: "$top"},
+		},
+		// Calculate the percentile from the users above (higher rank = higher percentile)
+		{
+			{Key: "$project", Value: bson.M{
+				"userId":    "$top._id",
+				"rank":      "$top.rank",
+				"wager":     "$top.wager",
+				"payout":    "$top.payout",
+				"ggr":       "$top.ggr",
+				"wagerUSD":  "$top.wagerUSD",
+				"payoutUSD": "$top.payoutUSD",
+				"ggrUSD":    "$top.ggrUSD",
+				"percentile": bson.M{"$subtract": bson.A{
+					100,
+					bson.M{"$multiply": bson.A{
+						bson.M{"$divide": bson.A{
+							bson.M{"$subtract": bson.A{"$top.rank", 1}},
+							bson.M{"$arrayElemAt": bson.A{"$total.users", 0}},
+						}},
+						100,
+					}},
+				}},
+				"_id": 0,
+			}},
+		},
+	}
+
+	// Native amounts of different currencies cannot be added together
+	if currency == "" {
+		pipeline = append(pipeline, bson.D{
+			{Key: "$project", Value: bson.M{
+				"wager":  0,
+				"payout": 0,
+				"ggr":    0,
+			}},
+		})
+	}
+
+	return pipeline
+}
+
+// FindRoundAnomalies finds rounds whose wagers and payouts do not reconcile.
+// A round is included when any of its transactions falls within the time period,
+// and all of its transactions are then considered, so rounds straddling the edges
+// of the period are not reported as missing a wager.
+func (r *TransactionRepository) FindRoundAnomalies(ctx context.Context, from, to time.Time) ([]model.RoundAnomaly, error) {
+	results := make([]model.RoundAnomaly, 0)
+	if err := aggregateAll(ctx, r.collection, "round_anomalies", r.roundAnomaliesPipeline(from, to), &results); err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// roundAnomaliesPipeline builds the query of FindRoundAnomalies
+func (r *TransactionRepository) roundAnomaliesPipeline(from, to time.Time) mongo.Pipeline {
+	isType := func(transactionType string) bson.M {
+		return bson.M{"$filter": bson.M{
+			"input": "$transactions",
+			"as":    "t",
+			"cond":  bson.M{"$eq": bson.A{"$$t.type", transactionType}},
+		}}
+	}
+	flagIf := func(cond bson.M, anomaly string) bson.M {
+		return bson.M{"$cond": bson.A{cond, bson.A{anomaly}, bson.A{}}}
+	}
+
+	return mongo.Pipeline{
+		// Match transactions within the given time period
+		{
+			{Key: "$match", Value: bson.M{
+				"createdAt": bson.M{
+					"$gte": from,
+					"$lte": to,
+				},
+			}},
+		},
+		// Collect the distinct rounds touched in the period
+		{
+			{Key: "$group", Value: bson.M{
+				"_id": "$roundId",
+			}},
+		},
+		// Load every transaction of each round, including those outside the period
+		{
+			{Key: "$lookup", Value: bson.M{
+				"from":         r.collection.Name(),
+				"localField":   "_id",
+				"foreignField": "roundId",
+				"as":           "transactions",
+			}},
+		},
+		// Split the round into wagers and payouts
+		{
+			{Key: "$project", Value: bson.M{
+				"userIds":    bson.M{"$setUnion": bson.A{"$transactions.userId"}},
+				"currencies": bson.M{"$setUnion": bson.A{"$transactions.currency"}},
+				"wagers":     isType(model.TransactionTypeWager),
+				"payouts":    isType(model.TransactionTypePayout),
+			}},
+		},
+		{
+			{Key: "$project", Value: bson.M{
+				"userIds":       1,
+				"currencies":    1,
+				"wagerCount":    bson.M{"$size": "$wagers"},
+				"payoutCount":   bson.M{"$size": "$payouts"},
+				"firstWagerAt":  bson.M{"$min": "$wagers.createdAt"},
+				"firstPayoutAt": bson.M{"$min": "$payouts.createdAt"},
+			}},
+		},
+		// Flag each reconciliation rule the round breaks
+		{
+			{Key: "$addFields", Value: bson.M{
+				"anomalies": bson.M{"$concatArrays": bson.A{
+					flagIf(bson.M{"$and": bson.A{
+						bson.M{"$gt": bson.A{"$payoutCount", 0}},
+						bson.M{"$eq": bson.A{"$wagerCount", 0}},
+					}}, model.RoundAnomalyPayoutWithoutWager),
+					flagIf(bson.M{"$gt": bson.A{"$wagerCount", 1}}, model.RoundAnomalyMultipleWagers),
+					flagIf(bson.M{"$gt": bson.A{bson.M{"$size": "$currencies"}, 1}}, model.RoundAnomalyCurrencyMismatch),
+					flagIf(bson.M{"$and": bson.A{
+						bson.M{"$gt": bson.A{"$wagerCount", 0}},
+						bson.M{"$gt": bson.A{"$payoutCount", 0}},
+						bson.M{"$lt": bson.A{"$firstPayoutAt", "$firstWagerAt"}},
+					}}, model.RoundAnomalyPayoutBeforeWager),
+				}},
+			}},
+		},
+		// Keep only rounds with at least one anomaly
+		{
+			{Key: "$match", Value: bson.M{
+				"anomalies.0": bson.M{"$exists": true},
+			}},
+		},
+		// Reshape for better response format
+		{
+			{Key: "$project", Value: bson.M{
+				"roundId":       "$_id",
+				"userIds":       1,
+				"currencies":    1,
+				"wagerCount":    1,
+				"payoutCount":   1,
+				"firstWagerAt":  1,
+				"firstPayoutAt": 1,
+				"anomalies":     1,
+				"_id":           0,
+			}},
+		},
+		// Sort by round
+		{
+			{Key: "$sort", Value: bson.M{
+				"roundId": 1,
+			}},
+		},
+	}
+}
+
+// StreamRows runs the query of a report type whose result is a list of rows, the one its
+// Calculate or Find method runs, and returns a cursor over the rows rather than reading
+// them all. The user summary and percentile are single documents and cannot be streamed.
+// The caller must close the cursor.
+func (r *TransactionRepository) StreamRows(ctx context.Context, reportType string, params model.ReportParams) (RowCursor, error) {
+	collection, pipeline := r.collection, mongo.Pipeline(nil)
+	name := reportType
+	switch reportType {
+	case model.ReportTypeGGR:
+		collection, pipeline = r.ggrPipeline(params.From, params.To, params.TimeBucket())
+	case model.ReportTypeGGRSeries:
+		collection, pipeline = r.ggrSeriesPipeline(params.From, params.To, params.TimeBucket())
+	case model.ReportTypeDailyWagerVolume:
+		collection, pipeline = r.dailyWagerPipeline(params.From, params.To, params.TimeBucket())
+		name = "daily_wager"
+	case model.ReportTypeLeaderboard:
+		pipeline = leaderboardPipeline(params.Metric, params.Currency, params.Limit, params.From, params.To)
+	case model.ReportTypeRoundAnomalies:
+		pipeline = r.roundAnomaliesPipeline(params.From, params.To)
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrNotStreamable, reportType)
+	}
 
-	return percentile, nil
+	return aggregateCursor(ctx, collection, name, pipeline)
 }
 
 // Ensure TransactionRepository implements TransactionRepositoryInterface
-var _ TransactionRepositoryInterface = (*TransactionRepository)(nil)
\ No newline at end of file
+var _ TransactionRepositoryInterface = (*TransactionRepository)(nil)
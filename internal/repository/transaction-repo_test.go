@@ -0,0 +1,587 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"os"
+	"sort"
+	"strconv"
+	"testing"
+	"time"
+
+	"admin-statistics-api/internal/model"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// linearScanPercentile sorts every user by total wager, descending, and ranks a user at
+// the position of the first user holding their total, so that tied users share the best
+// rank. The original algorithm instead took the user's own position in the sort, which
+// left the order of tied users to MongoDB.
+func linearScanPercentile(totals []float64, userTotal float64) float64 {
+	sorted := append([]float64(nil), totals...)
+	sort.Sort(sort.Reverse(sort.Float64Slice(sorted)))
+
+	userRank := 0
+	for i, total := range sorted {
+		if total == userTotal {
+			userRank = i + 1
+			break
+		}
+	}
+
+	return 100.0 - (float64(userRank-1) / float64(len(sorted)) * 100.0)
+}
+
+func TestWagerPercentile(t *testing.T) {
+	t.Run("top user scores 100", func(t *testing.T) {
+		assert.Equal(t, 100.0, wagerPercentile(0, 4))
+	})
+
+	t.Run("each user above lowers the percentile", func(t *testing.T) {
+		assert.Equal(t, 75.0, wagerPercentile(1, 4))
+		assert.Equal(t, 25.0, wagerPercentile(3, 4))
+	})
+
+	t.Run("no users returns zero", func(t *testing.T) {
+		assert.Equal(t, 0.0, wagerPercentile(0, 0))
+	})
+
+	t.Run("matches a linear scan ranking ties together", func(t *testing.T) {
+		// Arrange - few distinct values so that many users tie
+		rng := rand.New(rand.NewSource(42))
+		totals := make([]float64, 1000)
+		for i := range totals {
+			totals[i] = float64(rng.Intn(50)) * 10
+		}
+
+		for _, userTotal := range totals {
+			// Act
+			var usersAbove int64
+			for _, total := range totals {
+				if total > userTotal {
+					usersAbove++
+				}
+			}
+			got := wagerPercentile(usersAbove, int64(len(totals)))
+
+			// Assert
+			assert.Equal(t, linearScanPercentile(totals, userTotal), got)
+		}
+	})
+}
+
+func TestBucketLabel(t *testing.T) {
+	t.Run("truncates in the bucket time zone", func(t *testing.T) {
+		label := bucketLabel(model.TimeBucket{Granularity: model.GranularityHour, Timezone: "Asia/Singapore"})
+
+		dateToString := label["$dateToString"].(bson.M)
+		truncate := dateToString["date"].(bson.M)["$dateTrunc"].(bson.M)
+		assert.Equal(t, "%Y-%m-%dT%H:00", dateToString["format"])
+		assert.Equal(t, "Asia/Singapore", dateToString["timezone"])
+		assert.Equal(t, "hour", truncate["unit"])
+		assert.Equal(t, "Asia/Singapore", truncate["timezone"])
+		assert.NotContains(t, truncate, "startOfWeek")
+	})
+
+	t.Run("weeks start on monday", func(t *testing.T) {
+		label := bucketLabel(model.TimeBucket{Granularity: model.GranularityWeek})
+
+		dateToString := label["$dateToString"].(bson.M)
+		truncate := dateToString["date"].(bson.M)["$dateTrunc"].(bson.M)
+		assert.Equal(t, "%Y-%m-%d", dateToString["format"])
+		assert.Equal(t, "UTC", truncate["timezone"])
+		assert.Equal(t, "monday", truncate["startOfWeek"])
+	})
+
+	t.Run("months are labelled by year and month", func(t *testing.T) {
+		label := bucketLabel(model.TimeBucket{Granularity: model.GranularityMonth})
+
+		assert.Equal(t, "%Y-%m", label["$dateToString"].(bson.M)["format"])
+	})
+}
+
+// newIntegrationCollection connects to MongoDB and returns a uniquely named collection that is dropped on cleanup
+func newIntegrationCollection(tb testing.TB) *mongo.Collection {
+	tb.Helper()
+
+	mongoURI := os.Getenv("MONGODB_URI")
+	if mongoURI == "" {
+		mongoURI = "mongodb://localhost:27017"
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(mongoURI))
+	if err != nil {
+		tb.Fatalf("Failed to connect to MongoDB: %v", err)
+	}
+
+	collection := client.Database("casino_test").Collection("transactions_" + model.GenerateULID())
+	tb.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		_ = collection.Drop(ctx)
+		_ = client.Disconnect(ctx)
+	})
+
+	return collection
+}
+
+// seedWagers inserts one wager per amount for each user, all at createdAt
+func seedWagers(tb testing.TB, collection *mongo.Collection, wagers map[string][]string, createdAt time.Time) {
+	tb.Helper()
+
+	var docs []interface{}
+	for userID, amounts := range wagers {
+		for i, amount := range amounts {
+			usdAmount, err := primitive.ParseDecimal128(amount)
+			if err != nil {
+				tb.Fatalf("Invalid amount %q: %v", amount, err)
+			}
+			docs = append(docs, model.Transaction{
+				ID:        model.GenerateULID(),
+				CreatedAt: createdAt,
+				UserID:    userID,
+				RoundID:   fmt.Sprintf("%s-%d", userID, i),
+				Type:      model.TransactionTypeWager,
+				Amount:    usdAmount,
+				Currency:  model.CurrencyUSDT,
+				USDAmount: usdAmount,
+			})
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	for start := 0; start < len(docs); start += 1000 {
+		end := start + 1000
+		if end > len(docs) {
+			end = len(docs)
+		}
+		if _, err := collection.InsertMany(ctx, docs[start:end]); err != nil {
+			tb.Fatalf("Failed to seed wagers: %v", err)
+		}
+	}
+
+	if _, err := collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "createdAt", Value: 1}, {Key: "type", Value: 1}}},
+		{Keys: bson.D{{Key: "userId", Value: 1}, {Key: "createdAt", Value: 1}}},
+	}); err != nil {
+		tb.Fatalf("Failed to create indexes: %v", err)
+	}
+}
+
+func TestCalculateUserWagerPercentile_Integration(t *testing.T) {
+	// Skip real tests if INTEGRATION_TESTS environment variable is not set
+	if os.Getenv("INTEGRATION_TESTS") != "true" {
+		t.Skip("Skipping integration tests")
+	}
+
+	collection := newIntegrationCollection(t)
+	repo := &TransactionRepository{collection: collection}
+
+	ctx := context.Background()
+	createdAt := time.Date(2023, 1, 15, 0, 0, 0, 0, time.UTC)
+	from := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2023, 1, 31, 0, 0, 0, 0, time.UTC)
+
+	// Totals: top=300, tiedA=tiedB=150, low=50
+	seedWagers(t, collection, map[string][]string{
+		"top":   {"100", "200"},
+		"tiedA": {"150"},
+		"tiedB": {"75", "75"},
+		"low":   {"50"},
+	}, createdAt)
+
+	cases := map[string]float64{
+		"top":   100,
+		"tiedA": 75,
+		"tiedB": 75,
+		"low":   25,
+	}
+
+	for userID, expected := range cases {
+		t.Run(userID, func(t *testing.T) {
+			// Act
+			percentile, err := repo.CalculateUserWagerPercentile(ctx, userID, from, to)
+
+			// Assert
+			assert.NoError(t, err)
+			assert.Equal(t, expected, percentile)
+		})
+	}
+
+	t.Run("the leaderboard gives tied users the same rank and percentile", func(t *testing.T) {
+		// Act
+		rows, err := repo.CalculateLeaderboard(ctx, "wager", "", 10, from, to)
+
+		// Assert
+		assert.NoError(t, err)
+		ranks := map[string]int{}
+		for _, row := range rows {
+			ranks[row.UserID] = row.Rank
+			assert.Equal(t, cases[row.UserID], row.Percentile, row.UserID)
+		}
+		assert.Equal(t, map[string]int{"top": 1, "tiedA": 2, "tiedB": 2, "low": 4}, ranks)
+	})
+
+	t.Run("user without wagers scores zero", func(t *testing.T) {
+		// Act
+		percentile, err := repo.CalculateUserWagerPercentile(ctx, "nobody", from, to)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, 0.0, percentile)
+	})
+
+	t.Run("wagers outside the period are ignored", func(t *testing.T) {
+		// Act
+		percentile, err := repo.CalculateUserWagerPercentile(ctx, "top", from, from.Add(time.Hour))
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, 0.0, percentile)
+	})
+}
+
+// BenchmarkCalculateUserWagerPercentile measures the percentile query against a synthetic
+// dataset. Set BENCH_USERS to change the number of users (default 50000, 4 wagers each).
+func BenchmarkCalculateUserWagerPercentile(b *testing.B) {
+	if os.Getenv("INTEGRATION_TESTS") != "true" {
+		b.Skip("Skipping integration benchmarks")
+	}
+
+	numUsers := 50000
+	if value := os.Getenv("BENCH_USERS"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			b.Fatalf("Invalid BENCH_USERS: %v", err)
+		}
+		numUsers = n
+	}
+
+	collection := newIntegrationCollection(b)
+	repo := &TransactionRepository{collection: collection}
+
+	rng := rand.New(rand.NewSource(1))
+	wagers := make(map[string][]string, numUsers)
+	userIDs := make([]string, 0, numUsers)
+	for i := 0; i < numUsers; i++ {
+		userID := fmt.Sprintf("user-%07d", i)
+		userIDs = append(userIDs, userID)
+		for j := 0; j < 4; j++ {
+			wagers[userID] = append(wagers[userID], fmt.Sprintf("%.2f", 0.01+rng.Float64()*99.99))
+		}
+	}
+
+	createdAt := time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC)
+	seedWagers(b, collection, wagers, createdAt)
+
+	ctx := context.Background()
+	from := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2023, 12, 31, 0, 0, 0, 0, time.UTC)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := repo.CalculateUserWagerPercentile(ctx, userIDs[i%len(userIDs)], from, to); err != nil {
+			b.Fatalf("CalculateUserWagerPercentile failed: %v", err)
+		}
+	}
+}
+
+func TestClosedDays(t *testing.T) {
+	date := func(s string) time.Time {
+		parsed, err := time.Parse(time.RFC3339Nano, s)
+		if err != nil {
+			t.Fatalf("Invalid date %q: %v", s, err)
+		}
+		return parsed
+	}
+
+	cases := []struct {
+		name             string
+		from, to         string
+		firstDay, endDay string
+		ok               bool
+	}{
+		{"aligned to end of day", "2023-01-01T00:00:00Z", "2023-01-31T23:59:59.999Z", "2023-01-01T00:00:00Z", "2023-02-01T00:00:00Z", true},
+		{"partial days at both ends", "2023-01-01T12:00:00Z", "2023-01-31T00:00:00Z", "2023-01-02T00:00:00Z", "2023-01-31T00:00:00Z", true},
+		{"to at midnight leaves that instant as an edge", "2023-01-01T00:00:00Z", "2023-01-03T00:00:00Z", "2023-01-01T00:00:00Z", "2023-01-03T00:00:00Z", true},
+		{"sub-millisecond bounds are truncated", "2023-01-01T00:00:00.0005Z", "2023-01-01T23:59:59.9995Z", "2023-01-01T00:00:00Z", "2023-01-02T00:00:00Z", true},
+		{"other time zones are converted to UTC", "2023-01-01T08:00:00+08:00", "2023-01-02T07:59:59.999+08:00", "2023-01-01T00:00:00Z", "2023-01-02T00:00:00Z", true},
+		{"within a single day", "2023-01-01T01:00:00Z", "2023-01-01T23:00:00Z", "", "", false},
+		{"spanning midnight without a whole day", "2023-01-01T12:00:00Z", "2023-01-02T12:00:00Z", "", "", false},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Act
+			firstDay, endDay, ok := closedDays(date(tc.from), date(tc.to))
+
+			// Assert
+			assert.Equal(t, tc.ok, ok)
+			if tc.ok {
+				assert.Equal(t, date(tc.firstDay), firstDay)
+				assert.Equal(t, date(tc.endDay), endDay)
+			}
+		})
+	}
+}
+
+func TestClosedRollupDays(t *testing.T) {
+	// Arrange
+	now := time.Date(2023, 1, 10, 12, 0, 0, 0, time.UTC)
+	transactions := []model.Transaction{
+		{ID: "yesterday", CreatedAt: time.Date(2023, 1, 9, 23, 59, 59, 0, time.UTC)},
+		{ID: "today", CreatedAt: time.Date(2023, 1, 10, 0, 0, 0, 0, time.UTC)},
+		{ID: "later today", CreatedAt: now.Add(time.Hour)},
+		{ID: "last month", CreatedAt: time.Date(2022, 12, 31, 0, 0, 0, 0, time.UTC)},
+	}
+
+	// Act
+	closed := closedRollupDays(transactions, now)
+
+	// Assert
+	ids := make([]string, len(closed))
+	for i, transaction := range closed {
+		ids[i] = transaction.ID
+	}
+	assert.Equal(t, []string{"yesterday", "last month"}, ids)
+}
+
+func TestTransactionSource(t *testing.T) {
+	// Connecting is lazy, so no server is needed to build pipelines
+	client, err := mongo.Connect(context.Background(), options.Client().ApplyURI("mongodb://localhost:27017"))
+	assert.NoError(t, err)
+	t.Cleanup(func() { _ = client.Disconnect(context.Background()) })
+	database := client.Database("casino")
+
+	from := time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)
+	to := time.Date(2023, 1, 31, 23, 59, 59, 999000000, time.UTC)
+	wagers := bson.M{"type": model.TransactionTypeWager}
+
+	t.Run("scans raw transactions without rollups", func(t *testing.T) {
+		repo := NewTransactionRepository(database, "transactions")
+
+		collection, stages := repo.transactionSource(from, to, model.TimeBucket{}, wagers)
+
+		assert.Equal(t, "transactions", collection.Name())
+		assert.Len(t, stages, 1)
+		assert.Equal(t, "$match", stages[0][0].Key)
+	})
+
+	t.Run("scans raw transactions when rollups are write only", func(t *testing.T) {
+		repo := NewTransactionRepository(database, "transactions").WithDailyRollups("daily_stats", false)
+
+		collection, stages := repo.transactionSource(from, to, model.TimeBucket{}, wagers)
+
+		assert.Equal(t, "transactions", collection.Name())
+		assert.Len(t, stages, 1)
+	})
+
+	t.Run("reads closed days from rollups and scans the partial edge", func(t *testing.T) {
+		repo := NewTransactionRepository(database, "transactions").WithDailyRollups("daily_stats", true)
+
+		collection, stages := repo.transactionSource(from, to, model.TimeBucket{}, wagers)
+
+		assert.Equal(t, "transactions", collection.Name())
+		assert.Len(t, stages, 2)
+		edges := stages[0][0].Value.(bson.M)["$or"].(bson.A)
+		assert.Len(t, edges, 1, "Only the first day is partial")
+		assert.Equal(t, bson.M{
+			"createdAt": bson.M{"$gte": from, "$lt": time.Date(2023, 1, 2, 0, 0, 0, 0, time.UTC)},
+			"type":      model.TransactionTypeWager,
+		}, edges[0])
+
+		union := stages[1][0].Value.(bson.M)
+		assert.Equal(t, "daily_stats", union["coll"])
+		rollupMatch := union["pipeline"].(mongo.Pipeline)[0][0].Value.(bson.M)
+		assert.Equal(t, bson.M{
+			"day": bson.M{
+				"$gte": time.Date(2023, 1, 2, 0, 0, 0, 0, time.UTC),
+				"$lt":  time.Date(2023, 2, 1, 0, 0, 0, 0, time.UTC),
+			},
+			"type": model.TransactionTypeWager,
+		}, rollupMatch)
+	})
+
+	t.Run("reads only rollups for whole days", func(t *testing.T) {
+		repo := NewTransactionRepository(database, "transactions").WithDailyRollups("daily_stats", true)
+
+		collection, stages := repo.transactionSource(time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC), to, model.TimeBucket{}, nil)
+
+		assert.Equal(t, "daily_stats", collection.Name())
+		assert.Len(t, stages, 2)
+	})
+
+	t.Run("scans raw transactions for buckets smaller than a UTC day", func(t *testing.T) {
+		repo := NewTransactionRepository(database, "transactions").WithDailyRollups("daily_stats", true)
+		buckets := []model.TimeBucket{
+			{Granularity: model.GranularityHour},
+			{Granularity: model.GranularityDay, Timezone: "Asia/Singapore"},
+		}
+
+		for _, bucket := range buckets {
+			collection, stages := repo.transactionSource(from, to, bucket, nil)
+
+			assert.Equal(t, "transactions", collection.Name())
+			assert.Len(t, stages, 1)
+		}
+	})
+
+	t.Run("UTC weeks and months are built from days", func(t *testing.T) {
+		repo := NewTransactionRepository(database, "transactions").WithDailyRollups("daily_stats", true)
+		buckets := []model.TimeBucket{
+			{Granularity: model.GranularityDay, Timezone: "UTC"},
+			{Granularity: model.GranularityWeek},
+			{Granularity: model.GranularityMonth},
+		}
+
+		for _, bucket := range buckets {
+			_, stages := repo.transactionSource(from, to, bucket, nil)
+
+			assert.Len(t, stages, 2)
+		}
+	})
+}
+
+func TestDailyRollups_Integration(t *testing.T) {
+	// Skip real tests if INTEGRATION_TESTS environment variable is not set
+	if os.Getenv("INTEGRATION_TESTS") != "true" {
+		t.Skip("Skipping integration tests")
+	}
+
+	collection := newIntegrationCollection(t)
+	rollups := collection.Database().Collection(collection.Name() + "_daily_stats")
+	t.Cleanup(func() { _ = rollups.Drop(context.Background()) })
+
+	raw := &TransactionRepository{collection: collection}
+	rolledUp := (&TransactionRepository{collection: collection}).WithDailyRollups(rollups.Name(), true)
+
+	ctx := context.Background()
+	start := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	rng := rand.New(rand.NewSource(7))
+	currencies := []string{model.CurrencyETH, model.CurrencyBTC, model.CurrencyUSDT}
+	types := []string{model.TransactionTypeWager, model.TransactionTypePayout}
+
+	randomTransactions := func(n int) []model.Transaction {
+		transactions := make([]model.Transaction, n)
+		for i := range transactions {
+			amount, _ := primitive.ParseDecimal128(fmt.Sprintf("%d.%03d", rng.Intn(1000), rng.Intn(1000)))
+			usdAmount, _ := primitive.ParseDecimal128(fmt.Sprintf("%d.%02d", rng.Intn(100000), rng.Intn(100)))
+			transactions[i] = model.Transaction{
+				ID:        model.GenerateULID(),
+				CreatedAt: start.Add(time.Duration(rng.Int63n(int64(40 * day)))).Truncate(time.Millisecond),
+				UserID:    model.GenerateULID(),
+				RoundID:   fmt.Sprintf("round-%d", i),
+				Type:      types[rng.Intn(len(types))],
+				Amount:    amount,
+				Currency:  currencies[rng.Intn(len(currencies))],
+				USDAmount: usdAmount,
+			}
+		}
+		return transactions
+	}
+
+	// Backfill the first batch, then maintain rollups incrementally for the second,
+	// including a retried duplicate that must not be counted twice
+	backfilled := randomTransactions(2000)
+	_, err := raw.InsertTransactions(ctx, backfilled)
+	assert.NoError(t, err)
+	assert.NoError(t, rolledUp.EnsureDailyRollupIndexes(ctx))
+	assert.NoError(t, rolledUp.BackfillDailyRollups(ctx, time.Time{}, time.Time{}))
+
+	incremental := randomTransactions(500)
+	inserted, err := rolledUp.InsertTransactions(ctx, append(incremental, backfilled[0]))
+	assert.NoError(t, err)
+	assert.Equal(t, len(incremental), inserted)
+
+	// Inserts into closed days are marked for repair, since a backfill may race with them
+	dirty, err := rolledUp.dirtyRollups().CountDocuments(ctx, bson.M{})
+	assert.NoError(t, err)
+	assert.NotZero(t, dirty)
+
+	// Rollups missing an insert, as when the rollup update fails, are repaired from the marks
+	missed := randomTransactions(100)
+	assert.NoError(t, rolledUp.markRollupsDirty(ctx, missed))
+	_, err = raw.InsertTransactions(ctx, missed)
+	assert.NoError(t, err)
+	_, err = rolledUp.RepairDailyRollups(ctx)
+	assert.NoError(t, err)
+	dirty, err = rolledUp.dirtyRollups().CountDocuments(ctx, bson.M{})
+	assert.NoError(t, err)
+	assert.Zero(t, dirty)
+	t.Cleanup(func() { _ = rolledUp.dirtyRollups().Drop(context.Background()) })
+
+	ranges := map[string][2]time.Time{
+		"whole days":          {start, start.Add(40*day - time.Millisecond)},
+		"partial days":        {start.Add(5*day + 7*time.Hour), start.Add(31*day + 13*time.Hour)},
+		"ends at midnight":    {start.Add(2 * day), start.Add(9 * day)},
+		"within a single day": {start.Add(3*day + time.Hour), start.Add(3*day + 20*time.Hour)},
+	}
+
+	for name, r := range ranges {
+		from, to := r[0], r[1]
+		t.Run(name, func(t *testing.T) {
+			for _, bucket := range []model.TimeBucket{{}, {Granularity: model.GranularityWeek}, {Granularity: model.GranularityMonth}} {
+				expected, err := raw.CalculateGGR(ctx, from, to, bucket)
+				assert.NoError(t, err)
+				actual, err := rolledUp.CalculateGGR(ctx, from, to, bucket)
+				assert.NoError(t, err)
+				assert.Equal(t, expected, actual, "GGR by %q", bucket.Granularity)
+			}
+
+			expectedSeries, err := raw.CalculateGGRSeries(ctx, from, to, model.TimeBucket{})
+			assert.NoError(t, err)
+			actualSeries, err := rolledUp.CalculateGGRSeries(ctx, from, to, model.TimeBucket{})
+			assert.NoError(t, err)
+			assert.Equal(t, expectedSeries, actualSeries)
+
+			expectedVolume, err := raw.CalculateDailyWagerVolume(ctx, from, to, model.TimeBucket{})
+			assert.NoError(t, err)
+			actualVolume, err := rolledUp.CalculateDailyWagerVolume(ctx, from, to, model.TimeBucket{})
+			assert.NoError(t, err)
+			assert.Equal(t, expectedVolume, actualVolume)
+
+			// Streaming reads the same rows as the query it shares a pipeline with
+			cursor, err := rolledUp.StreamRows(ctx, model.ReportTypeDailyWagerVolume, model.ReportParams{From: from, To: to})
+			if assert.NoError(t, err) {
+				streamed := make([]model.DailyWagerRow, 0)
+				for cursor.Next(ctx) {
+					var row model.DailyWagerRow
+					assert.NoError(t, cursor.Decode(&row))
+					streamed = append(streamed, row)
+				}
+				assert.NoError(t, cursor.Err())
+				assert.NoError(t, cursor.Close(ctx))
+				assert.Equal(t, expectedVolume, streamed)
+			}
+		})
+	}
+
+	t.Run("backfill removes rollups of days without transactions", func(t *testing.T) {
+		// Arrange
+		emptied := start.Add(10 * day)
+		_, err := collection.DeleteMany(ctx, bson.M{"createdAt": bson.M{"$gte": emptied, "$lt": emptied.Add(day)}})
+		assert.NoError(t, err)
+
+		// Act
+		err = rolledUp.BackfillDailyRollups(ctx, emptied, emptied)
+
+		// Assert
+		assert.NoError(t, err)
+		count, err := rollups.CountDocuments(ctx, bson.M{"day": emptied})
+		assert.NoError(t, err)
+		assert.Zero(t, count)
+		others, err := rollups.CountDocuments(ctx, bson.M{"day": emptied.Add(day)})
+		assert.NoError(t, err)
+		assert.NotZero(t, others)
+	})
+}
@@ -0,0 +1,92 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"admin-statistics-api/internal/logging"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	// watchRetryDelay is how long WatchInserts waits before reopening a failed change stream
+	watchRetryDelay = 5 * time.Second
+
+	// watchBatchSize bounds how many inserts WatchInserts reports in one call
+	watchBatchSize = 1000
+)
+
+// insertEvent is the part of a change stream insert event WatchInserts reads
+type insertEvent struct {
+	FullDocument struct {
+		CreatedAt time.Time `bson:"createdAt"`
+	} `bson:"fullDocument"`
+}
+
+// WatchInserts follows a change stream of the transactions collection until ctx is done,
+// calling onInsert with the createdAt times of newly inserted transactions, so writes
+// made outside this API are noticed too. Inserts that arrive together are reported in
+// one call. The stream is reopened where it left off after an error. Change streams
+// need a replica set or sharded cluster.
+func (r *TransactionRepository) WatchInserts(ctx context.Context, onInsert func(createdAt ...time.Time)) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"operationType": "insert"}}},
+		{{Key: "$project", Value: bson.M{"fullDocument.createdAt": 1}}},
+	}
+
+	var resumeToken bson.Raw
+	for {
+		opts := options.ChangeStream()
+		if resumeToken != nil {
+			opts.SetResumeAfter(resumeToken)
+		}
+
+		stream, err := r.collection.Watch(ctx, pipeline, opts)
+		if err != nil {
+			logging.FromContext(ctx).Error("Failed to watch transactions", "error", err)
+			// The token may have fallen off the oplog, so start from now next time
+			resumeToken = nil
+		} else {
+			resumeToken = watchStream(ctx, stream, resumeToken, onInsert)
+			stream.Close(context.Background())
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(watchRetryDelay):
+		}
+	}
+}
+
+// watchStream reports the inserts on stream until it fails or ctx is done, and returns
+// the resume token after the last reported insert
+func watchStream(ctx context.Context, stream *mongo.ChangeStream, resumeToken bson.Raw, onInsert func(createdAt ...time.Time)) bson.Raw {
+	for stream.Next(ctx) {
+		batch := make([]time.Time, 0, 1)
+		for {
+			var event insertEvent
+			if err := stream.Decode(&event); err != nil {
+				logging.FromContext(ctx).Warn("Failed to decode transaction insert", "error", err)
+			} else {
+				batch = append(batch, event.FullDocument.CreatedAt)
+			}
+
+			// Collect inserts that are already waiting without blocking for more
+			if len(batch) >= watchBatchSize || !stream.TryNext(ctx) {
+				break
+			}
+		}
+
+		onInsert(batch...)
+		resumeToken = stream.ResumeToken()
+	}
+
+	if err := stream.Err(); err != nil && ctx.Err() == nil {
+		logging.FromContext(ctx).Error("Transaction change stream failed", "error", err)
+	}
+	return resumeToken
+}
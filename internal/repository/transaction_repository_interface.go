@@ -2,14 +2,33 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"time"
 
-	"go.mongodb.org/mongo-driver/bson"
+	"admin-statistics-api/internal/model"
 )
 
 // TransactionRepositoryInterface defines the interface for transaction repositories
 type TransactionRepositoryInterface interface {
-	CalculateGGR(ctx context.Context, from, to time.Time) ([]bson.M, error)
-	CalculateDailyWagerVolume(ctx context.Context, from, to time.Time) ([]bson.M, error)
+	CalculateGGR(ctx context.Context, from, to time.Time, bucket model.TimeBucket) ([]model.GGRRow, error)
+	CalculateGGRSeries(ctx context.Context, from, to time.Time, bucket model.TimeBucket) ([]model.GGRSeriesRow, error)
+	CalculateDailyWagerVolume(ctx context.Context, from, to time.Time, bucket model.TimeBucket) ([]model.DailyWagerRow, error)
 	CalculateUserWagerPercentile(ctx context.Context, userID string, from, to time.Time) (float64, error)
-}
\ No newline at end of file
+	CalculateUserSummary(ctx context.Context, userID string, from, to time.Time) (model.UserSummary, error)
+	CalculateLeaderboard(ctx context.Context, metric, currency string, limit int, from, to time.Time) ([]model.LeaderboardRow, error)
+	FindRoundAnomalies(ctx context.Context, from, to time.Time) ([]model.RoundAnomaly, error)
+	StreamRows(ctx context.Context, reportType string, params model.ReportParams) (RowCursor, error)
+	InsertTransactions(ctx context.Context, transactions []model.Transaction) (int, error)
+}
+
+// RowCursor iterates over the rows of a streamed query. It is satisfied by *mongo.Cursor.
+type RowCursor interface {
+	Next(ctx context.Context) bool
+	Decode(val interface{}) error
+	Err() error
+	Close(ctx context.Context) error
+}
+
+// ErrNotStreamable is returned by StreamRows for a report type whose result is not a
+// list of rows
+var ErrNotStreamable = errors.New("report type cannot be streamed")
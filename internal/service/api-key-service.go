@@ -0,0 +1,215 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"admin-statistics-api/internal/model"
+	"admin-statistics-api/internal/repository"
+)
+
+// apiKeyPrefix starts every generated secret key, which has the form "ak_<id>_<random hex>"
+const apiKeyPrefix = "ak"
+
+// apiKeySecretBytes is the number of random bytes in a generated secret key
+const apiKeySecretBytes = 32
+
+// defaultAPIKeyID identifies the single API key configured through cfg.Auth.APIKey
+const defaultAPIKeyID = "default"
+
+// ErrInvalidAPIKey is returned when a secret key is unknown, malformed, expired or revoked.
+// The reasons are deliberately not distinguished so that callers learn nothing from them.
+var ErrInvalidAPIKey = errors.New("invalid or missing API key")
+
+// ErrInvalidAPIKeyRequest is returned when an API key cannot be created as requested
+var ErrInvalidAPIKeyRequest = errors.New("invalid api key request")
+
+// APIKeyService authenticates secret keys and manages named API keys
+type APIKeyService struct {
+	store      repository.APIKeyStore
+	defaultKey atomic.Pointer[string]
+	now        func() time.Time
+}
+
+// NewAPIKeyService creates a new APIKeyService. A non-empty defaultKey is accepted as
+// well, with every scope, so existing clients keep working and new keys can be created.
+func NewAPIKeyService(store repository.APIKeyStore, defaultKey string) *APIKeyService {
+	s := &APIKeyService{
+		store: store,
+		now:   time.Now,
+	}
+	s.SetDefaultKey(defaultKey)
+	return s
+}
+
+// SetDefaultKey replaces the key accepted with every scope; an empty key disables it.
+// It is safe to call while requests are being authenticated.
+func (s *APIKeyService) SetDefaultKey(defaultKey string) {
+	s.defaultKey.Store(&defaultKey)
+}
+
+// Authenticate returns the active API key matching a secret key
+func (s *APIKeyService) Authenticate(ctx context.Context, secret string) (model.APIKey, error) {
+	if secret == "" {
+		return model.APIKey{}, ErrInvalidAPIKey
+	}
+
+	if defaultKey := *s.defaultKey.Load(); defaultKey != "" && subtle.ConstantTimeCompare([]byte(secret), []byte(defaultKey)) == 1 {
+		return model.APIKey{ID: defaultAPIKeyID, Name: defaultAPIKeyID, Scopes: model.AllScopes}, nil
+	}
+
+	id, ok := parseAPIKeyID(secret)
+	if !ok {
+		return model.APIKey{}, ErrInvalidAPIKey
+	}
+
+	key, err := s.store.FindAPIKey(ctx, id)
+	if errors.Is(err, repository.ErrAPIKeyNotFound) {
+		return model.APIKey{}, ErrInvalidAPIKey
+	}
+	if err != nil {
+		return model.APIKey{}, err
+	}
+
+	if subtle.ConstantTimeCompare([]byte(hashAPIKey(secret)), []byte(key.Hash)) != 1 {
+		return model.APIKey{}, ErrInvalidAPIKey
+	}
+	if !key.ActiveAt(s.now()) {
+		return model.APIKey{}, ErrInvalidAPIKey
+	}
+
+	return key, nil
+}
+
+// ListAPIKeys lists every stored API key
+func (s *APIKeyService) ListAPIKeys(ctx context.Context) ([]model.APIKey, error) {
+	return s.store.ListAPIKeys(ctx)
+}
+
+// CreateAPIKey creates a named API key and returns it with its secret key,
+// which is not stored and cannot be retrieved again
+func (s *APIKeyService) CreateAPIKey(ctx context.Context, name string, scopes []string, expiresAt *time.Time) (model.APIKey, string, error) {
+	if name == "" {
+		return model.APIKey{}, "", fmt.Errorf("%w: name is required", ErrInvalidAPIKeyRequest)
+	}
+	if len(scopes) == 0 {
+		return model.APIKey{}, "", fmt.Errorf("%w: at least one scope is required", ErrInvalidAPIKeyRequest)
+	}
+	for _, scope := range scopes {
+		if !isKnownScope(scope) {
+			return model.APIKey{}, "", fmt.Errorf("%w: unknown scope %q", ErrInvalidAPIKeyRequest, scope)
+		}
+	}
+
+	now := s.now()
+	if expiresAt != nil && !expiresAt.After(now) {
+		return model.APIKey{}, "", fmt.Errorf("%w: expiresAt must be in the future", ErrInvalidAPIKeyRequest)
+	}
+
+	secret, key, err := newAPIKey(now)
+	if err != nil {
+		return model.APIKey{}, "", err
+	}
+	key.Name = name
+	key.Scopes = scopes
+	key.ExpiresAt = expiresAt
+
+	if err := s.store.CreateAPIKey(ctx, key); err != nil {
+		return model.APIKey{}, "", err
+	}
+	return key, secret, nil
+}
+
+// RotateAPIKey replaces an API key with a new one that has the same name, scopes and
+// expiry. The old key keeps working for the grace period and is then revoked.
+func (s *APIKeyService) RotateAPIKey(ctx context.Context, id string, gracePeriod time.Duration) (model.APIKey, string, error) {
+	if gracePeriod < 0 {
+		return model.APIKey{}, "", fmt.Errorf("%w: grace period must not be negative", ErrInvalidAPIKeyRequest)
+	}
+
+	old, err := s.store.FindAPIKey(ctx, id)
+	if err != nil {
+		return model.APIKey{}, "", err
+	}
+
+	now := s.now()
+	if !old.ActiveAt(now) {
+		return model.APIKey{}, "", fmt.Errorf("%w: only active keys can be rotated", ErrInvalidAPIKeyRequest)
+	}
+
+	secret, key, err := newAPIKey(now)
+	if err != nil {
+		return model.APIKey{}, "", err
+	}
+	key.Name = old.Name
+	key.Scopes = old.Scopes
+	key.ExpiresAt = old.ExpiresAt
+
+	// Create the new key first so that a failure never leaves the caller without one
+	if err := s.store.CreateAPIKey(ctx, key); err != nil {
+		return model.APIKey{}, "", err
+	}
+	if err := s.store.RevokeAPIKey(ctx, id, now.Add(gracePeriod)); err != nil {
+		return model.APIKey{}, "", err
+	}
+	return key, secret, nil
+}
+
+// RevokeAPIKey revokes an API key immediately
+func (s *APIKeyService) RevokeAPIKey(ctx context.Context, id string) error {
+	return s.store.RevokeAPIKey(ctx, id, s.now())
+}
+
+// newAPIKey generates a secret key and the API key record that stores its hash
+func newAPIKey(now time.Time) (string, model.APIKey, error) {
+	random := make([]byte, apiKeySecretBytes)
+	if _, err := rand.Read(random); err != nil {
+		return "", model.APIKey{}, err
+	}
+
+	id := model.GenerateULID()
+	secret := fmt.Sprintf("%s_%s_%s", apiKeyPrefix, id, hex.EncodeToString(random))
+
+	return secret, model.APIKey{
+		ID:        id,
+		Hash:      hashAPIKey(secret),
+		CreatedAt: now.UTC(),
+	}, nil
+}
+
+// parseAPIKeyID extracts the key ID embedded in a generated secret key
+func parseAPIKeyID(secret string) (string, bool) {
+	parts := strings.SplitN(secret, "_", 3)
+	if len(parts) != 3 || parts[0] != apiKeyPrefix || parts[1] == "" || parts[2] == "" {
+		return "", false
+	}
+	return parts[1], true
+}
+
+// hashAPIKey returns the hex SHA-256 of a secret key. Generated keys carry 256 random
+// bits, so a fast hash is enough; a password hash would only slow down every request.
+func hashAPIKey(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// isKnownScope reports whether scope is one of model.AllScopes
+func isKnownScope(scope string) bool {
+	for _, s := range model.AllScopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// Ensure APIKeyService implements APIKeyServiceInterface
+var _ APIKeyServiceInterface = (*APIKeyService)(nil)
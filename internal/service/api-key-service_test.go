@@ -0,0 +1,236 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"admin-statistics-api/internal/model"
+	"admin-statistics-api/internal/repository"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAPIKeyService(t *testing.T) {
+	// Test data
+	ctx := context.Background()
+	now := time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	newService := func() (*APIKeyService, *repository.MockAPIKeyStore) {
+		store := repository.NewMockAPIKeyStore()
+		service := NewAPIKeyService(store, "test-api-key")
+		service.now = func() time.Time { return now }
+		return service, store
+	}
+
+	t.Run("creates a key that authenticates with its scopes", func(t *testing.T) {
+		// Arrange
+		service, _ := newService()
+
+		// Act
+		key, secret, err := service.CreateAPIKey(ctx, "dashboard", []string{model.ScopeStatsRead}, nil)
+		assert.NoError(t, err)
+		authenticated, authErr := service.Authenticate(ctx, secret)
+
+		// Assert
+		assert.NoError(t, authErr)
+		assert.Equal(t, key.ID, authenticated.ID)
+		assert.Equal(t, "dashboard", authenticated.Name)
+		assert.True(t, authenticated.HasScope(model.ScopeStatsRead))
+		assert.False(t, authenticated.HasScope(model.ScopeUserRead))
+		assert.True(t, strings.HasPrefix(secret, "ak_"+key.ID+"_"))
+	})
+
+	t.Run("stores only a hash of the secret", func(t *testing.T) {
+		// Arrange
+		service, store := newService()
+
+		// Act
+		key, secret, err := service.CreateAPIKey(ctx, "dashboard", []string{model.ScopeStatsRead}, nil)
+
+		// Assert
+		assert.NoError(t, err)
+		stored := store.Keys[key.ID]
+		assert.NotEmpty(t, stored.Hash)
+		assert.NotContains(t, stored.Hash, secret)
+		assert.NotContains(t, secret, stored.Hash)
+	})
+
+	t.Run("default key has every scope", func(t *testing.T) {
+		service, _ := newService()
+
+		key, err := service.Authenticate(ctx, "test-api-key")
+
+		assert.NoError(t, err)
+		for _, scope := range model.AllScopes {
+			assert.True(t, key.HasScope(scope), scope)
+		}
+	})
+
+	t.Run("empty default key is disabled", func(t *testing.T) {
+		service := NewAPIKeyService(repository.NewMockAPIKeyStore(), "")
+
+		_, err := service.Authenticate(ctx, "")
+
+		assert.ErrorIs(t, err, ErrInvalidAPIKey)
+	})
+
+	t.Run("rejects unknown, malformed and forged keys", func(t *testing.T) {
+		// Arrange
+		service, _ := newService()
+		key, secret, err := service.CreateAPIKey(ctx, "dashboard", []string{model.ScopeStatsRead}, nil)
+		assert.NoError(t, err)
+
+		secrets := []string{
+			"",
+			"not-a-key",
+			"ak__abc",
+			"ak_" + model.GenerateULID() + "_abc",
+			"ak_" + key.ID + "_" + strings.Repeat("0", 64),
+			secret + "0",
+		}
+
+		for _, s := range secrets {
+			// Act
+			_, err := service.Authenticate(ctx, s)
+
+			// Assert
+			assert.ErrorIs(t, err, ErrInvalidAPIKey, "%q should be rejected", s)
+		}
+	})
+
+	t.Run("rejects expired keys", func(t *testing.T) {
+		// Arrange
+		service, _ := newService()
+		expiresAt := now.Add(time.Hour)
+		_, secret, err := service.CreateAPIKey(ctx, "temporary", []string{model.ScopeStatsRead}, &expiresAt)
+		assert.NoError(t, err)
+
+		// Act
+		service.now = func() time.Time { return expiresAt }
+		_, authErr := service.Authenticate(ctx, secret)
+
+		// Assert
+		assert.ErrorIs(t, authErr, ErrInvalidAPIKey)
+	})
+
+	t.Run("rejects revoked keys", func(t *testing.T) {
+		// Arrange
+		service, _ := newService()
+		key, secret, err := service.CreateAPIKey(ctx, "dashboard", []string{model.ScopeStatsRead}, nil)
+		assert.NoError(t, err)
+
+		// Act
+		assert.NoError(t, service.RevokeAPIKey(ctx, key.ID))
+		_, authErr := service.Authenticate(ctx, secret)
+
+		// Assert
+		assert.ErrorIs(t, authErr, ErrInvalidAPIKey)
+	})
+
+	t.Run("returns store errors", func(t *testing.T) {
+		// Arrange
+		service, store := newService()
+		_, secret, err := service.CreateAPIKey(ctx, "dashboard", []string{model.ScopeStatsRead}, nil)
+		assert.NoError(t, err)
+		store.Err = errors.New("database error")
+
+		// Act
+		_, authErr := service.Authenticate(ctx, secret)
+
+		// Assert
+		assert.Equal(t, store.Err, authErr)
+	})
+
+	t.Run("validates create requests", func(t *testing.T) {
+		service, _ := newService()
+		past := now.Add(-time.Second)
+
+		_, _, err := service.CreateAPIKey(ctx, "", []string{model.ScopeStatsRead}, nil)
+		assert.ErrorIs(t, err, ErrInvalidAPIKeyRequest)
+		_, _, err = service.CreateAPIKey(ctx, "dashboard", nil, nil)
+		assert.ErrorIs(t, err, ErrInvalidAPIKeyRequest)
+		_, _, err = service.CreateAPIKey(ctx, "dashboard", []string{"stats:write"}, nil)
+		assert.ErrorIs(t, err, ErrInvalidAPIKeyRequest)
+		_, _, err = service.CreateAPIKey(ctx, "dashboard", []string{model.ScopeStatsRead}, &past)
+		assert.ErrorIs(t, err, ErrInvalidAPIKeyRequest)
+	})
+
+	t.Run("rotation keeps the old key working for the grace period", func(t *testing.T) {
+		// Arrange
+		service, _ := newService()
+		old, oldSecret, err := service.CreateAPIKey(ctx, "dashboard", []string{model.ScopeStatsRead, model.ScopeUserRead}, nil)
+		assert.NoError(t, err)
+
+		// Act
+		rotated, newSecret, err := service.RotateAPIKey(ctx, old.ID, time.Hour)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.NotEqual(t, old.ID, rotated.ID)
+		assert.Equal(t, old.Name, rotated.Name)
+		assert.Equal(t, old.Scopes, rotated.Scopes)
+
+		_, err = service.Authenticate(ctx, oldSecret)
+		assert.NoError(t, err, "Old key should work during the grace period")
+		_, err = service.Authenticate(ctx, newSecret)
+		assert.NoError(t, err)
+
+		service.now = func() time.Time { return now.Add(time.Hour) }
+		_, err = service.Authenticate(ctx, oldSecret)
+		assert.ErrorIs(t, err, ErrInvalidAPIKey, "Old key should be revoked after the grace period")
+		_, err = service.Authenticate(ctx, newSecret)
+		assert.NoError(t, err)
+	})
+
+	t.Run("rotation without grace revokes the old key immediately", func(t *testing.T) {
+		// Arrange
+		service, _ := newService()
+		old, oldSecret, err := service.CreateAPIKey(ctx, "dashboard", []string{model.ScopeStatsRead}, nil)
+		assert.NoError(t, err)
+
+		// Act
+		_, _, err = service.RotateAPIKey(ctx, old.ID, 0)
+
+		// Assert
+		assert.NoError(t, err)
+		_, err = service.Authenticate(ctx, oldSecret)
+		assert.ErrorIs(t, err, ErrInvalidAPIKey)
+	})
+
+	t.Run("replaces the default key", func(t *testing.T) {
+		// Arrange
+		service, _ := newService()
+
+		// Act
+		service.SetDefaultKey("new-api-key")
+
+		// Assert
+		_, oldErr := service.Authenticate(ctx, "test-api-key")
+		assert.ErrorIs(t, oldErr, ErrInvalidAPIKey)
+		key, newErr := service.Authenticate(ctx, "new-api-key")
+		assert.NoError(t, newErr)
+		assert.Equal(t, model.AllScopes, key.Scopes)
+
+		service.SetDefaultKey("")
+		_, err := service.Authenticate(ctx, "")
+		assert.ErrorIs(t, err, ErrInvalidAPIKey, "An empty default key disables it")
+	})
+
+	t.Run("rotating an unknown or revoked key fails", func(t *testing.T) {
+		// Arrange
+		service, _ := newService()
+		revoked, _, err := service.CreateAPIKey(ctx, "dashboard", []string{model.ScopeStatsRead}, nil)
+		assert.NoError(t, err)
+		assert.NoError(t, service.RevokeAPIKey(ctx, revoked.ID))
+
+		// Act
+		_, _, unknownErr := service.RotateAPIKey(ctx, "missing", 0)
+		_, _, revokedErr := service.RotateAPIKey(ctx, revoked.ID, 0)
+
+		// Assert
+		assert.ErrorIs(t, unknownErr, repository.ErrAPIKeyNotFound)
+		assert.ErrorIs(t, revokedErr, ErrInvalidAPIKeyRequest)
+	})
+}
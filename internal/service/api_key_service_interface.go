@@ -0,0 +1,17 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"admin-statistics-api/internal/model"
+)
+
+// APIKeyServiceInterface defines the interface for API key services
+type APIKeyServiceInterface interface {
+	Authenticate(ctx context.Context, secret string) (model.APIKey, error)
+	ListAPIKeys(ctx context.Context) ([]model.APIKey, error)
+	CreateAPIKey(ctx context.Context, name string, scopes []string, expiresAt *time.Time) (model.APIKey, string, error)
+	RotateAPIKey(ctx context.Context, id string, gracePeriod time.Duration) (model.APIKey, string, error)
+	RevokeAPIKey(ctx context.Context, id string) error
+}
@@ -0,0 +1,84 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"admin-statistics-api/internal/model"
+	"admin-statistics-api/internal/repository"
+)
+
+func TestInvalidateCache(t *testing.T) {
+	// Test data
+	ctx := context.Background()
+	january := [2]time.Time{time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2023, 1, 31, 0, 0, 0, 0, time.UTC)}
+	february := [2]time.Time{time.Date(2023, 2, 1, 0, 0, 0, 0, time.UTC), time.Date(2023, 2, 28, 0, 0, 0, 0, time.UTC)}
+	bigPayout := time.Date(2023, 1, 20, 18, 30, 0, 0, time.UTC)
+
+	t.Run("caches results with the range they cover", func(t *testing.T) {
+		// Arrange
+		mockCache := repository.NewMockCache()
+		service := NewTransactionService(repository.NewMockTransactionRepository(), mockCache)
+
+		// Act
+		service.CalculateGGR(ctx, january[0], january[1], model.TimeBucket{})
+		service.CalculateUserSummary(ctx, "01HRMD5HGTZB3TW3PGYXRD07CQ", february[0], february[1])
+
+		// Assert
+		assert.Equal(t, january, mockCache.Ranges["ggr:2023-01-01T00:00:00Z:2023-01-31T00:00:00Z"])
+		assert.Equal(t, february, mockCache.Ranges["user_summary:01HRMD5HGTZB3TW3PGYXRD07CQ:2023-02-01T00:00:00Z:2023-02-28T00:00:00Z"])
+	})
+
+	t.Run("queries again only for ranges covering the new transaction", func(t *testing.T) {
+		// Arrange
+		mockRepo := repository.NewMockTransactionRepository()
+		service := NewTransactionService(mockRepo, repository.NewMemoryCache())
+		service.CalculateGGR(ctx, january[0], january[1], model.TimeBucket{})
+		service.CalculateGGR(ctx, february[0], february[1], model.TimeBucket{})
+		service.CalculateDailyWagerVolume(ctx, january[0], january[1], model.TimeBucket{})
+
+		// Act
+		removed := service.InvalidateCache(ctx, bigPayout)
+		service.CalculateGGR(ctx, january[0], january[1], model.TimeBucket{})
+		service.CalculateGGR(ctx, february[0], february[1], model.TimeBucket{})
+		service.CalculateDailyWagerVolume(ctx, january[0], january[1], model.TimeBucket{})
+
+		// Assert
+		assert.Equal(t, 2, removed)
+		assert.Len(t, mockRepo.CalculateGGRCalls, 3, "Only the January GGR should be queried again")
+		assert.Len(t, mockRepo.CalculateDailyWagerVolumeCalls, 2)
+	})
+
+	t.Run("keeps a result queried during an invalidation only as stale", func(t *testing.T) {
+		// Arrange
+		mockRepo := repository.NewMockTransactionRepository()
+		mockCache := repository.NewMockCache()
+		service := NewTransactionService(mockRepo, mockCache).WithStaleGrace(time.Minute)
+		mockRepo.CalculateGGRFn = func(ctx context.Context, from, to time.Time, bucket model.TimeBucket) ([]model.GGRRow, error) {
+			service.InvalidateCache(ctx, bigPayout)
+			return []model.GGRRow{}, nil
+		}
+
+		// Act
+		_, err := service.CalculateGGR(ctx, january[0], january[1], model.TimeBucket{})
+
+		// Assert
+		assert.NoError(t, err)
+		entry, found := getCached[[]model.GGRRow](context.Background(), mockCache, "ggr:2023-01-01T00:00:00Z:2023-01-31T00:00:00Z")
+		assert.True(t, found)
+		assert.False(t, entry.fresh(time.Now()), "Result may be missing the new transaction")
+	})
+
+	t.Run("does nothing for a cache without a range index", func(t *testing.T) {
+		service := NewTransactionService(repository.NewMockTransactionRepository(), plainCache{repository.NewMockCache()})
+
+		assert.Equal(t, 0, service.InvalidateCache(ctx, bigPayout))
+	})
+}
+
+// plainCache hides the range index of the cache it wraps
+type plainCache struct {
+	repository.Cache
+}
@@ -0,0 +1,37 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+
+	"admin-statistics-api/internal/logging"
+)
+
+// CacheStatus tells how a query result was served
+type CacheStatus string
+
+// Cache statuses reported to the function set with WithCacheStatusReporter
+const (
+	CacheFresh  CacheStatus = "fresh"  // Served from the cache
+	CacheStale  CacheStatus = "stale"  // Served from the cache past its freshness while it is refreshed
+	CacheMiss   CacheStatus = "miss"   // Queried from the repository
+	CacheBypass CacheStatus = "bypass" // Streamed from the repository without the cache
+)
+
+// cacheStatusKey is the context key holding the cache status reporter
+type cacheStatusKey struct{}
+
+// WithCacheStatusReporter returns a context that makes the service report to report
+// how each query result was served
+func WithCacheStatusReporter(ctx context.Context, report func(CacheStatus)) context.Context {
+	return context.WithValue(ctx, cacheStatusKey{}, report)
+}
+
+// reportCacheStatus passes status to the reporter in ctx, if there is one, and adds it
+// to the request's log line
+func reportCacheStatus(ctx context.Context, status CacheStatus) {
+	logging.AddAttrs(ctx, slog.String("cache", string(status)))
+	if report, ok := ctx.Value(cacheStatusKey{}).(func(CacheStatus)); ok {
+		report(status)
+	}
+}
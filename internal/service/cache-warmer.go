@@ -0,0 +1,121 @@
+package service
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"admin-statistics-api/internal/logging"
+)
+
+// maxHotKeys bounds how many distinct cache keys are counted between warmer runs
+const maxHotKeys = 10000
+
+// minWarmHits is how many times a key must be requested between warmer runs to be
+// warmed. A range ending at the time of each request makes a new key every time, so
+// warming a key requested once would only refresh a result nobody asks for again.
+// Keys for a named range, such as "last 24h", are shared by every request for the
+// range and so are warmed after one request.
+const minWarmHits = 2
+
+// hotKey is a cache key's request count since the last warmer run and how to refresh it
+type hotKey struct {
+	key     string
+	hits    int
+	named   bool // Whether the key is for a named range, which refresh resolves again
+	refresh func(ctx context.Context, freshAfter time.Time) error
+}
+
+// hotKeys counts requests per cache key between warmer runs
+type hotKeys struct {
+	mu   sync.Mutex
+	keys map[string]*hotKey
+}
+
+// record counts a request for key, which is for a named range when named is true
+func (h *hotKeys) record(key string, named bool, refresh func(ctx context.Context, freshAfter time.Time) error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if hot, ok := h.keys[key]; ok {
+		hot.hits++
+		return
+	}
+	if len(h.keys) < maxHotKeys {
+		h.keys[key] = &hotKey{key: key, hits: 1, named: named, refresh: refresh}
+	}
+}
+
+// take returns the n most requested keys with at least minWarmHits requests, or any
+// request for a named range, and starts counting again
+func (h *hotKeys) take(n int) []*hotKey {
+	h.mu.Lock()
+	keys := h.keys
+	h.keys = make(map[string]*hotKey)
+	h.mu.Unlock()
+
+	hot := make([]*hotKey, 0, len(keys))
+	for _, key := range keys {
+		if key.hits >= minWarmHits || key.named {
+			hot = append(hot, key)
+		}
+	}
+	sort.Slice(hot, func(i, j int) bool {
+		if hot[i].hits != hot[j].hits {
+			return hot[i].hits > hot[j].hits
+		}
+		return hot[i].key < hot[j].key
+	})
+
+	if len(hot) > n {
+		hot = hot[:n]
+	}
+	return hot
+}
+
+// WithCacheWarmer counts requests per cache key so that RunCacheWarmer can refresh
+// the keys most requested since its last run, up to keys of them
+func (s *TransactionService) WithCacheWarmer(keys int) *TransactionService {
+	s.hotKeys = &hotKeys{keys: make(map[string]*hotKey)}
+	s.warmKeys = keys
+	return s
+}
+
+// RunCacheWarmer refreshes the most requested cache keys every interval until ctx is
+// done, so that they do not expire between runs. Named ranges such as "last 24h" or
+// "month to date" are queried up to the time of each run. Dashboards sending their own
+// from and to share a key, and so get it warmed, only when they round them the same way.
+// It does nothing unless WithCacheWarmer was called.
+func (s *TransactionService) RunCacheWarmer(ctx context.Context, interval time.Duration) {
+	if s.hotKeys == nil {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.warmCache(ctx, time.Now().Add(interval))
+		}
+	}
+}
+
+// warmCache refreshes the most requested cache keys whose results would no longer be
+// fresh at freshAfter, returning how many keys were checked
+func (s *TransactionService) warmCache(ctx context.Context, freshAfter time.Time) int {
+	hot := s.hotKeys.take(s.warmKeys)
+	for _, key := range hot {
+		if ctx.Err() != nil {
+			break
+		}
+		if err := key.refresh(ctx, freshAfter); err != nil {
+			logging.FromContext(ctx).Warn("Failed to warm cache key", "key", key.key, "error", err)
+		}
+	}
+	return len(hot)
+}
@@ -0,0 +1,257 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"admin-statistics-api/internal/model"
+	"admin-statistics-api/internal/repository"
+)
+
+func TestStaleWhileRevalidate(t *testing.T) {
+	// Test data
+	ctx := context.Background()
+	from := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2023, 1, 31, 0, 0, 0, 0, time.UTC)
+	cacheKey := "ggr:2023-01-01T00:00:00Z:2023-01-31T00:00:00Z"
+	staleRows := []model.GGRRow{{Currency: "BTC", GGR: model.MustParseDecimal("1.00")}}
+	freshRows := []model.GGRRow{{Currency: "BTC", GGR: model.MustParseDecimal("2.00")}}
+
+	// statusRecorder returns a context that records reported cache statuses
+	statusRecorder := func() (context.Context, *[]CacheStatus) {
+		statuses := &[]CacheStatus{}
+		return WithCacheStatusReporter(ctx, func(status CacheStatus) {
+			*statuses = append(*statuses, status)
+		}), statuses
+	}
+
+	t.Run("reports a miss, then a fresh hit", func(t *testing.T) {
+		// Arrange
+		service := NewTransactionService(repository.NewMockTransactionRepository(), repository.NewMockCache())
+		statusCtx, statuses := statusRecorder()
+
+		// Act
+		_, err := service.CalculateGGR(statusCtx, from, to, model.TimeBucket{})
+		assert.NoError(t, err)
+		_, err = service.CalculateGGR(statusCtx, from, to, model.TimeBucket{})
+		assert.NoError(t, err)
+
+		// Assert
+		assert.Equal(t, []CacheStatus{CacheMiss, CacheFresh}, *statuses)
+	})
+
+	t.Run("caches results for the stale grace period past their freshness", func(t *testing.T) {
+		// Arrange
+		mockCache := repository.NewMockCache()
+		service := NewTransactionService(repository.NewMockTransactionRepository(), mockCache).WithStaleGrace(time.Minute)
+
+		// Act
+		before := time.Now()
+		_, err := service.CalculateGGR(ctx, from, to, model.TimeBucket{})
+
+		// Assert
+		assert.NoError(t, err)
+		entry, ok := mockCache.SetCalls[cacheKey].(cachedResult[[]model.GGRRow])
+		assert.True(t, ok, "Result should be cached with its freshness")
+		assert.WithinDuration(t, before.Add(cacheExpiration), entry.FreshUntil, time.Second)
+	})
+
+	t.Run("keeps results fresh for the TTL of their cache key prefix", func(t *testing.T) {
+		// Arrange
+		mockCache := repository.NewMockCache()
+		service := NewTransactionService(repository.NewMockTransactionRepository(), mockCache).
+			WithCacheTTLs(time.Hour, map[string]time.Duration{"ggr": time.Minute, "leaderboard": 2 * time.Hour})
+
+		// Act
+		before := time.Now()
+		_, err := service.CalculateGGR(ctx, from, to, model.TimeBucket{})
+		service.WithCacheTTLs(10*time.Minute, nil)
+		_, seriesErr := service.CalculateGGRSeries(ctx, from, to, model.TimeBucket{})
+
+		// Assert
+		assert.NoError(t, err)
+		assert.NoError(t, seriesErr)
+		entry := mockCache.SetCalls[cacheKey].(cachedResult[[]model.GGRRow])
+		assert.WithinDuration(t, before.Add(time.Minute), entry.FreshUntil, time.Second)
+		for key, value := range mockCache.SetCalls {
+			if key != cacheKey {
+				series := value.(cachedResult[[]model.GGRSeriesRow])
+				assert.WithinDuration(t, before.Add(10*time.Minute), series.FreshUntil, time.Second, "Replaced TTLs apply to the next results")
+			}
+		}
+	})
+
+	t.Run("serves a stale result and refreshes it in the background", func(t *testing.T) {
+		// Arrange
+		mockRepo := repository.NewMockTransactionRepository()
+		mockRepo.CalculateGGRFn = func(ctx context.Context, from, to time.Time, bucket model.TimeBucket) ([]model.GGRRow, error) {
+			return freshRows, nil
+		}
+		mockCache := repository.NewMockCache()
+		mockCache.Set(ctx, cacheKey, cachedResult[[]model.GGRRow]{Value: staleRows, FreshUntil: time.Now().Add(-time.Second)}, time.Minute)
+		service := NewTransactionService(mockRepo, mockCache).WithStaleGrace(time.Minute)
+		statusCtx, statuses := statusRecorder()
+
+		// Act
+		stale, err := service.CalculateGGR(statusCtx, from, to, model.TimeBucket{})
+		assert.Eventually(t, func() bool {
+			entry, found := getCached[[]model.GGRRow](context.Background(), mockCache, cacheKey)
+			return found && entry.fresh(time.Now())
+		}, time.Second, 10*time.Millisecond, "Stale result should be refreshed")
+		fresh, _ := service.CalculateGGR(statusCtx, from, to, model.TimeBucket{})
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, staleRows, stale)
+		assert.Equal(t, freshRows, fresh)
+		assert.Equal(t, []CacheStatus{CacheStale, CacheFresh}, *statuses)
+		assert.Len(t, mockRepo.CalculateGGRCalls, 1)
+	})
+
+	t.Run("keeps serving the stale result when the refresh fails", func(t *testing.T) {
+		// Arrange
+		mockRepo := repository.NewMockTransactionRepository()
+		mockRepo.CalculateGGRFn = func(ctx context.Context, from, to time.Time, bucket model.TimeBucket) ([]model.GGRRow, error) {
+			return nil, errors.New("database error")
+		}
+		mockCache := repository.NewMockCache()
+		mockCache.Set(ctx, cacheKey, cachedResult[[]model.GGRRow]{Value: staleRows, FreshUntil: time.Now().Add(-time.Second)}, time.Minute)
+		service := NewTransactionService(mockRepo, mockCache).WithStaleGrace(time.Minute)
+
+		// Act
+		result, err := service.CalculateGGR(ctx, from, to, model.TimeBucket{})
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, staleRows, result)
+	})
+
+	t.Run("treats results cached without freshness as fresh", func(t *testing.T) {
+		// Arrange
+		mockRepo := repository.NewMockTransactionRepository()
+		mockCache := repository.NewMockCache()
+		mockCache.Set(ctx, cacheKey, []interface{}{map[string]interface{}{"currency": "BTC", "ggr": "1.00"}}, time.Minute)
+		service := NewTransactionService(mockRepo, mockCache)
+		statusCtx, statuses := statusRecorder()
+
+		// Act
+		result, err := service.CalculateGGR(statusCtx, from, to, model.TimeBucket{})
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, staleRows, result)
+		assert.Equal(t, []CacheStatus{CacheFresh}, *statuses)
+		assert.Len(t, mockRepo.CalculateGGRCalls, 0)
+	})
+}
+
+func TestCacheWarmer(t *testing.T) {
+	// Test data
+	ctx := context.Background()
+	from := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2023, 1, 31, 0, 0, 0, 0, time.UTC)
+	hotTo := time.Date(2023, 1, 15, 0, 0, 0, 0, time.UTC)
+
+	t.Run("refreshes the most requested keys", func(t *testing.T) {
+		// Arrange
+		mockRepo := repository.NewMockTransactionRepository()
+		service := NewTransactionService(mockRepo, repository.NewMockCache()).WithCacheWarmer(1)
+		for i := 0; i < 3; i++ {
+			service.CalculateGGR(ctx, from, hotTo, model.TimeBucket{})
+		}
+		service.CalculateGGR(ctx, from, to, model.TimeBucket{})
+		mockRepo.CalculateGGRCalls = nil
+
+		// Act
+		warmed := service.warmCache(ctx, time.Now().Add(time.Hour))
+
+		// Assert
+		assert.Equal(t, 1, warmed)
+		assert.Len(t, mockRepo.CalculateGGRCalls, 1)
+		assert.Equal(t, hotTo, mockRepo.CalculateGGRCalls[0].To)
+	})
+
+	t.Run("skips keys that stay fresh until the next run", func(t *testing.T) {
+		// Arrange
+		mockRepo := repository.NewMockTransactionRepository()
+		service := NewTransactionService(mockRepo, repository.NewMockCache()).WithCacheWarmer(10)
+		service.CalculateGGR(ctx, from, to, model.TimeBucket{})
+		service.CalculateGGR(ctx, from, to, model.TimeBucket{})
+		mockRepo.CalculateGGRCalls = nil
+
+		// Act
+		service.warmCache(ctx, time.Now().Add(time.Minute))
+
+		// Assert
+		assert.Len(t, mockRepo.CalculateGGRCalls, 0)
+	})
+
+	t.Run("only warms keys requested since the last run", func(t *testing.T) {
+		// Arrange
+		service := NewTransactionService(repository.NewMockTransactionRepository(), repository.NewMockCache()).WithCacheWarmer(10)
+		service.CalculateGGR(ctx, from, to, model.TimeBucket{})
+		service.CalculateGGR(ctx, from, to, model.TimeBucket{})
+
+		// Act
+		first := service.warmCache(ctx, time.Now().Add(time.Hour))
+		second := service.warmCache(ctx, time.Now().Add(time.Hour))
+
+		// Assert
+		assert.Equal(t, 1, first)
+		assert.Equal(t, 0, second)
+	})
+
+	t.Run("skips keys requested once, such as rolling windows", func(t *testing.T) {
+		// Arrange
+		mockRepo := repository.NewMockTransactionRepository()
+		service := NewTransactionService(mockRepo, repository.NewMockCache()).WithCacheWarmer(10)
+		now := time.Now()
+		for i := 0; i < 3; i++ {
+			service.CalculateGGR(ctx, now.Add(-24*time.Hour), now, model.TimeBucket{})
+			now = now.Add(time.Second)
+		}
+		mockRepo.CalculateGGRCalls = nil
+
+		// Act
+		warmed := service.warmCache(ctx, time.Now().Add(time.Hour))
+
+		// Assert
+		assert.Equal(t, 0, warmed)
+		assert.Len(t, mockRepo.CalculateGGRCalls, 0)
+	})
+
+	t.Run("warms named ranges after one request, up to the time of the run", func(t *testing.T) {
+		// Arrange
+		mockRepo := repository.NewMockTransactionRepository()
+		mockCache := repository.NewMockCache()
+		service := NewTransactionService(mockRepo, mockCache).WithCacheWarmer(10)
+		requested := time.Now().Add(-time.Minute)
+		service.CalculateGGR(WithNamedRange(ctx, model.RangeLast24h), requested.Add(-24*time.Hour), requested, model.TimeBucket{})
+		mockRepo.CalculateGGRCalls = nil
+
+		// Act
+		before := time.Now()
+		warmed := service.warmCache(ctx, time.Now().Add(time.Hour))
+
+		// Assert
+		assert.Equal(t, 1, warmed)
+		assert.Len(t, mockRepo.CalculateGGRCalls, 1)
+		call := mockRepo.CalculateGGRCalls[0]
+		assert.False(t, call.To.Before(before), "The range is resolved again when it is warmed")
+		assert.Equal(t, 24*time.Hour, call.To.Sub(call.From))
+		assert.Contains(t, mockCache.SetCalls, "ggr:last_24h")
+	})
+
+	t.Run("does nothing without WithCacheWarmer", func(t *testing.T) {
+		service := NewTransactionService(repository.NewMockTransactionRepository(), repository.NewMockCache())
+		ctx, cancel := context.WithCancel(ctx)
+		cancel()
+
+		service.RunCacheWarmer(ctx, time.Millisecond)
+
+		assert.Nil(t, service.hotKeys)
+	})
+}
@@ -0,0 +1,350 @@
+package service
+
+import (
+	"context"
+	"crypto"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+	"time"
+
+	"admin-statistics-api/internal/config"
+	"admin-statistics-api/internal/model"
+)
+
+// ErrInvalidToken is returned when a bearer token is malformed, badly signed, expired,
+// not yet valid, or issued by or for someone else. The reasons are not distinguished.
+var ErrInvalidToken = errors.New("invalid or expired token")
+
+// JWTService verifies HS256 and RS256 JSON Web Tokens and maps their roles to scopes
+type JWTService struct {
+	secret     []byte                    // HS256 shared secret from the configuration
+	hmacKeys   map[string][]byte         // HS256 keys from the JWKS file, by key ID
+	rsaKeys    map[string]*rsa.PublicKey // RS256 keys from the JWKS file, by key ID
+	issuer     string
+	audience   string
+	rolesClaim string
+	roleScopes map[string][]string
+	clockSkew  time.Duration
+	now        func() time.Time
+}
+
+// jwtHeader is the decoded JOSE header of a token
+type jwtHeader struct {
+	Algorithm string `json:"alg"`
+	KeyID     string `json:"kid"`
+}
+
+// jwk is one key of a JSON Web Key Set
+type jwk struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	N         string `json:"n"` // RSA modulus
+	E         string `json:"e"` // RSA exponent
+	K         string `json:"k"` // Symmetric key
+}
+
+// NewJWTService creates a JWTService from the JWT fields of the auth configuration,
+// loading the JWKS file if one is configured
+func NewJWTService(cfg config.AuthConfig) (*JWTService, error) {
+	if !cfg.JWTEnabled() {
+		return nil, errors.New("JWT auth requires a secret or a JWKS file")
+	}
+
+	s := &JWTService{
+		secret:     []byte(cfg.JWTSecret),
+		hmacKeys:   make(map[string][]byte),
+		rsaKeys:    make(map[string]*rsa.PublicKey),
+		issuer:     cfg.JWTIssuer,
+		audience:   cfg.JWTAudience,
+		rolesClaim: cfg.JWTRolesClaim,
+		roleScopes: cfg.JWTRoleScopes,
+		clockSkew:  cfg.JWTClockSkew,
+		now:        time.Now,
+	}
+
+	if cfg.JWTJWKSFile != "" {
+		if err := s.loadJWKS(cfg.JWTJWKSFile); err != nil {
+			return nil, fmt.Errorf("load JWKS file: %w", err)
+		}
+	}
+
+	return s, nil
+}
+
+// VerifyToken validates a compact JWT and returns the principal it identifies
+func (s *JWTService) VerifyToken(ctx context.Context, token string) (model.Principal, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return model.Principal{}, ErrInvalidToken
+	}
+
+	var header jwtHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return model.Principal{}, ErrInvalidToken
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return model.Principal{}, ErrInvalidToken
+	}
+	if err := s.verifySignature(header, parts[0]+"."+parts[1], signature); err != nil {
+		return model.Principal{}, ErrInvalidToken
+	}
+
+	var claims map[string]interface{}
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return model.Principal{}, ErrInvalidToken
+	}
+	if err := s.validateClaims(claims); err != nil {
+		return model.Principal{}, ErrInvalidToken
+	}
+
+	subject, _ := claims["sub"].(string)
+	roles := stringList(claims[s.rolesClaim])
+
+	return model.Principal{
+		ID:     "jwt:" + subject,
+		Name:   subject,
+		Scopes: s.scopesForRoles(roles),
+		Roles:  roles,
+	}, nil
+}
+
+// verifySignature checks the signature with a key matching the token's algorithm.
+// Only HS256 and RS256 are accepted, so "none" and algorithm confusion are rejected.
+func (s *JWTService) verifySignature(header jwtHeader, signingInput string, signature []byte) error {
+	digest := sha256.Sum256([]byte(signingInput))
+
+	switch header.Algorithm {
+	case "HS256":
+		for _, key := range s.hmacKeysFor(header.KeyID) {
+			mac := hmac.New(sha256.New, key)
+			mac.Write([]byte(signingInput))
+			if hmac.Equal(mac.Sum(nil), signature) {
+				return nil
+			}
+		}
+		return errors.New("signature mismatch")
+	case "RS256":
+		for _, key := range s.rsaKeysFor(header.KeyID) {
+			if rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature) == nil {
+				return nil
+			}
+		}
+		return errors.New("signature mismatch")
+	default:
+		return fmt.Errorf("unsupported algorithm %q", header.Algorithm)
+	}
+}
+
+// hmacKeysFor returns the HS256 keys to try for a key ID
+func (s *JWTService) hmacKeysFor(keyID string) [][]byte {
+	if key, ok := s.hmacKeys[keyID]; ok && keyID != "" {
+		return [][]byte{key}
+	}
+
+	var keys [][]byte
+	if len(s.secret) > 0 {
+		keys = append(keys, s.secret)
+	}
+	if keyID == "" {
+		for _, key := range s.hmacKeys {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// rsaKeysFor returns the RS256 keys to try for a key ID
+func (s *JWTService) rsaKeysFor(keyID string) []*rsa.PublicKey {
+	if keyID != "" {
+		if key, ok := s.rsaKeys[keyID]; ok {
+			return []*rsa.PublicKey{key}
+		}
+		return nil
+	}
+
+	keys := make([]*rsa.PublicKey, 0, len(s.rsaKeys))
+	for _, key := range s.rsaKeys {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// validateClaims checks sub, exp, nbf, iss and aud. Tokens must have a subject and must expire.
+func (s *JWTService) validateClaims(claims map[string]interface{}) error {
+	now := s.now()
+
+	if subject, _ := claims["sub"].(string); subject == "" {
+		return errors.New("missing sub")
+	}
+
+	exp, ok := numericDate(claims["exp"])
+	if !ok {
+		return errors.New("missing exp")
+	}
+	if !now.Before(exp.Add(s.clockSkew)) {
+		return errors.New("token expired")
+	}
+
+	if value, present := claims["nbf"]; present {
+		nbf, ok := numericDate(value)
+		if !ok {
+			return errors.New("invalid nbf")
+		}
+		if now.Add(s.clockSkew).Before(nbf) {
+			return errors.New("token not yet valid")
+		}
+	}
+
+	if s.issuer != "" {
+		if issuer, _ := claims["iss"].(string); issuer != s.issuer {
+			return errors.New("unexpected issuer")
+		}
+	}
+
+	if s.audience != "" {
+		found := false
+		for _, audience := range stringList(claims["aud"]) {
+			if audience == s.audience {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return errors.New("unexpected audience")
+		}
+	}
+
+	return nil
+}
+
+// scopesForRoles returns the union of the scopes granted by each role, in a stable order
+func (s *JWTService) scopesForRoles(roles []string) []string {
+	granted := make(map[string]bool)
+	for _, role := range roles {
+		for _, scope := range s.roleScopes[role] {
+			granted[scope] = true
+		}
+	}
+
+	scopes := make([]string, 0, len(granted))
+	for _, scope := range model.AllScopes {
+		if granted[scope] {
+			scopes = append(scopes, scope)
+		}
+	}
+	return scopes
+}
+
+// loadJWKS reads RSA and symmetric keys from a JSON Web Key Set file
+func (s *JWTService) loadJWKS(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return err
+	}
+
+	for _, key := range set.Keys {
+		if key.Use != "" && key.Use != "sig" {
+			continue
+		}
+
+		switch key.KeyType {
+		case "RSA":
+			if key.Algorithm != "" && key.Algorithm != "RS256" {
+				continue
+			}
+			publicKey, err := key.rsaPublicKey()
+			if err != nil {
+				return fmt.Errorf("key %q: %w", key.KeyID, err)
+			}
+			s.rsaKeys[key.KeyID] = publicKey
+		case "oct":
+			if key.Algorithm != "" && key.Algorithm != "HS256" {
+				continue
+			}
+			secret, err := base64.RawURLEncoding.DecodeString(key.K)
+			if err != nil {
+				return fmt.Errorf("key %q: %w", key.KeyID, err)
+			}
+			s.hmacKeys[key.KeyID] = secret
+		}
+	}
+
+	if len(s.rsaKeys) == 0 && len(s.hmacKeys) == 0 {
+		return errors.New("no usable signing keys")
+	}
+	return nil
+}
+
+// rsaPublicKey decodes the modulus and exponent of an RSA JWK
+func (k jwk) rsaPublicKey() (*rsa.PublicKey, error) {
+	n, err := base64.RawURLEncoding.DecodeString(k.N)
+	if err != nil {
+		return nil, fmt.Errorf("invalid modulus: %w", err)
+	}
+	e, err := base64.RawURLEncoding.DecodeString(k.E)
+	if err != nil {
+		return nil, fmt.Errorf("invalid exponent: %w", err)
+	}
+
+	exponent := new(big.Int).SetBytes(e)
+	if len(n) == 0 || !exponent.IsInt64() || exponent.Int64() < 3 || exponent.Int64() > 1<<31-1 {
+		return nil, errors.New("invalid RSA key")
+	}
+
+	return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exponent.Int64())}, nil
+}
+
+// decodeSegment decodes a base64url JSON segment of a token
+func decodeSegment(segment string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// numericDate converts a JWT NumericDate (seconds since the epoch) to a time
+func numericDate(value interface{}) (time.Time, bool) {
+	seconds, ok := value.(float64)
+	if !ok {
+		return time.Time{}, false
+	}
+	return time.Unix(0, int64(seconds*float64(time.Second))), true
+}
+
+// stringList reads a claim that holds a string or an array of strings
+func stringList(value interface{}) []string {
+	switch v := value.(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		list := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				list = append(list, s)
+			}
+		}
+		return list
+	default:
+		return nil
+	}
+}
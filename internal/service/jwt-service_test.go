@@ -0,0 +1,239 @@
+package service
+
+import (
+	"context"
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"admin-statistics-api/internal/config"
+	"admin-statistics-api/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+// signHS256 builds a compact JWT signed with HMAC-SHA256
+func signHS256(t *testing.T, header, claims map[string]interface{}, secret []byte) string {
+	t.Helper()
+	input := encodeSegment(t, header) + "." + encodeSegment(t, claims)
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(input))
+	return input + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// signRS256 builds a compact JWT signed with RSA PKCS #1 v1.5 and SHA-256
+func signRS256(t *testing.T, header, claims map[string]interface{}, key *rsa.PrivateKey) string {
+	t.Helper()
+	input := encodeSegment(t, header) + "." + encodeSegment(t, claims)
+	digest := sha256.Sum256([]byte(input))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatalf("Failed to sign token: %v", err)
+	}
+	return input + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func encodeSegment(t *testing.T, v interface{}) string {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("Failed to encode segment: %v", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// writeJWKS writes a JWKS file holding an RSA public key and a symmetric key
+func writeJWKS(t *testing.T, rsaKey *rsa.PublicKey, hmacKey []byte) string {
+	t.Helper()
+	set := map[string]interface{}{
+		"keys": []map[string]string{
+			{
+				"kty": "RSA",
+				"kid": "rsa-1",
+				"use": "sig",
+				"alg": "RS256",
+				"n":   base64.RawURLEncoding.EncodeToString(rsaKey.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(rsaKey.E)).Bytes()),
+			},
+			{
+				"kty": "oct",
+				"kid": "hmac-1",
+				"alg": "HS256",
+				"k":   base64.RawURLEncoding.EncodeToString(hmacKey),
+			},
+		},
+	}
+	data, err := json.Marshal(set)
+	if err != nil {
+		t.Fatalf("Failed to encode JWKS: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatalf("Failed to write JWKS: %v", err)
+	}
+	return path
+}
+
+func TestJWTService(t *testing.T) {
+	// Test data
+	ctx := context.Background()
+	now := time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)
+	secret := []byte("shared-secret")
+	jwksSecret := []byte("jwks-secret")
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	otherRSAKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+
+	cfg := config.AuthConfig{
+		JWTSecret:     string(secret),
+		JWTJWKSFile:   writeJWKS(t, &rsaKey.PublicKey, jwksSecret),
+		JWTIssuer:     "https://id.example.com",
+		JWTAudience:   "admin-statistics-api",
+		JWTRolesClaim: "roles",
+		JWTRoleScopes: map[string][]string{
+			"analyst": {model.ScopeStatsRead, model.ScopeUserRead},
+			"viewer":  {model.ScopeStatsRead},
+		},
+		JWTClockSkew: 30 * time.Second,
+	}
+	service, err := NewJWTService(cfg)
+	assert.NoError(t, err)
+	service.now = func() time.Time { return now }
+
+	hs256 := map[string]interface{}{"alg": "HS256", "typ": "JWT"}
+	rs256 := map[string]interface{}{"alg": "RS256", "typ": "JWT", "kid": "rsa-1"}
+	validClaims := func() map[string]interface{} {
+		return map[string]interface{}{
+			"sub":   "alice",
+			"iss":   "https://id.example.com",
+			"aud":   "admin-statistics-api",
+			"exp":   now.Add(5 * time.Minute).Unix(),
+			"nbf":   now.Add(-time.Minute).Unix(),
+			"roles": []string{"viewer", "analyst"},
+		}
+	}
+	withClaim := func(key string, value interface{}) map[string]interface{} {
+		claims := validClaims()
+		if value == nil {
+			delete(claims, key)
+		} else {
+			claims[key] = value
+		}
+		return claims
+	}
+
+	t.Run("accepts HS256 token signed with the configured secret", func(t *testing.T) {
+		principal, err := service.VerifyToken(ctx, signHS256(t, hs256, validClaims(), secret))
+
+		assert.NoError(t, err)
+		assert.Equal(t, "jwt:alice", principal.ID)
+		assert.Equal(t, "alice", principal.Name)
+		assert.Equal(t, []string{"viewer", "analyst"}, principal.Roles)
+		assert.Equal(t, []string{model.ScopeStatsRead, model.ScopeUserRead}, principal.Scopes)
+	})
+
+	t.Run("accepts HS256 token signed with a JWKS key", func(t *testing.T) {
+		header := map[string]interface{}{"alg": "HS256", "kid": "hmac-1"}
+
+		_, err := service.VerifyToken(ctx, signHS256(t, header, validClaims(), jwksSecret))
+
+		assert.NoError(t, err)
+	})
+
+	t.Run("accepts RS256 token signed with a JWKS key", func(t *testing.T) {
+		principal, err := service.VerifyToken(ctx, signRS256(t, rs256, validClaims(), rsaKey))
+
+		assert.NoError(t, err)
+		assert.Equal(t, "alice", principal.Name)
+	})
+
+	t.Run("maps roles to scopes", func(t *testing.T) {
+		cases := map[string]struct {
+			roles  interface{}
+			scopes []string
+		}{
+			"single role as string": {"viewer", []string{model.ScopeStatsRead}},
+			"unknown role":          {[]string{"intern"}, []string{}},
+			"no roles":              {nil, []string{}},
+		}
+
+		for name, tc := range cases {
+			t.Run(name, func(t *testing.T) {
+				principal, err := service.VerifyToken(ctx, signHS256(t, hs256, withClaim("roles", tc.roles), secret))
+
+				assert.NoError(t, err)
+				assert.Equal(t, tc.scopes, principal.Scopes)
+			})
+		}
+	})
+
+	t.Run("rejects invalid tokens", func(t *testing.T) {
+		valid := signHS256(t, hs256, validClaims(), secret)
+		parts := strings.Split(valid, ".")
+
+		tokens := map[string]string{
+			"malformed":              "not-a-token",
+			"wrong HS256 secret":     signHS256(t, hs256, validClaims(), []byte("wrong")),
+			"wrong RSA key":          signRS256(t, rs256, validClaims(), otherRSAKey),
+			"unknown key ID":         signRS256(t, map[string]interface{}{"alg": "RS256", "kid": "rsa-2"}, validClaims(), rsaKey),
+			"alg none":               encodeSegment(t, map[string]interface{}{"alg": "none"}) + "." + parts[1] + ".",
+			"unsupported alg":        signHS256(t, map[string]interface{}{"alg": "HS512"}, validClaims(), secret),
+			"tampered claims":        parts[0] + "." + encodeSegment(t, withClaim("roles", []string{"admin"})) + "." + parts[2],
+			"expired":                signHS256(t, hs256, withClaim("exp", now.Add(-time.Minute).Unix()), secret),
+			"missing exp":            signHS256(t, hs256, withClaim("exp", nil), secret),
+			"not yet valid":          signHS256(t, hs256, withClaim("nbf", now.Add(time.Minute).Unix()), secret),
+			"wrong issuer":           signHS256(t, hs256, withClaim("iss", "https://evil.example.com"), secret),
+			"missing issuer":         signHS256(t, hs256, withClaim("iss", nil), secret),
+			"wrong audience":         signHS256(t, hs256, withClaim("aud", "another-api"), secret),
+			"audience list without":  signHS256(t, hs256, withClaim("aud", []string{"a", "b"}), secret),
+			"missing subject":        signHS256(t, hs256, withClaim("sub", nil), secret),
+			"RS256 header with HMAC": signHS256(t, rs256, validClaims(), secret),
+		}
+
+		for name, token := range tokens {
+			t.Run(name, func(t *testing.T) {
+				_, err := service.VerifyToken(ctx, token)
+
+				assert.ErrorIs(t, err, ErrInvalidToken)
+			})
+		}
+	})
+
+	t.Run("accepts audience lists and clock skew", func(t *testing.T) {
+		tokens := map[string]string{
+			"audience list":          signHS256(t, hs256, withClaim("aud", []string{"other", "admin-statistics-api"}), secret),
+			"expired within skew":    signHS256(t, hs256, withClaim("exp", now.Add(-10*time.Second).Unix()), secret),
+			"not before within skew": signHS256(t, hs256, withClaim("nbf", now.Add(10*time.Second).Unix()), secret),
+		}
+
+		for name, token := range tokens {
+			t.Run(name, func(t *testing.T) {
+				_, err := service.VerifyToken(ctx, token)
+
+				assert.NoError(t, err)
+			})
+		}
+	})
+
+	t.Run("requires a secret or JWKS file", func(t *testing.T) {
+		_, err := NewJWTService(config.AuthConfig{})
+
+		assert.Error(t, err)
+	})
+
+	t.Run("fails on an unreadable JWKS file", func(t *testing.T) {
+		_, err := NewJWTService(config.AuthConfig{JWTJWKSFile: filepath.Join(t.TempDir(), "missing.json")})
+
+		assert.Error(t, err)
+	})
+}
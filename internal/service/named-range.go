@@ -0,0 +1,32 @@
+package service
+
+import (
+	"context"
+	"time"
+)
+
+// namedRangeKey is the context key holding the named range a query was asked for
+type namedRangeKey struct{}
+
+// WithNamedRange returns a context telling the service that from and to were resolved
+// from a named range such as model.RangeLast24h. Results for the range are cached under
+// its name rather than its bounds, so every request for it shares one result, and the
+// cache warmer resolves the range again each time it refreshes it.
+func WithNamedRange(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, namedRangeKey{}, name)
+}
+
+// namedRange returns the named range in ctx, if there is one
+func namedRange(ctx context.Context) (string, bool) {
+	name, ok := ctx.Value(namedRangeKey{}).(string)
+	return name, ok && name != ""
+}
+
+// rangeKey returns the part of a cache key naming the range from to: the range's name
+// when ctx has one, otherwise its bounds
+func rangeKey(ctx context.Context, from, to time.Time) string {
+	if name, ok := namedRange(ctx); ok {
+		return name
+	}
+	return from.Format(time.RFC3339) + ":" + to.Format(time.RFC3339)
+}
@@ -0,0 +1,209 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"admin-statistics-api/internal/logging"
+	"admin-statistics-api/internal/model"
+	"admin-statistics-api/internal/repository"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/sync/singleflight"
+)
+
+var tracer = otel.Tracer("admin-statistics-api/internal/service")
+
+const (
+	// cacheExpiration is how long query results stay fresh in the cache unless
+	// WithCacheTTLs sets otherwise
+	cacheExpiration = 5 * time.Minute
+
+	// coalescedQueryTimeout bounds a query shared by several callers, which no longer
+	// runs under any one caller's context. It is also how long its lock is held at most.
+	coalescedQueryTimeout = 30 * time.Second
+
+	// lockPollInterval is how often an instance waiting on another's query checks the cache
+	lockPollInterval = 50 * time.Millisecond
+)
+
+// coalesce returns the cached result for cacheKey, or runs query and caches its result.
+// A result past its freshness is still returned during the service's stale grace
+// period, while one caller refreshes it in the background.
+//
+// Concurrent misses for the same key in this process share one query, and when the
+// service has a Locker, so do misses on other instances. Each caller stops waiting
+// when its own context is done, without cancelling the query for the others.
+func coalesce[T any](ctx context.Context, s *TransactionService, cacheKey string, from, to time.Time, query func(ctx context.Context, from, to time.Time) (T, error)) (T, error) {
+	var zero T
+
+	if s.hotKeys != nil {
+		name, named := namedRange(ctx)
+		s.hotKeys.record(cacheKey, named, func(ctx context.Context, freshAfter time.Time) error {
+			// A named range ends when it is refreshed, not when it was requested
+			from, to := from, to
+			if named {
+				from, to, _ = model.ResolveRange(name, time.Now())
+			}
+			return (<-revalidate(ctx, s, cacheKey, from, to, query, freshAfter)).Err
+		})
+	}
+
+	// Check cache
+	now := time.Now()
+	logging.AddAttrs(ctx, slog.String("cache_key", cacheKey))
+	_, lookup := tracer.Start(ctx, "cache.lookup", trace.WithAttributes(attribute.String("cache.key", cacheKey)))
+	cached, found := getCached[T](ctx, s.cache, cacheKey)
+	status := CacheMiss
+	if found {
+		status = CacheStale
+		if cached.fresh(now) {
+			status = CacheFresh
+		}
+	}
+	lookup.SetAttributes(attribute.Bool("cache.hit", found), attribute.String("cache.status", string(status)))
+	lookup.End()
+
+	if status == CacheFresh {
+		reportCacheStatus(ctx, CacheFresh)
+		return cached.Value, nil
+	}
+
+	// Serve a stale result right away and refresh it for the next caller
+	if status == CacheStale {
+		refreshed := revalidate(ctx, s, cacheKey, from, to, query, now)
+		logger := logging.FromContext(ctx)
+		go func() {
+			if result := <-refreshed; result.Err != nil {
+				logger.Warn("Failed to refresh stale cache key", "key", cacheKey, "error", result.Err)
+			}
+		}()
+
+		reportCacheStatus(ctx, CacheStale)
+		return cached.Value, nil
+	}
+
+	// Only the first caller runs the query; the others wait for its result
+	select {
+	case result := <-revalidate(ctx, s, cacheKey, from, to, query, now):
+		if result.Err != nil {
+			return zero, result.Err
+		}
+		reportCacheStatus(ctx, CacheMiss)
+		return result.Val.(T), nil
+	case <-ctx.Done():
+		return zero, ctx.Err()
+	}
+}
+
+// revalidate starts a query for cacheKey unless one is already running in this process,
+// and returns a channel that receives its result. A cached result that is still fresh
+// at freshAfter is returned without querying.
+func revalidate[T any](ctx context.Context, s *TransactionService, cacheKey string, from, to time.Time, query func(ctx context.Context, from, to time.Time) (T, error), freshAfter time.Time) <-chan singleflight.Result {
+	parent := trace.SpanFromContext(ctx)
+	requestCtx := logging.CopyContext(context.Background(), ctx)
+	return s.flights.DoChan(cacheKey, func() (interface{}, error) {
+		// The query outlives the request that started it, so it must not use the
+		// request's context, which gin recycles once the handler returns. Its spans and
+		// logs still belong to the request that started it.
+		detached := logging.CopyContext(trace.ContextWithSpan(context.Background(), parent), requestCtx)
+		ctx, cancel := context.WithTimeout(detached, coalescedQueryTimeout)
+		defer cancel()
+
+		return queryOnce(ctx, s, cacheKey, from, to, query, freshAfter)
+	})
+}
+
+// queryOnce runs query and caches its result, unless the cache already holds a result
+// that is fresh at freshAfter, or another instance holding the lock for cacheKey caches
+// one first
+func queryOnce[T any](ctx context.Context, s *TransactionService, cacheKey string, from, to time.Time, query func(ctx context.Context, from, to time.Time) (T, error), freshAfter time.Time) (T, error) {
+	var zero T
+
+	// Another query may have cached the result since the caller checked
+	if cached, found := getCached[T](ctx, s.cache, cacheKey); found && cached.fresh(freshAfter) {
+		return cached.Value, nil
+	}
+
+	if s.locker != nil {
+		unlock, cached, found := waitForLock[T](ctx, s, cacheKey, freshAfter)
+		if found {
+			return cached, nil
+		}
+		if unlock != nil {
+			defer unlock()
+		}
+	}
+
+	// Query the repository
+	invalidations := s.invalidations.Load()
+	results, err := query(ctx, from, to)
+	if err != nil {
+		return zero, err
+	}
+
+	// Cache the results, keeping them past their freshness for the stale grace period.
+	// If the cache was invalidated meanwhile they may be missing new transactions,
+	// so they are only kept as stale.
+	ttl := s.cacheTTL(cacheKey)
+	entry := cachedResult[T]{Value: results, FreshUntil: time.Now().Add(ttl)}
+	if s.invalidations.Load() != invalidations {
+		entry.FreshUntil = time.Now()
+	}
+	_, set := tracer.Start(ctx, "cache.set", trace.WithAttributes(attribute.String("cache.key", cacheKey)))
+	if rangeCache, ok := s.cache.(repository.RangeCache); ok {
+		rangeCache.SetRange(ctx, cacheKey, entry, ttl+s.staleGrace, from, to)
+	} else {
+		s.cache.Set(ctx, cacheKey, entry, ttl+s.staleGrace)
+	}
+	set.End()
+
+	return results, nil
+}
+
+// waitForLock takes the lock for cacheKey, or waits for the instance holding it to cache
+// a result that is fresh at freshAfter. It returns the cached result if one appears,
+// otherwise the unlock function, which is nil if the lock could not be taken within
+// lockWait and the caller should query without it.
+func waitForLock[T any](ctx context.Context, s *TransactionService, cacheKey string, freshAfter time.Time) (func(), T, bool) {
+	var zero T
+
+	ticker := time.NewTicker(lockPollInterval)
+	defer ticker.Stop()
+	deadline := time.Now().Add(s.lockWait)
+
+	for {
+		unlock, acquired, err := s.locker.TryLock(ctx, cacheKey, coalescedQueryTimeout)
+		if err != nil {
+			logging.FromContext(ctx).Warn("Failed to lock cache key, querying without it", "key", cacheKey, "error", err)
+			return nil, zero, false
+		}
+
+		// Another instance may have cached the result just before releasing the lock
+		cached, found := getCached[T](ctx, s.cache, cacheKey)
+		found = found && cached.fresh(freshAfter)
+		if acquired {
+			if found {
+				unlock()
+				return nil, cached.Value, true
+			}
+			return unlock, zero, false
+		}
+		if found {
+			return nil, cached.Value, true
+		}
+
+		if time.Now().After(deadline) {
+			logging.FromContext(ctx).Warn("Timed out waiting for cache key lock, querying without it", "key", cacheKey)
+			return nil, zero, false
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, zero, false
+		case <-ticker.C:
+		}
+	}
+}
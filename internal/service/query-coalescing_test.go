@@ -0,0 +1,277 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+	"admin-statistics-api/internal/logging"
+	"admin-statistics-api/internal/model"
+	"admin-statistics-api/internal/repository"
+)
+
+// callConcurrently calls fn from n goroutines and returns their results once all are done.
+// The goroutines are started before release is closed, so they all miss the cache together.
+func callConcurrently(n int, release chan struct{}, fn func() ([]model.GGRRow, error)) ([][]model.GGRRow, []error) {
+	results := make([][]model.GGRRow, n)
+	errs := make([]error, n)
+
+	var started, done sync.WaitGroup
+	started.Add(n)
+	done.Add(n)
+	for i := 0; i < n; i++ {
+		go func(i int) {
+			defer done.Done()
+			started.Done()
+			results[i], errs[i] = fn()
+		}(i)
+	}
+
+	// Give every goroutine time to join the query in progress before it finishes
+	started.Wait()
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	done.Wait()
+
+	return results, errs
+}
+
+func TestCoalescing(t *testing.T) {
+	// Test data
+	ctx := context.Background()
+	from := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2023, 1, 31, 0, 0, 0, 0, time.UTC)
+	repoResult := []model.GGRRow{
+		{Currency: "BTC", GGR: model.MustParseDecimal("10.50"), GGRUSD: model.MustParseDecimal("525000.00")},
+	}
+
+	// blockingRepo returns a repository whose CalculateGGR waits for release
+	blockingRepo := func(release chan struct{}, err error) *repository.MockTransactionRepository {
+		mockRepo := repository.NewMockTransactionRepository()
+		mockRepo.CalculateGGRFn = func(ctx context.Context, from, to time.Time, bucket model.TimeBucket) ([]model.GGRRow, error) {
+			<-release
+			if err != nil {
+				return nil, err
+			}
+			return repoResult, nil
+		}
+		return mockRepo
+	}
+
+	t.Run("concurrent misses query the repository once", func(t *testing.T) {
+		// Arrange
+		release := make(chan struct{})
+		mockRepo := blockingRepo(release, nil)
+		service := NewTransactionService(mockRepo, repository.NewMockCache())
+
+		// Act
+		results, errs := callConcurrently(50, release, func() ([]model.GGRRow, error) {
+			return service.CalculateGGR(ctx, from, to, model.TimeBucket{})
+		})
+
+		// Assert
+		assert.Len(t, mockRepo.CalculateGGRCalls, 1, "Repository should be called once for all callers")
+		for i := range results {
+			assert.NoError(t, errs[i])
+			assert.Equal(t, repoResult, results[i])
+		}
+	})
+
+	t.Run("different cache keys query separately", func(t *testing.T) {
+		// Arrange
+		release := make(chan struct{})
+		mockRepo := blockingRepo(release, nil)
+		service := NewTransactionService(mockRepo, repository.NewMockCache())
+
+		// Act
+		callConcurrently(20, release, func() ([]model.GGRRow, error) {
+			return service.CalculateGGR(ctx, from, to, model.TimeBucket{})
+		})
+		callConcurrently(20, make(chan struct{}), func() ([]model.GGRRow, error) {
+			return service.CalculateGGR(ctx, from, to, model.TimeBucket{Granularity: model.GranularityDay})
+		})
+
+		// Assert
+		assert.Len(t, mockRepo.CalculateGGRCalls, 2)
+	})
+
+	t.Run("shares errors without caching them", func(t *testing.T) {
+		// Arrange
+		release := make(chan struct{})
+		mockRepo := blockingRepo(release, errors.New("database error"))
+		mockCache := repository.NewMockCache()
+		service := NewTransactionService(mockRepo, mockCache)
+
+		// Act
+		_, errs := callConcurrently(20, release, func() ([]model.GGRRow, error) {
+			return service.CalculateGGR(ctx, from, to, model.TimeBucket{})
+		})
+		_, retryErr := service.CalculateGGR(ctx, from, to, model.TimeBucket{})
+
+		// Assert
+		for _, err := range errs {
+			assert.EqualError(t, err, "database error")
+		}
+		assert.Error(t, retryErr)
+		assert.Len(t, mockRepo.CalculateGGRCalls, 2, "A failed query should be retried by the next caller")
+		assert.Empty(t, mockCache.SetCalls)
+	})
+
+	t.Run("a cancelled caller does not cancel the query for the others", func(t *testing.T) {
+		// Arrange
+		release := make(chan struct{})
+		mockRepo := repository.NewMockTransactionRepository()
+		var queryErr error
+		mockRepo.CalculateGGRFn = func(ctx context.Context, from, to time.Time, bucket model.TimeBucket) ([]model.GGRRow, error) {
+			<-release
+			queryErr = ctx.Err()
+			return repoResult, nil
+		}
+		service := NewTransactionService(mockRepo, repository.NewMockCache())
+		cancelledCtx, cancel := context.WithCancel(ctx)
+
+		// Act
+		var cancelledErr error
+		cancelled := make(chan struct{})
+		go func() {
+			_, cancelledErr = service.CalculateGGR(cancelledCtx, from, to, model.TimeBucket{})
+			close(cancelled)
+		}()
+		time.Sleep(20 * time.Millisecond)
+		cancel()
+		<-cancelled
+
+		results, errs := callConcurrently(5, release, func() ([]model.GGRRow, error) {
+			return service.CalculateGGR(ctx, from, to, model.TimeBucket{})
+		})
+
+		// Assert
+		assert.ErrorIs(t, cancelledErr, context.Canceled)
+		assert.NoError(t, queryErr)
+		assert.NoError(t, errs[0])
+		assert.Equal(t, repoResult, results[0])
+		assert.Len(t, mockRepo.CalculateGGRCalls, 1)
+	})
+}
+
+func TestCoalescing_AcrossInstances(t *testing.T) {
+	// Start a miniredis server shared by two service instances
+	s, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("Failed to start miniredis: %v", err)
+	}
+	defer s.Close()
+
+	cache, err := repository.NewRedisCache("redis://" + s.Addr())
+	assert.NoError(t, err)
+	defer cache.Close()
+
+	// Test data
+	ctx := context.Background()
+	from := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2023, 1, 31, 0, 0, 0, 0, time.UTC)
+	repoResult := []model.GGRRow{
+		{Currency: "BTC", GGR: model.MustParseDecimal("10.50"), GGRUSD: model.MustParseDecimal("525000.00")},
+	}
+
+	t.Run("waits for the instance holding the lock", func(t *testing.T) {
+		// Arrange
+		s.FlushAll()
+		release := make(chan struct{})
+		queried := make(chan struct{})
+		firstRepo := repository.NewMockTransactionRepository()
+		firstRepo.CalculateGGRFn = func(ctx context.Context, from, to time.Time, bucket model.TimeBucket) ([]model.GGRRow, error) {
+			close(queried)
+			<-release
+			return repoResult, nil
+		}
+		secondRepo := repository.NewMockTransactionRepository()
+		first := NewTransactionService(firstRepo, cache).WithLocker(repository.NewRedisLocker(cache), 5*time.Second)
+		second := NewTransactionService(secondRepo, cache).WithLocker(repository.NewRedisLocker(cache), 5*time.Second)
+
+		// Act
+		go first.CalculateGGR(ctx, from, to, model.TimeBucket{})
+		<-queried
+		time.AfterFunc(100*time.Millisecond, func() { close(release) })
+		result, err := second.CalculateGGR(ctx, from, to, model.TimeBucket{})
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, repoResult, result)
+		assert.Len(t, firstRepo.CalculateGGRCalls, 1)
+		assert.Len(t, secondRepo.CalculateGGRCalls, 0, "Second instance should use the first instance's result")
+	})
+
+	t.Run("queries without the lock after waiting too long", func(t *testing.T) {
+		// Arrange
+		s.FlushAll()
+		locker := repository.NewRedisLocker(cache)
+		_, acquired, err := locker.TryLock(ctx, "ggr:2023-01-01T00:00:00Z:2023-01-31T00:00:00Z", time.Minute)
+		assert.NoError(t, err)
+		assert.True(t, acquired)
+
+		mockRepo := repository.NewMockTransactionRepository()
+		mockRepo.CalculateGGRFn = func(ctx context.Context, from, to time.Time, bucket model.TimeBucket) ([]model.GGRRow, error) {
+			return repoResult, nil
+		}
+		service := NewTransactionService(mockRepo, cache).WithLocker(locker, 100*time.Millisecond)
+
+		// Act
+		result, err := service.CalculateGGR(ctx, from, to, model.TimeBucket{})
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, repoResult, result)
+		assert.Len(t, mockRepo.CalculateGGRCalls, 1)
+	})
+
+	t.Run("releases the lock after querying", func(t *testing.T) {
+		// Arrange
+		s.FlushAll()
+		service := NewTransactionService(repository.NewMockTransactionRepository(), cache).
+			WithLocker(repository.NewRedisLocker(cache), time.Second)
+
+		// Act
+		_, err := service.CalculateGGR(ctx, from, to, model.TimeBucket{})
+
+		// Assert
+		assert.NoError(t, err)
+		assert.False(t, s.Exists("lock:ggr:2023-01-01T00:00:00Z:2023-01-31T00:00:00Z"))
+		assert.True(t, s.Exists("ggr:2023-01-01T00:00:00Z:2023-01-31T00:00:00Z"))
+	})
+}
+
+func TestCoalescing_LogFields(t *testing.T) {
+	// Arrange
+	from := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2023, 1, 31, 0, 0, 0, 0, time.UTC)
+	mockRepo := repository.NewMockTransactionRepository()
+	mockRepo.CalculateGGRFn = func(ctx context.Context, from, to time.Time, bucket model.TimeBucket) ([]model.GGRRow, error) {
+		logging.AddDuration(ctx, "mongo_ms", 3*time.Millisecond)
+		return []model.GGRRow{}, nil
+	}
+	service := NewTransactionService(mockRepo, repository.NewMockCache())
+
+	// logFields calls the service for a request and returns the fields it logged
+	logFields := func() []slog.Attr {
+		fields := &logging.Fields{}
+		_, err := service.CalculateGGR(logging.ContextWithFields(context.Background(), fields), from, to, model.TimeBucket{})
+		assert.NoError(t, err)
+		return fields.Attrs()
+	}
+
+	// Act
+	miss := logFields()
+	hit := logFields()
+
+	// Assert
+	key := slog.String("cache_key", "ggr:2023-01-01T00:00:00Z:2023-01-31T00:00:00Z")
+	assert.Equal(t, []slog.Attr{key, slog.String("cache", "miss"), slog.Float64("mongo_ms", 3)}, miss,
+		"The query's time is logged for the request that started it")
+	assert.Equal(t, []slog.Attr{key, slog.String("cache", "fresh")}, hit)
+}
@@ -0,0 +1,355 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"admin-statistics-api/internal/logging"
+	"admin-statistics-api/internal/model"
+	"admin-statistics-api/internal/repository"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ErrInvalidReportRequest is returned when a report cannot be run as requested
+var ErrInvalidReportRequest = errors.New("invalid report request")
+
+// ErrReportQueueFull is returned when a report cannot be queued because the queue is full
+var ErrReportQueueFull = errors.New("report queue is full")
+
+// Report job defaults, which the configuration normally overrides
+const (
+	defaultReportTimeout = 10 * time.Minute
+	defaultReportTTL     = 24 * time.Hour
+)
+
+// reportStoreTimeout bounds a store update made after the worker's context is done
+const reportStoreTimeout = 10 * time.Second
+
+// reportShutdownQueued is the error of a job that was still queued when the API stopped
+const reportShutdownQueued = "The API shut down before the report ran; request it again"
+
+// reportResultNotStored is the error of a job whose result the store rejected, such as
+// one larger than a MongoDB document can hold
+const reportResultNotStored = "Report result could not be stored; request a narrower range or coarser granularity"
+
+// reportCancelPollInterval is how often a running job checks whether it was cancelled
+// through another instance, which cannot reach its context
+const reportCancelPollInterval = 5 * time.Second
+
+// ReportService runs reports on a bounded pool of workers and keeps their results in a
+// ReportStore. Jobs are queued in memory, so each one runs on the instance it was
+// requested from, while its status and result can be read through any instance.
+type ReportService struct {
+	repo    repository.TransactionRepositoryInterface
+	store   repository.ReportStore
+	queue   chan model.ReportJob
+	workers int
+	timeout time.Duration
+	ttl     time.Duration
+	now     func() time.Time
+
+	pollInterval time.Duration
+
+	mu      sync.Mutex
+	cancels map[string]context.CancelFunc // Running jobs by ID
+}
+
+// NewReportService creates a ReportService with workers workers and room for queueSize
+// jobs waiting for one. The workers start with Run.
+func NewReportService(repo repository.TransactionRepositoryInterface, store repository.ReportStore, workers, queueSize int) *ReportService {
+	return &ReportService{
+		repo:         repo,
+		store:        store,
+		queue:        make(chan model.ReportJob, queueSize),
+		workers:      workers,
+		timeout:      defaultReportTimeout,
+		ttl:          defaultReportTTL,
+		now:          time.Now,
+		pollInterval: reportCancelPollInterval,
+		cancels:      make(map[string]context.CancelFunc),
+	}
+}
+
+// WithTimeout sets how long a report may run before it fails
+func (s *ReportService) WithTimeout(timeout time.Duration) *ReportService {
+	s.timeout = timeout
+	return s
+}
+
+// WithTTL sets how long a job and its result are kept once it finishes
+func (s *ReportService) WithTTL(ttl time.Duration) *ReportService {
+	s.ttl = ttl
+	return s
+}
+
+// CreateReport stores a queued job for a report of reportType, requested by owner
+func (s *ReportService) CreateReport(ctx context.Context, owner, reportType string, params model.ReportParams) (model.ReportJob, error) {
+	if err := validateReport(reportType, params); err != nil {
+		return model.ReportJob{}, err
+	}
+
+	// A queued job expires as well, in case no worker ever finishes it
+	now := s.now().UTC()
+	job := model.ReportJob{
+		ID:        model.GenerateULID(),
+		Type:      reportType,
+		Params:    params,
+		Owner:     owner,
+		Status:    model.ReportStatusQueued,
+		CreatedAt: now,
+		ExpiresAt: now.Add(s.timeout + s.ttl),
+	}
+	if err := s.store.CreateReport(ctx, job); err != nil {
+		return model.ReportJob{}, err
+	}
+
+	select {
+	case s.queue <- job:
+		return job, nil
+	default:
+		// No worker would run the job, so it is not kept
+		if err := s.store.DeleteReport(context.WithoutCancel(ctx), job.ID); err != nil {
+			logging.FromContext(ctx).Warn("Failed to delete unqueued report", "report_id", job.ID, "error", err)
+		}
+		return model.ReportJob{}, ErrReportQueueFull
+	}
+}
+
+// GetReport returns a job requested by owner. Other principals' jobs are not found.
+func (s *ReportService) GetReport(ctx context.Context, owner, id string) (model.ReportJob, error) {
+	job, err := s.store.FindReport(ctx, id)
+	if err != nil {
+		return model.ReportJob{}, err
+	}
+	if job.Owner != owner {
+		return model.ReportJob{}, repository.ErrReportNotFound
+	}
+	return job, nil
+}
+
+// CancelReport cancels a queued or running job requested by owner and returns it. A
+// finished job is returned unchanged.
+func (s *ReportService) CancelReport(ctx context.Context, owner, id string) (model.ReportJob, error) {
+	job, err := s.GetReport(ctx, owner, id)
+	if err != nil {
+		return model.ReportJob{}, err
+	}
+	if job.Finished() {
+		return job, nil
+	}
+
+	finished := s.now().UTC()
+	job.Status = model.ReportStatusCancelled
+	job.FinishedAt = &finished
+	job.ExpiresAt = finished.Add(s.ttl)
+	err = s.store.UpdateReport(ctx, job, model.ReportStatusQueued, model.ReportStatusRunning)
+	if errors.Is(err, repository.ErrReportStatusChanged) {
+		// The job finished in the meantime
+		return s.GetReport(ctx, owner, id)
+	}
+	if err != nil {
+		return model.ReportJob{}, err
+	}
+
+	// Stop the query if it runs here; other instances notice the status instead
+	s.mu.Lock()
+	cancel := s.cancels[id]
+	s.mu.Unlock()
+	if cancel != nil {
+		cancel()
+	}
+	return job, nil
+}
+
+// Run runs queued jobs on the workers until ctx is cancelled. Jobs that are then
+// running or still queued are marked failed, since no instance will finish them.
+func (s *ReportService) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for i := 0; i < s.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case job := <-s.queue:
+					// Both may be ready at once; do not start a job while stopping
+					if ctx.Err() != nil {
+						s.finishJob(ctx, job, model.ReportStatusFailed, reportShutdownQueued, model.ReportStatusQueued)
+						return
+					}
+					s.runJob(ctx, job)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	for {
+		select {
+		case job := <-s.queue:
+			s.finishJob(ctx, job, model.ReportStatusFailed, reportShutdownQueued, model.ReportStatusQueued)
+		default:
+			return
+		}
+	}
+}
+
+// runJob runs a queued job and stores its result
+func (s *ReportService) runJob(ctx context.Context, job model.ReportJob) {
+	// Claim the job, unless it was cancelled while queued
+	started := s.now().UTC()
+	job.Status = model.ReportStatusRunning
+	job.StartedAt = &started
+	if err := s.store.UpdateReport(ctx, job, model.ReportStatusQueued); err != nil {
+		if !errors.Is(err, repository.ErrReportStatusChanged) && !errors.Is(err, repository.ErrReportNotFound) {
+			logging.FromContext(ctx).Error("Failed to start report", "report_id", job.ID, "error", err)
+		}
+		return
+	}
+
+	jobCtx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+	s.mu.Lock()
+	s.cancels[job.ID] = cancel
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.cancels, job.ID)
+		s.mu.Unlock()
+	}()
+	go s.watchCancel(jobCtx, job.ID, cancel)
+
+	spanCtx, span := tracer.Start(jobCtx, "report.run", trace.WithAttributes(attribute.String("report.id", job.ID), attribute.String("report.type", job.Type)))
+	result, err := s.execute(spanCtx, job)
+	if err == nil {
+		job.Result, err = json.Marshal(result)
+	}
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+
+	switch {
+	case err == nil:
+		s.finishJob(ctx, job, model.ReportStatusSucceeded, "", model.ReportStatusRunning)
+	case ctx.Err() != nil:
+		s.finishJob(ctx, job, model.ReportStatusFailed, "The API shut down while the report ran; request it again", model.ReportStatusRunning)
+	case errors.Is(jobCtx.Err(), context.DeadlineExceeded):
+		s.finishJob(ctx, job, model.ReportStatusFailed, fmt.Sprintf("Report did not finish within %s", s.timeout), model.ReportStatusRunning)
+	case errors.Is(jobCtx.Err(), context.Canceled):
+		// Cancelled through CancelReport, which stored the status
+	default:
+		logging.FromContext(ctx).Error("Report failed", "report_id", job.ID, "type", job.Type, "error", err)
+		s.finishJob(ctx, job, model.ReportStatusFailed, "Report query failed; request it again", model.ReportStatusRunning)
+	}
+}
+
+// finishJob stores a job's final status while it is still in status from. It runs on
+// shutdown too, so it does not use ctx's cancellation. A result the store rejects fails
+// the job, so it does not stay running until it expires.
+func (s *ReportService) finishJob(ctx context.Context, job model.ReportJob, status, message, from string) {
+	storeCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), reportStoreTimeout)
+	defer cancel()
+
+	finished := s.now().UTC()
+	job.Status = status
+	job.Error = message
+	job.FinishedAt = &finished
+	job.ExpiresAt = finished.Add(s.ttl)
+	if status != model.ReportStatusSucceeded {
+		job.Result = nil
+	}
+
+	err := s.store.UpdateReport(storeCtx, job, from)
+	if err == nil || errors.Is(err, repository.ErrReportStatusChanged) || errors.Is(err, repository.ErrReportNotFound) {
+		return
+	}
+	logging.FromContext(ctx).Error("Failed to store report", "report_id", job.ID, "status", status, "result_bytes", len(job.Result), "error", err)
+	if status == model.ReportStatusSucceeded {
+		s.finishJob(ctx, job, model.ReportStatusFailed, reportResultNotStored, from)
+	}
+}
+
+// watchCancel cancels a running job once its stored status is no longer running
+func (s *ReportService) watchCancel(ctx context.Context, id string, cancel context.CancelFunc) {
+	ticker := time.NewTicker(s.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			job, err := s.store.FindReport(ctx, id)
+			if errors.Is(err, repository.ErrReportNotFound) || (err == nil && job.Status != model.ReportStatusRunning) {
+				cancel()
+				return
+			}
+		}
+	}
+}
+
+// execute runs the repository query for a job's report type
+func (s *ReportService) execute(ctx context.Context, job model.ReportJob) (interface{}, error) {
+	p := job.Params
+	switch job.Type {
+	case model.ReportTypeGGR:
+		return nilOnError(s.repo.CalculateGGR(ctx, p.From, p.To, p.TimeBucket()))
+	case model.ReportTypeGGRSeries:
+		return nilOnError(s.repo.CalculateGGRSeries(ctx, p.From, p.To, p.TimeBucket()))
+	case model.ReportTypeDailyWagerVolume:
+		return nilOnError(s.repo.CalculateDailyWagerVolume(ctx, p.From, p.To, p.TimeBucket()))
+	case model.ReportTypeUserWagerPercentile:
+		percentile, err := s.repo.CalculateUserWagerPercentile(ctx, p.UserID, p.From, p.To)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"userID": p.UserID, "percentile": percentile}, nil
+	case model.ReportTypeUserSummary:
+		return nilOnError(s.repo.CalculateUserSummary(ctx, p.UserID, p.From, p.To))
+	case model.ReportTypeLeaderboard:
+		return nilOnError(s.repo.CalculateLeaderboard(ctx, p.Metric, p.Currency, p.Limit, p.From, p.To))
+	case model.ReportTypeRoundAnomalies:
+		return nilOnError(s.repo.FindRoundAnomalies(ctx, p.From, p.To))
+	}
+	return nil, fmt.Errorf("%w: unknown report type %q", ErrInvalidReportRequest, job.Type)
+}
+
+// nilOnError returns a query's result as a report result, or nil when it failed
+func nilOnError[T any](result T, err error) (interface{}, error) {
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// validateReport checks the parameters that every report type needs
+func validateReport(reportType string, params model.ReportParams) error {
+	if _, ok := model.ReportScopes[reportType]; !ok {
+		return fmt.Errorf("%w: unknown report type %q", ErrInvalidReportRequest, reportType)
+	}
+	if params.From.IsZero() || params.To.IsZero() || params.To.Before(params.From) {
+		return fmt.Errorf("%w: from and to must be a time range", ErrInvalidReportRequest)
+	}
+
+	switch reportType {
+	case model.ReportTypeUserWagerPercentile, model.ReportTypeUserSummary:
+		if params.UserID == "" {
+			return fmt.Errorf("%w: %s reports need a user ID", ErrInvalidReportRequest, reportType)
+		}
+	case model.ReportTypeLeaderboard:
+		if params.Metric == "" || params.Limit < 1 {
+			return fmt.Errorf("%w: leaderboard reports need a metric and limit", ErrInvalidReportRequest)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,279 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"admin-statistics-api/internal/model"
+	"admin-statistics-api/internal/repository"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReportService(t *testing.T) {
+	// Test data
+	ctx := context.Background()
+	params := model.ReportParams{
+		From:        time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC),
+		To:          time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		Granularity: model.GranularityDay,
+	}
+
+	// start runs the service's workers until the test ends
+	start := func(t *testing.T, service *ReportService) context.CancelFunc {
+		runCtx, stop := context.WithCancel(ctx)
+		done := make(chan struct{})
+		go func() {
+			service.Run(runCtx)
+			close(done)
+		}()
+		t.Cleanup(func() {
+			stop()
+			<-done
+		})
+		return func() {
+			stop()
+			<-done
+		}
+	}
+
+	// waitFor waits until the stored job has status
+	waitFor := func(t *testing.T, store *repository.MockReportStore, id, status string) model.ReportJob {
+		assert.Eventually(t, func() bool {
+			job, _ := store.Report(id)
+			return job.Status == status
+		}, time.Second, 5*time.Millisecond)
+		job, _ := store.Report(id)
+		return job
+	}
+
+	// blockingRepo returns a repository whose daily wager volume query runs until its
+	// context is done, with the channel it is started on
+	blockingRepo := func() (*repository.MockTransactionRepository, chan struct{}, chan error) {
+		started := make(chan struct{}, 1)
+		stopped := make(chan error, 1)
+		repo := &repository.MockTransactionRepository{
+			CalculateDailyWagerVolumeFn: func(ctx context.Context, from, to time.Time, bucket model.TimeBucket) ([]model.DailyWagerRow, error) {
+				started <- struct{}{}
+				<-ctx.Done()
+				select {
+				case stopped <- ctx.Err():
+				default:
+				}
+				return nil, ctx.Err()
+			},
+		}
+		return repo, started, stopped
+	}
+
+	t.Run("runs a queued report and keeps its result", func(t *testing.T) {
+		// Arrange
+		repo := &repository.MockTransactionRepository{
+			CalculateDailyWagerVolumeFn: func(ctx context.Context, from, to time.Time, bucket model.TimeBucket) ([]model.DailyWagerRow, error) {
+				return []model.DailyWagerRow{{Date: "2023-01-01", Currency: "ETH", WagerAmount: model.MustParseDecimal("1.5"), WagerUSDAmount: model.MustParseDecimal("3000")}}, nil
+			},
+		}
+		store := repository.NewMockReportStore()
+		service := NewReportService(repo, store, 1, 1).WithTTL(time.Hour)
+		start(t, service)
+
+		// Act
+		job, err := service.CreateReport(ctx, "default", model.ReportTypeDailyWagerVolume, params)
+		finished := waitFor(t, store, job.ID, model.ReportStatusSucceeded)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, model.ReportStatusQueued, job.Status)
+		assert.JSONEq(t, `[{"date":"2023-01-01","currency":"ETH","wagerAmount":"1.5","wagerUSDAmount":"3000"}]`, string(finished.Result))
+		if assert.NotNil(t, finished.StartedAt) && assert.NotNil(t, finished.FinishedAt) {
+			assert.Equal(t, finished.FinishedAt.Add(time.Hour), finished.ExpiresAt)
+		}
+		assert.Equal(t, params.From, repo.CalculateDailyWagerVolumeCalls[0].From)
+		assert.Equal(t, model.GranularityDay, repo.CalculateDailyWagerVolumeCalls[0].Bucket.Granularity)
+	})
+
+	t.Run("refuses a report when the queue is full", func(t *testing.T) {
+		// Arrange
+		store := repository.NewMockReportStore()
+		service := NewReportService(&repository.MockTransactionRepository{}, store, 1, 1)
+		_, err := service.CreateReport(ctx, "default", model.ReportTypeGGR, params)
+		assert.NoError(t, err)
+
+		// Act
+		_, err = service.CreateReport(ctx, "default", model.ReportTypeGGR, params)
+
+		// Assert
+		assert.ErrorIs(t, err, ErrReportQueueFull)
+		assert.Len(t, store.Reports, 1)
+		assert.Len(t, store.DeleteReportCalls, 1)
+	})
+
+	t.Run("rejects unknown types and missing parameters", func(t *testing.T) {
+		service := NewReportService(&repository.MockTransactionRepository{}, repository.NewMockReportStore(), 1, 1)
+
+		_, typeErr := service.CreateReport(ctx, "default", "revenue", params)
+		_, userErr := service.CreateReport(ctx, "default", model.ReportTypeUserSummary, params)
+		_, rangeErr := service.CreateReport(ctx, "default", model.ReportTypeGGR, model.ReportParams{From: params.To, To: params.From})
+
+		assert.ErrorIs(t, typeErr, ErrInvalidReportRequest)
+		assert.ErrorIs(t, userErr, ErrInvalidReportRequest)
+		assert.ErrorIs(t, rangeErr, ErrInvalidReportRequest)
+	})
+
+	t.Run("hides reports from other principals", func(t *testing.T) {
+		// Arrange
+		service := NewReportService(&repository.MockTransactionRepository{}, repository.NewMockReportStore(), 1, 1)
+		job, _ := service.CreateReport(ctx, "default", model.ReportTypeGGR, params)
+
+		// Act
+		_, getErr := service.GetReport(ctx, "jwt:analyst", job.ID)
+		_, cancelErr := service.CancelReport(ctx, "jwt:analyst", job.ID)
+
+		// Assert
+		assert.ErrorIs(t, getErr, repository.ErrReportNotFound)
+		assert.ErrorIs(t, cancelErr, repository.ErrReportNotFound)
+	})
+
+	t.Run("cancelling a running report stops its query", func(t *testing.T) {
+		// Arrange
+		repo, started, stopped := blockingRepo()
+		store := repository.NewMockReportStore()
+		service := NewReportService(repo, store, 1, 1)
+		start(t, service)
+		job, _ := service.CreateReport(ctx, "default", model.ReportTypeDailyWagerVolume, params)
+		<-started
+
+		// Act
+		cancelled, err := service.CancelReport(ctx, "default", job.ID)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, model.ReportStatusCancelled, cancelled.Status)
+		assert.ErrorIs(t, <-stopped, context.Canceled)
+		stored, _ := store.Report(job.ID)
+		assert.Equal(t, model.ReportStatusCancelled, stored.Status)
+	})
+
+	t.Run("a report cancelled while queued never runs", func(t *testing.T) {
+		// Arrange
+		repo := &repository.MockTransactionRepository{}
+		store := repository.NewMockReportStore()
+		service := NewReportService(repo, store, 1, 1)
+		job, _ := service.CreateReport(ctx, "default", model.ReportTypeDailyWagerVolume, params)
+		_, err := service.CancelReport(ctx, "default", job.ID)
+		assert.NoError(t, err)
+
+		// Act
+		stop := start(t, service)
+		stop()
+
+		// Assert
+		stored, _ := store.Report(job.ID)
+		assert.Equal(t, model.ReportStatusCancelled, stored.Status)
+		assert.Empty(t, repo.CalculateDailyWagerVolumeCalls)
+	})
+
+	t.Run("stops a report cancelled through another instance", func(t *testing.T) {
+		// Arrange
+		repo, started, stopped := blockingRepo()
+		store := repository.NewMockReportStore()
+		service := NewReportService(repo, store, 1, 1)
+		service.pollInterval = 5 * time.Millisecond
+		start(t, service)
+		job, _ := service.CreateReport(ctx, "default", model.ReportTypeDailyWagerVolume, params)
+		<-started
+
+		// Act
+		other := NewReportService(repo, store, 1, 1)
+		_, err := other.CancelReport(ctx, "default", job.ID)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.ErrorIs(t, <-stopped, context.Canceled)
+	})
+
+	t.Run("fails a report that runs past its timeout", func(t *testing.T) {
+		// Arrange
+		repo, _, _ := blockingRepo()
+		store := repository.NewMockReportStore()
+		service := NewReportService(repo, store, 1, 1).WithTimeout(20 * time.Millisecond)
+		start(t, service)
+
+		// Act
+		job, _ := service.CreateReport(ctx, "default", model.ReportTypeDailyWagerVolume, params)
+		failed := waitFor(t, store, job.ID, model.ReportStatusFailed)
+
+		// Assert
+		assert.Equal(t, "Report did not finish within 20ms", failed.Error)
+		assert.Empty(t, failed.Result)
+	})
+
+	t.Run("fails a report without exposing the query error", func(t *testing.T) {
+		// Arrange
+		repo := &repository.MockTransactionRepository{
+			CalculateUserSummaryFn: func(ctx context.Context, userID string, from, to time.Time) (model.UserSummary, error) {
+				return model.UserSummary{}, errors.New("(Location40324) Unrecognized pipeline stage")
+			},
+		}
+		store := repository.NewMockReportStore()
+		service := NewReportService(repo, store, 1, 1)
+		start(t, service)
+		userParams := model.ReportParams{From: params.From, To: params.To, UserID: "01HRMD5HGTZB3TW3PGYXRD07CQ"}
+
+		// Act
+		job, _ := service.CreateReport(ctx, "default", model.ReportTypeUserSummary, userParams)
+		failed := waitFor(t, store, job.ID, model.ReportStatusFailed)
+
+		// Assert
+		assert.Equal(t, "Report query failed; request it again", failed.Error)
+	})
+
+	t.Run("fails a report whose result the store rejects", func(t *testing.T) {
+		// Arrange
+		repo := &repository.MockTransactionRepository{
+			CalculateGGRFn: func(ctx context.Context, from, to time.Time, bucket model.TimeBucket) ([]model.GGRRow, error) {
+				return []model.GGRRow{{Currency: "ETH"}}, nil
+			},
+		}
+		store := repository.NewMockReportStore()
+		store.UpdateReportFn = func(job model.ReportJob) error {
+			if job.Status == model.ReportStatusSucceeded {
+				return errors.New("BSONObjectTooLarge: object to insert too large")
+			}
+			return nil
+		}
+		service := NewReportService(repo, store, 1, 1)
+		start(t, service)
+
+		// Act
+		job, _ := service.CreateReport(ctx, "default", model.ReportTypeGGR, params)
+		failed := waitFor(t, store, job.ID, model.ReportStatusFailed)
+
+		// Assert
+		assert.Equal(t, reportResultNotStored, failed.Error)
+		assert.Empty(t, failed.Result)
+	})
+
+	t.Run("shutdown fails running and queued reports", func(t *testing.T) {
+		// Arrange
+		repo, started, _ := blockingRepo()
+		store := repository.NewMockReportStore()
+		service := NewReportService(repo, store, 1, 2)
+		stop := start(t, service)
+		running, _ := service.CreateReport(ctx, "default", model.ReportTypeDailyWagerVolume, params)
+		<-started
+		queued, _ := service.CreateReport(ctx, "default", model.ReportTypeDailyWagerVolume, params)
+
+		// Act
+		stop()
+
+		// Assert
+		runningJob, _ := store.Report(running.ID)
+		queuedJob, _ := store.Report(queued.ID)
+		assert.Equal(t, model.ReportStatusFailed, runningJob.Status)
+		assert.Equal(t, "The API shut down while the report ran; request it again", runningJob.Error)
+		assert.Equal(t, model.ReportStatusFailed, queuedJob.Status)
+		assert.Equal(t, "The API shut down before the report ran; request it again", queuedJob.Error)
+	})
+}
@@ -0,0 +1,14 @@
+package service
+
+import (
+	"context"
+
+	"admin-statistics-api/internal/model"
+)
+
+// ReportServiceInterface defines the interface for report services
+type ReportServiceInterface interface {
+	CreateReport(ctx context.Context, owner, reportType string, params model.ReportParams) (model.ReportJob, error)
+	GetReport(ctx context.Context, owner, id string) (model.ReportJob, error)
+	CancelReport(ctx context.Context, owner, id string) (model.ReportJob, error)
+}
@@ -2,155 +2,346 @@ package service
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
-	"strconv"
+	"strings"
+	"sync/atomic"
 	"time"
 
+	"admin-statistics-api/internal/logging"
+	"admin-statistics-api/internal/model"
 	"admin-statistics-api/internal/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"golang.org/x/sync/singleflight"
 )
 
+// MaxTransactionBatchSize is the largest number of transactions accepted in one ingestion call
+const MaxTransactionBatchSize = 1000
+
+// ErrInvalidTransaction is returned when a transaction fails validation
+var ErrInvalidTransaction = errors.New("invalid transaction")
+
 // TransactionService provides business logic for transactions
 type TransactionService struct {
-	repo  repository.TransactionRepositoryInterface
-	cache repository.Cache
+	repo       repository.TransactionRepositoryInterface
+	cache      repository.Cache
+	flights    singleflight.Group // Queries in progress in this process, by cache key
+	locker     repository.Locker  // Coalesces queries across instances; nil when disabled
+	lockWait   time.Duration      // How long to wait for another instance's query
+	staleGrace time.Duration      // How long past its freshness a result may still be served
+	hotKeys    *hotKeys           // Requests per cache key for the cache warmer; nil when disabled
+	warmKeys   int                // How many of the most requested keys the warmer refreshes
+
+	// cacheTTLs is how long results stay fresh, replaced as a whole by WithCacheTTLs
+	cacheTTLs atomic.Pointer[cacheTTLs]
+
+	// invalidations counts calls to InvalidateCache, so that a query that was running
+	// during one does not cache a result that may miss the new transactions
+	invalidations atomic.Uint64
 }
 
 // NewTransactionService creates a new TransactionService
 func NewTransactionService(repo repository.TransactionRepositoryInterface, cache repository.Cache) *TransactionService {
-	return &TransactionService{
+	s := &TransactionService{
 		repo:  repo,
 		cache: cache,
 	}
+	s.WithCacheTTLs(cacheExpiration, nil)
+	return s
 }
 
-// CalculateGGR calculates the Gross Gaming Revenue
-func (s *TransactionService) CalculateGGR(ctx context.Context, from, to time.Time) ([]map[string]interface{}, error) {
-	// Create cache key
-	cacheKey := fmt.Sprintf("ggr:%s:%s", from.Format(time.RFC3339), to.Format(time.RFC3339))
-
-	// Check cache
-	if cachedData, found := s.cache.Get(cacheKey); found {
-		// When using Redis, we need to handle the type conversion correctly
-		switch data := cachedData.(type) {
-		case []map[string]interface{}:
-			return data, nil
-		case []interface{}:
-			// Convert from generic slice to the expected type
-			result := make([]map[string]interface{}, len(data))
-			for i, item := range data {
-				if mapItem, ok := item.(map[string]interface{}); ok {
-					result[i] = mapItem
-				}
-			}
-			return result, nil
-		default:
-			// If we can't properly convert, just fetch from DB
-			log.Printf("Cache type mismatch for key %s, fetching from DB", cacheKey)
-		}
+// WithLocker makes instances sharing the cache coalesce their queries too. An instance
+// that misses the cache while another one runs the same query waits up to lockWait for
+// the result to be cached, then runs the query itself.
+func (s *TransactionService) WithLocker(locker repository.Locker, lockWait time.Duration) *TransactionService {
+	s.locker = locker
+	s.lockWait = lockWait
+	return s
+}
+
+// WithStaleGrace keeps results cached for grace past their freshness. A request for a
+// stale result gets it right away while the result is refreshed in the background.
+func (s *TransactionService) WithStaleGrace(grace time.Duration) *TransactionService {
+	s.staleGrace = grace
+	return s
+}
+
+// cacheTTLs are how long query results stay fresh in the cache
+type cacheTTLs struct {
+	fallback time.Duration
+	byPrefix map[string]time.Duration // By cache key prefix, e.g. "leaderboard"
+}
+
+// WithCacheTTLs sets how long query results stay fresh in the cache: ttl, or the TTL in
+// byPrefix for the prefix of their cache key, such as "leaderboard". It is safe to call
+// while serving, and applies to results cached afterwards.
+func (s *TransactionService) WithCacheTTLs(ttl time.Duration, byPrefix map[string]time.Duration) *TransactionService {
+	s.cacheTTLs.Store(&cacheTTLs{fallback: ttl, byPrefix: byPrefix})
+	return s
+}
+
+// cacheTTL returns how long the result cached under cacheKey stays fresh
+func (s *TransactionService) cacheTTL(cacheKey string) time.Duration {
+	ttls := s.cacheTTLs.Load()
+	prefix, _, _ := strings.Cut(cacheKey, ":")
+	if ttl, ok := ttls.byPrefix[prefix]; ok {
+		return ttl
 	}
+	return ttls.fallback
+}
 
-	// Query the repository
-	results, err := s.repo.CalculateGGR(ctx, from, to)
-	if err != nil {
-		return nil, err
+// CalculateGGR calculates the Gross Gaming Revenue, as one total or per time bucket
+func (s *TransactionService) CalculateGGR(ctx context.Context, from, to time.Time, bucket model.TimeBucket) ([]model.GGRRow, error) {
+	// Create cache key
+	cacheKey := fmt.Sprintf("ggr:%s%s", rangeKey(ctx, from, to), bucketKey(bucket))
+
+	// Check the cache, or query the repository once for all concurrent callers
+	return coalesce(ctx, s, cacheKey, from, to, func(ctx context.Context, from, to time.Time) ([]model.GGRRow, error) {
+		return s.repo.CalculateGGR(ctx, from, to, bucket)
+	})
+}
+
+// CalculateGGRSeries calculates wager, payout, GGR and hold percentage per time bucket, which defaults to a UTC day
+func (s *TransactionService) CalculateGGRSeries(ctx context.Context, from, to time.Time, bucket model.TimeBucket) ([]model.GGRSeriesRow, error) {
+	if bucket.IsZero() {
+		bucket.Granularity = model.GranularityDay
 	}
 
-	// Convert to a more generic type
-	response := make([]map[string]interface{}, len(results))
-	for i, result := range results {
-		response[i] = result
+	// Create cache key
+	cacheKey := fmt.Sprintf("ggr_series:%s%s", rangeKey(ctx, from, to), bucketKey(bucket))
+
+	// Check the cache, or query the repository once for all concurrent callers
+	return coalesce(ctx, s, cacheKey, from, to, func(ctx context.Context, from, to time.Time) ([]model.GGRSeriesRow, error) {
+		return s.repo.CalculateGGRSeries(ctx, from, to, bucket)
+	})
+}
+
+// CalculateDailyWagerVolume calculates wager volume per time bucket, which defaults to a UTC day
+func (s *TransactionService) CalculateDailyWagerVolume(ctx context.Context, from, to time.Time, bucket model.TimeBucket) ([]model.DailyWagerRow, error) {
+	// A UTC day is the default bucket and keeps the original cache key
+	if bucket.Granularity == model.GranularityDay && bucket.Location() == "UTC" {
+		bucket = model.TimeBucket{}
 	}
 
-	// Cache the results
-	s.cache.Set(cacheKey, response, 5*time.Minute)
+	// Create cache key
+	cacheKey := fmt.Sprintf("daily_wager:%s%s", rangeKey(ctx, from, to), bucketKey(bucket))
 
-	return response, nil
+	// Check the cache, or query the repository once for all concurrent callers
+	return coalesce(ctx, s, cacheKey, from, to, func(ctx context.Context, from, to time.Time) ([]model.DailyWagerRow, error) {
+		return s.repo.CalculateDailyWagerVolume(ctx, from, to, bucket)
+	})
 }
 
-// CalculateDailyWagerVolume calculates daily wager volume
-func (s *TransactionService) CalculateDailyWagerVolume(ctx context.Context, from, to time.Time) ([]map[string]interface{}, error) {
+// CalculateUserSummary calculates a user's activity totals per currency and in USD
+func (s *TransactionService) CalculateUserSummary(ctx context.Context, userID string, from, to time.Time) (model.UserSummary, error) {
 	// Create cache key
-	cacheKey := fmt.Sprintf("daily_wager:%s:%s", from.Format(time.RFC3339), to.Format(time.RFC3339))
-
-	// Check cache
-	if cachedData, found := s.cache.Get(cacheKey); found {
-		// When using Redis, we need to handle the type conversion correctly
-		switch data := cachedData.(type) {
-		case []map[string]interface{}:
-			return data, nil
-		case []interface{}:
-			// Convert from generic slice to the expected type
-			result := make([]map[string]interface{}, len(data))
-			for i, item := range data {
-				if mapItem, ok := item.(map[string]interface{}); ok {
-					result[i] = mapItem
-				}
-			}
-			return result, nil
-		default:
-			// If we can't properly convert, just fetch from DB
-			log.Printf("Cache type mismatch for key %s, fetching from DB", cacheKey)
-		}
-	}
+	cacheKey := fmt.Sprintf("user_summary:%s:%s", userID, rangeKey(ctx, from, to))
 
-	// Query the repository
-	results, err := s.repo.CalculateDailyWagerVolume(ctx, from, to)
-	if err != nil {
-		return nil, err
-	}
+	// Check the cache, or query the repository once for all concurrent callers
+	return coalesce(ctx, s, cacheKey, from, to, func(ctx context.Context, from, to time.Time) (model.UserSummary, error) {
+		return s.repo.CalculateUserSummary(ctx, userID, from, to)
+	})
+}
 
-	// Convert to a more generic type
-	response := make([]map[string]interface{}, len(results))
-	for i, result := range results {
-		response[i] = result
-	}
+// CalculateLeaderboard ranks the top users by wager, payout or GGR
+func (s *TransactionService) CalculateLeaderboard(ctx context.Context, metric, currency string, limit int, from, to time.Time) ([]model.LeaderboardRow, error) {
+	// Create cache key
+	cacheKey := fmt.Sprintf("leaderboard:%s:%s:%d:%s", metric, currency, limit, rangeKey(ctx, from, to))
 
-	// Cache the results
-	s.cache.Set(cacheKey, response, 5*time.Minute)
+	// Check the cache, or query the repository once for all concurrent callers
+	return coalesce(ctx, s, cacheKey, from, to, func(ctx context.Context, from, to time.Time) ([]model.LeaderboardRow, error) {
+		return s.repo.CalculateLeaderboard(ctx, metric, currency, limit, from, to)
+	})
+}
 
-	return response, nil
+// FindRoundAnomalies finds rounds whose wagers and payouts do not reconcile
+func (s *TransactionService) FindRoundAnomalies(ctx context.Context, from, to time.Time) ([]model.RoundAnomaly, error) {
+	// Create cache key
+	cacheKey := fmt.Sprintf("round_anomalies:%s", rangeKey(ctx, from, to))
+
+	// Check the cache, or query the repository once for all concurrent callers
+	return coalesce(ctx, s, cacheKey, from, to, func(ctx context.Context, from, to time.Time) ([]model.RoundAnomaly, error) {
+		return s.repo.FindRoundAnomalies(ctx, from, to)
+	})
 }
 
 // CalculateUserWagerPercentile calculates user's wager percentile
 func (s *TransactionService) CalculateUserWagerPercentile(ctx context.Context, userID string, from, to time.Time) (float64, error) {
 	// Create cache key
-	cacheKey := fmt.Sprintf("percentile:%s:%s:%s", userID, from.Format(time.RFC3339), to.Format(time.RFC3339))
-
-	// Check cache
-	if cachedData, found := s.cache.Get(cacheKey); found {
-		// When using Redis, we need to handle the type conversion correctly
-		switch data := cachedData.(type) {
-		case float64:
-			return data, nil
-		case int:
-			return float64(data), nil
-		case string:
-			// Try to parse string to float64
-			if val, err := strconv.ParseFloat(data, 64); err == nil {
-				return val, nil
-			}
-		case map[string]interface{}:
-			// Sometimes JSON unmarshals numbers into strings or floats
-			if val, ok := data["value"].(float64); ok {
-				return val, nil
-			}
-		default:
-			// If we can't properly convert, just fetch from DB
-			log.Printf("Cache type mismatch for key %s, fetching from DB", cacheKey)
+	cacheKey := fmt.Sprintf("percentile:%s:%s", userID, rangeKey(ctx, from, to))
+
+	// Check the cache, or query the repository once for all concurrent callers
+	return coalesce(ctx, s, cacheKey, from, to, func(ctx context.Context, from, to time.Time) (float64, error) {
+		return s.repo.CalculateUserWagerPercentile(ctx, userID, from, to)
+	})
+}
+
+// StreamRows returns a cursor over the rows of a report type's result, read from the
+// repository as the caller iterates. Streams skip the cache: a large export is neither
+// held in memory nor cached. The caller must close the cursor.
+func (s *TransactionService) StreamRows(ctx context.Context, reportType string, params model.ReportParams) (repository.RowCursor, error) {
+	reportCacheStatus(ctx, CacheBypass)
+	return s.repo.StreamRows(ctx, reportType, params)
+}
+
+// bucketKey returns the cache key suffix identifying a time bucket, empty when there is none
+func bucketKey(bucket model.TimeBucket) string {
+	if bucket.IsZero() {
+		return ""
+	}
+	return fmt.Sprintf(":%s:%s", bucket.Granularity, bucket.Location())
+}
+
+// cachedResult is a query result as stored in the cache. It is served as is until
+// FreshUntil, then served stale while it is refreshed until the cache entry expires.
+type cachedResult[T any] struct {
+	Value      T         `json:"value"`
+	FreshUntil time.Time `json:"freshUntil"`
+}
+
+// fresh reports whether the result can be served without refreshing it. Entries cached
+// before results carried a freshness time are fresh until they expire.
+func (r cachedResult[T]) fresh(now time.Time) bool {
+	return r.FreshUntil.IsZero() || now.Before(r.FreshUntil)
+}
+
+// getCached looks up a result of type T in the cache.
+// In-memory caches hand back the value that was stored, while Redis hands back
+// the generic result of decoding its JSON (maps, slices, strings and float64s).
+// Those generic values are decoded again into T so that a hit returns exactly
+// the same shape as the miss that populated it.
+func getCached[T any](ctx context.Context, cache repository.Cache, cacheKey string) (cachedResult[T], bool) {
+	cachedData, found := cache.Get(ctx, cacheKey)
+	if !found {
+		return cachedResult[T]{}, false
+	}
+
+	switch typed := cachedData.(type) {
+	case cachedResult[T]:
+		return typed, true
+	case T:
+		return cachedResult[T]{Value: typed}, true
+	}
+
+	data, err := json.Marshal(cachedData)
+	if err == nil {
+		var result cachedResult[T]
+		if json.Unmarshal(data, &result) == nil && !result.FreshUntil.IsZero() {
+			return result, true
+		}
+
+		// Fall back to a bare value cached without a freshness time
+		var value T
+		err = json.Unmarshal(data, &value)
+		if err == nil {
+			return cachedResult[T]{Value: value}, true
 		}
 	}
 
-	// Query the repository
-	percentile, err := s.repo.CalculateUserWagerPercentile(ctx, userID, from, to)
-	if err != nil {
-		return 0, err
+	// If we can't properly convert, just fetch from DB
+	logging.FromContext(ctx).Warn("Cache type mismatch, fetching from DB", "key", cacheKey, "error", err)
+	return cachedResult[T]{}, false
+}
+
+// CreateTransactions validates and stores transactions, returning how many were newly inserted.
+// Transactions whose ID already exists are skipped, so retrying a batch is safe.
+func (s *TransactionService) CreateTransactions(ctx context.Context, transactions []model.Transaction) (int, error) {
+	if len(transactions) == 0 {
+		return 0, fmt.Errorf("%w: at least one transaction is required", ErrInvalidTransaction)
+	}
+	if len(transactions) > MaxTransactionBatchSize {
+		return 0, fmt.Errorf("%w: batch size %d exceeds maximum of %d", ErrInvalidTransaction, len(transactions), MaxTransactionBatchSize)
 	}
 
-	// Cache the result
-	s.cache.Set(cacheKey, percentile, 5*time.Minute)
+	// Reject the whole batch if any transaction is invalid
+	seen := make(map[string]struct{}, len(transactions))
+	for i, transaction := range transactions {
+		if err := validateTransaction(transaction); err != nil {
+			return 0, fmt.Errorf("transaction %d: %w", i, err)
+		}
+		if _, ok := seen[transaction.ID]; ok {
+			return 0, fmt.Errorf("transaction %d: %w: duplicate id %s in batch", i, ErrInvalidTransaction, transaction.ID)
+		}
+		seen[transaction.ID] = struct{}{}
+	}
+
+	inserted, err := s.repo.InsertTransactions(ctx, transactions)
+	if inserted > 0 {
+		createdAt := make([]time.Time, len(transactions))
+		for i, transaction := range transactions {
+			createdAt[i] = transaction.CreatedAt
+		}
+		s.InvalidateCache(ctx, createdAt...)
+	}
+	return inserted, err
+}
+
+// InvalidateCache removes the cached results computed over a range that covers one of
+// the createdAt times, so that the next request includes the new transactions. It
+// only removes anything if the cache is a repository.RangeCache.
+func (s *TransactionService) InvalidateCache(ctx context.Context, createdAt ...time.Time) int {
+	s.invalidations.Add(1)
 
-	return percentile, nil
+	rangeCache, ok := s.cache.(repository.RangeCache)
+	if !ok || len(createdAt) == 0 {
+		return 0
+	}
+	return rangeCache.InvalidateTimes(ctx, createdAt...)
+}
+
+// validateTransaction checks that a transaction is well formed before it is stored
+func validateTransaction(t model.Transaction) error {
+	if _, err := model.ParseULID(t.ID); err != nil {
+		return fmt.Errorf("%w: id must be a ULID", ErrInvalidTransaction)
+	}
+	if _, err := model.ParseULID(t.UserID); err != nil {
+		return fmt.Errorf("%w: userId must be a ULID", ErrInvalidTransaction)
+	}
+	if _, err := model.ParseULID(t.RoundID); err != nil {
+		return fmt.Errorf("%w: roundId must be a ULID", ErrInvalidTransaction)
+	}
+	if t.CreatedAt.IsZero() {
+		return fmt.Errorf("%w: createdAt is required", ErrInvalidTransaction)
+	}
+
+	switch t.Type {
+	case model.TransactionTypeWager, model.TransactionTypePayout:
+	default:
+		return fmt.Errorf("%w: type must be %s or %s", ErrInvalidTransaction, model.TransactionTypeWager, model.TransactionTypePayout)
+	}
+
+	switch t.Currency {
+	case model.CurrencyETH, model.CurrencyBTC, model.CurrencyUSDT:
+	default:
+		return fmt.Errorf("%w: unknown currency %q", ErrInvalidTransaction, t.Currency)
+	}
+
+	// A missing or null amount would be summed as 0 by every query
+	if t.Amount == (primitive.Decimal128{}) {
+		return fmt.Errorf("%w: amount is required", ErrInvalidTransaction)
+	}
+	if !isNonNegative(t.Amount) {
+		return fmt.Errorf("%w: amount must be a number >= 0", ErrInvalidTransaction)
+	}
+	if t.USDAmount == (primitive.Decimal128{}) {
+		return fmt.Errorf("%w: usdAmount is required", ErrInvalidTransaction)
+	}
+	if !isNonNegative(t.USDAmount) {
+		return fmt.Errorf("%w: usdAmount must be a number >= 0", ErrInvalidTransaction)
+	}
+
+	return nil
+}
+
+// isNonNegative reports whether d is a finite decimal greater than or equal to zero
+func isNonNegative(d primitive.Decimal128) bool {
+	coefficient, _, err := d.BigInt()
+	if err != nil {
+		// NaN and Infinity cannot be represented as a big.Int
+		return false
+	}
+	return coefficient.Sign() >= 0
 }
 
 // Ensure TransactionService implements TransactionServiceInterface
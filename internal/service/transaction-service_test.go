@@ -6,9 +6,11 @@ import (
 	"testing"
 	"time"
 
+	"github.com/alicebob/miniredis/v2"
 	"github.com/stretchr/testify/assert"
+	"admin-statistics-api/internal/model"
 	"admin-statistics-api/internal/repository"
-	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 func TestCalculateGGR(t *testing.T) {
@@ -26,17 +28,17 @@ func TestCalculateGGR(t *testing.T) {
 	// Test cases
 	t.Run("returns cached data when available", func(t *testing.T) {
 		// Arrange
-		cachedResult := []map[string]interface{}{
+		cachedResult := []model.GGRRow{
 			{
-				"currency": "BTC",
-				"ggr":      "10.50",
-				"ggrUSD":   "525000.00",
+				Currency: "BTC",
+				GGR:      model.MustParseDecimal("10.50"),
+				GGRUSD:   model.MustParseDecimal("525000.00"),
 			},
 		}
-		mockCache.Set(cacheKey, cachedResult, time.Minute)
+		mockCache.Set(ctx, cacheKey, cachedResult, time.Minute)
 
 		// Act
-		result, err := service.CalculateGGR(ctx, from, to)
+		result, err := service.CalculateGGR(ctx, from, to, model.TimeBucket{})
 
 		// Assert
 		assert.NoError(t, err)
@@ -52,24 +54,24 @@ func TestCalculateGGR(t *testing.T) {
 		service = NewTransactionService(mockRepo, mockCache)
 
 		// Setup expected repository response
-		repoResult := []bson.M{
+		repoResult := []model.GGRRow{
 			{
-				"currency": "BTC",
-				"ggr":      "10.50",
-				"ggrUSD":   "525000.00",
+				Currency: "BTC",
+				GGR:      model.MustParseDecimal("10.50"),
+				GGRUSD:   model.MustParseDecimal("525000.00"),
 			},
 		}
-		mockRepo.CalculateGGRFn = func(ctx context.Context, from, to time.Time) ([]bson.M, error) {
+		mockRepo.CalculateGGRFn = func(ctx context.Context, from, to time.Time, bucket model.TimeBucket) ([]model.GGRRow, error) {
 			return repoResult, nil
 		}
 
 		// Act
-		result, err := service.CalculateGGR(ctx, from, to)
+		result, err := service.CalculateGGR(ctx, from, to, model.TimeBucket{})
 
 		// Assert
 		assert.NoError(t, err)
 		assert.Len(t, result, 1)
-		assert.Equal(t, "BTC", result[0]["currency"])
+		assert.Equal(t, "BTC", result[0].Currency)
 		assert.Len(t, mockRepo.CalculateGGRCalls, 1, "Repository should be called when cache miss")
 		assert.Contains(t, mockCache.GetCalls, cacheKey, "Cache should be queried")
 		assert.Contains(t, mockCache.SetCalls, cacheKey, "Result should be cached")
@@ -83,12 +85,12 @@ func TestCalculateGGR(t *testing.T) {
 
 		// Setup expected repository error
 		expectedError := errors.New("database error")
-		mockRepo.CalculateGGRFn = func(ctx context.Context, from, to time.Time) ([]bson.M, error) {
+		mockRepo.CalculateGGRFn = func(ctx context.Context, from, to time.Time, bucket model.TimeBucket) ([]model.GGRRow, error) {
 			return nil, expectedError
 		}
 
 		// Act
-		result, err := service.CalculateGGR(ctx, from, to)
+		result, err := service.CalculateGGR(ctx, from, to, model.TimeBucket{})
 
 		// Assert
 		assert.Error(t, err)
@@ -98,6 +100,102 @@ func TestCalculateGGR(t *testing.T) {
 	})
 }
 
+func TestCalculateGGR_Bucketing(t *testing.T) {
+	// Test data
+	ctx := context.Background()
+	from := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2023, 1, 31, 0, 0, 0, 0, time.UTC)
+
+	t.Run("caches each bucket separately", func(t *testing.T) {
+		// Arrange
+		mockRepo := repository.NewMockTransactionRepository()
+		mockCache := repository.NewMockCache()
+		service := NewTransactionService(mockRepo, mockCache)
+		hourly := model.TimeBucket{Granularity: model.GranularityHour, Timezone: "Asia/Tokyo"}
+
+		// Act
+		_, err := service.CalculateGGR(ctx, from, to, model.TimeBucket{})
+		assert.NoError(t, err)
+		_, err = service.CalculateGGR(ctx, from, to, hourly)
+		assert.NoError(t, err)
+
+		// Assert
+		assert.Len(t, mockRepo.CalculateGGRCalls, 2)
+		assert.Equal(t, hourly, mockRepo.CalculateGGRCalls[1].Bucket)
+		assert.Contains(t, mockCache.SetCalls, "ggr:2023-01-01T00:00:00Z:2023-01-31T00:00:00Z")
+		assert.Contains(t, mockCache.SetCalls, "ggr:2023-01-01T00:00:00Z:2023-01-31T00:00:00Z:hour:Asia/Tokyo")
+	})
+}
+
+func TestCalculateGGRSeries(t *testing.T) {
+	// Test data
+	ctx := context.Background()
+	from := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2023, 1, 31, 0, 0, 0, 0, time.UTC)
+	cacheKey := "ggr_series:2023-01-01T00:00:00Z:2023-01-31T00:00:00Z:day:UTC"
+
+	t.Run("returns cached data", func(t *testing.T) {
+		// Arrange
+		mockRepo := repository.NewMockTransactionRepository()
+		mockCache := repository.NewMockCache()
+		service := NewTransactionService(mockRepo, mockCache)
+
+		// Redis returns JSON-decoded values as []interface{}
+		mockCache.Set(ctx, cacheKey, []interface{}{
+			map[string]interface{}{"date": "2023-01-01", "currency": "BTC", "ggr": "1.5", "holdPercent": "3.00"},
+		}, time.Minute)
+
+		// Act
+		result, err := service.CalculateGGRSeries(ctx, from, to, model.TimeBucket{})
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Len(t, result, 1)
+		assert.Equal(t, "1.5", result[0].GGR.String())
+		assert.Equal(t, "3.00", result[0].HoldPercent.String())
+		assert.Len(t, mockRepo.CalculateGGRSeriesCalls, 0, "Repository should not be called when cache hit")
+	})
+
+	t.Run("defaults to UTC days and caches data", func(t *testing.T) {
+		// Arrange
+		mockRepo := repository.NewMockTransactionRepository()
+		mockCache := repository.NewMockCache()
+		service := NewTransactionService(mockRepo, mockCache)
+
+		mockRepo.CalculateGGRSeriesFn = func(ctx context.Context, from, to time.Time, bucket model.TimeBucket) ([]model.GGRSeriesRow, error) {
+			return []model.GGRSeriesRow{{Date: "2023-01-01", Currency: "BTC"}}, nil
+		}
+
+		// Act
+		result, err := service.CalculateGGRSeries(ctx, from, to, model.TimeBucket{})
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Len(t, result, 1)
+		assert.Len(t, mockRepo.CalculateGGRSeriesCalls, 1, "Repository should be called when cache miss")
+		assert.Equal(t, model.TimeBucket{Granularity: model.GranularityDay}, mockRepo.CalculateGGRSeriesCalls[0].Bucket)
+		assert.Contains(t, mockCache.SetCalls, cacheKey, "Result should be cached")
+	})
+
+	t.Run("handles repository errors", func(t *testing.T) {
+		// Arrange
+		mockRepo := repository.NewMockTransactionRepository()
+		service := NewTransactionService(mockRepo, repository.NewMockCache())
+
+		expectedError := errors.New("database error")
+		mockRepo.CalculateGGRSeriesFn = func(ctx context.Context, from, to time.Time, bucket model.TimeBucket) ([]model.GGRSeriesRow, error) {
+			return nil, expectedError
+		}
+
+		// Act
+		result, err := service.CalculateGGRSeries(ctx, from, to, model.TimeBucket{})
+
+		// Assert
+		assert.Equal(t, expectedError, err)
+		assert.Nil(t, result)
+	})
+}
+
 func TestCalculateDailyWagerVolume(t *testing.T) {
 	// Setup
 	mockRepo := repository.NewMockTransactionRepository()
@@ -113,18 +211,18 @@ func TestCalculateDailyWagerVolume(t *testing.T) {
 	// Test cases
 	t.Run("returns cached data when available", func(t *testing.T) {
 		// Arrange
-		cachedResult := []map[string]interface{}{
+		cachedResult := []model.DailyWagerRow{
 			{
-				"date":           "2023-01-01",
-				"currency":       "ETH",
-				"wagerAmount":    "150.75",
-				"wagerUSDAmount": "301500.00",
+				Date:           "2023-01-01",
+				Currency:       "ETH",
+				WagerAmount:    model.MustParseDecimal("150.75"),
+				WagerUSDAmount: model.MustParseDecimal("301500.00"),
 			},
 		}
-		mockCache.Set(cacheKey, cachedResult, time.Minute)
+		mockCache.Set(ctx, cacheKey, cachedResult, time.Minute)
 
 		// Act
-		result, err := service.CalculateDailyWagerVolume(ctx, from, to)
+		result, err := service.CalculateDailyWagerVolume(ctx, from, to, model.TimeBucket{})
 
 		// Assert
 		assert.NoError(t, err)
@@ -139,27 +237,289 @@ func TestCalculateDailyWagerVolume(t *testing.T) {
 		service = NewTransactionService(mockRepo, mockCache)
 
 		// Setup expected repository response
-		repoResult := []bson.M{
+		repoResult := []model.DailyWagerRow{
 			{
-				"date":           "2023-01-01",
-				"currency":       "ETH",
-				"wagerAmount":    "150.75",
-				"wagerUSDAmount": "301500.00",
+				Date:           "2023-01-01",
+				Currency:       "ETH",
+				WagerAmount:    model.MustParseDecimal("150.75"),
+				WagerUSDAmount: model.MustParseDecimal("301500.00"),
 			},
 		}
-		mockRepo.CalculateDailyWagerVolumeFn = func(ctx context.Context, from, to time.Time) ([]bson.M, error) {
+		mockRepo.CalculateDailyWagerVolumeFn = func(ctx context.Context, from, to time.Time, bucket model.TimeBucket) ([]model.DailyWagerRow, error) {
 			return repoResult, nil
 		}
 
 		// Act
-		result, err := service.CalculateDailyWagerVolume(ctx, from, to)
+		result, err := service.CalculateDailyWagerVolume(ctx, from, to, model.TimeBucket{})
 
 		// Assert
 		assert.NoError(t, err)
 		assert.Len(t, result, 1)
-		assert.Equal(t, "2023-01-01", result[0]["date"])
+		assert.Equal(t, "2023-01-01", result[0].Date)
 		assert.Len(t, mockRepo.CalculateDailyWagerVolumeCalls, 1, "Repository should be called when cache miss")
 	})
+
+	t.Run("UTC day shares the default cache key", func(t *testing.T) {
+		// Arrange
+		mockRepo = repository.NewMockTransactionRepository()
+		mockCache = repository.NewMockCache()
+		service = NewTransactionService(mockRepo, mockCache)
+
+		// Act
+		_, err := service.CalculateDailyWagerVolume(ctx, from, to, model.TimeBucket{Granularity: model.GranularityDay, Timezone: "UTC"})
+		assert.NoError(t, err)
+		_, err = service.CalculateDailyWagerVolume(ctx, from, to, model.TimeBucket{Granularity: model.GranularityDay, Timezone: "Asia/Singapore"})
+		assert.NoError(t, err)
+
+		// Assert
+		assert.Contains(t, mockCache.SetCalls, cacheKey)
+		assert.Contains(t, mockCache.SetCalls, cacheKey+":day:Asia/Singapore")
+		assert.Len(t, mockRepo.CalculateDailyWagerVolumeCalls, 2)
+	})
+}
+
+func TestCalculateUserSummary(t *testing.T) {
+	// Test data
+	ctx := context.Background()
+	userID := "01HRMD5HGTZB3TW3PGYXRD07CQT"
+	from := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2023, 1, 31, 0, 0, 0, 0, time.UTC)
+	cacheKey := "user_summary:" + userID + ":2023-01-01T00:00:00Z:2023-01-31T00:00:00Z"
+
+	t.Run("returns cached data when available", func(t *testing.T) {
+		// Arrange
+		mockRepo := repository.NewMockTransactionRepository()
+		mockCache := repository.NewMockCache()
+		service := NewTransactionService(mockRepo, mockCache)
+
+		cachedResult := model.UserSummary{
+			Currencies: []model.UserCurrencySummary{},
+			Total:      &model.UserTotalSummary{Rounds: 4},
+		}
+		mockCache.Set(ctx, cacheKey, cachedResult, time.Minute)
+
+		// Act
+		result, err := service.CalculateUserSummary(ctx, userID, from, to)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, cachedResult, result)
+		assert.Len(t, mockRepo.CalculateUserSummaryCalls, 0, "Repository should not be called when cache hit")
+	})
+
+	t.Run("fetches and caches data when not in cache", func(t *testing.T) {
+		// Arrange
+		mockRepo := repository.NewMockTransactionRepository()
+		mockCache := repository.NewMockCache()
+		service := NewTransactionService(mockRepo, mockCache)
+
+		mockRepo.CalculateUserSummaryFn = func(ctx context.Context, userID string, from, to time.Time) (model.UserSummary, error) {
+			return model.UserSummary{
+				Currencies: []model.UserCurrencySummary{{Currency: "ETH", Rounds: 2}},
+				Total:      &model.UserTotalSummary{Rounds: 2},
+			}, nil
+		}
+
+		// Act
+		result, err := service.CalculateUserSummary(ctx, userID, from, to)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Len(t, result.Currencies, 1)
+		assert.Equal(t, 2, result.Total.Rounds)
+		assert.Len(t, mockRepo.CalculateUserSummaryCalls, 1, "Repository should be called when cache miss")
+		assert.Equal(t, userID, mockRepo.CalculateUserSummaryCalls[0].UserID)
+		assert.Contains(t, mockCache.SetCalls, cacheKey, "Result should be cached")
+	})
+
+	t.Run("handles repository errors", func(t *testing.T) {
+		// Arrange
+		mockRepo := repository.NewMockTransactionRepository()
+		service := NewTransactionService(mockRepo, repository.NewMockCache())
+
+		expectedError := errors.New("database error")
+		mockRepo.CalculateUserSummaryFn = func(ctx context.Context, userID string, from, to time.Time) (model.UserSummary, error) {
+			return model.UserSummary{}, expectedError
+		}
+
+		// Act
+		result, err := service.CalculateUserSummary(ctx, userID, from, to)
+
+		// Assert
+		assert.Equal(t, expectedError, err)
+		assert.Nil(t, result.Total)
+	})
+}
+
+func TestCalculateLeaderboard(t *testing.T) {
+	// Test data
+	ctx := context.Background()
+	from := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2023, 1, 31, 0, 0, 0, 0, time.UTC)
+	cacheKey := "leaderboard:ggr:BTC:10:2023-01-01T00:00:00Z:2023-01-31T00:00:00Z"
+
+	t.Run("returns cached data when available", func(t *testing.T) {
+		// Arrange
+		mockRepo := repository.NewMockTransactionRepository()
+		mockCache := repository.NewMockCache()
+		service := NewTransactionService(mockRepo, mockCache)
+
+		cachedResult := []model.LeaderboardRow{
+			{UserID: "01HRMD5HGTZB3TW3PGYXRD07CQ", Rank: 1},
+		}
+		mockCache.Set(ctx, cacheKey, cachedResult, time.Minute)
+
+		// Act
+		result, err := service.CalculateLeaderboard(ctx, "ggr", "BTC", 10, from, to)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, cachedResult, result)
+		assert.Len(t, mockRepo.CalculateLeaderboardCalls, 0, "Repository should not be called when cache hit")
+	})
+
+	t.Run("caches each metric separately", func(t *testing.T) {
+		// Arrange
+		mockRepo := repository.NewMockTransactionRepository()
+		mockCache := repository.NewMockCache()
+		service := NewTransactionService(mockRepo, mockCache)
+
+		mockRepo.CalculateLeaderboardFn = func(ctx context.Context, metric, currency string, limit int, from, to time.Time) ([]model.LeaderboardRow, error) {
+			return []model.LeaderboardRow{{UserID: "01HRMD5HGTZB3TW3PGYXRD07CQ", Rank: 1}}, nil
+		}
+
+		// Act
+		_, err := service.CalculateLeaderboard(ctx, "ggr", "BTC", 10, from, to)
+		assert.NoError(t, err)
+		result, err := service.CalculateLeaderboard(ctx, "wager", "BTC", 10, from, to)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Len(t, result, 1)
+		assert.Len(t, mockRepo.CalculateLeaderboardCalls, 2, "Each metric should query the repository")
+		assert.Equal(t, "wager", mockRepo.CalculateLeaderboardCalls[1].Metric)
+		assert.Contains(t, mockCache.SetCalls, cacheKey, "Result should be cached")
+	})
+
+	t.Run("handles repository errors", func(t *testing.T) {
+		// Arrange
+		mockRepo := repository.NewMockTransactionRepository()
+		service := NewTransactionService(mockRepo, repository.NewMockCache())
+
+		expectedError := errors.New("database error")
+		mockRepo.CalculateLeaderboardFn = func(ctx context.Context, metric, currency string, limit int, from, to time.Time) ([]model.LeaderboardRow, error) {
+			return nil, expectedError
+		}
+
+		// Act
+		result, err := service.CalculateLeaderboard(ctx, "wager", "", 10, from, to)
+
+		// Assert
+		assert.Equal(t, expectedError, err)
+		assert.Nil(t, result)
+	})
+}
+
+func TestFindRoundAnomalies(t *testing.T) {
+	// Test data
+	ctx := context.Background()
+	from := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2023, 1, 31, 0, 0, 0, 0, time.UTC)
+	cacheKey := "round_anomalies:2023-01-01T00:00:00Z:2023-01-31T00:00:00Z"
+
+	t.Run("returns cached data from redis shape", func(t *testing.T) {
+		// Arrange
+		mockRepo := repository.NewMockTransactionRepository()
+		mockCache := repository.NewMockCache()
+		service := NewTransactionService(mockRepo, mockCache)
+
+		// Redis returns JSON-decoded values as []interface{}
+		mockCache.Set(ctx, cacheKey, []interface{}{
+			map[string]interface{}{"roundId": "round-1", "wagerCount": float64(2)},
+		}, time.Minute)
+
+		// Act
+		result, err := service.FindRoundAnomalies(ctx, from, to)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Len(t, result, 1)
+		assert.Equal(t, "round-1", result[0].RoundID)
+		assert.Equal(t, 2, result[0].WagerCount)
+		assert.Len(t, mockRepo.FindRoundAnomaliesCalls, 0, "Repository should not be called when cache hit")
+	})
+
+	t.Run("fetches and caches data when not in cache", func(t *testing.T) {
+		// Arrange
+		mockRepo := repository.NewMockTransactionRepository()
+		mockCache := repository.NewMockCache()
+		service := NewTransactionService(mockRepo, mockCache)
+
+		mockRepo.FindRoundAnomaliesFn = func(ctx context.Context, from, to time.Time) ([]model.RoundAnomaly, error) {
+			return []model.RoundAnomaly{
+				{
+					RoundID:   "round-1",
+					Anomalies: []string{model.RoundAnomalyMultipleWagers},
+				},
+			}, nil
+		}
+
+		// Act
+		result, err := service.FindRoundAnomalies(ctx, from, to)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Len(t, result, 1)
+		assert.Equal(t, "round-1", result[0].RoundID)
+		assert.Len(t, mockRepo.FindRoundAnomaliesCalls, 1, "Repository should be called when cache miss")
+		assert.Contains(t, mockCache.SetCalls, cacheKey, "Result should be cached")
+	})
+
+	t.Run("handles repository errors", func(t *testing.T) {
+		// Arrange
+		mockRepo := repository.NewMockTransactionRepository()
+		service := NewTransactionService(mockRepo, repository.NewMockCache())
+
+		expectedError := errors.New("database error")
+		mockRepo.FindRoundAnomaliesFn = func(ctx context.Context, from, to time.Time) ([]model.RoundAnomaly, error) {
+			return nil, expectedError
+		}
+
+		// Act
+		result, err := service.FindRoundAnomalies(ctx, from, to)
+
+		// Assert
+		assert.Equal(t, expectedError, err)
+		assert.Nil(t, result)
+	})
+}
+
+func TestStreamRows(t *testing.T) {
+	t.Run("streams from the repository without the cache", func(t *testing.T) {
+		// Arrange
+		mockRepo := repository.NewMockTransactionRepository()
+		mockCache := repository.NewMockCache()
+		service := NewTransactionService(mockRepo, mockCache)
+		var statuses []CacheStatus
+		ctx := WithCacheStatusReporter(context.Background(), func(status CacheStatus) {
+			statuses = append(statuses, status)
+		})
+		params := model.ReportParams{
+			From: time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC),
+			To:   time.Date(2023, 1, 31, 0, 0, 0, 0, time.UTC),
+		}
+
+		// Act
+		cursor, err := service.StreamRows(ctx, model.ReportTypeRoundAnomalies, params)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.NotNil(t, cursor)
+		assert.Len(t, mockRepo.StreamRowsCalls, 1)
+		assert.Equal(t, params, mockRepo.StreamRowsCalls[0].Params)
+		assert.Empty(t, mockCache.SetCalls)
+		assert.Equal(t, []CacheStatus{CacheBypass}, statuses)
+	})
 }
 
 func TestCalculateUserWagerPercentile(t *testing.T) {
@@ -179,7 +539,7 @@ func TestCalculateUserWagerPercentile(t *testing.T) {
 	t.Run("returns cached data when available", func(t *testing.T) {
 		// Arrange
 		cachedResult := 95.5
-		mockCache.Set(cacheKey, cachedResult, time.Minute)
+		mockCache.Set(ctx, cacheKey, cachedResult, time.Minute)
 
 		// Act
 		result, err := service.CalculateUserWagerPercentile(ctx, userID, from, to)
@@ -232,4 +592,288 @@ func TestCalculateUserWagerPercentile(t *testing.T) {
 		assert.Equal(t, float64(0), result)
 		assert.Len(t, mockRepo.CalculateUserWagerPercentileCalls, 1, "Repository should be called when cache miss")
 	})
-}
\ No newline at end of file
+}
+
+func TestCreateTransactions(t *testing.T) {
+	ctx := context.Background()
+
+	// validTransaction returns a transaction that passes validation
+	validTransaction := func() model.Transaction {
+		amount, _ := primitive.ParseDecimal128("10.50")
+		usdAmount, _ := primitive.ParseDecimal128("21000.00")
+		return model.Transaction{
+			ID:        model.GenerateULID(),
+			CreatedAt: time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC),
+			UserID:    "01HRMD5HGTZB3TW3PGYXRD07CQ",
+			RoundID:   "01HRMD5HGTZB3TW3PGYXRD07CS",
+			Type:      model.TransactionTypeWager,
+			Amount:    amount,
+			Currency:  model.CurrencyETH,
+			USDAmount: usdAmount,
+		}
+	}
+
+	t.Run("stores valid transactions", func(t *testing.T) {
+		// Arrange
+		mockRepo := repository.NewMockTransactionRepository()
+		service := NewTransactionService(mockRepo, repository.NewMockCache())
+		transactions := []model.Transaction{validTransaction(), validTransaction()}
+
+		// Act
+		inserted, err := service.CreateTransactions(ctx, transactions)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, 2, inserted)
+		assert.Len(t, mockRepo.InsertTransactionsCalls, 1)
+		assert.Equal(t, transactions, mockRepo.InsertTransactionsCalls[0])
+	})
+
+	t.Run("invalidates cached results covering the new transactions", func(t *testing.T) {
+		// Arrange
+		mockCache := repository.NewMockCache()
+		service := NewTransactionService(repository.NewMockTransactionRepository(), mockCache)
+		transaction := validTransaction()
+
+		// Act
+		_, err := service.CreateTransactions(ctx, []model.Transaction{transaction})
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, [][]time.Time{{transaction.CreatedAt}}, mockCache.InvalidateCalls)
+	})
+
+	t.Run("does not invalidate when every transaction was a duplicate", func(t *testing.T) {
+		// Arrange
+		mockRepo := repository.NewMockTransactionRepository()
+		mockRepo.InsertTransactionsFn = func(ctx context.Context, transactions []model.Transaction) (int, error) {
+			return 0, nil
+		}
+		mockCache := repository.NewMockCache()
+		service := NewTransactionService(mockRepo, mockCache)
+
+		// Act
+		_, err := service.CreateTransactions(ctx, []model.Transaction{validTransaction()})
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Empty(t, mockCache.InvalidateCalls)
+	})
+
+	t.Run("passes through duplicate counts from repository", func(t *testing.T) {
+		// Arrange
+		mockRepo := repository.NewMockTransactionRepository()
+		mockRepo.InsertTransactionsFn = func(ctx context.Context, transactions []model.Transaction) (int, error) {
+			return 0, nil
+		}
+		service := NewTransactionService(mockRepo, repository.NewMockCache())
+
+		// Act
+		inserted, err := service.CreateTransactions(ctx, []model.Transaction{validTransaction()})
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, 0, inserted)
+	})
+
+	t.Run("rejects invalid transactions", func(t *testing.T) {
+		negative, _ := primitive.ParseDecimal128("-1")
+		nan, _ := primitive.ParseDecimal128("NaN")
+
+		cases := map[string]func(tx *model.Transaction){
+			"non-ULID id":       func(tx *model.Transaction) { tx.ID = "not-a-ulid" },
+			"non-ULID user id":  func(tx *model.Transaction) { tx.UserID = "" },
+			"missing round id":  func(tx *model.Transaction) { tx.RoundID = "" },
+			"non-ULID round id": func(tx *model.Transaction) { tx.RoundID = "round-1" },
+			"missing createdAt": func(tx *model.Transaction) { tx.CreatedAt = time.Time{} },
+			"unknown type":      func(tx *model.Transaction) { tx.Type = "Bonus" },
+			"unknown currency":  func(tx *model.Transaction) { tx.Currency = "DOGE" },
+			"negative amount":   func(tx *model.Transaction) { tx.Amount = negative },
+			"NaN usd amount":    func(tx *model.Transaction) { tx.USDAmount = nan },
+			"missing amount":    func(tx *model.Transaction) { tx.Amount = primitive.Decimal128{} },
+			"null usdAmount":    func(tx *model.Transaction) { tx.USDAmount = primitive.Decimal128{} },
+		}
+
+		for name, mutate := range cases {
+			t.Run(name, func(t *testing.T) {
+				// Arrange
+				mockRepo := repository.NewMockTransactionRepository()
+				service := NewTransactionService(mockRepo, repository.NewMockCache())
+				transaction := validTransaction()
+				mutate(&transaction)
+
+				// Act
+				_, err := service.CreateTransactions(ctx, []model.Transaction{validTransaction(), transaction})
+
+				// Assert
+				assert.ErrorIs(t, err, ErrInvalidTransaction)
+				assert.Len(t, mockRepo.InsertTransactionsCalls, 0, "Repository should not be called for invalid batch")
+			})
+		}
+	})
+
+	t.Run("rejects duplicate ids within a batch", func(t *testing.T) {
+		// Arrange
+		mockRepo := repository.NewMockTransactionRepository()
+		service := NewTransactionService(mockRepo, repository.NewMockCache())
+		transaction := validTransaction()
+
+		// Act
+		_, err := service.CreateTransactions(ctx, []model.Transaction{transaction, transaction})
+
+		// Assert
+		assert.ErrorIs(t, err, ErrInvalidTransaction)
+		assert.Len(t, mockRepo.InsertTransactionsCalls, 0)
+	})
+
+	t.Run("rejects empty and oversized batches", func(t *testing.T) {
+		// Arrange
+		mockRepo := repository.NewMockTransactionRepository()
+		service := NewTransactionService(mockRepo, repository.NewMockCache())
+
+		// Act
+		_, emptyErr := service.CreateTransactions(ctx, nil)
+		_, oversizedErr := service.CreateTransactions(ctx, make([]model.Transaction, MaxTransactionBatchSize+1))
+
+		// Assert
+		assert.ErrorIs(t, emptyErr, ErrInvalidTransaction)
+		assert.ErrorIs(t, oversizedErr, ErrInvalidTransaction)
+	})
+
+	t.Run("handles repository errors", func(t *testing.T) {
+		// Arrange
+		mockRepo := repository.NewMockTransactionRepository()
+		expectedError := errors.New("database error")
+		mockRepo.InsertTransactionsFn = func(ctx context.Context, transactions []model.Transaction) (int, error) {
+			return 0, expectedError
+		}
+		service := NewTransactionService(mockRepo, repository.NewMockCache())
+
+		// Act
+		_, err := service.CreateTransactions(ctx, []model.Transaction{validTransaction()})
+
+		// Assert
+		assert.Equal(t, expectedError, err)
+	})
+}
+
+func TestCachedResultsRoundTripThroughRedis(t *testing.T) {
+	// Start a miniredis server
+	s, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("Failed to start miniredis: %v", err)
+	}
+	defer s.Close()
+
+	redisCache, err := repository.NewRedisCache("redis://" + s.Addr())
+	assert.NoError(t, err)
+	defer redisCache.Close()
+
+	// Test data
+	ctx := context.Background()
+	userID := "01HRMD5HGTZB3TW3PGYXRD07CQ"
+	from := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2023, 1, 31, 0, 0, 0, 0, time.UTC)
+	seen := time.Date(2023, 1, 2, 3, 4, 5, 600, time.UTC)
+	btc := model.MustParseDecimal("1.25")
+
+	mockRepo := repository.NewMockTransactionRepository()
+	mockRepo.CalculateGGRFn = func(ctx context.Context, from, to time.Time, bucket model.TimeBucket) ([]model.GGRRow, error) {
+		return []model.GGRRow{
+			{Currency: "BTC", GGR: model.MustParseDecimal("15.230"), GGRUSD: model.MustParseDecimal("761500.00")},
+		}, nil
+	}
+	mockRepo.CalculateGGRSeriesFn = func(ctx context.Context, from, to time.Time, bucket model.TimeBucket) ([]model.GGRSeriesRow, error) {
+		holdPercent := model.MustParseDecimal("4.00")
+		return []model.GGRSeriesRow{
+			{Date: "2023-01-01", Currency: "BTC", Wager: btc, GGR: model.MustParseDecimal("0.05"), HoldPercent: &holdPercent},
+			{Date: "2023-01-02", Currency: "BTC", Payout: btc},
+		}, nil
+	}
+	mockRepo.CalculateUserSummaryFn = func(ctx context.Context, userID string, from, to time.Time) (model.UserSummary, error) {
+		return model.UserSummary{
+			Currencies: []model.UserCurrencySummary{
+				{Currency: "BTC", Wagered: btc, Net: model.MustParseDecimal("-0.5"), Rounds: 3, FirstSeen: seen, LastSeen: seen},
+			},
+			Total: &model.UserTotalSummary{Rounds: 3, FirstSeen: seen, LastSeen: seen},
+		}, nil
+	}
+	mockRepo.CalculateLeaderboardFn = func(ctx context.Context, metric, currency string, limit int, from, to time.Time) ([]model.LeaderboardRow, error) {
+		return []model.LeaderboardRow{
+			{UserID: userID, Rank: 1, Percentile: 100, Wager: &btc, WagerUSD: model.MustParseDecimal("62500")},
+			{UserID: "other", Rank: 2, Percentile: 50},
+		}, nil
+	}
+	mockRepo.CalculateUserWagerPercentileFn = func(ctx context.Context, userID string, from, to time.Time) (float64, error) {
+		return 66.66666666666667, nil
+	}
+	service := NewTransactionService(mockRepo, redisCache)
+
+	t.Run("GGR rows", func(t *testing.T) {
+		miss, err := service.CalculateGGR(ctx, from, to, model.TimeBucket{})
+		assert.NoError(t, err)
+		hit, err := service.CalculateGGR(ctx, from, to, model.TimeBucket{})
+		assert.NoError(t, err)
+
+		assert.Equal(t, miss, hit)
+		assert.Equal(t, "15.230", hit[0].GGR.String())
+		assert.Len(t, mockRepo.CalculateGGRCalls, 1, "Second call should be served from Redis")
+	})
+
+	t.Run("GGR series rows", func(t *testing.T) {
+		miss, err := service.CalculateGGRSeries(ctx, from, to, model.TimeBucket{})
+		assert.NoError(t, err)
+		hit, err := service.CalculateGGRSeries(ctx, from, to, model.TimeBucket{})
+		assert.NoError(t, err)
+
+		assert.Equal(t, miss, hit)
+		assert.Nil(t, hit[1].HoldPercent)
+		assert.Len(t, mockRepo.CalculateGGRSeriesCalls, 1, "Second call should be served from Redis")
+	})
+
+	t.Run("user summary", func(t *testing.T) {
+		miss, err := service.CalculateUserSummary(ctx, userID, from, to)
+		assert.NoError(t, err)
+		hit, err := service.CalculateUserSummary(ctx, userID, from, to)
+		assert.NoError(t, err)
+
+		assert.Equal(t, miss, hit)
+		assert.Len(t, mockRepo.CalculateUserSummaryCalls, 1, "Second call should be served from Redis")
+	})
+
+	t.Run("leaderboard rows", func(t *testing.T) {
+		miss, err := service.CalculateLeaderboard(ctx, "wager", "BTC", 10, from, to)
+		assert.NoError(t, err)
+		hit, err := service.CalculateLeaderboard(ctx, "wager", "BTC", 10, from, to)
+		assert.NoError(t, err)
+
+		assert.Equal(t, miss, hit)
+		assert.Nil(t, hit[1].Wager)
+		assert.Len(t, mockRepo.CalculateLeaderboardCalls, 1, "Second call should be served from Redis")
+	})
+
+	t.Run("percentile", func(t *testing.T) {
+		miss, err := service.CalculateUserWagerPercentile(ctx, userID, from, to)
+		assert.NoError(t, err)
+		hit, err := service.CalculateUserWagerPercentile(ctx, userID, from, to)
+		assert.NoError(t, err)
+
+		assert.Equal(t, miss, hit)
+		assert.Len(t, mockRepo.CalculateUserWagerPercentileCalls, 1, "Second call should be served from Redis")
+	})
+
+	t.Run("falls back to repository on undecodable cache entry", func(t *testing.T) {
+		// Arrange
+		mockCache := repository.NewMockCache()
+		fallbackRepo := repository.NewMockTransactionRepository()
+		fallbackService := NewTransactionService(fallbackRepo, mockCache)
+		mockCache.Set(ctx, "ggr:2023-01-01T00:00:00Z:2023-01-31T00:00:00Z", "not a list of rows", time.Minute)
+
+		// Act
+		_, err := fallbackService.CalculateGGR(ctx, from, to, model.TimeBucket{})
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Len(t, fallbackRepo.CalculateGGRCalls, 1)
+	})
+}
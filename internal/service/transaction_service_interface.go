@@ -3,11 +3,20 @@ package service
 import (
 	"context"
 	"time"
+
+	"admin-statistics-api/internal/model"
+	"admin-statistics-api/internal/repository"
 )
 
 // TransactionServiceInterface defines the interface for transaction services
 type TransactionServiceInterface interface {
-	CalculateGGR(ctx context.Context, from, to time.Time) ([]map[string]interface{}, error)
-	CalculateDailyWagerVolume(ctx context.Context, from, to time.Time) ([]map[string]interface{}, error)
+	CalculateGGR(ctx context.Context, from, to time.Time, bucket model.TimeBucket) ([]model.GGRRow, error)
+	CalculateGGRSeries(ctx context.Context, from, to time.Time, bucket model.TimeBucket) ([]model.GGRSeriesRow, error)
+	CalculateDailyWagerVolume(ctx context.Context, from, to time.Time, bucket model.TimeBucket) ([]model.DailyWagerRow, error)
 	CalculateUserWagerPercentile(ctx context.Context, userID string, from, to time.Time) (float64, error)
+	CalculateUserSummary(ctx context.Context, userID string, from, to time.Time) (model.UserSummary, error)
+	CalculateLeaderboard(ctx context.Context, metric, currency string, limit int, from, to time.Time) ([]model.LeaderboardRow, error)
+	FindRoundAnomalies(ctx context.Context, from, to time.Time) ([]model.RoundAnomaly, error)
+	StreamRows(ctx context.Context, reportType string, params model.ReportParams) (repository.RowCursor, error)
+	CreateTransactions(ctx context.Context, transactions []model.Transaction) (int, error)
 }
\ No newline at end of file